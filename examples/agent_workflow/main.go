@@ -4,10 +4,17 @@ import (
 	"beluga/pkg/agents"
 	"beluga/pkg/agents/adapter"
 	"beluga/pkg/agents/config"
+	"beluga/pkg/actions"
+	"beluga/pkg/analysis"
+	"beluga/pkg/codec"
 	"beluga/pkg/monitoring"
+	"beluga/pkg/notify"
 	"beluga/pkg/orchestration"
+	"beluga/pkg/rules"
 	"fmt"
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"time"
 )
@@ -45,7 +52,21 @@ func main() {
 	for _, agentConfig := range agentConfigs {
 		log.Printf("Creating agent: %s (%s)", agentConfig.Name, agentConfig.Type)
 		
-		agent, err := factory.CreateAgent(agentConfig.Type, agentConfig.Name, agentConfig.Settings)
+		// The factory records dependencies so agents can be shut down in the
+		// right order
+		agent, err := factory.CreateAgentFromConfig(&agents.AgentConfig{
+			Type:                   agentConfig.Type,
+			Name:                   agentConfig.Name,
+			Role:                   agentConfig.Role,
+			Settings:               agentConfig.Settings,
+			MaxRetries:             agentConfig.MaxRetries,
+			RetryDelay:             agentConfig.RetryDelay,
+			RetryPolicy:            agentConfig.RetryPolicy,
+			HealthCheckRetryPolicy: agentConfig.HealthCheckRetryPolicy,
+			MessagingRetryPolicy:   agentConfig.MessagingRetryPolicy,
+			Dependencies:           agentConfig.Dependencies,
+			Description:            agentConfig.Description,
+		})
 		if err != nil {
 			log.Printf("Failed to create agent %s: %v", agentConfig.Name, err)
 			continue
@@ -55,22 +76,7 @@ func main() {
 		messagingAdapter.RegisterAgent(agentConfig.Name, agent)
 		
 		// Setup health checks
-		healthCheckFunc := monitoring.CreateAgentHealthCheckFunc(func() map[string]interface{} {
-			if baseAgent, ok := agent.(*agents.BaseAgent); ok {
-				return baseAgent.CheckHealth()
-			}
-			return map[string]interface{}{
-				"name":  agentConfig.Name,
-				"state": "unknown",
-			}
-		})
-		
-		healthCheck := monitoring.NewHealthCheck(
-			"agent_health",
-			agentConfig.Name,
-			time.Duration(60)*time.Second,
-			healthCheckFunc,
-		)
+		healthCheck := agents.NewHealthCheck("agent_health", agentConfig.Name, 60*time.Second, agent)
 		
 		healthCheck.RegisterAlert(func(result *monitoring.HealthCheckResult) {
 			log.Printf("Health alert for %s: %s - %s", result.ComponentID, result.Status, result.Message)
@@ -82,12 +88,16 @@ func main() {
 	// 6. Setup message handling
 	setupMessageHandlers(messagingAdapter, factory.Registry)
 	
-	// 7. Create workflow
+	// 7. Create workflow, fetching from a local stand-in for the content API
+	contentServer := newContentServer()
+	defer contentServer.Close()
 	workflow := adapter.NewAgentWorkflow("data_processing_workflow")
-	setupWorkflow(workflow, factory.Registry)
+	setupWorkflow(workflow, factory.Registry, contentServer.URL)
 	
-	// 8. Start services
+	// 8. Start services, with the system monitor feeding its samples to the
+	// health checks
 	log.Println("Starting services...")
+	monitor := startMonitor(factory.Registry, healthManager)
 	messagingAdapter.StartMessageProcessing()
 	healthManager.StartAllChecks()
 	
@@ -104,22 +114,53 @@ func main() {
 	log.Println("Workflow completed, monitoring system health...")
 	time.Sleep(3 * time.Second)
 	
+	if monitor != nil {
+		for target, result := range monitor.GetMonitorResults() {
+			log.Printf("Monitor %s: %v", target, result)
+		}
+	}
+	
 	status, results := healthManager.CheckSystemHealth()
 	log.Printf("System health: %s", status)
+	for id, result := range results {
+		log.Printf("  %s: %s - %s", id, result.Status, result.Message)
+	}
 	
 	// 11. Graceful shutdown
 	log.Println("Shutting down...")
 	healthManager.StopAllChecks()
 	messagingAdapter.StopMessageProcessing()
+	if err := factory.Registry.ShutdownAll(5 * time.Second); err != nil {
+		log.Printf("Agent shutdown incomplete: %v", err)
+	}
 	
 	log.Println("Demo completed successfully")
 }
 
+// startMonitor registers the health checks of the system monitor and starts
+// its collection
+func startMonitor(registry *agents.AgentRegistry, healthManager *monitoring.HealthCheckManager) *agents.MonitorAgent {
+	agent, exists := registry.GetAgent("system_monitor")
+	if !exists {
+		return nil
+	}
+	monitor, ok := agent.(*agents.MonitorAgent)
+	if !ok {
+		return nil
+	}
+	if err := monitor.RegisterHealthChecks(healthManager); err != nil {
+		log.Printf("Failed to register monitor health checks: %v", err)
+	}
+	if err := monitor.Execute(); err != nil {
+		log.Printf("Failed to start monitor: %v", err)
+	}
+	return monitor
+}
+
 // setupMessageHandlers configures message handlers for agents
 func setupMessageHandlers(msgAdapter *adapter.AgentMessagingAdapter, registry *agents.AgentRegistry) {
 	for _, agentName := range registry.ListAgents() {
-		agent, exists := registry.GetAgent(agentName)
-		if !exists {
+		if _, exists := registry.GetAgent(agentName); !exists {
 			continue
 		}
 		
@@ -133,8 +174,17 @@ func setupMessageHandlers(msgAdapter *adapter.AgentMessagingAdapter, registry *a
 	}
 }
 
+// newContentServer serves sample content in place of a real content API
+func newContentServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id": "sample-content", "text": "This is a sample text that will be analyzed for sentiment. `+
+			`The sample is very positive and engaging content that users will enjoy."}`)
+	}))
+}
+
 // setupWorkflow configures the workflow tasks
-func setupWorkflow(workflow *adapter.AgentWorkflow, registry *agents.AgentRegistry) {
+func setupWorkflow(workflow *adapter.AgentWorkflow, registry *agents.AgentRegistry, contentURL string) {
 	// Find our agents
 	webFetcher, exists := registry.GetAgent("web_data_fetcher")
 	if !exists {
@@ -159,7 +209,7 @@ func setupWorkflow(workflow *adapter.AgentWorkflow, registry *agents.AgentRegist
 	// Create task: data fetching
 	fetchTask := adapter.NewAgentTask(webFetcher, "fetch_data")
 	fetchTask.WithInput(map[string]interface{}{
-		"url": "https://example.com/sample-content",
+		"url": contentURL + "/sample-content",
 		"headers": map[string]string{
 			"User-Agent": "Beluga Agent",
 		},
@@ -181,15 +231,12 @@ func setupWorkflow(workflow *adapter.AgentWorkflow, registry *agents.AgentRegist
 	fetchTask.WithResultHandler(func(output interface{}) error {
 		log.Println("Data fetching completed, passing data to analyzer")
 		
-		// In a real implementation, we would actually extract real data
-		// Here we're using a mock response for demonstration purposes
-		mockData := "This is a sample text that will be analyzed for sentiment. " +
-			"The sample is very positive and engaging content that users will enjoy."
-			
-		// Set the mock data as input for the analyzer
-		if analyzerAgent, ok := analyzer.(*agents.AnalyzerAgent); ok {
-			analyzerAgent.SetInputData(mockData)
+		// Pass the fetched text as input to the analyzer
+		content, ok := output.(*codec.Dataset)
+		if !ok || len(content.Records) == 0 {
+			return fmt.Errorf("unexpected content %v", output)
 		}
+		analyzeTask.WithInput(content.Records[0]["text"])
 		
 		return nil
 	})
@@ -197,23 +244,25 @@ func setupWorkflow(workflow *adapter.AgentWorkflow, registry *agents.AgentRegist
 	analyzeTask.WithResultHandler(func(output interface{}) error {
 		log.Println("Analysis completed, passing results to recommender")
 		
-		// Set mock analysis data
-		mockAnalysis := map[string]interface{}{
-			"sentiment": "positive",
-			"score": 0.85,
-			"keywords": []string{"engaging", "positive", "enjoy"},
+		sentiment, ok := output.(*analysis.SentimentResult)
+		if !ok {
+			return fmt.Errorf("unexpected analysis result %v", output)
 		}
+		log.Printf("Sentiment is %s with score %.2f", sentiment.Label, sentiment.Score)
 		
-		// Pass the results to the decision maker
-		if decisionAgent, ok := recommender.(*agents.DecisionMakerAgent); ok {
-			decisionAgent.SetAnalysisData(mockAnalysis)
-		}
+		// Pass the results to the decision maker, whose rules read the label
+		// and score of the analysis
+		recommendTask.WithInput(sentiment)
 		
 		return nil
 	})
 	
 	recommendTask.WithResultHandler(func(output interface{}) error {
-		log.Println("Recommendation generated, preparing notification")
+		decision, ok := output.(*rules.Decision)
+		if !ok {
+			return fmt.Errorf("unexpected decision %v", output)
+		}
+		log.Printf("Recommendation generated, preparing notification: %s", decision)
 		
 		// Create mock parameters for the executor
 		mockParams := map[string]interface{}{
@@ -221,21 +270,30 @@ func setupWorkflow(workflow *adapter.AgentWorkflow, registry *agents.AgentRegist
 			"subject": "Content Recommendation",
 			"template": "recommendation_template",
 			"data": map[string]interface{}{
+				"decision": decision.Outcome,
 				"recommendations": []string{"Article 1", "Article 2", "Article 3"},
 				"userPreferences": true,
 			},
 		}
 		
 		// Pass the parameters to the executor
-		if executorAgent, ok := executor.(*agents.ExecutorAgent); ok {
-			executorAgent.SetParams(mockParams)
-		}
+		notifyTask.WithInput(mockParams)
 		
 		return nil
 	})
 	
 	notifyTask.WithResultHandler(func(output interface{}) error {
-		log.Println("Notification sent successfully")
+		result, ok := output.(*actions.ExecutionResult)
+		if !ok {
+			return fmt.Errorf("unexpected execution result %v", output)
+		}
+		report, ok := result.Output.(*notify.Report)
+		if !ok {
+			return fmt.Errorf("unexpected notification report %v", result.Output)
+		}
+		for _, delivery := range report.Deliveries {
+			log.Printf("Notification %q to %s via %s: %s", delivery.Subject, delivery.Recipient, delivery.Channel, delivery.Status)
+		}
 		log.Println("Workflow completed")
 		return nil
 	})
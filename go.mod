@@ -3,6 +3,6 @@ module beluga
 go 1.22.2
 
 require (
-	github.com/trustmaster/goflow v0.0.0-20210928125717-b7d4fd465ab2 // indirect
-	gopkg.in/yaml.v2 v2.4.0 // indirect
+	github.com/trustmaster/goflow v0.0.0-20210928125717-b7d4fd465ab2
+	gopkg.in/yaml.v2 v2.4.0
 )
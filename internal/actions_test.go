@@ -0,0 +1,270 @@
+package internal
+
+import (
+	"beluga/pkg/actions"
+	"beluga/pkg/agents"
+	"beluga/pkg/orchestration"
+	"beluga/pkg/retry"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newAction(t *testing.T, name string, settings map[string]interface{}) actions.Action {
+	t.Helper()
+	action, err := actions.DefaultRegistry().New(name, actions.Config{Settings: settings})
+	if err != nil {
+		t.Fatalf("Failed to create action %s: %v", name, err)
+	}
+	return action
+}
+
+func TestCommandAction(t *testing.T) {
+	t.Setenv("BELUGA_VISIBLE", "yes")
+	t.Setenv("BELUGA_HIDDEN", "no")
+	dir := t.TempDir()
+
+	action := newAction(t, "command", map[string]interface{}{
+		"command":    []interface{}{"sh", "-c", `echo "$BELUGA_VISIBLE $BELUGA_HIDDEN $BELUGA_EXTRA $*"; pwd; cat; echo oops >&2`, "sh"},
+		"workdir":    dir,
+		"env":        []interface{}{"BELUGA_VISIBLE", "BELUGA_EXTRA"},
+		"allow_args": true,
+	})
+	result, err := actions.Execute(context.Background(), action, actions.Request{Action: "command", Params: map[string]interface{}{
+		"args":  []interface{}{"a", "b"},
+		"env":   map[string]interface{}{"BELUGA_EXTRA": "set"},
+		"stdin": "from stdin",
+	}})
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	output := result.Output.(*actions.CommandOutput)
+	realDir, _ := filepath.EvalSymlinks(dir)
+	if output.Stdout != "yes  set a b\n"+realDir+"\nfrom stdin" || output.Stderr != "oops\n" || output.ExitCode != 0 || !result.Success {
+		t.Errorf("Unexpected output %+v", output)
+	}
+
+	// Params cannot set variables off the allowlist or add arguments unless allowed
+	if _, err := action.Execute(context.Background(), actions.Request{Params: map[string]interface{}{"env": map[string]interface{}{"BELUGA_HIDDEN": "x"}}}); err == nil || retry.Retryable(err) {
+		t.Errorf("Expected a permanent error for a hidden variable, got %v", err)
+	}
+	strict := newAction(t, "command", map[string]interface{}{"command": "echo fixed"})
+	if _, err := strict.Execute(context.Background(), actions.Request{Params: map[string]interface{}{"args": []interface{}{"; rm -rf /"}}}); err == nil {
+		t.Errorf("Expected args to be refused")
+	}
+
+	failing := newAction(t, "command", map[string]interface{}{"command": []interface{}{"sh", "-c", "echo partial; echo broken >&2; exit 3"}})
+	result, err = actions.Execute(context.Background(), failing, actions.Request{Action: "command"})
+	var exitErr *actions.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode != 3 || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("Expected exit status 3, got %v", err)
+	}
+	if retry.Retryable(err) {
+		t.Errorf("Expected a failed command not to be retried")
+	}
+	retried := newAction(t, "command", map[string]interface{}{"command": []interface{}{"sh", "-c", "exit 1"}, "retry": true})
+	if _, err := retried.Execute(context.Background(), actions.Request{}); !retry.Retryable(err) {
+		t.Errorf("Expected retry to allow running the command again, got %v", err)
+	}
+	if result.Success || result.Output.(*actions.CommandOutput).Stdout != "partial\n" || result.Error == "" {
+		t.Errorf("Expected the failed command's output, got %+v", result)
+	}
+
+	slow := newAction(t, "command", map[string]interface{}{"command": "sleep 5", "timeout": 1})
+	started := time.Now()
+	if _, err := slow.Execute(context.Background(), actions.Request{}); err == nil || !strings.Contains(err.Error(), "timed out after 1s") || retry.Retryable(err) {
+		t.Errorf("Expected a permanent timeout, got %v", err)
+	}
+	if time.Since(started) > 4*time.Second {
+		t.Errorf("Expected the command to be killed at the timeout")
+	}
+
+	chatty := newAction(t, "command", map[string]interface{}{"command": []interface{}{"sh", "-c", "printf 0123456789"}, "max_output": 4})
+	chattyOutput, _ := chatty.Execute(context.Background(), actions.Request{})
+	if out := chattyOutput.(*actions.CommandOutput); out.Stdout != "0123" || !out.Truncated {
+		t.Errorf("Expected truncated output, got %+v", out)
+	}
+
+	missing := newAction(t, "command", map[string]interface{}{"command": "beluga-no-such-command"})
+	if _, err := missing.Execute(context.Background(), actions.Request{}); err == nil || retry.Retryable(err) {
+		t.Errorf("Expected a missing command to fail permanently, got %v", err)
+	}
+	for _, settings := range []map[string]interface{}{{}, {"command": 3}, {"command": "ls", "timeout": "soon"}, {"command": "ls", "env": "PATH"}, {"command": "ls", "retry": "yes"}} {
+		if _, err := actions.DefaultRegistry().New("command", actions.Config{Settings: settings}); err == nil {
+			t.Errorf("Expected %v to fail", settings)
+		}
+	}
+}
+
+func TestWebhookAction(t *testing.T) {
+	var received map[string]interface{}
+	var header string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("X-Token")
+		body, _ := io.ReadAll(r.Body)
+		if r.URL.Path == "/busy" {
+			http.Error(w, "try later", http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Path == "/gone" {
+			http.Error(w, "no such hook", http.StatusNotFound)
+			return
+		}
+		json.Unmarshal(body, &received)
+		w.Header().Set("X-Request-Id", "42")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	action := newAction(t, "webhook", map[string]interface{}{"headers": map[interface{}]interface{}{"X-Token": "secret"}, "timeout": 5})
+	output, err := action.Execute(context.Background(), actions.Request{Target: server.URL + "/hook", Params: map[string]interface{}{"decision": "recommend"}})
+	if err != nil {
+		t.Fatalf("Webhook failed: %v", err)
+	}
+	response := output.(*actions.WebhookResponse)
+	if response.StatusCode != http.StatusAccepted || response.Body != `{"ok":true}` || response.Headers["X-Request-Id"] != "42" {
+		t.Errorf("Unexpected response %+v", response)
+	}
+	if received["decision"] != "recommend" || header != "secret" {
+		t.Errorf("Expected the params and headers to be sent, got %v and %q", received, header)
+	}
+
+	var status *actions.StatusError
+	_, err = action.Execute(context.Background(), actions.Request{Target: server.URL + "/busy"})
+	if !errors.As(err, &status) || status.StatusCode != http.StatusServiceUnavailable || !retry.Retryable(err) {
+		t.Errorf("Expected a retryable status error, got %v", err)
+	}
+	if _, err = action.Execute(context.Background(), actions.Request{Target: server.URL + "/gone"}); err == nil || retry.Retryable(err) {
+		t.Errorf("Expected a permanent status error, got %v", err)
+	}
+	if _, err = action.Execute(context.Background(), actions.Request{Target: "email_service"}); err == nil || retry.Retryable(err) {
+		t.Errorf("Expected a target that is not a URL to fail permanently, got %v", err)
+	}
+}
+
+func TestFileAction(t *testing.T) {
+	dir := t.TempDir()
+	action := newAction(t, "file", map[string]interface{}{"dir": dir, "mode": "0600"})
+	output, err := action.Execute(context.Background(), actions.Request{Target: "reports/decision.json", Params: map[string]interface{}{"data": map[string]interface{}{"decision": "hold"}}})
+	if err != nil {
+		t.Fatalf("File write failed: %v", err)
+	}
+	written := output.(*actions.FileOutput)
+	data, _ := os.ReadFile(filepath.Join(dir, "reports", "decision.json"))
+	if written.Path != filepath.Join(dir, "reports", "decision.json") || string(data) != "{\n  \"decision\": \"hold\"\n}\n" || written.Bytes != len(data) {
+		t.Errorf("Unexpected file %+v with %q", written, data)
+	}
+	if info, _ := os.Stat(written.Path); info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected mode 0600, got %v", info.Mode())
+	}
+	if entries, _ := os.ReadDir(filepath.Join(dir, "reports")); len(entries) != 1 {
+		t.Errorf("Expected no temporary files to remain, got %v", entries)
+	}
+
+	// Files outside the directory cannot be written
+	for _, path := range []string{"../escape.txt", "/etc/beluga.txt", "reports/../../escape.txt"} {
+		if _, err := action.Execute(context.Background(), actions.Request{Params: map[string]interface{}{"path": path, "content": "x"}}); err == nil {
+			t.Errorf("Expected %s to be refused", path)
+		}
+	}
+
+	log := newAction(t, "file", map[string]interface{}{"path": filepath.Join(dir, "audit.log"), "append": true})
+	for _, line := range []string{"first\n", "second\n"} {
+		if _, err := log.Execute(context.Background(), actions.Request{Params: map[string]interface{}{"content": line}}); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "audit.log")); string(data) != "first\nsecond\n" {
+		t.Errorf("Expected appended lines, got %q", data)
+	}
+	// Without a directory, params cannot choose the file
+	if _, err := log.Execute(context.Background(), actions.Request{Params: map[string]interface{}{"path": filepath.Join(dir, "other.log"), "content": "x"}}); err == nil || retry.Retryable(err) {
+		t.Errorf("Expected a path param to be refused without a dir, got %v", err)
+	}
+}
+
+func TestExecutorActions(t *testing.T) {
+	// Registered actions take their settings from the executor
+	dir := t.TempDir()
+	factory := agents.NewAgentFactory()
+	agent, err := factory.CreateAgent("ExecutorAgent", "archiver", map[string]interface{}{
+		"action":      "file",
+		"target":      "decision.txt",
+		"dir":         dir,
+		"max_retries": 0,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	executor := agent.(*agents.ExecutorAgent)
+	output, err := executor.Run(context.Background(), map[string]interface{}{"content": "recommend"})
+	if err != nil {
+		t.Fatalf("Execution failed: %v", err)
+	}
+	result := output.(*actions.ExecutionResult)
+	if !result.Success || result.Action != "file" || result.Target != "decision.txt" || executor.GetExecutionResult() != result {
+		t.Errorf("Unexpected result %+v", result)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "decision.txt")); string(data) != "recommend" {
+		t.Errorf("Expected the file to be written, got %q", data)
+	}
+	if _, err := factory.CreateAgent("ExecutorAgent", "broken", map[string]interface{}{"action": "command"}); err == nil {
+		t.Errorf("Expected a command action without a command to fail")
+	}
+
+	// Messages go out on the executor's messaging system
+	messaging := orchestration.NewMessagingSystem(1)
+	notifier := agents.NewExecutorAgent("notifier", "message", "content_recommender")
+	notifier.SetMessaging(messaging)
+	if err := notifier.Initialize(map[string]interface{}{"message_type": "recommendation", "max_retries": 0}); err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+	if _, err := notifier.Run(context.Background(), map[string]interface{}{"decision": "hold"}); err != nil {
+		t.Fatalf("Message failed: %v", err)
+	}
+	message, err := messaging.ReceiveMessage()
+	if err != nil || message.Sender != "notifier" || message.Receiver != "content_recommender" || message.Type != "recommendation" || message.Payload["decision"] != "hold" {
+		t.Errorf("Unexpected message %+v (%v)", message, err)
+	}
+	// A full queue fails the execution, and the result records it
+	notifier.Run(context.Background(), map[string]interface{}{"n": 1})
+	if _, err := notifier.Run(context.Background(), map[string]interface{}{"n": 2}); err == nil {
+		t.Errorf("Expected a full queue to fail")
+	}
+	if result := notifier.GetExecutionResult(); result.Success || !strings.Contains(result.Error, "queue is full") {
+		t.Errorf("Expected a failed result, got %+v", result)
+	}
+
+	// Custom actions are registered by name
+	registry := actions.NewRegistry()
+	registry.MustRegister("page", func(config actions.Config) (actions.Action, error) {
+		prefix, _ := config.Settings["prefix"].(string)
+		return actions.ActionFunc(func(ctx context.Context, request actions.Request) (interface{}, error) {
+			return prefix + request.Target + ": " + request.Params["text"].(string), nil
+		}), nil
+	})
+	if err := registry.Register("page", nil); err == nil {
+		t.Errorf("Expected a duplicate action to fail")
+	}
+	pager := agents.NewExecutorAgent("pager", "page", "oncall")
+	pager.Actions = registry
+	if err := pager.Initialize(map[string]interface{}{"prefix": "@", "max_retries": 0}); err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+	output, err = pager.Run(context.Background(), map[string]interface{}{"text": "disk full"})
+	if err != nil || output.(*actions.ExecutionResult).Output != "@oncall: disk full" {
+		t.Errorf("Unexpected output %v (%v)", output, err)
+	}
+	if names := registry.Names(); strings.Join(names, ",") != "command,file,message,notification,page,webhook" {
+		t.Errorf("Unexpected actions %v", names)
+	}
+}
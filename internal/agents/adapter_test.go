@@ -1,9 +1,14 @@
 package agents
 
 import (
+	"context"
+	"errors"
 	"beluga/pkg/agents"
 	"beluga/pkg/agents/adapter"
+	"beluga/pkg/analysis"
 	"beluga/pkg/orchestration"
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
@@ -60,13 +65,36 @@ func TestAgentTask(t *testing.T) {
 	}
 }
 
+func TestAgentTaskInputOutput(t *testing.T) {
+	analyzer := agents.NewAnalyzerAgent("io_analyzer", "basic")
+	if err := analyzer.Initialize(map[string]interface{}{"test_key": "test_value"}); err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+	
+	// The task input is passed to the agent and its output is captured
+	agentTask := adapter.NewAgentTask(analyzer, "io_task").WithInput("test data")
+	if err := agentTask.Execute(); err != nil {
+		t.Fatalf("Agent task execution failed: %v", err)
+	}
+	if agentTask.GetOutput() == nil {
+		t.Errorf("Expected agent output to be captured")
+	}
+	
+	// A cancelled task does not run the agent
+	cancelled := adapter.NewAgentTask(analyzer, "cancelled_task").WithInput("test data")
+	cancelled.Cancel()
+	if err := cancelled.ExecuteContext(context.Background()); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
 func TestAgentWorkflow(t *testing.T) {
 	// Create a test workflow
 	workflow := adapter.NewAgentWorkflow("test_workflow")
 	
 	// Create agents and tasks
 	agent1 := agents.NewDataFetcherAgent("agent1", "source1", "json")
-	agent2 := agents.NewAnalyzerAgent("agent2", "test_analysis")
+	agent2 := agents.NewAnalyzerAgent("agent2", "basic")
 	agent3 := agents.NewDecisionMakerAgent("agent3")
 	
 	// Initialize agents
@@ -83,9 +111,15 @@ func TestAgentWorkflow(t *testing.T) {
 	
 	// Create agent tasks
 	task1 := adapter.NewAgentTask(agent1, "task1")
-	task2 := adapter.NewAgentTask(agent2, "task2").WithDependencies("task1")
+	task2 := adapter.NewAgentTask(agent2, "task2").WithDependencies("task1").WithInput("test data")
 	task3 := adapter.NewAgentTask(agent3, "task3").WithDependencies("task2")
 	
+	// Pass the analysis result on to the decision maker
+	task2.WithResultHandler(func(output interface{}) error {
+		task3.WithInput(output)
+		return nil
+	})
+	
 	// Add tasks to workflow
 	if err := workflow.AddTask(task1); err != nil {
 		t.Fatalf("Failed to add task1: %v", err)
@@ -110,7 +144,7 @@ func TestAgentMessagingAdapter(t *testing.T) {
 	
 	// Create agents to register
 	agent1 := agents.NewDataFetcherAgent("agent1", "source1", "json")
-	agent2 := agents.NewAnalyzerAgent("agent2", "test_analysis")
+	agent2 := agents.NewAnalyzerAgent("agent2", "basic")
 	
 	// Initialize agents
 	config := map[string]interface{}{"test_key": "test_value"}
@@ -196,9 +230,14 @@ func TestAgentIntegration(t *testing.T) {
 	workflow := adapter.NewAgentWorkflow("integrated_workflow")
 	
 	// Create agents using factory
+	dataPath := filepath.Join(t.TempDir(), "reviews.json")
+	if err := os.WriteFile(dataPath, []byte(`[{"review": "A great, reliable product"}, {"review": "Fast and excellent support"}]`), 0o644); err != nil {
+		t.Fatalf("Failed to write data: %v", err)
+	}
 	dataFetchConfig := map[string]interface{}{
 		"data_source": "test_api",
 		"data_format": "json",
+		"path":        dataPath,
 	}
 	
 	analyzerConfig := map[string]interface{}{
@@ -258,4 +297,9 @@ func TestAgentIntegration(t *testing.T) {
 	
 	// Stop message processing
 	msgAdapter.StopMessageProcessing()
+
+	// The fetched records were analyzed
+	if result, ok := task2.GetOutput().(*analysis.SentimentResult); !ok || result.Label != analysis.Positive || len(result.Documents) != 2 {
+		t.Errorf("Expected the sentiment of the fetched reviews, got %+v", task2.GetOutput())
+	}
 }
\ No newline at end of file
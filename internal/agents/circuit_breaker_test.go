@@ -0,0 +1,155 @@
+package agents
+
+import (
+	"beluga/pkg/agents"
+	"beluga/pkg/monitoring"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerStates(t *testing.T) {
+	breaker := agents.NewCircuitBreaker(2, 30*time.Millisecond)
+
+	var mutex sync.Mutex
+	var changes []agents.CircuitState
+	breaker.OnStateChange = func(from, to agents.CircuitState) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		changes = append(changes, to)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := breaker.Allow(); err != nil {
+			t.Fatalf("Expected closed breaker to allow calls, got %v", err)
+		}
+		breaker.RecordFailure(errors.New("downstream unavailable"))
+	}
+	if breaker.GetState() != agents.CircuitOpen {
+		t.Fatalf("Expected breaker to open after 2 failures, got %s", breaker.GetState())
+	}
+	if err := breaker.Allow(); !errors.Is(err, agents.ErrCircuitOpen) {
+		t.Errorf("Expected open breaker to reject calls, got %v", err)
+	}
+
+	// After the cool-down a single trial call is let through
+	time.Sleep(40 * time.Millisecond)
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("Expected half-open breaker to allow a trial call, got %v", err)
+	}
+	if err := breaker.Allow(); !errors.Is(err, agents.ErrCircuitOpen) {
+		t.Errorf("Expected a second trial call to be rejected, got %v", err)
+	}
+
+	// A failed trial reopens the breaker, a successful one closes it
+	breaker.RecordFailure(errors.New("still down"))
+	if breaker.GetState() != agents.CircuitOpen {
+		t.Fatalf("Expected failed trial to reopen the breaker, got %s", breaker.GetState())
+	}
+	time.Sleep(40 * time.Millisecond)
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("Expected half-open breaker to allow a trial call, got %v", err)
+	}
+	breaker.RecordSuccess()
+	if breaker.GetState() != agents.CircuitClosed {
+		t.Fatalf("Expected successful trial to close the breaker, got %s", breaker.GetState())
+	}
+
+	expected := []agents.CircuitState{
+		agents.CircuitOpen, agents.CircuitHalfOpen, agents.CircuitOpen, agents.CircuitHalfOpen, agents.CircuitClosed,
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected state changes %v, got %v", expected, changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Fatalf("Expected state changes %v, got %v", expected, changes)
+		}
+	}
+}
+
+func TestAgentCircuitBreaker(t *testing.T) {
+	agent := agents.NewBaseAgent("breaker_agent")
+	err := agent.Initialize(map[string]interface{}{
+		"max_retries": 5,
+		"retry_delay": 0,
+		"circuit_breaker": map[string]interface{}{
+			"failure_threshold": 3,
+			"cool_down":         "50ms",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+
+	opened := make(chan interface{}, 1)
+	agent.RegisterEventHandler("circuit_open", func(data interface{}) error {
+		opened <- data
+		return nil
+	})
+
+	failing := true
+	calls := 0
+	agent.SetBehavior(agents.BehaviorFunc(func(ctx context.Context, input interface{}) (interface{}, error) {
+		calls++
+		if failing {
+			return nil, errors.New("downstream unavailable")
+		}
+		return "ok", nil
+	}))
+
+	// Retries stop as soon as the breaker opens
+	if err := agent.Execute(); !errors.Is(err, agents.ErrCircuitOpen) {
+		t.Fatalf("Expected execution to be stopped by the breaker, got %v", err)
+	}
+	if calls != 3 || agent.ErrorCount != 3 {
+		t.Errorf("Expected 3 attempts before the breaker opened, got %d calls and %d errors", calls, agent.ErrorCount)
+	}
+	select {
+	case data := <-opened:
+		if stats, ok := data.(agents.CircuitBreakerStats); !ok || stats.State != agents.CircuitOpen {
+			t.Errorf("Expected circuit_open event with breaker stats, got %v", data)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Expected circuit_open event")
+	}
+
+	// Executions are rejected without running while the breaker is open
+	if err := agent.Execute(); !errors.Is(err, agents.ErrCircuitOpen) {
+		t.Errorf("Expected execution to be rejected, got %v", err)
+	}
+	if calls != 3 || agent.ErrorCount != 3 {
+		t.Errorf("Expected rejected execution not to run, got %d calls and %d errors", calls, agent.ErrorCount)
+	}
+
+	health := agent.CheckHealth()
+	if health["circuit_state"] != agents.CircuitOpen {
+		t.Errorf("Expected health to report an open circuit, got %v", health["circuit_state"])
+	}
+	result := monitoring.CreateAgentHealthCheckFunc(agent.CheckHealth)()
+	if result.Status != monitoring.StatusDegraded {
+		t.Errorf("Expected open circuit to degrade health, got %s (%s)", result.Status, result.Message)
+	}
+	failed := map[string]interface{}{"name": "breaker_agent", "state": "error", "circuit_state": agents.CircuitOpen}
+	if result := monitoring.CreateAgentHealthCheckFunc(func() map[string]interface{} { return failed })(); result.Status != monitoring.StatusUnhealthy {
+		t.Errorf("Expected an open circuit not to mask a failed agent, got %s (%s)", result.Status, result.Message)
+	}
+
+	// Once the dependency recovers, the trial execution closes the breaker
+	failing = false
+	time.Sleep(60 * time.Millisecond)
+	if err := agent.Execute(); err != nil {
+		t.Fatalf("Expected trial execution to succeed, got %v", err)
+	}
+	if state := agent.CheckHealth()["circuit_state"]; state != agents.CircuitClosed {
+		t.Errorf("Expected circuit to be closed, got %v", state)
+	}
+
+	if err := agent.Initialize(map[string]interface{}{"circuit_breaker": "on"}); err == nil {
+		t.Errorf("Expected invalid circuit_breaker settings to be rejected")
+	}
+}
@@ -0,0 +1,123 @@
+package agents
+
+import (
+	"beluga/pkg/agents"
+	"beluga/pkg/events"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAgentEvents(t *testing.T) {
+	agent := agents.NewBaseAgent("events_agent")
+	if err := agent.Initialize(map[string]interface{}{"max_retries": 0}); err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+
+	received := make(chan events.Event, 20)
+	agent.Subscribe("*", func(event events.Event) {
+		// Handlers may read agent state: they run outside the agent's lock
+		agent.GetState()
+		received <- event
+	})
+
+	fromGlobal := make(chan agents.ExecutionFinished, 20)
+	global := events.SubscribeTyped(events.Global(), func(e agents.ExecutionFinished) {
+		if e.Agent == "events_agent" {
+			fromGlobal <- e
+		}
+	})
+	defer global.Cancel()
+
+	agent.SetBehavior(agents.BehaviorFunc(func(ctx context.Context, input interface{}) (interface{}, error) {
+		return nil, errors.New("boom")
+	}))
+	if _, err := agent.Run(context.Background(), "input"); err == nil {
+		t.Fatalf("Expected execution to fail")
+	}
+
+	expected := []string{
+		agents.EventStateChanged,
+		agents.EventExecutionStarted,
+		agents.EventStateChanged,
+		agents.EventExecutionFinished,
+	}
+	var got []events.Event
+	for len(got) < len(expected) {
+		select {
+		case event := <-received:
+			got = append(got, event)
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for events, got %v", got)
+		}
+	}
+	for i, event := range got {
+		if event.EventType() != expected[i] {
+			t.Fatalf("Expected events %v, got %v", expected, got)
+		}
+	}
+
+	started := got[1].(agents.ExecutionStarted)
+	if started.Agent != "events_agent" || started.Input != "input" {
+		t.Errorf("Unexpected ExecutionStarted event: %+v", started)
+	}
+	changed := got[2].(agents.StateChanged)
+	if changed.From != agents.StateRunning || changed.To != agents.StateError {
+		t.Errorf("Expected running -> error, got %s -> %s", changed.From, changed.To)
+	}
+	finished := got[3].(agents.ExecutionFinished)
+	if finished.ExecutionID != started.ExecutionID || finished.Attempts != 1 || finished.Err == nil {
+		t.Errorf("Unexpected ExecutionFinished event: %+v", finished)
+	}
+
+	// The global bus aggregates events from every agent
+	select {
+	case e := <-fromGlobal:
+		if e.ExecutionID != started.ExecutionID {
+			t.Errorf("Expected the same execution on the global bus, got %+v", e)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Expected ExecutionFinished on the global bus")
+	}
+
+	// Shutting down delivers the final state change, then ends subscriptions
+	sub := agent.Subscribe(agents.EventStateChanged, func(event events.Event) {
+		received <- event
+	})
+	agent.Shutdown()
+	<-sub.Done()
+	if event := <-received; event.(agents.StateChanged).To != agents.StateShutdown {
+		t.Errorf("Expected shutdown state change, got %v", event)
+	}
+}
+
+func TestMonitorAgentMetricsEvent(t *testing.T) {
+	agent := agents.NewMonitorAgent("events_monitor", 10*time.Millisecond)
+	if err := agent.Initialize(map[string]interface{}{"key": "value"}); err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+	agent.AddMonitorTarget("target")
+
+	updates := make(chan agents.MetricsUpdated, 10)
+	events.SubscribeTyped(agent.Events, func(e agents.MetricsUpdated) {
+		select {
+		case updates <- e:
+		default:
+		}
+	})
+
+	if err := agent.Execute(); err != nil {
+		t.Fatalf("Failed to execute agent: %v", err)
+	}
+	defer agent.Shutdown()
+
+	select {
+	case update := <-updates:
+		if _, ok := update.Metrics["target"]; !ok {
+			t.Errorf("Expected metrics for target, got %v", update.Metrics)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Expected a MetricsUpdated event")
+	}
+}
@@ -0,0 +1,203 @@
+package agents
+
+import (
+	"beluga/pkg/agents"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyLimitQueuesExecutions(t *testing.T) {
+	agent := agents.NewBaseAgent("queue_agent")
+	if err := agent.Initialize(map[string]interface{}{"max_concurrent_executions": 2}); err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+
+	var running, peak int32
+	agent.SetBehavior(agents.BehaviorFunc(func(ctx context.Context, input interface{}) (interface{}, error) {
+		current := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			observed := atomic.LoadInt32(&peak)
+			if current <= observed || atomic.CompareAndSwapInt32(&peak, observed, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		return nil, nil
+	}))
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 6)
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- agent.Execute()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Expected queued execution to succeed, got %v", err)
+		}
+	}
+	if peak > 2 {
+		t.Errorf("Expected at most 2 concurrent executions, got %d", peak)
+	}
+}
+
+func TestConcurrencyLimitRejectsExecutions(t *testing.T) {
+	agent := agents.NewBaseAgent("reject_agent")
+	err := agent.Initialize(map[string]interface{}{
+		"max_concurrent_executions": 1,
+		"limit_policy":              "reject",
+	})
+	if err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	agent.SetBehavior(agents.BehaviorFunc(func(ctx context.Context, input interface{}) (interface{}, error) {
+		close(started)
+		<-release
+		return nil, nil
+	}))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- agent.Execute()
+	}()
+	<-started
+
+	if err := agent.Execute(); !errors.Is(err, agents.ErrConcurrencyLimit) {
+		t.Errorf("Expected ErrConcurrencyLimit, got %v", err)
+	}
+	limits, _ := agent.CheckHealth()["limits"].(agents.ExecutionLimiterStats)
+	if limits.Active != 1 || limits.Rejected != 1 {
+		t.Errorf("Expected 1 active and 1 rejected execution, got %+v", limits)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("Expected admitted execution to succeed, got %v", err)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	agent := agents.NewBaseAgent("rate_agent")
+	err := agent.Initialize(map[string]interface{}{
+		"rate_limit":   map[string]interface{}{"rate": float64(20), "burst": 2},
+		"limit_policy": "reject",
+	})
+	if err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+
+	// The burst is available immediately, the next execution is rejected
+	for i := 0; i < 2; i++ {
+		if err := agent.Execute(); err != nil {
+			t.Fatalf("Expected execution within burst to succeed, got %v", err)
+		}
+	}
+	if err := agent.Execute(); !errors.Is(err, agents.ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited, got %v", err)
+	}
+
+	// With the queue policy executions wait for the bucket to refill
+	queued := agents.NewBaseAgent("rate_queue_agent")
+	if err := queued.Initialize(map[string]interface{}{"rate_limit": 20}); err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := queued.Execute(); err != nil {
+			t.Fatalf("Expected queued execution to succeed, got %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("Expected rate limit to space executions out, took %v", elapsed)
+	}
+
+	// A queued execution gives up when its context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := queued.Run(ctx, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected queued execution to time out, got %v", err)
+	}
+
+	if err := agent.Initialize(map[string]interface{}{"rate_limit": 1, "limit_policy": "drop"}); err == nil {
+		t.Errorf("Expected unknown limit policy to be rejected")
+	}
+}
+
+func TestLimiterAdmissionOrder(t *testing.T) {
+	t.Run("open_breaker", func(t *testing.T) {
+		agent := agents.NewBaseAgent("breaker_rate_agent")
+		err := agent.Initialize(map[string]interface{}{
+			"retry_delay":     0,
+			"rate_limit":      map[string]interface{}{"rate": 0.001, "burst": 1},
+			"limit_policy":    "reject",
+			"circuit_breaker": map[string]interface{}{"failure_threshold": 1, "cool_down": "1m"},
+		})
+		if err != nil {
+			t.Fatalf("Failed to initialize agent: %v", err)
+		}
+		agent.CircuitBreaker.RecordFailure(errors.New("downstream unavailable"))
+
+		// Rejected executions leave the rate token for when the breaker closes
+		for i := 0; i < 3; i++ {
+			if err := agent.Execute(); !errors.Is(err, agents.ErrCircuitOpen) {
+				t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+			}
+		}
+		limits, _ := agent.CheckHealth()["limits"].(agents.ExecutionLimiterStats)
+		if limits.Tokens < 0.99 || limits.Rejected != 0 {
+			t.Errorf("Expected the open breaker not to use rate tokens, got %+v", limits)
+		}
+	})
+
+	t.Run("paused", func(t *testing.T) {
+		agent := agents.NewBaseAgent("paused_slot_agent")
+		if err := agent.Initialize(map[string]interface{}{"max_concurrent_executions": 1}); err != nil {
+			t.Fatalf("Failed to initialize agent: %v", err)
+		}
+		if err := agent.Pause(context.Background()); err != nil {
+			t.Fatalf("Failed to pause agent: %v", err)
+		}
+
+		done := make(chan error, 2)
+		for i := 0; i < 2; i++ {
+			go func() {
+				done <- agent.Execute()
+			}()
+		}
+		time.Sleep(30 * time.Millisecond)
+
+		// Callers waiting for Resume hold no slots
+		limits, _ := agent.CheckHealth()["limits"].(agents.ExecutionLimiterStats)
+		if limits.Active != 0 || limits.Queued != 0 {
+			t.Errorf("Expected paused callers to hold no slots, got %+v", limits)
+		}
+
+		if err := agent.Resume(); err != nil {
+			t.Fatalf("Failed to resume agent: %v", err)
+		}
+		for i := 0; i < 2; i++ {
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Errorf("Expected execution to succeed after resume, got %v", err)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("Execution did not run after resume")
+			}
+		}
+	})
+}
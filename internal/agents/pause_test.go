@@ -0,0 +1,160 @@
+package agents
+
+import (
+	"beluga/pkg/agents"
+	"beluga/pkg/monitoring"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPauseBlocksExecution(t *testing.T) {
+	agent := agents.NewBaseAgent("pause_block_agent")
+	if err := agent.Initialize(map[string]interface{}{"key": "value"}); err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+
+	if err := agent.Pause(context.Background()); err != nil {
+		t.Fatalf("Failed to pause agent: %v", err)
+	}
+	if agent.GetState() != agents.StatePaused || !agent.IsPaused() {
+		t.Fatalf("Expected agent to be paused, got %s", agent.GetState())
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- agent.Execute()
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("Execute returned while paused: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := agent.Resume(); err != nil {
+		t.Fatalf("Failed to resume agent: %v", err)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Execute failed after resume: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Execute did not run after resume")
+	}
+
+	if agent.GetState() != agents.StateReady {
+		t.Errorf("Expected agent to be ready, got %s", agent.GetState())
+	}
+	if err := agent.Resume(); err == nil {
+		t.Errorf("Expected resuming an agent that is not paused to fail")
+	}
+}
+
+func TestPauseRejectsExecution(t *testing.T) {
+	agent := agents.NewBaseAgent("pause_reject_agent")
+	if err := agent.Initialize(map[string]interface{}{"pause_policy": "reject"}); err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+
+	if err := agent.Pause(context.Background()); err != nil {
+		t.Fatalf("Failed to pause agent: %v", err)
+	}
+	if err := agent.Execute(); !errors.Is(err, agents.ErrAgentPaused) {
+		t.Errorf("Expected ErrAgentPaused, got %v", err)
+	}
+
+	if err := agent.Initialize(map[string]interface{}{"pause_policy": "sometimes"}); err == nil {
+		t.Errorf("Expected unknown pause policy to be rejected")
+	}
+}
+
+func TestPauseWaitsForInFlightExecution(t *testing.T) {
+	agent := agents.NewBaseAgent("pause_inflight_agent")
+	if err := agent.Initialize(map[string]interface{}{"key": "value"}); err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	agent.SetBehavior(agents.BehaviorFunc(func(ctx context.Context, input interface{}) (interface{}, error) {
+		close(started)
+		<-release
+		return "done", nil
+	}))
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := agent.Run(context.Background(), nil)
+		done <- err
+	}()
+	<-started
+
+	// The execution is still in flight, so Pause gives up when its context expires
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := agent.Pause(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected Pause to time out, got %v", err)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("In-flight execution failed: %v", err)
+	}
+	if agent.GetState() != agents.StatePaused {
+		t.Errorf("Expected agent to stay paused after the execution finished, got %s", agent.GetState())
+	}
+	if err := agent.Pause(context.Background()); err != nil {
+		t.Errorf("Expected Pause to succeed once idle, got %v", err)
+	}
+}
+
+func TestMonitorAgentPause(t *testing.T) {
+	agent := agents.NewMonitorAgent("pause_monitor", 10*time.Millisecond)
+	if err := agent.Initialize(map[string]interface{}{"key": "value"}); err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+	agent.AddMonitorTarget("target")
+	if err := agent.Execute(); err != nil {
+		t.Fatalf("Failed to execute agent: %v", err)
+	}
+	defer agent.Shutdown()
+
+	lastCollected := func() time.Time {
+		result, _ := agent.GetMonitorResults()["target"].(map[string]interface{})
+		timestamp, _ := result["timestamp"].(time.Time)
+		return timestamp
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if err := agent.Pause(context.Background()); err != nil {
+		t.Fatalf("Failed to pause agent: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	pausedAt := lastCollected()
+	if pausedAt.IsZero() {
+		t.Fatalf("Expected metrics to be collected before pausing")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if !lastCollected().Equal(pausedAt) {
+		t.Errorf("Expected no metrics to be collected while paused")
+	}
+
+	// Health checks report the paused agent as degraded
+	result := monitoring.CreateAgentHealthCheckFunc(agent.CheckHealth)()
+	if result.Status != monitoring.StatusDegraded {
+		t.Errorf("Expected paused agent to be degraded, got %s (%s)", result.Status, result.Message)
+	}
+
+	if err := agent.Resume(); err != nil {
+		t.Fatalf("Failed to resume agent: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if !lastCollected().After(pausedAt) {
+		t.Errorf("Expected metrics collection to continue after resume")
+	}
+}
@@ -0,0 +1,216 @@
+package agents
+
+import (
+	"beluga/pkg/agents"
+	"beluga/pkg/events"
+	"beluga/pkg/llm"
+	"beluga/pkg/prompts"
+	"beluga/pkg/tools"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// replayLLM answers with canned outputs in order, repeating the last one.
+type replayLLM struct {
+	mutex    sync.Mutex
+	outputs  []string
+	tokens   int
+	requests []llm.Request
+}
+
+func (m *replayLLM) Chat(ctx context.Context, request llm.Request) (*llm.Response, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.requests = append(m.requests, request)
+	output := m.outputs[0]
+	if len(m.outputs) > 1 {
+		m.outputs = m.outputs[1:]
+	}
+	return &llm.Response{
+		Message: llm.Message{Role: llm.RoleAssistant, Content: output},
+		Usage:   llm.Usage{TotalTokens: m.tokens},
+	}, nil
+}
+
+func (m *replayLLM) ChatStream(ctx context.Context, request llm.Request, onChunk func(llm.Chunk) error) (*llm.Response, error) {
+	return m.Chat(ctx, request)
+}
+
+func (m *replayLLM) GetUsage() llm.Usage { return llm.Usage{} }
+
+func weatherTools() *tools.Registry {
+	registry := tools.NewRegistry()
+	registry.MustRegister(tools.NewTool("weather", "Returns the weather in a city.",
+		tools.Object(map[string]*tools.Schema{"city": tools.String("City name")}, "city"),
+		func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			return "sunny in " + args["city"].(string), nil
+		}))
+	return registry
+}
+
+func TestReasoningAgent(t *testing.T) {
+	factory := agents.NewAgentFactory()
+	created, err := factory.CreateAgent("ReasoningAgent", "reasoner", map[string]interface{}{
+		"goal":         "Should I take an umbrella in Paris?",
+		"max_steps":    5,
+		"token_budget": 1000,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	agent := created.(*agents.ReasoningAgent)
+	agent.SetTools(weatherTools())
+	model := &replayLLM{tokens: 10, outputs: []string{
+		"Thought: I need the weather.\nAction: weather\nAction Input: {\"city\": \"Paris\"}",
+		"Thought: Let me try something else.\nAction: forecast\nAction Input: {}",
+		"I am not sure what to do.",
+		"Thought: It is sunny, so no umbrella.\nFinal Answer: No, it is sunny in Paris.",
+	}}
+	agent.SetLLM(model)
+
+	steps := make(chan agents.ReasoningStepCompleted, 10)
+	events.SubscribeTyped(agent.Events, func(e agents.ReasoningStepCompleted) { steps <- e })
+
+	output, err := agent.Run(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Reasoning failed: %v", err)
+	}
+	if output != "No, it is sunny in Paris." || agent.GetFinalAnswer() != output {
+		t.Errorf("Unexpected final answer: %v", output)
+	}
+
+	transcript := agent.GetTranscript()
+	if len(transcript) != 4 {
+		t.Fatalf("Expected 4 steps, got %+v", transcript)
+	}
+	first := transcript[0]
+	if first.Thought != "I need the weather." || first.Action != "weather" || first.ActionInput["city"] != "Paris" || first.Observation != "sunny in Paris" {
+		t.Errorf("Unexpected first step: %+v", first)
+	}
+	if !strings.Contains(transcript[1].Observation, "unknown tool") {
+		t.Errorf("Expected an unknown tool observation, got %q", transcript[1].Observation)
+	}
+	if !strings.HasPrefix(transcript[2].Observation, "Invalid format") {
+		t.Errorf("Expected an invalid format observation, got %q", transcript[2].Observation)
+	}
+	if transcript[3].FinalAnswer != output || transcript[3].Thought != "It is sunny, so no umbrella." {
+		t.Errorf("Unexpected final step: %+v", transcript[3])
+	}
+
+	// Observations are fed back and the tools are described to the model
+	last := model.requests[len(model.requests)-1]
+	if !strings.Contains(last.Messages[0].Content, "- weather: Returns the weather in a city.") {
+		t.Errorf("Expected the system prompt to describe the tools, got %q", last.Messages[0].Content)
+	}
+	if last.Messages[1].Content != "Goal: Should I take an umbrella in Paris?" || last.Messages[3].Content != "Observation: sunny in Paris" {
+		t.Errorf("Unexpected conversation: %+v", last.Messages)
+	}
+	if len(last.Stop) == 0 {
+		t.Errorf("Expected the model to stop before writing observations")
+	}
+
+	for i := 1; i <= 4; i++ {
+		select {
+		case e := <-steps:
+			if e.Step.Index != i {
+				t.Errorf("Expected step %d, got %d", i, e.Step.Index)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected an event for step %d", i)
+		}
+	}
+}
+
+func TestReasoningAgentBudgets(t *testing.T) {
+	looping := "Thought: Again.\nAction: weather\nAction Input: {\"city\": \"Oslo\"}"
+
+	agent := agents.NewReasoningAgent("step_limited", "Loop forever")
+	agent.SetTools(weatherTools())
+	// Limits end the run without retries
+	if err := agent.Initialize(map[string]interface{}{"max_retries": 2, "retry_delay": 0, "max_steps": 3}); err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+	stepModel := &replayLLM{tokens: 10, outputs: []string{looping}}
+	agent.SetLLM(stepModel)
+	if _, err := agent.Run(context.Background(), nil); !errors.Is(err, agents.ErrMaxStepsExceeded) {
+		t.Errorf("Expected the step limit, got %v", err)
+	}
+	if len(stepModel.requests) != 3 {
+		t.Errorf("Expected a single run of 3 model calls, got %d", len(stepModel.requests))
+	}
+	if len(agent.GetTranscript()) != 3 {
+		t.Errorf("Expected the transcript of the failed run, got %d steps", len(agent.GetTranscript()))
+	}
+
+	budgeted := agents.NewReasoningAgent("token_limited", "")
+	budgeted.SetTools(weatherTools())
+	if err := budgeted.Initialize(map[string]interface{}{"max_retries": 2, "retry_delay": 0, "token_budget": 25}); err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+	budgetModel := &replayLLM{tokens: 10, outputs: []string{looping}}
+	budgeted.SetLLM(budgetModel)
+	if _, err := budgeted.Run(context.Background(), "Loop forever"); !errors.Is(err, agents.ErrTokenBudgetExceeded) {
+		t.Errorf("Expected the token budget to be exhausted, got %v", err)
+	}
+	if len(budgetModel.requests) != 3 || budgeted.TokensUsed != 30 {
+		t.Errorf("Expected a single run of 3 model calls, got %d using %d tokens", len(budgetModel.requests), budgeted.TokensUsed)
+	}
+	if len(budgeted.GetTranscript()) != 3 {
+		t.Errorf("Expected 3 steps within the budget, got %d", len(budgeted.GetTranscript()))
+	}
+
+	// A custom detector decides what counts as a final answer
+	custom := agents.NewReasoningAgent("custom_detector", "Say done")
+	custom.Initialize(map[string]interface{}{"max_retries": 0})
+	custom.FinalAnswerDetector = func(output string) (string, bool) {
+		return output, strings.HasSuffix(output, "DONE")
+	}
+	custom.SetLLM(&replayLLM{outputs: []string{"All DONE"}})
+	if output, err := custom.Run(context.Background(), nil); err != nil || output != "All DONE" {
+		t.Errorf("Expected the custom detector to end the run, got %v (%v)", output, err)
+	}
+
+	if err := agents.NewReasoningAgent("invalid", "").Initialize(map[string]interface{}{"max_steps": 0}); err == nil {
+		t.Errorf("Expected a non-positive max_steps to fail")
+	}
+}
+
+func TestReasoningAgentPrompt(t *testing.T) {
+	library := prompts.NewLibrary()
+	library.Parse("react", `{{role "system"}}{{.agent}} may use: {{join .tool_names ", "}}
+{{role "user"}}Task: {{.goal}}`)
+
+	agent := agents.NewReasoningAgent("templated", "Check the weather")
+	agent.SetTools(weatherTools())
+	agent.SetPrompts(library)
+	if err := agent.Initialize(map[string]interface{}{"prompt": "react"}); err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+	model := &replayLLM{outputs: []string{"Final Answer: done"}}
+	agent.SetLLM(model)
+	if _, err := agent.Run(context.Background(), nil); err != nil {
+		t.Fatalf("Reasoning failed: %v", err)
+	}
+
+	messages := model.requests[0].Messages
+	if len(messages) != 2 || messages[0].Content != "templated may use: weather" || messages[1].Content != "Task: Check the weather" {
+		t.Errorf("Expected the rendered prompt template, got %+v", messages)
+	}
+}
+
+func TestParseReActAction(t *testing.T) {
+	thought, action, input, err := agents.ParseReActAction("Thought: look it up\nAction: `search`\nAction Input: ```json\n{\"q\": \"go\"}\n```")
+	if err != nil || thought != "look it up" || action != "search" || input["q"] != "go" {
+		t.Errorf("Unexpected parse: %q %q %v %v", thought, action, input, err)
+	}
+	if _, _, _, err := agents.ParseReActAction("Action: search\nAction Input: not json"); err == nil {
+		t.Errorf("Expected an invalid action input to fail")
+	}
+	if answer, ok := agents.DetectFinalAnswer("Thought: done\nFinal Answer:  42 "); !ok || answer != "42" {
+		t.Errorf("Expected the final answer 42, got %q", answer)
+	}
+}
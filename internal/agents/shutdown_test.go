@@ -0,0 +1,169 @@
+package agents
+
+import (
+	"beluga/pkg/agents"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGracefulShutdownDrainsExecutions(t *testing.T) {
+	agent := agents.NewBaseAgent("drain_agent")
+	if err := agent.Initialize(map[string]interface{}{"key": "value"}); err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+
+	started := make(chan struct{})
+	agent.SetBehavior(agents.BehaviorFunc(func(ctx context.Context, input interface{}) (interface{}, error) {
+		close(started)
+		select {
+		case <-time.After(100 * time.Millisecond):
+			return "finished", nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}))
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := agent.Run(context.Background(), nil)
+		done <- err
+	}()
+	<-started
+
+	shutdownDone := make(chan error, 1)
+	go func() {
+		shutdownDone <- agent.GracefulShutdown(time.Second)
+	}()
+
+	// New work is refused while the agent drains
+	time.Sleep(20 * time.Millisecond)
+	if err := agent.Execute(); !errors.Is(err, agents.ErrAgentShuttingDown) {
+		t.Errorf("Expected ErrAgentShuttingDown during drain, got %v", err)
+	}
+
+	if err := <-done; err != nil {
+		t.Errorf("Expected in-flight execution to finish, got %v", err)
+	}
+	if err := <-shutdownDone; err != nil {
+		t.Errorf("Graceful shutdown failed: %v", err)
+	}
+	if agent.GetState() != agents.StateShutdown {
+		t.Errorf("Expected agent state to be shutdown, got %s", agent.GetState())
+	}
+}
+
+func TestGracefulShutdownReportsAbandonedWork(t *testing.T) {
+	agent := agents.NewBaseAgent("abandon_agent")
+	if err := agent.Initialize(map[string]interface{}{"max_retries": 0}); err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+
+	started := make(chan struct{})
+	agent.SetBehavior(agents.BehaviorFunc(func(ctx context.Context, input interface{}) (interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}))
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := agent.Run(context.Background(), nil)
+		done <- err
+	}()
+	<-started
+
+	err := agent.GracefulShutdown(50 * time.Millisecond)
+	var abandonedErr *agents.ErrWorkAbandoned
+	if !errors.As(err, &abandonedErr) {
+		t.Fatalf("Expected ErrWorkAbandoned, got %v", err)
+	}
+	if len(abandonedErr.Abandoned) != 1 || abandonedErr.Abandoned[0].Kind != agents.WorkExecution {
+		t.Errorf("Expected one abandoned execution, got %v", abandonedErr.Abandoned)
+	}
+
+	// The abandoned execution is cancelled by the forced shutdown
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected abandoned execution to be cancelled, got %v", err)
+	}
+}
+
+func TestGracefulShutdownStopsMonitorLoop(t *testing.T) {
+	agent := agents.NewMonitorAgent("drain_monitor", 10*time.Millisecond)
+	if err := agent.Initialize(map[string]interface{}{"key": "value"}); err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+	if err := agent.Execute(); err != nil {
+		t.Fatalf("Failed to execute agent: %v", err)
+	}
+
+	work := agent.GetInFlightWork()
+	if len(work) != 1 || work[0].Kind != agents.WorkGoroutine || work[0].Name != "monitor_loop" {
+		t.Fatalf("Expected the monitor loop to be tracked, got %v", work)
+	}
+
+	if err := agent.GracefulShutdown(time.Second); err != nil {
+		t.Errorf("Graceful shutdown failed: %v", err)
+	}
+	if work := agent.GetInFlightWork(); len(work) != 0 {
+		t.Errorf("Expected no work in flight after shutdown, got %v", work)
+	}
+}
+
+// orderedAgent records the order in which agents are shut down.
+type orderedAgent struct {
+	name  string
+	mutex *sync.Mutex
+	order *[]string
+}
+
+func (o *orderedAgent) Initialize(config map[string]interface{}) error { return nil }
+func (o *orderedAgent) Execute() error                                 { return nil }
+func (o *orderedAgent) Shutdown() error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	*o.order = append(*o.order, o.name)
+	return nil
+}
+
+func TestRegistryShutdownAll(t *testing.T) {
+	var mutex sync.Mutex
+	var order []string
+	newAgent := func(name string) *orderedAgent {
+		return &orderedAgent{name: name, mutex: &mutex, order: &order}
+	}
+
+	registry := agents.NewAgentRegistry()
+	registry.RegisterAgentWithDependencies("notifier", newAgent("notifier"), []string{"recommender"})
+	registry.RegisterAgentWithDependencies("fetcher", newAgent("fetcher"), nil)
+	registry.RegisterAgentWithDependencies("recommender", newAgent("recommender"), []string{"analyzer"})
+	registry.RegisterAgentWithDependencies("analyzer", newAgent("analyzer"), []string{"fetcher", "external"})
+
+	if err := registry.ShutdownAll(time.Second); err != nil {
+		t.Fatalf("ShutdownAll failed: %v", err)
+	}
+
+	expected := []string{"notifier", "recommender", "analyzer", "fetcher"}
+	if len(order) != len(expected) {
+		t.Fatalf("Expected shutdown order %v, got %v", expected, order)
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Fatalf("Expected shutdown order %v, got %v", expected, order)
+		}
+	}
+
+	// A dependency cycle is reported, but every agent is still shut down
+	order = nil
+	cyclic := agents.NewAgentRegistry()
+	cyclic.RegisterAgentWithDependencies("a", newAgent("a"), []string{"b"})
+	cyclic.RegisterAgentWithDependencies("b", newAgent("b"), []string{"a"})
+	if err := cyclic.ShutdownAll(time.Second); err == nil {
+		t.Errorf("Expected dependency cycle to be reported")
+	}
+	if len(order) != 2 {
+		t.Errorf("Expected both agents to be shut down, got %v", order)
+	}
+}
@@ -0,0 +1,135 @@
+package agents
+
+import (
+	"beluga/pkg/agents"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestStateTransitions(t *testing.T) {
+	testCases := []struct {
+		from    agents.AgentState
+		to      agents.AgentState
+		allowed bool
+	}{
+		{agents.StateInitializing, agents.StateReady, true},
+		{agents.StateInitializing, agents.StateRunning, false},
+		{agents.StateReady, agents.StateRunning, true},
+		{agents.StateRunning, agents.StateReady, true},
+		{agents.StateRunning, agents.StateError, true},
+		{agents.StateError, agents.StateRunning, true},
+		{agents.StatePaused, agents.StateRunning, true},
+		{agents.StateShutdown, agents.StateRunning, false},
+		{agents.StateShutdown, agents.StateReady, false},
+	}
+
+	for _, tc := range testCases {
+		if got := agents.CanTransition(tc.from, tc.to); got != tc.allowed {
+			t.Errorf("CanTransition(%s, %s) = %v, expected %v", tc.from, tc.to, got, tc.allowed)
+		}
+	}
+}
+
+func TestExecuteAfterShutdownIsRejected(t *testing.T) {
+	agent := agents.NewBaseAgent("shutdown_agent")
+	if err := agent.Initialize(map[string]interface{}{"key": "value"}); err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+	if err := agent.Shutdown(); err != nil {
+		t.Fatalf("Failed to shutdown agent: %v", err)
+	}
+
+	err := agent.Execute()
+	var transitionErr *agents.ErrInvalidTransition
+	if !errors.As(err, &transitionErr) {
+		t.Fatalf("Expected ErrInvalidTransition, got %v", err)
+	}
+	if transitionErr.From != agents.StateShutdown || transitionErr.To != agents.StateRunning {
+		t.Errorf("Unexpected transition in error: %s -> %s", transitionErr.From, transitionErr.To)
+	}
+	if agent.GetState() != agents.StateShutdown {
+		t.Errorf("Expected agent to stay shut down, got %s", agent.GetState())
+	}
+
+	if err := agent.Initialize(map[string]interface{}{"key": "value"}); !errors.As(err, &transitionErr) {
+		t.Errorf("Expected re-initialization after shutdown to be rejected, got %v", err)
+	}
+}
+
+func TestStateHistory(t *testing.T) {
+	agent := agents.NewBaseAgent("history_agent")
+	if err := agent.Initialize(map[string]interface{}{"max_retries": 0}); err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+
+	agent.SetBehavior(agents.BehaviorFunc(func(ctx context.Context, input interface{}) (interface{}, error) {
+		return nil, errors.New("database unreachable")
+	}))
+	if err := agent.Execute(); err == nil {
+		t.Fatalf("Expected execution to fail")
+	}
+
+	history := agent.GetStateHistory()
+	expected := []agents.AgentState{agents.StateReady, agents.StateRunning, agents.StateError}
+	if len(history) != len(expected) {
+		t.Fatalf("Expected %d transitions, got %d: %v", len(expected), len(history), history)
+	}
+	for i, state := range expected {
+		if history[i].To != state {
+			t.Errorf("Transition %d: expected %s, got %s", i, state, history[i].To)
+		}
+		if history[i].Timestamp.IsZero() {
+			t.Errorf("Transition %d has no timestamp", i)
+		}
+	}
+
+	// The health check explains why the agent is in the error state
+	health := agent.CheckHealth()
+	reason, _ := health["state_reason"].(string)
+	if !strings.Contains(reason, "database unreachable") {
+		t.Errorf("Expected state_reason to mention the failure, got %q", reason)
+	}
+	if healthHistory, ok := health["state_history"].([]agents.StateTransition); !ok || len(healthHistory) != len(expected) {
+		t.Errorf("Expected state_history in health check, got %v", health["state_history"])
+	}
+}
+
+func TestStateHistoryIsBounded(t *testing.T) {
+	agent := agents.NewBaseAgent("bounded_agent")
+	if err := agent.Initialize(map[string]interface{}{"state_history_size": 3}); err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		if err := agent.Execute(); err != nil {
+			t.Fatalf("Failed to execute agent: %v", err)
+		}
+	}
+
+	history := agent.GetStateHistory()
+	if len(history) != 3 {
+		t.Fatalf("Expected history to be capped at 3 entries, got %d", len(history))
+	}
+	if last := history[len(history)-1]; last.To != agents.StateReady || last.Reason != "execution succeeded" {
+		t.Errorf("Expected the most recent transition to be kept, got %+v", last)
+	}
+
+	// Numbers decoded from JSON configuration are float64
+	decoded := agents.NewBaseAgent("decoded_agent")
+	if err := decoded.Initialize(map[string]interface{}{"state_history_size": 2.0}); err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		decoded.Execute()
+	}
+	if history := decoded.GetStateHistory(); len(history) != 2 {
+		t.Errorf("Expected history to be capped at 2 entries, got %d", len(history))
+	}
+	for _, size := range []interface{}{0, -1.0, "ten"} {
+		if err := agents.NewBaseAgent("invalid_agent").Initialize(map[string]interface{}{"state_history_size": size}); err == nil {
+			t.Errorf("Expected state_history_size %v to be rejected", size)
+		}
+	}
+}
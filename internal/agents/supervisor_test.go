@@ -0,0 +1,214 @@
+package agents
+
+import (
+	"beluga/pkg/agents"
+	"beluga/pkg/monitoring"
+	"context"
+	"errors"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newFailingAgent creates an initialized agent whose executions always fail
+// without retrying, and registers it in registry.
+func newFailingAgent(t *testing.T, registry *agents.AgentRegistry, name string, dependencies ...string) *agents.BaseAgent {
+	t.Helper()
+	agent := agents.NewBaseAgent(name)
+	if err := agent.Initialize(map[string]interface{}{"max_retries": 0}); err != nil {
+		t.Fatalf("Failed to initialize agent %s: %v", name, err)
+	}
+	agent.SetBehavior(agents.BehaviorFunc(func(ctx context.Context, input interface{}) (interface{}, error) {
+		return nil, errors.New("crashed")
+	}))
+	registry.RegisterAgentWithDependencies(name, agent, dependencies)
+	return agent
+}
+
+// restartCount returns how many times the agent has been restarted.
+func restartCount(agent *agents.BaseAgent) int {
+	count := 0
+	for _, transition := range agent.GetStateHistory() {
+		if transition.Reason == "restarted" {
+			count++
+		}
+	}
+	return count
+}
+
+// waitFor polls cond until it holds or the timeout expires.
+func waitFor(t *testing.T, description string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", description)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSupervisorStrategies(t *testing.T) {
+	cases := []struct {
+		strategy agents.RestartStrategy
+		expected []int
+	}{
+		{agents.OneForOne, []int{0, 1, 0}},
+		{agents.OneForAll, []int{1, 1, 1}},
+		{agents.RestForOne, []int{0, 1, 1}},
+	}
+
+	for _, c := range cases {
+		t.Run(string(c.strategy), func(t *testing.T) {
+			registry := agents.NewAgentRegistry()
+			fetcher := newFailingAgent(t, registry, "fetcher")
+			analyzer := newFailingAgent(t, registry, "analyzer", "fetcher")
+			recommender := newFailingAgent(t, registry, "recommender", "analyzer")
+			children := []*agents.BaseAgent{fetcher, analyzer, recommender}
+
+			supervisor := agents.NewSupervisor("root", registry, c.strategy)
+			supervisor.CheckInterval = 10 * time.Millisecond
+			if err := supervisor.SuperviseRegistry(); err != nil {
+				t.Fatalf("Failed to supervise registry: %v", err)
+			}
+			if err := supervisor.Start(); err != nil {
+				t.Fatalf("Failed to start supervisor: %v", err)
+			}
+			defer supervisor.Stop()
+
+			if err := analyzer.Execute(); err == nil {
+				t.Fatalf("Expected execution to fail")
+			}
+			waitFor(t, "the failed agent to be restarted", func() bool {
+				return analyzer.GetState() == agents.StateReady
+			})
+			supervisor.Stop()
+
+			for i, child := range children {
+				if got := restartCount(child); got != c.expected[i] {
+					t.Errorf("Expected %s to be restarted %d time(s), got %d", child.Name, c.expected[i], got)
+				}
+			}
+			if analyzer.ErrorCount != 0 {
+				t.Errorf("Expected restart to clear the error count, got %d", analyzer.ErrorCount)
+			}
+		})
+	}
+}
+
+func TestSupervisorEscalation(t *testing.T) {
+	registry := agents.NewAgentRegistry()
+	agent := newFailingAgent(t, registry, "worker")
+
+	child := agents.NewSupervisor("child", registry, agents.OneForOne)
+	child.CheckInterval = 10 * time.Millisecond
+	child.MaxRestarts = 1
+	child.Period = time.Minute
+	if err := child.Supervise("worker"); err != nil {
+		t.Fatalf("Failed to supervise worker: %v", err)
+	}
+
+	parent := agents.NewSupervisor("parent", registry, agents.OneForOne)
+	parent.CheckInterval = 10 * time.Millisecond
+	parent.AddSupervisor(child)
+	if err := child.Start(); err != nil {
+		t.Fatalf("Failed to start child supervisor: %v", err)
+	}
+	if err := parent.Start(); err != nil {
+		t.Fatalf("Failed to start parent supervisor: %v", err)
+	}
+	defer parent.Stop()
+	defer child.Stop()
+
+	// The first failure is within the child's restart intensity
+	agent.Execute()
+	waitFor(t, "the child supervisor to restart the worker", func() bool {
+		return restartCount(agent) == 1 && agent.GetState() == agents.StateReady
+	})
+	if parent.GetRestartCount() != 0 {
+		t.Errorf("Expected the parent not to be involved yet")
+	}
+
+	// The second failure exceeds it, so the child escalates to the parent,
+	// which restarts the whole subtree
+	agent.Execute()
+	waitFor(t, "the parent supervisor to restart the subtree", func() bool {
+		return parent.GetRestartCount() == 1 && agent.GetState() == agents.StateReady
+	})
+	if child.GetState() != agents.StateRunning || child.Err() != nil {
+		t.Errorf("Expected the child supervisor to be running again, got %s (%v)", child.GetState(), child.Err())
+	}
+	if child.GetRestartCount() != 0 {
+		t.Errorf("Expected the child's restart history to be cleared, got %d", child.GetRestartCount())
+	}
+
+	// Without a parent, exceeding the intensity stops the supervisor
+	orphan := agents.NewSupervisor("orphan", registry, agents.OneForOne)
+	orphan.CheckInterval = 10 * time.Millisecond
+	orphan.MaxRestarts = 0
+	lonely := newFailingAgent(t, registry, "lonely")
+	orphan.AddChild("lonely", lonely)
+	orphan.Start()
+	lonely.Execute()
+	waitFor(t, "the orphan supervisor to give up", func() bool {
+		return orphan.GetState() == agents.StateError
+	})
+	var intensityErr *agents.ErrRestartIntensity
+	if !errors.As(orphan.Err(), &intensityErr) {
+		t.Errorf("Expected ErrRestartIntensity, got %v", orphan.Err())
+	}
+	if lonely.GetState() != agents.StateError {
+		t.Errorf("Expected the agent to be left in error, got %s", lonely.GetState())
+	}
+}
+
+func TestSupervisorRejectsUnknownAgents(t *testing.T) {
+	registry := agents.NewAgentRegistry()
+	registry.RegisterAgent("legacy", &orderedAgent{})
+	supervisor := agents.NewSupervisor("root", registry, agents.OneForOne)
+
+	if err := supervisor.Supervise("missing"); err == nil {
+		t.Errorf("Expected unregistered agent to be rejected")
+	}
+	if err := supervisor.Supervise("legacy"); err == nil {
+		t.Errorf("Expected agent without Restart to be rejected")
+	}
+}
+
+func TestMonitorRestartResumesMonitoring(t *testing.T) {
+	var samples int64
+	collectors := monitoring.NewCollectors()
+	collectors.MustRegister("fake", monitoring.CollectorFunc(func(ctx context.Context, target *url.URL) (*monitoring.Sample, error) {
+		atomic.AddInt64(&samples, 1)
+		return &monitoring.Sample{Status: monitoring.StatusHealthy}, nil
+	}))
+	monitor := agents.NewMonitorAgent("monitor", 10*time.Millisecond)
+	monitor.SetCollectors(collectors)
+	monitor.AddMonitorTarget("fake://host")
+	if err := monitor.Initialize(map[string]interface{}{}); err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+	defer monitor.Shutdown()
+
+	// A monitor that was not started is not started by a restart
+	if err := monitor.Restart(time.Second); err != nil {
+		t.Fatalf("Failed to restart agent: %v", err)
+	}
+	if work := monitor.GetInFlightWork(); len(work) != 0 {
+		t.Errorf("Expected no monitor loop, got %v", work)
+	}
+
+	if err := monitor.Execute(); err != nil {
+		t.Fatalf("Failed to execute agent: %v", err)
+	}
+	waitFor(t, "the first samples", func() bool { return atomic.LoadInt64(&samples) >= 2 })
+	if err := monitor.Restart(time.Second); err != nil {
+		t.Fatalf("Failed to restart agent: %v", err)
+	}
+	if work := monitor.GetInFlightWork(); len(work) != 1 || work[0].Name != "monitor_loop" {
+		t.Errorf("Expected the monitor loop to run again, got %v", work)
+	}
+	restarted := atomic.LoadInt64(&samples)
+	waitFor(t, "samples after the restart", func() bool { return atomic.LoadInt64(&samples) >= restarted+2 })
+}
@@ -0,0 +1,282 @@
+package internal
+
+import (
+	"beluga/pkg/agents"
+	"beluga/pkg/analysis"
+	"beluga/pkg/codec"
+	"beluga/pkg/llm"
+	"beluga/pkg/memory"
+	"beluga/pkg/prompts"
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+// analyze creates the analyzer of analysisType from the default registry
+// and runs it on input.
+func analyze(t *testing.T, analysisType string, settings map[string]interface{}, input interface{}) analysis.Result {
+	t.Helper()
+	analyzer, err := analysis.DefaultRegistry().New(analysisType, analysis.Config{Settings: settings})
+	if err != nil {
+		t.Fatalf("Failed to create %s analyzer: %v", analysisType, err)
+	}
+	result, err := analyzer.Analyze(context.Background(), input)
+	if err != nil {
+		t.Fatalf("%s analysis failed: %v", analysisType, err)
+	}
+	return result
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestSummaryAnalysis(t *testing.T) {
+	dataset := &codec.Dataset{}
+	for i := 1; i <= 10; i++ {
+		record := codec.Record{"latency": int64(i * 10), "region": "eu", "load": map[string]interface{}{"cpu": float64(i)}}
+		if i == 10 {
+			delete(record, "latency")
+		}
+		dataset.Add(record)
+	}
+
+	result := analyze(t, "statistical", map[string]interface{}{"percentiles": []interface{}{50.0, 90, 99.5}}, dataset).(*analysis.SummaryResult)
+	if result.Records != 10 || len(result.Fields) != 2 {
+		t.Fatalf("Expected 10 records with 2 numeric fields, got %+v", result)
+	}
+	latency := result.Fields["latency"]
+	if latency.Count != 9 || latency.Missing != 1 || latency.Mean != 50 || latency.Min != 10 || latency.Max != 90 {
+		t.Errorf("Unexpected latency stats %+v", latency)
+	}
+	if !near(latency.StdDev, math.Sqrt(750)) {
+		t.Errorf("Expected the sample standard deviation, got %v", latency.StdDev)
+	}
+	if latency.Percentiles["p50"] != 50 || !near(latency.Percentiles["p90"], 82) || !near(latency.Percentiles["p99.5"], 89.6) {
+		t.Errorf("Unexpected percentiles %v", latency.Percentiles)
+	}
+	if cpu := result.Fields["load.cpu"]; cpu == nil || cpu.Sum != 55 {
+		t.Errorf("Expected nested fields by dotted path, got %+v", cpu)
+	}
+
+	// Bare numbers are the field "value"; text contributes no numbers
+	result = analyze(t, "summary", nil, []interface{}{3, 1.5, "n/a"}).(*analysis.SummaryResult)
+	if result.Records != 3 || result.Fields["value"].Count != 2 || result.Fields["value"].Mean != 2.25 {
+		t.Errorf("Unexpected summary %+v", result.Fields["value"])
+	}
+
+	if _, err := analysis.DefaultRegistry().New("summary", analysis.Config{Settings: map[string]interface{}{"percentiles": []interface{}{101}}}); err == nil {
+		t.Errorf("Expected a percentile above 100 to fail")
+	}
+}
+
+func TestKeywordAnalysis(t *testing.T) {
+	documents := []string{
+		"The checkout payment failed. Payment retries failed, payment declined.",
+		"Shipping was fast and the checkout was easy.",
+		"Checkout worked, delivery was slow.",
+	}
+	result := analyze(t, "tfidf", map[string]interface{}{"max_keywords": 3}, documents).(*analysis.KeywordResult)
+	if result.Documents != 3 || len(result.Keywords) != 3 || len(result.PerDocument) != 3 {
+		t.Fatalf("Unexpected result %+v", result)
+	}
+	// Terms frequent in one document outrank terms found everywhere
+	if result.Keywords[0].Term != "payment" || result.Keywords[0].Count != 3 {
+		t.Errorf("Expected payment to be the top keyword, got %+v", result.Keywords)
+	}
+	if result.PerDocument[0][0].Term != "payment" || result.PerDocument[1][0].Term == "checkout" {
+		t.Errorf("Unexpected per-document keywords %+v", result.PerDocument)
+	}
+	for _, keyword := range result.Keywords {
+		if keyword.Term == "the" || keyword.Term == "was" {
+			t.Errorf("Expected stop words to be ignored, got %s", keyword.Term)
+		}
+	}
+
+	// Records are analyzed by their text field
+	records := []map[string]interface{}{{"id": 1, "body": "delayed parcel"}, {"id": 2, "body": "parcel lost"}}
+	result = analyze(t, "keyword", map[string]interface{}{"text_field": "body"}, records).(*analysis.KeywordResult)
+	if result.Documents != 2 || len(result.Keywords) != 3 || result.Keywords[2].Term != "parcel" || result.Keywords[2].Documents != 2 {
+		t.Errorf("Unexpected keywords %+v", result.Keywords)
+	}
+}
+
+func TestSentimentAnalysis(t *testing.T) {
+	testCases := []struct {
+		name     string
+		settings map[string]interface{}
+		input    interface{}
+		label    string
+	}{
+		{"Positive", nil, "I love this product, the support was excellent!", analysis.Positive},
+		{"Negative", nil, "Terrible experience, the app keeps crashing.", analysis.Negative},
+		{"Neutral", nil, "The parcel arrived on Tuesday.", analysis.Neutral},
+		{"Negation", nil, "This was not good at all.", analysis.Negative},
+		{"Threshold", map[string]interface{}{"threshold": 0.75}, "It is good.", analysis.Neutral},
+		{"Language", map[string]interface{}{"language": "de-DE"}, "Der Service war hervorragend, die Lieferung nicht schlecht.", analysis.Positive},
+		{"Lexicon", map[string]interface{}{"lexicon": map[string]interface{}{"sluggish": -3}}, "Sluggish and sluggish again.", analysis.Negative},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := analyze(t, "sentiment", tc.settings, tc.input).(*analysis.SentimentResult)
+			if result.Label != tc.label {
+				t.Errorf("Expected %s, got %s (score %v)", tc.label, result.Label, result.Score)
+			}
+			if result.Score < -1 || result.Score > 1 {
+				t.Errorf("Expected a score between -1 and 1, got %v", result.Score)
+			}
+		})
+	}
+
+	result := analyze(t, "sentiment", nil, []string{"great", "awful"}).(*analysis.SentimentResult)
+	if len(result.Documents) != 2 || result.Documents[0].Label != analysis.Positive || result.Label != analysis.Neutral {
+		t.Errorf("Expected per-document sentiment averaging to neutral, got %+v", result)
+	}
+	if strings.Join(result.Positive, ",") != "great" || strings.Join(result.Negative, ",") != "awful" {
+		t.Errorf("Unexpected contributing words %v and %v", result.Positive, result.Negative)
+	}
+
+	for _, settings := range []map[string]interface{}{{"language": "xx"}, {"threshold": 2}, {"threshold": "high"}} {
+		if _, err := analysis.DefaultRegistry().New("sentiment", analysis.Config{Settings: settings}); err == nil {
+			t.Errorf("Expected settings %v to fail", settings)
+		}
+	}
+}
+
+func TestLLMAnalysis(t *testing.T) {
+	model := &scriptedLLM{responses: []llm.Message{
+		{Role: llm.RoleAssistant, Content: "Findings:\n```json\n{\"topic\": \"billing\", \"urgent\": true}\n```"},
+	}}
+	analyzer, err := analysis.DefaultRegistry().New("llm", analysis.Config{
+		Settings: map[string]interface{}{"instructions": "Classify the ticket.", "require_json": true},
+		LLM:      model,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	result, err := analyzer.Analyze(context.Background(), map[string]interface{}{"ticket": "charged twice"})
+	if err != nil {
+		t.Fatalf("Analysis failed: %v", err)
+	}
+	answer := result.(*analysis.LLMResult)
+	if answer.Data["topic"] != "billing" || answer.Data["urgent"] != true {
+		t.Errorf("Expected the JSON object of the answer, got %+v", answer)
+	}
+	request := model.requests[0]
+	if request.Messages[0].Content != "Classify the ticket." || !strings.Contains(request.Messages[1].Content, `"ticket": "charged twice"`) {
+		t.Errorf("Unexpected request %+v", request.Messages)
+	}
+
+	// What the agent recalled is sent after the instructions
+	recalled := analysis.Recalled{
+		History: []memory.Entry{{Input: "card declined", Output: map[string]interface{}{"topic": "payments"}}},
+		Related: []memory.SearchResult{{Document: memory.Document{Content: "refund requested"}, Score: 0.9}},
+	}
+	if _, err := analysis.AnalyzeWith(context.Background(), analyzer, "charged twice", recalled); err != nil {
+		t.Fatalf("Analysis failed: %v", err)
+	}
+	messages := model.requests[1].Messages
+	if len(messages) != 3 || messages[1].Role != llm.RoleSystem || messages[2].Content != "charged twice" {
+		t.Fatalf("Unexpected messages %+v", messages)
+	}
+	for _, part := range []string{"- input: card declined\n  output: {\"topic\":\"payments\"}", "- refund requested (similarity 0.90)"} {
+		if !strings.Contains(messages[1].Content, part) {
+			t.Errorf("Expected the recalled context to contain %q, got %q", part, messages[1].Content)
+		}
+	}
+
+	model.responses = []llm.Message{{Role: llm.RoleAssistant, Content: "I cannot tell."}}
+	if _, err := analyzer.Analyze(context.Background(), "text"); err == nil {
+		t.Errorf("Expected an answer without JSON to fail")
+	}
+	if _, err := analysis.DefaultRegistry().New("llm", analysis.Config{}); err == nil {
+		t.Errorf("Expected the llm analysis to require a model")
+	}
+}
+
+// lengthResult is the result of a custom analysis.
+type lengthResult struct{ Length int }
+
+func (*lengthResult) Type() string { return "length" }
+
+type lengthAnalyzer struct{}
+
+func (lengthAnalyzer) Analyze(ctx context.Context, input interface{}) (analysis.Result, error) {
+	text, ok := input.(string)
+	if !ok {
+		return nil, errors.New("expected text")
+	}
+	return &lengthResult{Length: len(text)}, nil
+}
+
+func TestAnalyzerAgent(t *testing.T) {
+	factory := agents.NewAgentFactory()
+	agent, err := factory.CreateAgent("AnalyzerAgent", "reviews", map[string]interface{}{
+		"analysis_type": "sentiment",
+		"threshold":     0.5,
+		"max_retries":   0,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	analyzer := agent.(*agents.AnalyzerAgent)
+	output, err := analyzer.Run(context.Background(), "The new release is fantastic and fast")
+	if err != nil {
+		t.Fatalf("Analysis failed: %v", err)
+	}
+	if result, ok := output.(*analysis.SentimentResult); !ok || result.Label != analysis.Positive || analyzer.GetAnalysisResult() != output {
+		t.Errorf("Expected a positive sentiment result, got %+v", output)
+	}
+
+	// Unknown analysis types fail at initialization
+	if err := agents.NewAnalyzerAgent("unknown", "unknown").Initialize(map[string]interface{}{"max_retries": 0}); !errors.Is(err, analysis.ErrUnknownAnalysis) {
+		t.Errorf("Expected an unknown analysis type to fail, got %v", err)
+	}
+	if _, err := factory.CreateAgent("AnalyzerAgent", "unknown", map[string]interface{}{"analysis_type": "unknown"}); !errors.Is(err, analysis.ErrUnknownAnalysis) {
+		t.Errorf("Expected the factory to refuse an unknown analysis type, got %v", err)
+	}
+
+	// Invalid settings and a missing model fail at initialization
+	if err := agents.NewAnalyzerAgent("invalid", "sentiment").Initialize(map[string]interface{}{"language": "xx"}); err == nil {
+		t.Errorf("Expected an unsupported language to fail")
+	}
+	if err := agents.NewAnalyzerAgent("no_model", "llm").Initialize(nil); err == nil {
+		t.Errorf("Expected the llm analysis without a model to fail")
+	}
+
+	// Custom analyzers come from the agent's registry
+	registry := analysis.NewRegistry()
+	registry.MustRegister("length", func(config analysis.Config) (analysis.Analyzer, error) {
+		return lengthAnalyzer{}, nil
+	})
+	if err := registry.Register("length", nil); err == nil {
+		t.Errorf("Expected a duplicate analysis type to be rejected")
+	}
+	custom := agents.NewAnalyzerAgent("custom", "length")
+	custom.Analyzers = registry
+	custom.Initialize(map[string]interface{}{"max_retries": 0})
+	if output, err := custom.Run(context.Background(), "four"); err != nil || output.(*lengthResult).Length != 4 {
+		t.Errorf("Expected the custom analysis, got %v (%v)", output, err)
+	}
+
+	// The llm analysis renders the agent's prompt template
+	library := prompts.NewLibrary()
+	library.Parse("triage", `{{role "system"}}{{.instructions}} Reply in {{.language}}.{{role "user"}}{{.input}}`)
+	model := &scriptedLLM{responses: []llm.Message{{Role: llm.RoleAssistant, Content: `{"priority": "high"}`}}}
+	triage := agents.NewAnalyzerAgent("triage", "llm")
+	triage.SetPrompts(library)
+	err = triage.Initialize(map[string]interface{}{"llm": llm.LLM(model), "prompt": "triage", "language": "French", "max_retries": 0})
+	if err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+	output, err = triage.Run(context.Background(), "server down")
+	if err != nil || output.(*analysis.LLMResult).Data["priority"] != "high" {
+		t.Fatalf("Expected the model's analysis, got %v (%v)", output, err)
+	}
+	messages := model.requests[0].Messages
+	if len(messages) != 2 || !strings.HasSuffix(messages[0].Content, "Reply in French.") || messages[1].Content != "server down" {
+		t.Errorf("Unexpected messages %+v", messages)
+	}
+}
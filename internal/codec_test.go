@@ -0,0 +1,336 @@
+package internal
+
+import (
+	"beluga/pkg/agents"
+	"beluga/pkg/codec"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// decodeString decodes input with the named codec of the default registry.
+func decodeString(t *testing.T, format string, options map[string]interface{}, input string) *codec.Dataset {
+	t.Helper()
+	decoding, err := codec.DefaultRegistry().Lookup(format, options)
+	if err != nil {
+		t.Fatalf("Failed to look up codec %s: %v", format, err)
+	}
+	decoder, err := decoding.NewDecoder(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Failed to create %s decoder: %v", format, err)
+	}
+	dataset, err := codec.ReadAll(decoder)
+	if err != nil {
+		t.Fatalf("Failed to decode %s: %v", format, err)
+	}
+	return dataset
+}
+
+// fieldTypes returns the schema as "name:type" strings, with "?" marking
+// nullable fields.
+func fieldTypes(schema *codec.Schema) string {
+	var fields []string
+	for _, field := range schema.Fields {
+		nullable := ""
+		if field.Nullable {
+			nullable = "?"
+		}
+		fields = append(fields, field.Name+":"+field.Type+nullable)
+	}
+	return strings.Join(fields, " ")
+}
+
+func TestJSONCodecs(t *testing.T) {
+	dataset := decodeString(t, "json", nil, `[{"id": 1, "price": 9.5, "tags": ["a"]}, {"id": 2, "price": 10, "extra": null}]`)
+	want := []codec.Record{
+		{"id": int64(1), "price": 9.5, "tags": []interface{}{"a"}},
+		{"id": int64(2), "price": int64(10), "extra": nil},
+	}
+	if !reflect.DeepEqual(dataset.Records, want) {
+		t.Errorf("Expected %v, got %v", want, dataset.Records)
+	}
+	if got := fieldTypes(dataset.Schema); got != "id:integer price:number tags:array? extra:null?" {
+		t.Errorf("Unexpected schema %s", got)
+	}
+
+	dataset = decodeString(t, "json", nil, `{"name": "one"} "two"`)
+	if !reflect.DeepEqual(dataset.Records, []codec.Record{{"name": "one"}, {"value": "two"}}) {
+		t.Errorf("Expected each top-level value to be a record, got %v", dataset.Records)
+	}
+
+	dataset = decodeString(t, "json", map[string]interface{}{"records_path": "data.items"}, `{"data": {"items": [{"id": 1}, {"id": 2}]}}`)
+	if len(dataset.Records) != 2 || dataset.Records[1]["id"] != int64(2) {
+		t.Errorf("Expected the records at data.items, got %v", dataset.Records)
+	}
+
+	dataset = decodeString(t, "ndjson", nil, "{\"id\": 1}\n\n{\"id\": 2}\r\n[3]")
+	if len(dataset.Records) != 3 || dataset.Records[2]["value"] == nil {
+		t.Errorf("Expected three records, got %v", dataset.Records)
+	}
+
+	decoder, _ := codec.NDJSONCodec{}.NewDecoder(strings.NewReader("{\"id\": 1}\n{\"id\": \n"))
+	if _, err := codec.ReadAll(decoder); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected an error on line 2, got %v", err)
+	}
+}
+
+// endlessNDJSON produces NDJSON records without end, counting the bytes read.
+type endlessNDJSON struct {
+	next    int
+	pending []byte
+	read    int
+}
+
+func (e *endlessNDJSON) Read(p []byte) (int, error) {
+	if len(e.pending) == 0 {
+		e.next++
+		e.pending = []byte(fmt.Sprintf("{\"id\": %d, \"payload\": %q}\n", e.next, strings.Repeat("x", 100)))
+	}
+	n := copy(p, e.pending)
+	e.pending = e.pending[n:]
+	e.read += n
+	return n, nil
+}
+
+func TestStreamingDecode(t *testing.T) {
+	for _, format := range []string{"ndjson", "json"} {
+		t.Run(format, func(t *testing.T) {
+			source := &endlessNDJSON{}
+			var input io.Reader = source
+			if format == "json" {
+				input = io.MultiReader(strings.NewReader("["), &commaSeparated{source: source})
+			}
+			decoding, _ := codec.DefaultRegistry().Get(format)
+			decoder, err := decoding.NewDecoder(input)
+			if err != nil {
+				t.Fatalf("Failed to create decoder: %v", err)
+			}
+			for i := 1; i <= 1000; i++ {
+				record, err := decoder.Next()
+				if err != nil {
+					t.Fatalf("Failed to decode record %d: %v", i, err)
+				}
+				if record["id"] != int64(i) {
+					t.Fatalf("Expected record %d, got %v", i, record["id"])
+				}
+			}
+			// An endless input can only be decoded if it is not read ahead
+			if source.read > 1000*130+64*1024 {
+				t.Errorf("Expected the input to be read incrementally, read %d bytes", source.read)
+			}
+		})
+	}
+}
+
+// commaSeparated turns NDJSON lines into the elements of a JSON array.
+type commaSeparated struct {
+	source *endlessNDJSON
+}
+
+func (c *commaSeparated) Read(p []byte) (int, error) {
+	n, err := c.source.Read(p)
+	for i := 0; i < n; i++ {
+		if p[i] == '\n' {
+			p[i] = ','
+		}
+	}
+	return n, err
+}
+
+func TestCSVCodec(t *testing.T) {
+	dataset := decodeString(t, "csv", nil, "\ufeffid,name,zip,score,active\n1,Ada,01234,9.5,true\n2,Bob,10115,,false\n3,,20095,7,TRUE\n")
+	want := []codec.Record{
+		{"id": int64(1), "name": "Ada", "zip": "01234", "score": 9.5, "active": true},
+		{"id": int64(2), "name": "Bob", "zip": "10115", "score": nil, "active": false},
+		{"id": int64(3), "name": nil, "zip": "20095", "score": 7.0, "active": true},
+	}
+	if !reflect.DeepEqual(dataset.Records, want) {
+		t.Errorf("Expected %v, got %v", want, dataset.Records)
+	}
+	if got := fieldTypes(dataset.Schema); got != "id:integer name:string? zip:string score:number? active:boolean" {
+		t.Errorf("Unexpected schema %s", got)
+	}
+
+	t.Run("Header inference", func(t *testing.T) {
+		dataset := decodeString(t, "csv", nil, "1,2\n3,4\n")
+		if len(dataset.Records) != 2 || dataset.Records[0]["column_1"] != int64(1) {
+			t.Errorf("Expected a first row of numbers to be data, got %v", dataset.Records)
+		}
+		dataset = decodeString(t, "csv", map[string]interface{}{"header": true}, "1,2\n3,4\n")
+		if len(dataset.Records) != 1 || dataset.Records[0]["1"] != int64(3) {
+			t.Errorf("Expected a forced header, got %v", dataset.Records)
+		}
+	})
+
+	t.Run("Options", func(t *testing.T) {
+		options := map[string]interface{}{
+			"delimiter":  ";",
+			"comment":    "#",
+			"infer_rows": 1,
+			"types":      map[string]interface{}{"code": "string"},
+		}
+		dataset := decodeString(t, "csv", options, "# export\ncode;amount\n7;1\n8;n/a\n")
+		want := []codec.Record{{"code": "7", "amount": int64(1)}, {"code": "8", "amount": "n/a"}}
+		if !reflect.DeepEqual(dataset.Records, want) {
+			t.Errorf("Expected %v, got %v", want, dataset.Records)
+		}
+		// The cell that did not fit the inferred type widens the schema
+		if got := fieldTypes(dataset.Schema); got != "code:string amount:string" {
+			t.Errorf("Unexpected schema %s", got)
+		}
+
+		if _, err := codec.DefaultRegistry().Lookup("csv", map[string]interface{}{"delimiter": ";;"}); err == nil {
+			t.Errorf("Expected an invalid delimiter to fail")
+		}
+		if _, err := codec.DefaultRegistry().Lookup("csv", map[string]interface{}{"types": map[string]interface{}{"a": "date"}}); err == nil {
+			t.Errorf("Expected an unsupported column type to fail")
+		}
+	})
+}
+
+func TestXMLAndYAMLCodecs(t *testing.T) {
+	input := `<?xml version="1.0"?>
+<catalog>
+  <book id="b1" available="true">
+    <title>Go</title>
+    <author>Ann</author>
+    <author>Ben</author>
+    <price currency="EUR">30.5</price>
+  </book>
+  <book id="b2"><title>Rust</title></book>
+</catalog>`
+	dataset := decodeString(t, "xml", nil, input)
+	want := []codec.Record{
+		{
+			"@id": "b1", "@available": true, "title": "Go",
+			"author": []interface{}{"Ann", "Ben"},
+			"price":  map[string]interface{}{"@currency": "EUR", "#text": 30.5},
+		},
+		{"@id": "b2", "title": "Rust"},
+	}
+	if !reflect.DeepEqual(dataset.Records, want) {
+		t.Errorf("Expected %v, got %v", want, dataset.Records)
+	}
+
+	dataset = decodeString(t, "xml", map[string]interface{}{"record_element": "title", "keep_strings": true}, input)
+	if len(dataset.Records) != 2 || dataset.Records[1]["value"] != "Rust" {
+		t.Errorf("Expected the title elements as records, got %v", dataset.Records)
+	}
+
+	dataset = decodeString(t, "yaml", nil, "- name: a\n  size: 1\n- name: b\n  size: 2.5\n---\nname: c\nnested: {x: 1}\n")
+	if len(dataset.Records) != 3 || dataset.Records[2]["nested"].(map[string]interface{})["x"] != int64(1) {
+		t.Errorf("Expected three records across documents, got %v", dataset.Records)
+	}
+	if got := fieldTypes(dataset.Schema); got != "name:string size:number? nested:object?" {
+		t.Errorf("Unexpected schema %s", got)
+	}
+}
+
+// upperCodec is a custom codec decoding each line as an upper-cased record.
+type upperCodec struct{}
+
+func (upperCodec) Name() string { return "upper" }
+
+func (upperCodec) NewDecoder(r io.Reader) (codec.Decoder, error) {
+	lines, err := codec.LinesCodec{}.NewDecoder(r)
+	if err != nil {
+		return nil, err
+	}
+	return upperDecoder{lines}, nil
+}
+
+type upperDecoder struct{ lines codec.Decoder }
+
+func (d upperDecoder) Next() (codec.Record, error) {
+	record, err := d.lines.Next()
+	if err != nil {
+		return nil, err
+	}
+	return codec.Record{"value": strings.ToUpper(record["text"].(string))}, nil
+}
+
+func TestCodecRegistry(t *testing.T) {
+	registry := codec.NewRegistry()
+	if got := strings.Join(registry.Names(), ","); got != "csv,json,lines,ndjson,text,xml,yaml" {
+		t.Errorf("Unexpected built-in codecs %s", got)
+	}
+	if err := registry.Register(upperCodec{}); err != nil {
+		t.Fatalf("Failed to register codec: %v", err)
+	}
+	if err := registry.Register(upperCodec{}); err == nil {
+		t.Errorf("Expected a duplicate codec to be rejected")
+	}
+
+	dataset, err := registry.Decode("UPPER", strings.NewReader("a\nb\n"))
+	if err != nil || len(dataset.Records) != 2 || dataset.Records[1]["value"] != "B" {
+		t.Errorf("Expected the custom codec to decode, got %v (%v)", dataset, err)
+	}
+	if _, err := registry.Decode("parquet", strings.NewReader("")); !errors.Is(err, codec.ErrUnknownCodec) {
+		t.Errorf("Expected ErrUnknownCodec, got %v", err)
+	}
+	if _, err := registry.Lookup("upper", map[string]interface{}{"x": 1}); err == nil {
+		t.Errorf("Expected options for a codec without options to fail")
+	}
+	if _, ok := codec.DefaultRegistry().Get("upper"); ok {
+		t.Errorf("Expected the default registry to be unaffected")
+	}
+}
+
+func TestDataFetcherCodecs(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sales.csv")
+	os.WriteFile(path, []byte("region;units\nnorth;12\nsouth;7\n"), 0644)
+
+	fetcher := agents.NewDataFetcherAgent("csv_fetcher", path, "csv")
+	err := fetcher.Initialize(map[string]interface{}{
+		"format_options": map[string]interface{}{"delimiter": ";"},
+		"max_retries":    0,
+	})
+	if err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+	output, err := fetcher.Run(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	dataset := output.(*codec.Dataset)
+	if len(dataset.Records) != 2 || dataset.Records[0]["units"] != int64(12) || fieldTypes(dataset.Schema) != "region:string units:integer" {
+		t.Errorf("Unexpected dataset %v with schema %s", dataset.Records, fieldTypes(dataset.Schema))
+	}
+
+	// Streaming hands over records without collecting them
+	ndjson := filepath.Join(dir, "events.ndjson")
+	os.WriteFile(ndjson, []byte("{\"n\": 1}\n{\"n\": 2}\n{\"n\": 3}\n"), 0644)
+	total := int64(0)
+	schema, err := fetcher.Stream(context.Background(), map[string]interface{}{"path": ndjson, "data_format": "ndjson"}, func(record codec.Record) error {
+		total += record["n"].(int64)
+		return nil
+	})
+	if err != nil || total != 6 || fieldTypes(schema) != "n:integer" {
+		t.Errorf("Expected to stream 3 records, got total %d and schema %v (%v)", total, schema, err)
+	}
+
+	// A failing handler stops the stream without the fetch being retried
+	retried := agents.NewDataFetcherAgent("retried_fetcher", ndjson, "ndjson")
+	retried.Initialize(map[string]interface{}{"max_retries": 3, "retry_delay": 0})
+	calls := 0
+	_, err = retried.Stream(context.Background(), nil, func(record codec.Record) error {
+		calls++
+		return errors.New("sink full")
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("Expected the stream to stop at the first record, got %d calls (%v)", calls, err)
+	}
+
+	if err := agents.NewDataFetcherAgent("bad_options", path, "csv").Initialize(map[string]interface{}{
+		"format_options": map[string]interface{}{"quote": "'"},
+	}); err == nil {
+		t.Errorf("Expected unknown format options to fail")
+	}
+}
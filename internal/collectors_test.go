@@ -0,0 +1,241 @@
+package internal
+
+import (
+	"beluga/pkg/agents"
+	"beluga/pkg/monitoring"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProcessCollector(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "4242")
+	if err := os.MkdirAll(filepath.Join(dir, "fd"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// Fields after the command: state, then fields 4 to 24 of stat, with
+	// utime 300, stime 100, 7 threads and a start 10s after boot
+	fields := make([]string, 22)
+	for i := range fields {
+		fields[i] = "0"
+	}
+	fields[0], fields[11], fields[12], fields[17], fields[19] = "S", "300", "100", "7", "1000"
+	files := map[string]string{
+		"uptime":      "30.00 55.00\n",
+		"4242/stat":   "4242 (my (odd) proc) " + strings.Join(fields, " ") + "\n",
+		"4242/status": "Name:\tmy proc\nVmRSS:\t   20480 kB\nThreads:\t7\n",
+		"4242/fd/0":   "",
+		"4242/fd/1":   "",
+		"4242/fd/2":   "",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	collector := monitoring.NewProcessCollector()
+	collector.Root = root
+	sample, err := collector.Collect(context.Background(), &url.URL{Scheme: "proc", Host: "4242"})
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	// 4s of CPU over the 20s the process has been running
+	expected := map[string]float64{"cpu_usage": 20, "memory_use": 20, "open_fds": 3, "threads": 7}
+	for name, value := range expected {
+		if sample.Metrics[name] != value {
+			t.Errorf("Expected %s %v, got %v", name, value, sample.Metrics[name])
+		}
+	}
+	if _, ok := sample.Metrics["goroutines"]; ok || sample.Details["state"] != "S" {
+		t.Errorf("Unexpected sample %+v", sample)
+	}
+	if _, err := collector.Collect(context.Background(), &url.URL{Scheme: "proc", Host: "init"}); err == nil {
+		t.Errorf("Expected a target that is not a pid to fail")
+	}
+	if _, err := collector.Collect(context.Background(), &url.URL{Scheme: "proc", Host: "99"}); err == nil {
+		t.Errorf("Expected a missing process to fail")
+	}
+
+	// This process, through the default collectors
+	if _, err := os.Stat("/proc/self/stat"); err != nil {
+		t.Skip("no /proc on this system")
+	}
+	self := monitoring.DefaultCollectors().Collect(context.Background(), "proc://self")
+	if self.Status != monitoring.StatusHealthy || self.Metrics["memory_use"] <= 0 || self.Metrics["goroutines"] < 1 || self.Metrics["open_fds"] < 1 {
+		t.Errorf("Unexpected sample of this process %+v", self)
+	}
+}
+
+func TestProbeCollectors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	collectors := monitoring.NewCollectors()
+	if schemes := strings.Join(collectors.Schemes(), ","); schemes != "disk,http,https,proc,tcp" {
+		t.Errorf("Unexpected schemes %s", schemes)
+	}
+
+	sample := collectors.Collect(context.Background(), server.URL+"/health")
+	if sample.Status != monitoring.StatusHealthy || sample.Metrics["status_code"] != 200 || sample.Metrics["latency_ms"] <= 0 || sample.Target != server.URL+"/health" {
+		t.Errorf("Unexpected http sample %+v", sample)
+	}
+	if sample := collectors.Collect(context.Background(), server.URL+"/broken"); sample.Status != monitoring.StatusUnhealthy || sample.Metrics["status_code"] != 503 {
+		t.Errorf("Expected an unhealthy http sample, got %+v", sample)
+	}
+
+	address := strings.TrimPrefix(server.URL, "http://")
+	if sample := collectors.Collect(context.Background(), "tcp://"+address); sample.Status != monitoring.StatusHealthy || sample.Metrics["latency_ms"] <= 0 {
+		t.Errorf("Unexpected tcp sample %+v", sample)
+	}
+	closed, _ := net.Listen("tcp", "127.0.0.1:0")
+	closedAddress := closed.Addr().String()
+	closed.Close()
+	if sample := collectors.Collect(context.Background(), "tcp://"+closedAddress); sample.Status != monitoring.StatusUnhealthy || sample.Message == "" {
+		t.Errorf("Expected a refused connection to be unhealthy, got %+v", sample)
+	}
+
+	sample = collectors.Collect(context.Background(), "disk://"+t.TempDir())
+	used := sample.Metrics["disk_used_percent"]
+	if sample.Status != monitoring.StatusHealthy || sample.Metrics["disk_total_bytes"] <= 0 || used < 0 || used > 100 {
+		t.Errorf("Unexpected disk sample %+v", sample)
+	}
+	if sample := collectors.Collect(context.Background(), "disk:///no/such/dir"); sample.Status != monitoring.StatusUnhealthy {
+		t.Errorf("Expected a missing path to be unhealthy, got %+v", sample)
+	}
+	if sample := collectors.Collect(context.Background(), "ftp://example.com"); sample.Status != monitoring.StatusUnhealthy || !strings.Contains(sample.Message, "no collector for scheme ftp") {
+		t.Errorf("Expected an unknown scheme to be unhealthy, got %+v", sample)
+	}
+	if target, err := monitoring.ParseTarget("web_data_fetcher"); err != nil || target.Scheme != monitoring.AgentScheme || target.Opaque != "web_data_fetcher" {
+		t.Errorf("Expected an agent target, got %v (%v)", target, err)
+	}
+
+	// Thresholds degrade healthy samples
+	sample = &monitoring.Sample{Status: monitoring.StatusHealthy, Metrics: map[string]float64{"cpu_usage": 93.5, "memory_use": 10}}
+	sample.ApplyThresholds(map[string]float64{"cpu_usage": 80, "memory_use": 1024})
+	if sample.Status != monitoring.StatusDegraded || sample.Message != "cpu_usage 93.5 exceeds 80" {
+		t.Errorf("Expected a degraded sample, got %+v", sample)
+	}
+}
+
+func TestMetricHistory(t *testing.T) {
+	history := monitoring.NewHistory(3)
+	start := time.Now().Add(-time.Minute)
+	for i := 0; i < 5; i++ {
+		history.Add(&monitoring.Sample{
+			Target:    "proc://self",
+			Status:    monitoring.StatusHealthy,
+			Timestamp: start.Add(time.Duration(i) * time.Second),
+			Metrics:   map[string]float64{"cpu_usage": float64(10 * i)},
+		})
+	}
+	if samples := history.Samples("proc://self"); len(samples) != 3 || samples[0].Metrics["cpu_usage"] != 20 {
+		t.Errorf("Expected the 3 latest samples, got %d", len(samples))
+	}
+	points := history.Series("proc://self", "cpu_usage", start.Add(3*time.Second))
+	if len(points) != 2 || points[0].Value != 30 || points[1].Value != 40 {
+		t.Errorf("Unexpected series %+v", points)
+	}
+	summary := monitoring.Summarize(history.Series("proc://self", "cpu_usage", time.Time{}))
+	if summary.Count != 3 || summary.Min != 20 || summary.Max != 40 || summary.Mean != 30 || summary.Last != 40 {
+		t.Errorf("Unexpected summary %+v", summary)
+	}
+
+	// Health checks report the latest sample, unless it is stale
+	check := history.HealthCheck("monitor", "proc://self", time.Hour)
+	check.RunCheck()
+	if result := check.GetLastResult(); result.Status != monitoring.StatusHealthy || result.Details["cpu_usage"] != 40.0 || result.ComponentID != "proc://self" {
+		t.Errorf("Unexpected health check result %+v", result)
+	}
+	stale := history.HealthCheck("monitor", "proc://self", time.Second)
+	stale.RunCheck()
+	if result := stale.GetLastResult(); result.Status != monitoring.StatusUnknown {
+		t.Errorf("Expected a stale sample to be unknown, got %+v", result)
+	}
+	missing := history.HealthCheck("monitor", "tcp://db:5432", time.Hour)
+	missing.RunCheck()
+	if result := missing.GetLastResult(); result.Status != monitoring.StatusUnknown {
+		t.Errorf("Expected no sample to be unknown, got %+v", result)
+	}
+}
+
+func TestMonitorAgentCollectors(t *testing.T) {
+	var load int64
+	collectors := monitoring.NewCollectors()
+	collectors.MustRegister("fake", monitoring.CollectorFunc(func(ctx context.Context, target *url.URL) (*monitoring.Sample, error) {
+		return &monitoring.Sample{Metrics: map[string]float64{"load": float64(atomic.AddInt64(&load, 1))}}, nil
+	}))
+
+	factory := agents.NewAgentFactory()
+	worker, err := factory.CreateAgentFromConfig(&agents.AgentConfig{Name: "worker", Type: "AnalyzerAgent", Settings: map[string]interface{}{}})
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	defer worker.Shutdown()
+
+	monitor := agents.NewMonitorAgent("monitor", 20*time.Millisecond)
+	monitor.Agents = factory.Registry
+	monitor.SetCollectors(collectors)
+	if err := monitor.Initialize(map[string]interface{}{
+		"alert_thresholds": map[string]interface{}{"load": 2},
+		"history_size":     5,
+	}); err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+	for _, target := range []string{"fake://host", "worker", "ghost", "gopher://nowhere"} {
+		monitor.AddMonitorTarget(target)
+	}
+	manager := monitoring.NewHealthCheckManager()
+	if err := monitor.RegisterHealthChecks(manager); err != nil {
+		t.Fatalf("Failed to register health checks: %v", err)
+	}
+	if _, ok := manager.GetCheckResults()["fake://host:monitor"]; !ok {
+		t.Errorf("Expected a health check per target, got %v", manager.GetCheckResults())
+	}
+	if err := monitor.Execute(); err != nil {
+		t.Fatalf("Failed to execute agent: %v", err)
+	}
+	defer monitor.Shutdown()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadInt64(&load) < 7 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	monitor.Pause(context.Background())
+
+	history := monitor.GetHistory()
+	if samples := history.Samples("fake://host"); len(samples) != 5 {
+		t.Errorf("Expected 5 samples kept, got %d", len(samples))
+	}
+	results := monitor.GetMonitorResults()
+	fake := results["fake://host"].(map[string]interface{})
+	if fake["status"] != "degraded" || !strings.Contains(fake["message"].(string), "load") {
+		t.Errorf("Expected the threshold to degrade the target, got %v", fake)
+	}
+	if agent := results["worker"].(map[string]interface{}); agent["status"] != "healthy" || agent["error_count"] != 0.0 {
+		t.Errorf("Unexpected agent result %v", agent)
+	}
+	if ghost := results["ghost"].(map[string]interface{}); ghost["status"] != "unknown" {
+		t.Errorf("Expected an unregistered agent to be unknown, got %v", ghost)
+	}
+	if unknown := results["gopher://nowhere"].(map[string]interface{}); unknown["status"] != "unhealthy" {
+		t.Errorf("Expected an unknown scheme to be unhealthy, got %v", unknown)
+	}
+	if _, err := factory.CreateAgent("MonitorAgent", "broken", map[string]interface{}{"alert_thresholds": map[string]interface{}{"cpu_usage": "high"}}); err == nil {
+		t.Errorf("Expected a threshold that is not a number to fail")
+	}
+}
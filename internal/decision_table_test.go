@@ -0,0 +1,293 @@
+package internal
+
+import (
+	"beluga/pkg/agents"
+	"beluga/pkg/agents/config"
+	"beluga/pkg/analysis"
+	"beluga/pkg/rules"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func recommendationTable(t *testing.T, hitPolicy string, rows []interface{}, extra map[string]interface{}) *rules.DecisionTable {
+	t.Helper()
+	settings := map[string]interface{}{
+		"name":       "recommendation",
+		"hit_policy": hitPolicy,
+		"inputs": []interface{}{
+			"score",
+			map[string]interface{}{"name": "label", "values": []interface{}{"positive", "neutral", "negative"}},
+		},
+		"outputs": []interface{}{"decision"},
+		"rows":    rows,
+	}
+	for key, value := range extra {
+		settings[key] = value
+	}
+	table, err := rules.TableFromMap(settings, "")
+	if err != nil {
+		t.Fatalf("Failed to load table: %v", err)
+	}
+	return table
+}
+
+func TestDecisionTable(t *testing.T) {
+	rows := []interface{}{
+		map[string]interface{}{"name": "promote", "when": []interface{}{">= 0.6", "positive"}, "then": "recommend"},
+		map[string]interface{}{"name": "review", "when": []interface{}{"[-0.4..0.6)", "-"}, "then": "review"},
+		map[string]interface{}{"name": "suppress", "when": map[string]interface{}{"label": "negative"}, "then": map[string]interface{}{"decision": "suppress"}},
+	}
+	first := recommendationTable(t, rules.HitFirst, rows, map[string]interface{}{"default": "hold"})
+	if first.Inputs[0].Type != rules.TypeNumber || first.Inputs[1].Type != rules.TypeString {
+		t.Errorf("Expected inferred input types, got %+v", first.Inputs)
+	}
+
+	testCases := []struct {
+		data    interface{}
+		outcome string
+		row     string
+		fired   int
+	}{
+		{&analysis.SentimentResult{Label: analysis.Positive, Score: 0.84}, "recommend", "promote", 1},
+		{map[string]interface{}{"label": "positive", "score": 0.6}, "recommend", "promote", 1},
+		{map[string]interface{}{"label": "negative", "score": 0.1}, "review", "review", 2},
+		{map[string]interface{}{"label": "negative", "score": -0.9}, "suppress", "suppress", 1},
+		{map[string]interface{}{"label": "neutral", "score": 0.9}, "hold", "", 0},
+		{map[string]interface{}{"label": "neutral"}, "hold", "", 0},
+	}
+	for _, tc := range testCases {
+		decision, err := first.Decide(tc.data)
+		if err != nil {
+			t.Fatalf("Decision on %v failed: %v", tc.data, err)
+		}
+		if decision.Outcome != tc.outcome || decision.Rule != tc.row || len(decision.Fired) != tc.fired {
+			t.Errorf("Decision on %v: expected %s by %q, got %+v", tc.data, tc.outcome, tc.row, decision)
+		}
+	}
+
+	decision, _ := first.Decide(map[string]interface{}{"label": "positive", "score": 0.7})
+	if decision.Outputs[0]["decision"] != "recommend" || decision.HitPolicy != rules.HitFirst {
+		t.Errorf("Expected the outputs of the first row, got %+v", decision)
+	}
+	explanation := decision.Explanation()
+	if !strings.Contains(explanation, `row promote decided recommend: score: >= 0.6, label: positive with label = "positive", score = 0.7`) {
+		t.Errorf("Unexpected explanation %q", explanation)
+	}
+
+	// A unique table fails when rows overlap
+	unique := recommendationTable(t, rules.HitUnique, rows, map[string]interface{}{"default": "hold"})
+	if _, err := unique.Decide(map[string]interface{}{"label": "negative", "score": 0}); err == nil || !strings.Contains(err.Error(), "rows review and suppress both match") {
+		t.Errorf("Expected overlapping rows to fail, got %v", err)
+	}
+	if _, err := unique.Decide(map[string]interface{}{"label": "positive", "score": "high"}); err == nil {
+		t.Errorf("Expected a string score to fail")
+	}
+
+	// A priority table uses the row whose output comes first
+	priority := recommendationTable(t, rules.HitPriority, rows, map[string]interface{}{
+		"outputs": []interface{}{map[string]interface{}{"name": "decision", "values": []interface{}{"suppress", "review", "recommend"}}},
+	})
+	decision, err := priority.Decide(map[string]interface{}{"label": "negative", "score": 0.2})
+	if err != nil || decision.Outcome != "suppress" || decision.Fired[1].Rule != "review" {
+		t.Errorf("Expected suppress to take priority, got %+v, %v", decision, err)
+	}
+
+	// A collect table uses every matching row
+	collect := recommendationTable(t, rules.HitCollect, rows, nil)
+	decision, _ = collect.Decide(map[string]interface{}{"label": "negative", "score": 0.2})
+	if decision.Outcome != "review, suppress" || len(decision.Outputs) != 2 {
+		t.Errorf("Expected both outcomes, got %+v", decision)
+	}
+	if !strings.Contains(decision.Explanation(), "row review collected review") || !strings.Contains(decision.Explanation(), "row suppress collected suppress") {
+		t.Errorf("Unexpected explanation %q", decision.Explanation())
+	}
+
+	points, err := rules.TableFromMap(map[string]interface{}{
+		"hit_policy":  "collect",
+		"aggregation": "sum",
+		"inputs":      []interface{}{map[string]interface{}{"name": "words", "expression": "len(keywords)"}, "urgent"},
+		"outputs":     []interface{}{"points"},
+		"rows": []interface{}{
+			map[string]interface{}{"when": []interface{}{"> 2", "-"}, "then": 2},
+			map[string]interface{}{"when": []interface{}{"-", true}, "then": 5},
+			map[string]interface{}{"when": []interface{}{"not(0, 1)", "false"}, "then": 0.5},
+		},
+	}, "")
+	if err != nil {
+		t.Fatalf("Failed to load table: %v", err)
+	}
+	if points.Inputs[1].Type != rules.TypeBoolean {
+		t.Errorf("Expected a boolean input, got %s", points.Inputs[1].Type)
+	}
+	decision, err = points.Decide(map[string]interface{}{"keywords": []string{"a", "b", "c"}, "urgent": false})
+	if err != nil || decision.Outcome != "2.5" {
+		t.Errorf("Expected the sum 2.5, got %+v, %v", decision, err)
+	}
+
+	invalid := []map[string]interface{}{
+		{"inputs": []interface{}{"score"}, "outputs": []interface{}{"decision"}, "hit_policy": "any"},
+		{"inputs": []interface{}{"score"}, "outputs": []interface{}{"decision"}, "aggregation": "sum"},
+		{"inputs": []interface{}{map[string]interface{}{"name": "score", "type": "number"}}, "outputs": []interface{}{"decision"}, "rows": []interface{}{map[string]interface{}{"when": []interface{}{"[1..0]"}, "then": "x"}}},
+		{"inputs": []interface{}{"score"}, "outputs": []interface{}{"decision"}, "rows": []interface{}{map[string]interface{}{"when": []interface{}{"1", "2"}, "then": "x"}}},
+		{"inputs": []interface{}{map[string]interface{}{"name": "label", "values": []interface{}{"a"}}}, "outputs": []interface{}{"decision"}, "rows": []interface{}{map[string]interface{}{"when": []interface{}{"b"}, "then": "x"}}},
+		{"inputs": []interface{}{"score"}, "outputs": []interface{}{"decision"}, "hit_policy": "priority"},
+		{"inputs": []interface{}{"score"}, "outputs": []interface{}{"decision"}, "rows": []interface{}{map[string]interface{}{"when": map[string]interface{}{"level": "1"}, "then": "x"}}},
+		{"inputs": []interface{}{"score"}, "outputs": []interface{}{"decision"}, "default": map[string]interface{}{"outcome": "x"}},
+		{"inputs": []interface{}{"score"}, "outputs": []interface{}{"decision"}, "columns": 2},
+		{"path": "table.txt"},
+	}
+	for _, settings := range invalid {
+		if _, err := rules.TableFromMap(settings, ""); err == nil {
+			t.Errorf("Expected %v to fail", settings)
+		}
+	}
+}
+
+func TestDecisionTableValidation(t *testing.T) {
+	rows := []interface{}{
+		map[string]interface{}{"name": "promote", "when": []interface{}{">= 0.6", "positive"}, "then": "recommend"},
+		map[string]interface{}{"name": "review", "when": []interface{}{"[-0.4..0.6]", "-"}, "then": "review"},
+		map[string]interface{}{"name": "suppress", "when": []interface{}{"< -0.4", "not(positive)"}, "then": "suppress"},
+	}
+	table := recommendationTable(t, rules.HitUnique, rows, nil)
+	err := table.Validate()
+	var validation *rules.ValidationError
+	if !errors.As(err, &validation) {
+		t.Fatalf("Expected a validation error, got %v", err)
+	}
+	want := []string{
+		`gap at score: < -0.4, label: "positive"`,
+		`rows promote, review overlap at score: 0.6, label: "positive"`,
+		`gap at score: > 0.6, label: "neutral", "negative"`,
+	}
+	if strings.Join(validation.Problems, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected problems %q, got %q", want, validation.Problems)
+	}
+
+	// A default fills the gaps, and the first hit policy allows overlaps
+	if err := recommendationTable(t, rules.HitFirst, rows, map[string]interface{}{"default": "hold"}).Validate(); err != nil {
+		t.Errorf("Expected a valid table, got %v", err)
+	}
+
+	// String inputs without listed values leave other strings uncovered
+	table, err = rules.TableFromMap(map[string]interface{}{
+		"inputs":  []interface{}{"label"},
+		"outputs": []interface{}{"decision"},
+		"rows": []interface{}{
+			map[string]interface{}{"when": []interface{}{`"positive", neutral`}, "then": "keep"},
+			map[string]interface{}{"when": []interface{}{"negative"}, "then": "drop"},
+		},
+	}, "")
+	if err != nil {
+		t.Fatalf("Failed to load table: %v", err)
+	}
+	if err := table.Validate(); err == nil || !strings.Contains(err.Error(), "gap at label: other values") {
+		t.Errorf("Expected a gap for other values, got %v", err)
+	}
+}
+
+func TestDecisionTableFiles(t *testing.T) {
+	dir := t.TempDir()
+	csvTable := "# Recommendations by sentiment\n" +
+		"score,label,decision,description\n" +
+		">= 0.6,positive,recommend,Clearly positive\n" +
+		"\"[-0.4..0.6)\",-,review,Undecided\n" +
+		">= 0.6,\"not(positive)\",review,\n" +
+		"< -0.4,-,suppress,Clearly negative\n"
+	yamlTable := "hit_policy: first\n" +
+		"inputs:\n" +
+		"  - name: score\n" +
+		"    expression: sentiment.score\n" +
+		"outputs: [decision, weight]\n" +
+		"rows:\n" +
+		"  - when: ['>= 0']\n" +
+		"    then: {decision: recommend, weight: 1}\n" +
+		"  - when: ['< 0']\n" +
+		"    then: [suppress, 0]\n"
+	files := map[string]string{"recommend.csv": csvTable, "tables/recommend.yaml": yamlTable}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	table, err := rules.LoadTable(filepath.Join(dir, "recommend.csv"))
+	if err != nil {
+		t.Fatalf("Failed to load the CSV table: %v", err)
+	}
+	if len(table.Rows) != 4 || table.Rows[0].Description != "Clearly positive" || table.HitPolicy != rules.HitUnique {
+		t.Errorf("Unexpected table %+v", table)
+	}
+	if err := table.Validate(); err != nil {
+		t.Errorf("Expected a complete table, got %v", err)
+	}
+
+	table, err = rules.LoadTable(filepath.Join(dir, "tables", "recommend.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to load the YAML table: %v", err)
+	}
+	decision, err := table.Decide(map[string]interface{}{"sentiment": map[string]interface{}{"score": -0.5}})
+	if err != nil || decision.Outcome != "suppress" || decision.Outputs[0]["weight"] != 0.0 {
+		t.Errorf("Unexpected decision %+v, %v", decision, err)
+	}
+
+	// The config manager resolves and checks the tables of agents
+	writeConfig := func(settings string) (*config.ConfigManager, error) {
+		path := filepath.Join(dir, "agents.yaml")
+		content := "agents:\n  - name: recommender\n    type: DecisionMakerAgent\n    settings:\n" + settings
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		manager := config.NewConfigManager()
+		return manager, manager.LoadConfig(path)
+	}
+	manager, err := writeConfig("      decision_table: recommend.csv\n")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	agentConfig, _ := manager.GetAgentConfig("recommender")
+	if agentConfig.Settings["decision_table"] != filepath.Join(dir, "recommend.csv") {
+		t.Errorf("Expected the resolved table path, got %v", agentConfig.Settings["decision_table"])
+	}
+
+	agent, err := agents.NewAgentFactory().CreateAgent("DecisionMakerAgent", "recommender", agentConfig.Settings)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	decider := agent.(*agents.DecisionMakerAgent)
+	output, err := decider.Run(context.Background(), &analysis.SentimentResult{Label: analysis.Negative, Score: -0.7})
+	if err != nil {
+		t.Fatalf("Decision failed: %v", err)
+	}
+	if decision := output.(*rules.Decision); decision.Outcome != "suppress" || decision.Rule != "row_4" || decider.GetDecision() != "suppress" {
+		t.Errorf("Unexpected decision %+v", decision)
+	}
+
+	// Settings next to the path override the table file
+	_, err = writeConfig("      decision_table:\n        path: tables/recommend.yaml\n        hit_policy: unique\n        default: {decision: hold, weight: 0}\n")
+	if err != nil {
+		t.Errorf("Expected the YAML table to be valid, got %v", err)
+	}
+
+	_, err = writeConfig("      decision_table:\n        path: tables/recommend.yaml\n        rows: [{when: ['> 0'], then: [recommend, 1]}]\n")
+	if err == nil || !strings.Contains(err.Error(), "agent recommender: decision table decision_table: gap at score: <= 0") {
+		t.Errorf("Expected a gap for scores up to 0, got %v", err)
+	}
+	if _, err := writeConfig("      decision_table: missing.csv\n"); err == nil {
+		t.Errorf("Expected a missing table to fail")
+	}
+	if err := agents.NewDecisionMakerAgent("both").Initialize(map[string]interface{}{
+		"decision_table": filepath.Join(dir, "recommend.csv"),
+		"decision_rules": map[string]interface{}{},
+	}); err == nil {
+		t.Errorf("Expected decision_rules and decision_table together to fail")
+	}
+}
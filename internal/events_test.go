@@ -0,0 +1,141 @@
+package internal
+
+import (
+	"beluga/pkg/events"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testEvent struct {
+	kind  string
+	value int
+}
+
+func (e testEvent) EventType() string { return e.kind }
+
+// collector gathers events delivered to a subscription.
+type collector struct {
+	mutex  sync.Mutex
+	events []events.Event
+}
+
+func (c *collector) handle(event events.Event) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.events = append(c.events, event)
+}
+
+func (c *collector) count() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.events)
+}
+
+func waitForCount(t *testing.T, c *collector, expected int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for c.count() < expected {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d events, got %d", expected, c.count())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestEventBusSubscriptions(t *testing.T) {
+	bus := events.NewBus()
+	defer bus.Close()
+
+	var all, executions, exact collector
+	bus.Subscribe("*", all.handle)
+	bus.Subscribe("execution_*", executions.handle)
+	bus.Subscribe("state_change", exact.handle)
+
+	bus.Publish(testEvent{kind: "execution_started"})
+	bus.Publish(testEvent{kind: "execution_finished"})
+	bus.Publish(testEvent{kind: "state_change"})
+
+	waitForCount(t, &all, 3)
+	waitForCount(t, &executions, 2)
+	waitForCount(t, &exact, 1)
+
+	// Events are delivered to each subscription in the order they were published
+	all.mutex.Lock()
+	if all.events[0].EventType() != "execution_started" || all.events[2].EventType() != "state_change" {
+		t.Errorf("Expected events in publish order, got %v", all.events)
+	}
+	all.mutex.Unlock()
+
+	var typed []testEvent
+	done := make(chan struct{})
+	sub := events.SubscribeTyped(bus, func(e testEvent) {
+		typed = append(typed, e)
+		if len(typed) == 2 {
+			close(done)
+		}
+	})
+	bus.Publish(testEvent{kind: "a", value: 1})
+	bus.Publish(testEvent{kind: "b", value: 2})
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Expected typed events to be delivered")
+	}
+
+	// A cancelled subscription receives nothing more
+	sub.Cancel()
+	sub.Cancel()
+	<-sub.Done()
+	if bus.SubscriberCount() != 3 {
+		t.Errorf("Expected 3 subscriptions after cancel, got %d", bus.SubscriberCount())
+	}
+	bus.Publish(testEvent{kind: "c", value: 3})
+	waitForCount(t, &all, 6)
+	if len(typed) != 2 {
+		t.Errorf("Expected cancelled subscription to receive no events, got %v", typed)
+	}
+}
+
+func TestEventBusBoundedBuffer(t *testing.T) {
+	bus := &events.Bus{BufferSize: 2}
+
+	release := make(chan struct{})
+	var received collector
+	sub := bus.Subscribe("*", func(event events.Event) {
+		<-release
+		received.handle(event)
+	})
+
+	// The handler blocks on the first event, two more fill the buffer and the
+	// rest are dropped without blocking the publisher
+	published := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			bus.Publish(testEvent{kind: "tick", value: i})
+			if i == 0 {
+				time.Sleep(20 * time.Millisecond)
+			}
+		}
+		close(published)
+	}()
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatalf("Publish blocked on a slow subscriber")
+	}
+	if sub.Dropped() != 7 {
+		t.Errorf("Expected 7 dropped events, got %d", sub.Dropped())
+	}
+
+	// Closing the bus still delivers the buffered events
+	close(release)
+	bus.Close()
+	<-sub.Done()
+	if received.count() != 3 {
+		t.Errorf("Expected 3 delivered events, got %d", received.count())
+	}
+
+	late := bus.Subscribe("*", received.handle)
+	<-late.Done()
+}
@@ -0,0 +1,325 @@
+package internal
+
+import (
+	"beluga/pkg/agents"
+	"beluga/pkg/codec"
+	"beluga/pkg/fetch"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pagedServer serves the items 1 to 5 in pages of two, paginated by page
+// number, cursor or Link header depending on the path.
+func pagedServer(t *testing.T) *httptest.Server {
+	items := []int{1, 2, 3, 4, 5}
+	page := func(n int) []int {
+		start := (n - 1) * 2
+		if start >= len(items) || start < 0 {
+			return []int{}
+		}
+		end := start + 2
+		if end > len(items) {
+			end = len(items)
+		}
+		return items[start:end]
+	}
+	toJSON := func(values []int) string {
+		parts := make([]string, len(values))
+		for i, v := range values {
+			parts[i] = strconv.Itoa(v)
+		}
+		return "[" + strings.Join(parts, ",") + "]"
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pages":
+			n, _ := strconv.Atoi(r.URL.Query().Get("p"))
+			fmt.Fprintf(w, `{"data": {"items": %s}}`, toJSON(page(n)))
+		case "/cursor":
+			n := 1
+			if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+				n, _ = strconv.Atoi(strings.TrimPrefix(cursor, "c"))
+			}
+			next := ""
+			if len(page(n+1)) > 0 {
+				next = "c" + strconv.Itoa(n+1)
+			}
+			fmt.Fprintf(w, `{"items": %s, "meta": {"next": %q}}`, toJSON(page(n)), next)
+		case "/linked":
+			n, _ := strconv.Atoi(r.URL.Query().Get("n"))
+			if n == 0 {
+				n = 1
+			}
+			if len(page(n+1)) > 0 {
+				w.Header().Set("Link", fmt.Sprintf(`</linked?n=%d>; rel="next", </linked?n=1>; rel="first"`, n+1))
+			}
+			fmt.Fprint(w, toJSON(page(n)))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+// fetchRecords fetches all documents of driver, decoding them with the named
+// codec, and returns their records and the number of documents.
+func fetchRecords(driver fetch.Driver, format string, options map[string]interface{}) ([]codec.Record, int, error) {
+	decoding, err := codec.DefaultRegistry().Lookup(format, options)
+	if err != nil {
+		return nil, 0, err
+	}
+	var records []codec.Record
+	documents := 0
+	err = driver.Fetch(context.Background(), func(document fetch.Document) error {
+		documents++
+		decoder, err := decoding.NewDecoder(document.Body)
+		if err != nil {
+			return err
+		}
+		dataset, err := codec.ReadAll(decoder)
+		if err != nil {
+			return err
+		}
+		records = append(records, dataset.Records...)
+		return nil
+	})
+	return records, documents, err
+}
+
+func TestHTTPDriver(t *testing.T) {
+	t.Run("Request options", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body := make([]byte, r.ContentLength)
+			r.Body.Read(body)
+			fmt.Fprintf(w, `{"method": %q, "token": %q, "q": %q, "body": %q}`,
+				r.Method, r.Header.Get("X-Token"), r.URL.Query().Get("q"), body)
+		}))
+		defer server.Close()
+
+		config, err := fetch.ConfigFromMap(map[string]interface{}{
+			"url":     server.URL + "/search",
+			"method":  "post",
+			"headers": map[string]interface{}{"X-Token": "secret"},
+			"query":   map[string]interface{}{"q": "beluga"},
+			"body":    `{"limit": 1}`,
+		})
+		if err != nil {
+			t.Fatalf("Failed to read config: %v", err)
+		}
+		driver, err := config.New()
+		if err != nil {
+			t.Fatalf("Failed to create driver: %v", err)
+		}
+		records, _, err := fetchRecords(driver, "json", nil)
+		if err != nil {
+			t.Fatalf("Fetch failed: %v", err)
+		}
+		want := []codec.Record{{"method": "POST", "token": "secret", "q": "beluga", "body": `{"limit": 1}`}}
+		if !reflect.DeepEqual(records, want) {
+			t.Errorf("Expected %v, got %v", want, records)
+		}
+	})
+
+	t.Run("Status errors and timeouts", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/slow":
+				time.Sleep(200 * time.Millisecond)
+			case "/busy":
+				w.WriteHeader(http.StatusServiceUnavailable)
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		defer server.Close()
+
+		var statusErr *fetch.StatusError
+		_, _, err := fetchRecords(&fetch.HTTPDriver{URL: server.URL + "/busy", Method: http.MethodGet}, "json", nil)
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable || !statusErr.Retryable() {
+			t.Errorf("Expected a retryable status error, got %v", err)
+		}
+		_, _, err = fetchRecords(&fetch.HTTPDriver{URL: server.URL + "/missing", Method: http.MethodGet}, "json", nil)
+		if !errors.As(err, &statusErr) || statusErr.Retryable() {
+			t.Errorf("Expected a permanent status error, got %v", err)
+		}
+
+		config := fetch.Config{Source: server.URL + "/slow", Timeout: 50 * time.Millisecond}
+		driver, _ := config.New()
+		if _, _, err := fetchRecords(driver, "text", nil); err == nil {
+			t.Errorf("Expected the request to time out")
+		}
+	})
+
+	t.Run("Pagination", func(t *testing.T) {
+		server := pagedServer(t)
+		defer server.Close()
+
+		testCases := []struct {
+			name       string
+			path       string
+			pagination map[string]interface{}
+			pages      int
+		}{
+			// Page pagination stops at the first empty page
+			{"page", "/pages", map[string]interface{}{"type": "page", "param": "p", "items_path": "data.items"}, 4},
+			{"cursor", "/cursor", map[string]interface{}{"type": "cursor", "items_path": "items", "cursor_path": "meta.next"}, 3},
+			{"link", "/linked", map[string]interface{}{"type": "link"}, 3},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				config, err := fetch.ConfigFromMap(map[string]interface{}{
+					"url":        server.URL + tc.path,
+					"pagination": tc.pagination,
+				})
+				if err != nil {
+					t.Fatalf("Failed to read config: %v", err)
+				}
+				driver, _ := config.New()
+				options := map[string]interface{}{"records_path": config.Pagination.ItemsPath}
+				records, pages, err := fetchRecords(driver, "json", options)
+				if err != nil {
+					t.Fatalf("Fetch failed: %v", err)
+				}
+				var items []interface{}
+				for _, record := range records {
+					items = append(items, record["value"])
+				}
+				want := []interface{}{int64(1), int64(2), int64(3), int64(4), int64(5)}
+				if !reflect.DeepEqual(items, want) || pages != tc.pages {
+					t.Errorf("Expected items %v, got %v from %d pages", want, items, pages)
+				}
+			})
+		}
+
+		config, _ := fetch.ConfigFromMap(map[string]interface{}{
+			"url":        server.URL + "/pages",
+			"pagination": map[string]interface{}{"type": "page", "param": "p", "items_path": "data.items", "max_pages": 2},
+		})
+		driver, _ := config.New()
+		if _, pages, _ := fetchRecords(driver, "text", nil); pages != 2 {
+			t.Errorf("Expected max_pages to stop after 2 pages, got %d", pages)
+		}
+
+		if _, err := fetch.PaginationFromMap(map[string]interface{}{"type": "offset"}); err == nil {
+			t.Errorf("Expected an unknown pagination type to fail")
+		}
+	})
+}
+
+func TestFileDriver(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "b.json"), []byte(`[{"id": 3}]`), 0644)
+	os.WriteFile(filepath.Join(dir, "a.json"), []byte(`[{"id": 1}, {"id": 2}]`), 0644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("hello"), 0644)
+	os.Mkdir(filepath.Join(dir, "nested.json"), 0755)
+
+	driver, _ := fetch.Config{Source: "file://" + filepath.Join(dir, "*.json")}.New()
+	var sources []string
+	err := driver.Fetch(context.Background(), func(document fetch.Document) error {
+		if document.ContentType != "application/json" {
+			t.Errorf("Expected a JSON content type, got %q", document.ContentType)
+		}
+		sources = append(sources, document.Source)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if len(sources) != 2 || sources[0] != filepath.Join(dir, "a.json") {
+		t.Fatalf("Expected a.json and b.json in order, got %v", sources)
+	}
+	if records, _, _ := fetchRecords(driver, "json", nil); len(records) != 3 {
+		t.Errorf("Expected the records of both files, got %v", records)
+	}
+
+	driver, _ = fetch.Config{Paths: []string{filepath.Join(dir, "notes.txt"), filepath.Join(dir, "*.txt")}}.New()
+	if records, files, _ := fetchRecords(driver, "text", nil); files != 1 || records[0]["text"] != "hello" {
+		t.Errorf("Expected each file to be read once as text, got %v", records)
+	}
+
+	driver, _ = fetch.Config{Source: filepath.Join(dir, "*.csv")}.New()
+	if _, _, err := fetchRecords(driver, "csv", nil); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected a glob without matches to fail, got %v", err)
+	}
+}
+
+func TestDataFetcherAgent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != "Beluga/1.0" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		fmt.Fprintf(w, `{"path": %q}`, r.URL.Path)
+	}))
+	defer server.Close()
+
+	factory := agents.NewAgentFactory()
+	created, err := factory.CreateAgent("DataFetcherAgent", "web_fetcher", map[string]interface{}{
+		"data_source": "web_api",
+		"data_format": "json",
+		"base_url":    server.URL + "/data",
+		"timeout":     5,
+		"headers":     map[string]interface{}{"user-agent": "Beluga/1.0"},
+		"max_retries": 0,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	fetcher := created.(*agents.DataFetcherAgent)
+
+	output, err := fetcher.Run(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	dataset, ok := output.(*codec.Dataset)
+	if !ok || !reflect.DeepEqual(dataset.Records, []codec.Record{{"path": "/data"}}) || fetcher.GetData() != dataset {
+		t.Errorf("Unexpected output %v", output)
+	}
+
+	// Input overrides the location and keeps the configured headers
+	output, err = fetcher.Run(context.Background(), map[string]interface{}{"url": server.URL + "/other", "data_format": "text"})
+	if err != nil || output.(*codec.Dataset).Records[0]["text"] != `{"path": "/other"}` {
+		t.Errorf("Expected the overridden URL as text, got %v (%v)", output, err)
+	}
+
+	t.Run("Stdin", func(t *testing.T) {
+		agent := agents.NewDataFetcherAgent("stdin_fetcher", "stdin", "text")
+		agent.Source.Stdin = strings.NewReader("piped input")
+		if err := agent.Initialize(map[string]interface{}{}); err != nil {
+			t.Fatalf("Failed to initialize agent: %v", err)
+		}
+		if output, err := agent.Run(context.Background(), nil); err != nil || output.(*codec.Dataset).Records[0]["text"] != "piped input" {
+			t.Errorf("Expected the piped input, got %v (%v)", output, err)
+		}
+	})
+
+	t.Run("Named source without location", func(t *testing.T) {
+		agent := agents.NewDataFetcherAgent("named_fetcher", "crm_export", "json")
+		if err := agent.Initialize(map[string]interface{}{}); err != nil {
+			t.Fatalf("Failed to initialize agent: %v", err)
+		}
+		if output, err := agent.Run(context.Background(), nil); err != nil || output != nil {
+			t.Errorf("Expected nothing to be fetched, got %v (%v)", output, err)
+		}
+	})
+
+	t.Run("Invalid settings", func(t *testing.T) {
+		if err := agents.NewDataFetcherAgent("bad_format", "stdin", "parquet").Initialize(map[string]interface{}{}); !errors.Is(err, codec.ErrUnknownCodec) {
+			t.Errorf("Expected ErrUnknownCodec, got %v", err)
+		}
+		err := agents.NewDataFetcherAgent("bad_source", "ftp://example.com/data", "json").Initialize(map[string]interface{}{})
+		if err == nil {
+			t.Errorf("Expected an unsupported scheme to fail")
+		}
+	})
+}
@@ -0,0 +1,209 @@
+package internal
+
+import (
+	"beluga/pkg/agents"
+	"beluga/pkg/llm"
+	"beluga/pkg/retry"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// chatServer stands in for an OpenAI-compatible chat completions API.
+func chatServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" || r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":{"message":"invalid api key","type":"invalid_request_error","code":"invalid_api_key"}}`)
+			return
+		}
+
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Invalid request body: %v", err)
+		}
+		messages := body["messages"].([]interface{})
+		last := messages[len(messages)-1].(map[string]interface{})["content"].(string)
+
+		switch {
+		case last == "overloaded":
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"error":{"message":"rate limit reached","type":"rate_limit_error"}}`)
+		case body["stream"] == true:
+			if options, _ := body["stream_options"].(map[string]interface{}); options["include_usage"] != true {
+				t.Errorf("Expected streaming requests to ask for usage, got %v", body)
+			}
+			w.Header().Set("Content-Type", "text/event-stream")
+			for _, word := range []string{"Hello", ", ", "world"} {
+				fmt.Fprintf(w, "data: {\"model\":%q,\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", body["model"], word)
+			}
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n")
+			fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":7,\"completion_tokens\":3,\"total_tokens\":10}}\n\n")
+			fmt.Fprint(w, "data: [DONE]\n\n")
+		default:
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"model": body["model"],
+				"choices": []interface{}{map[string]interface{}{
+					"message":       map[string]interface{}{"role": "assistant", "content": fmt.Sprintf("echo: %s (temperature %v)", last, body["temperature"])},
+					"finish_reason": "stop",
+				}},
+				"usage": map[string]interface{}{"prompt_tokens": 5, "completion_tokens": 4, "total_tokens": 9},
+			})
+		}
+	}))
+}
+
+func TestOpenAIClientChat(t *testing.T) {
+	server := chatServer(t)
+	defer server.Close()
+
+	temperature := 0.2
+	client, err := llm.NewOpenAIClient(llm.Config{
+		Model:       "test-model",
+		BaseURL:     server.URL + "/v1/",
+		APIKey:      "test-key",
+		Temperature: &temperature,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	response, err := client.Chat(context.Background(), llm.Request{Messages: []llm.Message{
+		{Role: llm.RoleSystem, Content: "Be brief."},
+		{Role: llm.RoleUser, Content: "hi"},
+	}})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if response.Message.Content != "echo: hi (temperature 0.2)" || response.Message.Role != llm.RoleAssistant {
+		t.Errorf("Unexpected message: %+v", response.Message)
+	}
+	if response.Model != "test-model" || response.FinishReason != "stop" || response.Usage.TotalTokens != 9 {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+
+	var chunks []string
+	streamed, err := client.ChatStream(context.Background(), llm.Request{
+		Model:    "other-model",
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: "stream"}},
+	}, func(chunk llm.Chunk) error {
+		chunks = append(chunks, chunk.Content)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
+	}
+	if strings.Join(chunks, "|") != "Hello|, |world|" {
+		t.Errorf("Unexpected chunks: %q", chunks)
+	}
+	if streamed.Message.Content != "Hello, world" || streamed.Model != "other-model" || streamed.FinishReason != "stop" {
+		t.Errorf("Unexpected streamed response: %+v", streamed)
+	}
+	if streamed.Usage.TotalTokens != 10 {
+		t.Errorf("Expected streamed usage of 10 tokens, got %+v", streamed.Usage)
+	}
+
+	// Usage accumulates across requests
+	if usage := client.GetUsage(); usage != (llm.Usage{PromptTokens: 12, CompletionTokens: 7, TotalTokens: 19}) {
+		t.Errorf("Unexpected total usage: %+v", usage)
+	}
+
+	// A chunk handler error stops the stream
+	stop := errors.New("stop")
+	_, err = client.ChatStream(context.Background(), llm.Request{Messages: []llm.Message{{Role: llm.RoleUser, Content: "stream"}}},
+		func(llm.Chunk) error { return stop })
+	if !errors.Is(err, stop) {
+		t.Errorf("Expected the handler error, got %v", err)
+	}
+}
+
+func TestOpenAIClientErrors(t *testing.T) {
+	server := chatServer(t)
+	defer server.Close()
+
+	client, _ := llm.NewOpenAIClient(llm.Config{Model: "test-model", BaseURL: server.URL + "/v1", APIKey: "test-key"})
+	_, err := client.Chat(context.Background(), llm.Request{Messages: []llm.Message{{Role: llm.RoleUser, Content: "overloaded"}}})
+	var apiErr *llm.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests || apiErr.Type != "rate_limit_error" {
+		t.Fatalf("Expected a rate limit error, got %v", err)
+	}
+	if !retry.Retryable(err) {
+		t.Errorf("Expected rate limit errors to be retryable")
+	}
+
+	unauthorized, _ := llm.NewOpenAIClient(llm.Config{Model: "test-model", BaseURL: server.URL + "/v1", APIKey: "wrong"})
+	_, err = unauthorized.Chat(context.Background(), llm.Request{Messages: []llm.Message{{Role: llm.RoleUser, Content: "hi"}}})
+	if !errors.As(err, &apiErr) || apiErr.Code != "invalid_api_key" || retry.Retryable(err) {
+		t.Errorf("Expected a permanent authentication error, got %v", err)
+	}
+
+	if _, err := llm.NewOpenAIClient(llm.Config{}); err == nil {
+		t.Errorf("Expected a client without a model to fail")
+	}
+}
+
+type fakeLLM struct{ model string }
+
+func (f *fakeLLM) Chat(ctx context.Context, request llm.Request) (*llm.Response, error) {
+	return &llm.Response{Model: f.model}, nil
+}
+
+func (f *fakeLLM) ChatStream(ctx context.Context, request llm.Request, onChunk func(llm.Chunk) error) (*llm.Response, error) {
+	return f.Chat(ctx, request)
+}
+
+func (f *fakeLLM) GetUsage() llm.Usage { return llm.Usage{} }
+
+func TestAgentLLMSettings(t *testing.T) {
+	server := chatServer(t)
+	defer server.Close()
+	t.Setenv("BELUGA_TEST_LLM_KEY", "test-key")
+
+	// The model falls back to nlp_model and the key comes from the environment
+	agent := agents.NewAnalyzerAgent("llm_analyzer", "sentiment")
+	err := agent.Initialize(map[string]interface{}{
+		"nlp_model": "test-model",
+		"llm": map[string]interface{}{
+			"provider":    "openai",
+			"base_url":    server.URL + "/v1",
+			"api_key_env": "BELUGA_TEST_LLM_KEY",
+			"temperature": 0.5,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+	response, err := agent.GetLLM().Chat(context.Background(), llm.Request{Messages: []llm.Message{{Role: llm.RoleUser, Content: "hi"}}})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if response.Model != "test-model" || response.Message.Content != "echo: hi (temperature 0.5)" {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+
+	// Custom providers are selected by name
+	llm.RegisterProvider("fake", func(config llm.Config) (llm.LLM, error) {
+		return &fakeLLM{model: config.Model}, nil
+	})
+	fake := agents.NewBaseAgent("fake_llm_agent")
+	if err := fake.Initialize(map[string]interface{}{"llm": map[string]interface{}{"provider": "fake", "model": "m"}}); err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+	if model, ok := fake.GetLLM().(*fakeLLM); !ok || model.model != "m" {
+		t.Errorf("Expected the fake provider, got %#v", fake.GetLLM())
+	}
+
+	invalid := agents.NewBaseAgent("invalid_llm_agent")
+	if err := invalid.Initialize(map[string]interface{}{"llm": map[string]interface{}{"provider": "unknown", "model": "m"}}); err == nil {
+		t.Errorf("Expected an unknown provider to fail")
+	}
+}
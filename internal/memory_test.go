@@ -0,0 +1,176 @@
+package internal
+
+import (
+	"beluga/pkg/agents"
+	"beluga/pkg/analysis"
+	"beluga/pkg/memory"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInMemory(t *testing.T) {
+	store := memory.NewInMemory(3)
+	for i := 1; i <= 5; i++ {
+		if err := store.Append(memory.Entry{Input: i, Output: i * 10}); err != nil {
+			t.Fatalf("Failed to append entry: %v", err)
+		}
+	}
+
+	// Only the three most recent entries are kept, oldest first
+	entries, _ := store.Last(0)
+	if len(entries) != 3 || entries[0].Input != 3 || entries[2].Input != 5 {
+		t.Fatalf("Expected entries 3..5, got %v", entries)
+	}
+	if entries[0].Timestamp.IsZero() {
+		t.Errorf("Expected appended entries to be timestamped")
+	}
+	entries, _ = store.Last(2)
+	if len(entries) != 2 || entries[0].Input != 4 || entries[1].Output != 50 {
+		t.Errorf("Expected entries 4 and 5, got %v", entries)
+	}
+
+	store.Clear()
+	if entries, _ := store.Last(10); len(entries) != 0 {
+		t.Errorf("Expected no entries after clear, got %v", entries)
+	}
+}
+
+func TestFileMemory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "memory.jsonl")
+	store, err := memory.NewFileMemory(path, 2)
+	if err != nil {
+		t.Fatalf("Failed to open file memory: %v", err)
+	}
+	for _, input := range []string{"a", "b", "c", "d", "e"} {
+		if err := store.Append(memory.Entry{Input: input, Metadata: map[string]interface{}{"n": 1}}); err != nil {
+			t.Fatalf("Failed to append entry: %v", err)
+		}
+	}
+
+	// Reopening restores the most recent entries, decoded as JSON values
+	reopened, err := memory.NewFileMemory(path, 2)
+	if err != nil {
+		t.Fatalf("Failed to reopen file memory: %v", err)
+	}
+	entries, _ := reopened.Last(5)
+	if len(entries) != 2 || entries[0].Input != "d" || entries[1].Input != "e" {
+		t.Fatalf("Expected entries d and e after reopening, got %v", entries)
+	}
+	if entries[1].Metadata["n"] != float64(1) {
+		t.Errorf("Expected metadata to survive reopening, got %v", entries[1].Metadata)
+	}
+
+	// The file is compacted instead of growing without bound
+	data, _ := os.ReadFile(path)
+	if lines := bytes.Count(data, []byte("\n")); lines > 4 {
+		t.Errorf("Expected the file to be compacted, got %d lines", lines)
+	}
+
+	// A line truncated by an interrupted write is ignored
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"input":"trunc`)
+	f.Close()
+	if _, err := memory.NewFileMemory(path, 2); err != nil {
+		t.Errorf("Expected a truncated last line to be ignored, got %v", err)
+	}
+
+	if err := reopened.Clear(); err != nil {
+		t.Fatalf("Failed to clear memory: %v", err)
+	}
+	cleared, _ := memory.NewFileMemory(path, 2)
+	if entries, _ := cleared.Last(0); len(entries) != 0 {
+		t.Errorf("Expected clear to empty the file, got %v", entries)
+	}
+
+	if err := store.Append(memory.Entry{Output: func() {}}); err == nil {
+		t.Errorf("Expected an error appending an entry that is not JSON")
+	}
+}
+
+func TestMemoryConfig(t *testing.T) {
+	if _, err := (memory.Config{Type: "file"}).New(); err == nil {
+		t.Errorf("Expected file memory without a path to fail")
+	}
+	if _, err := (memory.Config{Type: "redis"}).New(); err == nil {
+		t.Errorf("Expected an unknown memory type to fail")
+	}
+	if _, err := memory.ConfigFromMap(map[string]interface{}{"capacity": "ten"}); err == nil {
+		t.Errorf("Expected a non-numeric capacity to fail")
+	}
+
+	config, err := memory.ConfigFromMap(map[string]interface{}{"type": "in_memory", "capacity": float64(1)})
+	if err != nil {
+		t.Fatalf("Failed to read memory config: %v", err)
+	}
+	store, _ := config.New()
+	store.Append(memory.Entry{Input: 1})
+	store.Append(memory.Entry{Input: 2})
+	if entries, _ := store.Last(0); len(entries) != 1 {
+		t.Errorf("Expected capacity 1 to be honoured, got %v", entries)
+	}
+}
+
+func TestAgentMemory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "analyzer.jsonl")
+	settings := map[string]interface{}{
+		"max_retries":    0,
+		"memory":         map[string]interface{}{"type": "file", "path": path},
+		"memory_context": 2,
+	}
+
+	analyzer := agents.NewAnalyzerAgent("memory_analyzer", "sentiment")
+	if err := analyzer.Initialize(settings); err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+	for _, input := range []string{"first", "second", "third"} {
+		if _, err := analyzer.Run(context.Background(), input); err != nil {
+			t.Fatalf("Failed to run agent: %v", err)
+		}
+	}
+	// A failed execution is not remembered
+	analyzer.Run(context.Background(), nil)
+
+	// The last run saw the two runs before it
+	analyzer.Mutex.RLock()
+	history := analyzer.History
+	analyzer.Mutex.RUnlock()
+	if len(history) != 2 || history[0].Input != "first" || history[1].Input != "second" {
+		t.Fatalf("Expected the analyzer to recall first and second, got %v", history)
+	}
+	result, ok := history[1].Output.(*analysis.SentimentResult)
+	if !ok || result.Label != analysis.Neutral || history[1].Metadata["agent"] != "memory_analyzer" {
+		t.Errorf("Unexpected remembered entry: %+v", history[1])
+	}
+
+	// A new agent on the same file continues from the earlier context
+	decider := agents.NewDecisionMakerAgent("memory_decider")
+	if err := decider.Initialize(settings); err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+	if _, err := decider.Run(context.Background(), "analysis"); err != nil {
+		t.Fatalf("Failed to run agent: %v", err)
+	}
+	decider.Mutex.RLock()
+	history = decider.History
+	decider.Mutex.RUnlock()
+	if len(history) != 2 || history[1].Input != "third" {
+		t.Errorf("Expected the decision maker to recall the analyzer's runs, got %v", history)
+	}
+
+	// Agents without a memory setting keep no record of their executions
+	forgetful := agents.NewAnalyzerAgent("forgetful", "sentiment")
+	forgetful.Initialize(map[string]interface{}{"max_retries": 0})
+	forgetful.Run(context.Background(), "first")
+	forgetful.Run(context.Background(), "second")
+	if history, _ := forgetful.Recall(); forgetful.GetMemory() != nil || len(history) != 0 {
+		t.Errorf("Expected no memory by default, got %v", history)
+	}
+
+	invalid := agents.NewBaseAgent("invalid_memory")
+	if err := invalid.Initialize(map[string]interface{}{"memory": 42}); err == nil {
+		t.Errorf("Expected an invalid memory setting to fail")
+	}
+}
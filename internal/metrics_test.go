@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"beluga/pkg/agents"
+	"beluga/pkg/monitoring"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsRegistryExposition(t *testing.T) {
+	registry := monitoring.NewMetricsRegistry()
+
+	requests := registry.Counter("requests_total", "Requests handled.\nPer path.", "path")
+	requests.WithLabelValues("/a").Add(2)
+	requests.WithLabelValues(`/b"\`).Inc()
+	registry.Gauge("queue_depth", "Items queued.").WithLabelValues().Set(4)
+	latency := registry.Histogram("latency_seconds", "Request latency.", []float64{0.5, 0.1, 1})
+	for _, v := range []float64{0.05, 0.3, 0.3, 2} {
+		latency.WithLabelValues().Observe(v)
+	}
+	registry.Counter("unused_total", "Never incremented.")
+
+	var out strings.Builder
+	if err := registry.WritePrometheus(&out); err != nil {
+		t.Fatalf("Failed to write metrics: %v", err)
+	}
+
+	expected := `# HELP latency_seconds Request latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.1"} 1
+latency_seconds_bucket{le="0.5"} 3
+latency_seconds_bucket{le="1"} 3
+latency_seconds_bucket{le="+Inf"} 4
+latency_seconds_sum 2.65
+latency_seconds_count 4
+# HELP queue_depth Items queued.
+# TYPE queue_depth gauge
+queue_depth 4
+# HELP requests_total Requests handled.\nPer path.
+# TYPE requests_total counter
+requests_total{path="/a"} 2
+requests_total{path="/b\"\\"} 1
+`
+	if out.String() != expected {
+		t.Errorf("Unexpected exposition:\n%s\nexpected:\n%s", out.String(), expected)
+	}
+
+	// Registering the same name with a different type is a programming error
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected conflicting registration to panic")
+		}
+	}()
+	registry.Gauge("requests_total", "Conflicting.", "path")
+}
+
+func TestAgentExecutionMetrics(t *testing.T) {
+	registry := monitoring.NewMetricsRegistry()
+	agent := agents.NewBaseAgent("metrics_agent")
+	agent.Metrics = monitoring.NewAgentMetrics(registry, "metrics_agent")
+	if err := agent.Initialize(map[string]interface{}{"max_retries": 1, "retry_delay": 0}); err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+
+	calls := 0
+	agent.SetBehavior(agents.BehaviorFunc(func(ctx context.Context, input interface{}) (interface{}, error) {
+		calls++
+		if input == "fail" || calls == 1 {
+			return nil, errors.New("failed")
+		}
+		return "ok", nil
+	}))
+
+	// One success after a retry, one failure after a retry and one cancellation
+	agent.Run(context.Background(), "succeed")
+	agent.Run(context.Background(), "fail")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	agent.Run(ctx, "cancelled")
+
+	snapshot, _ := agent.CheckHealth()["metrics"].(map[string]interface{})
+	expected := map[string]float64{
+		"executions":    3,
+		"successes":     1,
+		"failures":      1,
+		"cancellations": 1,
+		"retries":       2,
+		"in_flight":     0,
+	}
+	for key, want := range expected {
+		if snapshot[key] != want {
+			t.Errorf("Expected %s to be %v, got %v", key, want, snapshot[key])
+		}
+	}
+
+	server := httptest.NewServer(monitoring.MetricsHandler(registry))
+	defer server.Close()
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to fetch metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+		t.Errorf("Unexpected content type: %s", contentType)
+	}
+	for _, line := range []string{
+		`beluga_agent_executions_total{agent="metrics_agent"} 3`,
+		`beluga_agent_execution_retries_total{agent="metrics_agent"} 2`,
+		`beluga_agent_executions_in_flight{agent="metrics_agent"} 0`,
+		`beluga_agent_execution_duration_seconds_count{agent="metrics_agent"} 3`,
+	} {
+		if !strings.Contains(string(body), line+"\n") {
+			t.Errorf("Expected exposition to contain %q, got:\n%s", line, body)
+		}
+	}
+}
@@ -0,0 +1,418 @@
+package internal
+
+import (
+	"beluga/pkg/actions"
+	"beluga/pkg/agents"
+	"beluga/pkg/agents/config"
+	"beluga/pkg/notify"
+	"beluga/pkg/retry"
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSMTP is a local SMTP server recording the mail it accepts. It refuses
+// recipients at blocked.example.com permanently and at busy.example.com
+// temporarily.
+type fakeSMTP struct {
+	listener net.Listener
+	mutex    sync.Mutex
+	mails    []fakeMail
+}
+
+type fakeMail struct {
+	From string
+	To   []string
+	Data string
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := &fakeSMTP{listener: listener}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { listener.Close() })
+	return server
+}
+
+func (s *fakeSMTP) Addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeSMTP) Mails() []fakeMail {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]fakeMail(nil), s.mails...)
+}
+
+func (s *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	reply("220 fake ESMTP")
+	var mail fakeMail
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		command := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250-fake")
+			reply("250 8BITMIME")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			from, _, _ := strings.Cut(line[len("MAIL FROM:"):], " ")
+			mail = fakeMail{From: strings.Trim(from, "<>")}
+			reply("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			to := strings.Trim(line[len("RCPT TO:"):], "<> ")
+			switch {
+			case strings.HasSuffix(to, "@blocked.example.com"):
+				reply("550 mailbox unavailable")
+			case strings.HasSuffix(to, "@busy.example.com"):
+				reply("451 try again later")
+			default:
+				mail.To = append(mail.To, to)
+				reply("250 OK")
+			}
+		case command == "DATA":
+			reply("354 end with .")
+			var data strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(line, "."))
+			}
+			mail.Data = data.String()
+			s.mutex.Lock()
+			s.mails = append(s.mails, mail)
+			s.mutex.Unlock()
+			reply("250 queued")
+		case command == "RSET", command == "NOOP":
+			reply("250 OK")
+		case command == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func newRecommendationTemplate(t *testing.T) *notify.Template {
+	t.Helper()
+	template, err := notify.NewTemplate("recommendation",
+		"{{.Subject}} for {{.Recipient.Name}}",
+		"{{.Data.greeting}} {{.Recipient.Name}}, read {{join .Data.items \", \"}}.\n",
+		"<p>{{.Data.greeting}} {{.Recipient.Name}}, read {{.Data.title}}</p>\n")
+	if err != nil {
+		t.Fatalf("Failed to parse template: %v", err)
+	}
+	return template
+}
+
+func TestNotifySMTP(t *testing.T) {
+	server := newFakeSMTP(t)
+	notifier, err := notify.FromMap(map[string]interface{}{
+		"channels": map[string]interface{}{
+			"email": map[string]interface{}{"type": "smtp", "addr": server.Addr(), "from": "Beluga <beluga@example.com>", "timeout": 5},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create notifier: %v", err)
+	}
+	notifier.Templates.Add(newRecommendationTemplate(t))
+
+	report, err := notifier.Notify(context.Background(), notify.Notification{
+		Template: "recommendation",
+		Subject:  "Weekly picks",
+		Recipients: []notify.Recipient{
+			{Address: "ada@example.com", Name: "Ada"},
+			{Address: "bob@example.com", Name: "Bob", Data: map[string]interface{}{"greeting": "Yo"}},
+			{Address: "eve@blocked.example.com", Name: "Eve"},
+		},
+		Data: map[string]interface{}{"greeting": "Hello", "items": []string{"a", "b"}, "title": "<Tom & Jerry>"},
+	})
+	var deliveryErr *notify.DeliveryError
+	if !errors.As(err, &deliveryErr) || retry.Retryable(err) {
+		t.Fatalf("Expected a permanent delivery error, got %v", err)
+	}
+	if report.Sent != 2 || report.Failed != 1 || len(report.Deliveries) != 3 {
+		t.Fatalf("Unexpected report %+v", report)
+	}
+	failed := report.Deliveries[2]
+	if failed.Status != notify.StatusFailed || !strings.Contains(failed.Error, "550") || !strings.Contains(err.Error(), "eve@blocked.example.com") {
+		t.Errorf("Unexpected failed delivery %+v", failed)
+	}
+	if sent := report.Deliveries[0]; sent.Status != notify.StatusSent || sent.Channel != "email" || sent.Subject != "Weekly picks for Ada" || sent.SentAt.IsZero() {
+		t.Errorf("Unexpected delivery %+v", sent)
+	}
+	if stored, ok := notifier.GetReport(report.ID); !ok || stored != report {
+		t.Errorf("Expected the report to be kept")
+	}
+
+	// Each recipient gets their own rendering
+	mails := server.Mails()
+	if len(mails) != 2 {
+		t.Fatalf("Expected 2 mails, got %d", len(mails))
+	}
+	ada, bob := mails[0], mails[1]
+	if ada.From != "beluga@example.com" || strings.Join(ada.To, ",") != "ada@example.com" {
+		t.Errorf("Unexpected envelope %+v", ada)
+	}
+	for _, expected := range []string{
+		"Subject: Weekly picks for Ada", `To: "Ada" <ada@example.com>`, "multipart/alternative",
+		"Hello Ada, read a, b.", "Hello Ada, read &lt;Tom &amp; Jerry&gt;",
+	} {
+		if !strings.Contains(ada.Data, expected) {
+			t.Errorf("Expected Ada's mail to contain %q:\n%s", expected, ada.Data)
+		}
+	}
+	if !strings.Contains(bob.Data, "Yo Bob, read a, b.") || strings.Contains(bob.Data, "Ada") {
+		t.Errorf("Expected Bob's own rendering:\n%s", bob.Data)
+	}
+
+	// Temporary refusals with nothing sent may be retried
+	_, err = notifier.Notify(context.Background(), notify.Notification{
+		Subject:    "Ping",
+		Text:       "ping",
+		Recipients: []notify.Recipient{{Address: "ops@busy.example.com"}},
+	})
+	if err == nil || !retry.Retryable(err) || !strings.Contains(err.Error(), "451") {
+		t.Errorf("Expected a retryable error, got %v", err)
+	}
+}
+
+func TestNotifyChannels(t *testing.T) {
+	var received []notify.WebhookMessage
+	var mutex sync.Mutex
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message notify.WebhookMessage
+		json.NewDecoder(r.Body).Decode(&message)
+		mutex.Lock()
+		received = append(received, message)
+		mutex.Unlock()
+		if r.Header.Get("X-Token") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	outbox := t.TempDir()
+	notifier, err := notify.FromMap(map[string]interface{}{
+		"templates": map[string]interface{}{
+			"alert": map[interface{}]interface{}{"subject": "Alert: {{.Data.name}}", "text": "{{.Data.name}} is {{.Data.status}}"},
+		},
+		"channels": map[string]interface{}{
+			"chat":   map[string]interface{}{"type": "webhook", "url": server.URL, "headers": map[string]interface{}{"X-Token": "secret"}},
+			"outbox": map[string]interface{}{"type": "file", "dir": outbox},
+		},
+		"channel": "chat",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create notifier: %v", err)
+	}
+
+	recipients, err := notify.ParseRecipients([]interface{}{
+		"Ops <ops@example.com>",
+		map[string]interface{}{"address": "archive@example.com", "channel": "outbox"},
+	})
+	if err != nil || recipients[0].Name != "Ops" || recipients[1].Channel != "outbox" {
+		t.Fatalf("Unexpected recipients %+v (%v)", recipients, err)
+	}
+	report, err := notifier.Notify(context.Background(), notify.Notification{
+		Template:   "alert",
+		Recipients: recipients,
+		Data:       map[string]interface{}{"name": "db", "status": "down"},
+	})
+	if err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if report.Deliveries[0].Channel != "chat" || report.Deliveries[1].Channel != "outbox" {
+		t.Errorf("Unexpected channels %+v", report.Deliveries)
+	}
+	if len(received) != 1 || received[0].Subject != "Alert: db" || received[0].Text != "db is down" || received[0].Recipient.Address != "ops@example.com" {
+		t.Errorf("Unexpected webhook messages %+v", received)
+	}
+	data, err := os.ReadFile(filepath.Join(outbox, report.Deliveries[1].MessageID+".eml"))
+	if err != nil || !strings.Contains(string(data), "Subject: Alert: db") || !strings.Contains(string(data), "db is down") {
+		t.Errorf("Unexpected outbox file %q (%v)", data, err)
+	}
+
+	// Server errors may be retried, unknown channels and templates not
+	status = http.StatusServiceUnavailable
+	_, err = notifier.Notify(context.Background(), notify.Notification{Template: "alert", Recipients: recipients[:1], Data: map[string]interface{}{"name": "db", "status": "up"}})
+	var webhookErr *notify.WebhookStatusError
+	if !errors.As(err, &webhookErr) || !retry.Retryable(err) {
+		t.Errorf("Expected a retryable webhook error, got %v", err)
+	}
+	report, err = notifier.Notify(context.Background(), notify.Notification{Text: "hi", Channel: "pager", Recipients: recipients[:1]})
+	if err == nil || retry.Retryable(err) || report.Deliveries[0].Status != notify.StatusFailed || !strings.Contains(err.Error(), "unknown channel: pager") {
+		t.Errorf("Expected an unknown channel to fail, got %v", err)
+	}
+	if _, err := notifier.Notify(context.Background(), notify.Notification{Template: "missing", Recipients: recipients}); !errors.Is(err, notify.ErrUnknownTemplate) {
+		t.Errorf("Expected an unknown template, got %v", err)
+	}
+	// A missing key fails the rendering
+	_, err = notifier.Notify(context.Background(), notify.Notification{Template: "alert", Recipients: recipients[:1], Data: map[string]interface{}{"name": "db"}})
+	if err == nil || retry.Retryable(err) || !strings.Contains(err.Error(), "status") {
+		t.Errorf("Expected a rendering error, got %v", err)
+	}
+	if _, err := notify.FromMap(map[string]interface{}{"channels": map[string]interface{}{"x": map[string]interface{}{"type": "sms"}}}); err == nil {
+		t.Errorf("Expected an unknown channel type to fail")
+	}
+}
+
+func TestNotifyTemplateDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"welcome.subject.tmpl": "Welcome {{.Recipient.Name}}",
+		"welcome.txt.tmpl":     "Hi {{.Recipient.Name | upper}}",
+		"welcome.html.tmpl":    "<b>{{.Recipient.Name}}</b>",
+		"digest.txt.tmpl":      "{{len .Data.items}} items",
+		"README.md":            "ignored",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	templates := notify.NewTemplates()
+	if err := templates.LoadDir(dir); err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+	if names := strings.Join(templates.Names(), ","); names != "digest,welcome" {
+		t.Errorf("Unexpected templates %s", names)
+	}
+	welcome, _ := templates.Get("welcome")
+	content, err := welcome.Render(notify.TemplateData{Recipient: notify.Recipient{Name: "ada"}})
+	if err != nil || content.Subject != "Welcome ada" || content.Text != "Hi ADA" || content.HTML != "<b>ada</b>" {
+		t.Errorf("Unexpected content %+v (%v)", content, err)
+	}
+
+	// The config manager resolves template directories against the config
+	configDir := t.TempDir()
+	os.Rename(dir, filepath.Join(configDir, "templates"))
+	path := filepath.Join(configDir, "agents.yaml")
+	configContent := "agents:\n  - name: mailer\n    type: ExecutorAgent\n    settings:\n      action: notification\n      templates_path: templates\n"
+	if err := os.WriteFile(path, []byte(configContent), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	manager := config.NewConfigManager()
+	if err := manager.LoadConfig(path); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	agentConfig, _ := manager.GetAgentConfig("mailer")
+	if agentConfig.Settings["templates_path"] != filepath.Join(configDir, "templates") {
+		t.Errorf("Expected the resolved templates path, got %v", agentConfig.Settings["templates_path"])
+	}
+
+	os.WriteFile(filepath.Join(configDir, "templates", "broken.txt.tmpl"), []byte("{{.Data"), 0o644)
+	if err := manager.LoadConfig(path); err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("Expected a parse error, got %v", err)
+	}
+	if _, err := notify.NewTemplate("empty", "subject", "", ""); err == nil {
+		t.Errorf("Expected a template without body to fail")
+	}
+}
+
+func TestExecutorNotify(t *testing.T) {
+	server := newFakeSMTP(t)
+	outbox := t.TempDir()
+	factory := agents.NewAgentFactory()
+	agent, err := factory.CreateAgent("ExecutorAgent", "notification_executor", map[string]interface{}{
+		"action": "notification",
+		"target": "email_service",
+		"templates": map[string]interface{}{
+			"recommendation_template": map[string]interface{}{
+				"subject": "{{.Subject}}",
+				"text":    "We recommend {{.Data.decision}}: {{join .Data.recommendations \", \"}}",
+			},
+		},
+		"channels": map[string]interface{}{
+			"email_service": map[string]interface{}{"type": "smtp", "addr": server.Addr(), "from": "beluga@example.com"},
+			"outbox":        map[string]interface{}{"type": "file", "dir": outbox},
+		},
+		"max_retries": 0,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	executor := agent.(*agents.ExecutorAgent)
+	executor.SetParams(map[string]interface{}{
+		"recipients": []string{"user@example.com", "admin@example.com"},
+		"subject":    "Content Recommendation",
+		"template":   "recommendation_template",
+		"data": map[string]interface{}{
+			"decision":        "recommend",
+			"recommendations": []string{"Article 1", "Article 2"},
+		},
+	})
+	output, err := executor.Run(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Execution failed: %v", err)
+	}
+	report := output.(*actions.ExecutionResult).Output.(*notify.Report)
+	if report.Sent != 2 || report.Deliveries[0].Channel != "email_service" || report.Template != "recommendation_template" {
+		t.Errorf("Unexpected report %+v", report)
+	}
+	mails := server.Mails()
+	if len(mails) != 2 || !strings.Contains(mails[1].Data, "We recommend recommend: Article 1, Article 2") || mails[1].To[0] != "admin@example.com" {
+		t.Errorf("Unexpected mails %+v", mails)
+	}
+
+	// The params may pick another channel; failed deliveries fail the execution
+	_, err = executor.Run(context.Background(), map[string]interface{}{
+		"recipients": []interface{}{"ok@example.com", "no@blocked.example.com"},
+		"text":       "plain",
+		"channel":    "outbox",
+	})
+	if err != nil {
+		t.Errorf("Expected the outbox to accept every recipient, got %v", err)
+	}
+	if entries, _ := os.ReadDir(outbox); len(entries) != 2 {
+		t.Errorf("Expected 2 outbox files, got %d", len(entries))
+	}
+	_, err = executor.Run(context.Background(), map[string]interface{}{
+		"recipients": []interface{}{"ok@example.com", "no@blocked.example.com"},
+		"text":       "plain",
+	})
+	result := executor.GetExecutionResult()
+	if err == nil || result.Success || result.Output.(*notify.Report).Failed != 1 {
+		t.Errorf("Expected a partial failure, got %+v (%v)", result, err)
+	}
+
+	if _, err := factory.CreateAgent("ExecutorAgent", "broken", map[string]interface{}{"action": "notification", "template": "missing"}); err == nil {
+		t.Errorf("Expected an unknown default template to fail")
+	}
+}
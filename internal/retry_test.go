@@ -0,0 +1,261 @@
+package internal
+
+import (
+	"beluga/pkg/agents"
+	"beluga/pkg/agents/adapter"
+	"beluga/pkg/monitoring"
+	"beluga/pkg/orchestration"
+	"beluga/pkg/retry"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackoffStrategies(t *testing.T) {
+	constant := retry.ConstantBackoff{Interval: 10 * time.Millisecond}
+	if d := constant.Delay(3, 0); d != 10*time.Millisecond {
+		t.Errorf("Expected constant delay of 10ms, got %v", d)
+	}
+
+	exponential := retry.ExponentialBackoff{Initial: 10 * time.Millisecond, Multiplier: 2, Max: 50 * time.Millisecond}
+	expected := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond, 50 * time.Millisecond}
+	for i, want := range expected {
+		if d := exponential.Delay(i+1, 0); d != want {
+			t.Errorf("Expected exponential delay %v for attempt %d, got %v", want, i+1, d)
+		}
+	}
+
+	jitter := retry.DecorrelatedJitterBackoff{Base: 10 * time.Millisecond, Max: 100 * time.Millisecond}
+	var previous time.Duration
+	for attempt := 1; attempt <= 20; attempt++ {
+		d := jitter.Delay(attempt, previous)
+		upper := 3 * previous
+		if upper < 30*time.Millisecond {
+			upper = 30 * time.Millisecond
+		}
+		if d < 10*time.Millisecond || d > 100*time.Millisecond || d > upper {
+			t.Fatalf("Decorrelated jitter delay %v out of range (previous %v)", d, previous)
+		}
+		previous = d
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	policy := retry.Constant(3, time.Millisecond)
+
+	calls := 0
+	attempts, err := policy.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return errors.New("transient")
+		}
+		return nil
+	})
+	if err != nil || attempts != 3 {
+		t.Errorf("Expected success after 3 attempts, got %d attempts and %v", attempts, err)
+	}
+
+	// Retries are exhausted
+	attempts, err = policy.Do(context.Background(), func(ctx context.Context) error {
+		return errors.New("always fails")
+	})
+	if err == nil || attempts != 4 {
+		t.Errorf("Expected failure after 4 attempts, got %d attempts and %v", attempts, err)
+	}
+
+	// Permanent errors are not retried
+	permanent := errors.New("bad request")
+	attempts, err = policy.Do(context.Background(), func(ctx context.Context) error {
+		return retry.Permanent(permanent)
+	})
+	if !errors.Is(err, permanent) || attempts != 1 {
+		t.Errorf("Expected permanent error after 1 attempt, got %d attempts and %v", attempts, err)
+	}
+
+	// Max elapsed time stops retries before they are exhausted
+	slow := &retry.Policy{
+		MaxRetries:     10,
+		Backoff:        retry.ConstantBackoff{Interval: 20 * time.Millisecond},
+		MaxElapsedTime: 50 * time.Millisecond,
+	}
+	attempts, _ = slow.Do(context.Background(), func(ctx context.Context) error {
+		return errors.New("always fails")
+	})
+	if attempts != 3 {
+		t.Errorf("Expected max elapsed time to allow 3 attempts, got %d", attempts)
+	}
+
+	// Cancelling the context stops the wait for the next retry
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = retry.Constant(3, time.Second).Do(ctx, func(ctx context.Context) error {
+		return errors.New("always fails")
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context deadline error, got %v", err)
+	}
+}
+
+type temporaryError struct{ temporary bool }
+
+func (e temporaryError) Error() string   { return "temporary error" }
+func (e temporaryError) Temporary() bool { return e.temporary }
+
+func TestRetryable(t *testing.T) {
+	cases := []struct {
+		err       error
+		retryable bool
+	}{
+		{nil, false},
+		{errors.New("transient"), true},
+		{retry.Permanent(errors.New("bad request")), false},
+		{fmt.Errorf("wrapped: %w", retry.Permanent(errors.New("bad request"))), false},
+		{context.Canceled, false},
+		{fmt.Errorf("wrapped: %w", context.DeadlineExceeded), false},
+		{temporaryError{temporary: true}, true},
+		{temporaryError{temporary: false}, false},
+	}
+	for _, c := range cases {
+		if got := retry.Retryable(c.err); got != c.retryable {
+			t.Errorf("Retryable(%v) = %v, expected %v", c.err, got, c.retryable)
+		}
+	}
+}
+
+func TestRetryConfig(t *testing.T) {
+	cfg, err := retry.ConfigFromMap(map[string]interface{}{
+		"strategy":      "exponential",
+		"max_retries":   float64(4),
+		"initial_delay": 0.5,
+		"max_delay":     2,
+	})
+	if err != nil {
+		t.Fatalf("Failed to read retry config: %v", err)
+	}
+	policy, err := cfg.Policy()
+	if err != nil {
+		t.Fatalf("Failed to build retry policy: %v", err)
+	}
+	if policy.MaxRetries != 4 {
+		t.Errorf("Expected 4 retries, got %d", policy.MaxRetries)
+	}
+	if d := policy.Backoff.Delay(4, 0); d != 2*time.Second {
+		t.Errorf("Expected delay to be capped at 2s, got %v", d)
+	}
+
+	if _, err := (retry.Config{Strategy: "fibonacci"}).Policy(); err == nil {
+		t.Errorf("Expected unknown strategy to be rejected")
+	}
+}
+
+func TestAgentRetryPolicy(t *testing.T) {
+	agent := agents.NewBaseAgent("retry_agent")
+	err := agent.Initialize(map[string]interface{}{
+		"retry_policy": map[string]interface{}{
+			"strategy":      "constant",
+			"max_retries":   2,
+			"initial_delay": 0.001,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+
+	calls := 0
+	agent.SetBehavior(agents.BehaviorFunc(func(ctx context.Context, input interface{}) (interface{}, error) {
+		calls++
+		return nil, errors.New("always fails")
+	}))
+	if err := agent.Execute(); err == nil {
+		t.Fatalf("Expected execution to fail")
+	}
+	if calls != 3 || agent.ErrorCount != 3 {
+		t.Errorf("Expected 3 attempts, got %d calls and %d errors", calls, agent.ErrorCount)
+	}
+
+	// Permanent errors fail the execution without retrying
+	calls = 0
+	agent.SetBehavior(agents.BehaviorFunc(func(ctx context.Context, input interface{}) (interface{}, error) {
+		calls++
+		return nil, retry.Permanent(errors.New("invalid input"))
+	}))
+	if err := agent.Execute(); err == nil {
+		t.Fatalf("Expected execution to fail")
+	}
+	if calls != 1 {
+		t.Errorf("Expected permanent error to skip retries, got %d calls", calls)
+	}
+
+	if err := agent.Initialize(map[string]interface{}{"retry_policy": "often"}); err == nil {
+		t.Errorf("Expected invalid retry_policy to be rejected")
+	}
+}
+
+func TestHealthCheckRetryPolicy(t *testing.T) {
+	calls := 0
+	check := monitoring.NewHealthCheck("flaky", "component", time.Minute, func() *monitoring.HealthCheckResult {
+		calls++
+		status := monitoring.StatusUnhealthy
+		if calls == 3 {
+			status = monitoring.StatusHealthy
+		}
+		return &monitoring.HealthCheckResult{Status: status, Message: "checked", Timestamp: time.Now()}
+	})
+	check.RetryPolicy = retry.Constant(5, time.Millisecond)
+
+	check.RunCheck()
+	result := check.GetLastResult()
+	if calls != 3 || result.Status != monitoring.StatusHealthy {
+		t.Errorf("Expected recovery on the third check, got %d calls and status %s", calls, result.Status)
+	}
+}
+
+func TestAgentConfigRetryPolicies(t *testing.T) {
+	var config agents.AgentConfig
+	err := json.Unmarshal([]byte(`{
+		"type": "ExecutorAgent",
+		"name": "configured_sender",
+		"settings": {"action": "message", "target": "receiver", "max_retries": 0},
+		"health_check_retry_policy": {"strategy": "constant", "max_retries": 4, "initial_delay": 0.001},
+		"messaging_retry_policy": {"strategy": "constant", "max_retries": 2, "initial_delay": 0.001}
+	}`), &config)
+	if err != nil {
+		t.Fatalf("Failed to decode agent config: %v", err)
+	}
+	agent, err := agents.NewAgentFactory().CreateAgentFromConfig(&config)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+
+	check := agents.NewHealthCheck("agent_health", config.Name, time.Minute, agent)
+	if check.RetryPolicy == nil || check.RetryPolicy.MaxRetries != 4 {
+		t.Errorf("Expected the health check to retry 4 times, got %+v", check.RetryPolicy)
+	}
+	check.RunCheck()
+	if result := check.GetLastResult(); result.Status != monitoring.StatusHealthy {
+		t.Errorf("Expected a healthy agent, got %s (%s)", result.Status, result.Message)
+	}
+
+	// A messaging system without a buffer is always full, so every send fails
+	full := orchestration.NewMessagingSystem(0)
+	executor := agent.(*agents.ExecutorAgent)
+	executor.SetMessaging(full)
+	if err := executor.Execute(); err == nil || !strings.Contains(err.Error(), "after 3 attempts") {
+		t.Errorf("Expected the message action to try 3 times, got %v", err)
+	}
+
+	messaging := adapter.NewAgentMessagingAdapter(full)
+	messaging.RegisterAgent(config.Name, agent)
+	if err := messaging.SendMessage(config.Name, "receiver", "update", nil); err == nil || !strings.Contains(err.Error(), "after 3 attempts") {
+		t.Errorf("Expected the adapter to try 3 times, got %v", err)
+	}
+
+	config.MessagingRetryPolicy = &retry.Config{Strategy: "fibonacci"}
+	if _, err := agents.NewAgentFactory().CreateAgentFromConfig(&config); err == nil {
+		t.Errorf("Expected an invalid messaging_retry_policy to be rejected")
+	}
+}
@@ -0,0 +1,265 @@
+package internal
+
+import (
+	"beluga/pkg/agents"
+	"beluga/pkg/agents/config"
+	"beluga/pkg/analysis"
+	"beluga/pkg/events"
+	"beluga/pkg/rules"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpressions(t *testing.T) {
+	vars := map[string]interface{}{
+		"score":     0.72,
+		"count":     int64(3),
+		"sentiment": "positive",
+		"tags":      []string{"news", "tech"},
+		"author":    map[string]interface{}{"name": "Ada", "roles": []interface{}{"editor"}},
+		"flagged":   false,
+		"params":    map[string]interface{}{"range": []interface{}{0.5, 0.8}, "limit": 2},
+	}
+	testCases := []struct {
+		expression string
+		want       interface{}
+	}{
+		{`score > 0.7 and sentiment == "positive"`, true},
+		{`score >= 0.8 || count == 3`, true},
+		{`not flagged && !(count < 3)`, true},
+		{`count * 2 + 1 - 4 / 2 % 3`, 5.0},
+		{`-count`, -3.0},
+		{`score between params.range[0] and params.range[1]`, true},
+		{`count between 4 and 5`, false},
+		{`"tech" in tags and "sports" not in tags`, true},
+		{`'edit' in author.roles[0] and "name" in author`, true},
+		{`sentiment in ["positive", "neutral"]`, true},
+		{`author.name == 'Ada' and author['name'] == "Ada"`, true},
+		{`tags[-1] == "tech" and tags[5] == null`, true},
+		{`len(tags) > params.limit - 1 and len(author.name) == 3`, true},
+		{`abs(-score) == score and upper(lower("Ab")) == "AB"`, true},
+		{`max(1, count, 2) == 3 and min([4, 2, 9]) == 2`, true},
+		{`missing.field == null and (missing > 1) == false`, true},
+		{`missing or count == 3`, true},
+		{`"a" < "b" and [1, "x"] == [1, "x"]`, true},
+		{`1.5e1 == 15`, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.expression, func(t *testing.T) {
+			expression, err := rules.Compile(tc.expression)
+			if err != nil {
+				t.Fatalf("Failed to compile: %v", err)
+			}
+			got, err := expression.Evaluate(vars)
+			if err != nil {
+				t.Fatalf("Failed to evaluate: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("Expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+
+	// Expressions cannot call anything but their functions
+	for _, source := range []string{`os.Exit(1)`, `score >`, `"open`, `score = 1`, `count count`, `(score`, `len()`, `and`} {
+		if _, err := rules.Compile(source); err == nil {
+			t.Errorf("Expected %q to fail to compile", source)
+		}
+	}
+	for _, source := range []string{`score > "high"`, `count / 0`, `sentiment`, `1 in count`} {
+		expression, err := rules.Compile(source)
+		if err != nil {
+			t.Fatalf("Failed to compile %q: %v", source, err)
+		}
+		if _, err := expression.Matches(vars); err == nil {
+			t.Errorf("Expected %q to fail to evaluate", source)
+		}
+	}
+}
+
+func TestRuleSet(t *testing.T) {
+	ruleSet, err := rules.FromMap(map[string]interface{}{
+		"positive_threshold": 0.6,
+		"neutral_range":      []interface{}{-0.4, 0.6},
+		"default":            "hold",
+		"rules": []interface{}{
+			map[string]interface{}{"name": "review", "when": "score between params.neutral_range[0] and params.neutral_range[1]", "outcome": "review", "priority": 10},
+			map[string]interface{}{"name": "promote", "when": "label == 'positive' and score >= params.positive_threshold", "outcome": "recommend", "priority": 30, "description": "Clearly positive"},
+			map[string]interface{}{"name": "any_positive", "when": "label == 'positive'", "outcome": "consider", "priority": 10},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to load rules: %v", err)
+	}
+	if names := ruleSet.Rules(); names[0].Name != "promote" || names[1].Name != "review" || names[2].Name != "any_positive" {
+		t.Errorf("Expected rules by priority, then order, got %v", names)
+	}
+
+	// Analysis results are decided on by their fields
+	decision, err := ruleSet.Decide(&analysis.SentimentResult{Label: analysis.Positive, Score: 0.6})
+	if err != nil {
+		t.Fatalf("Decision failed: %v", err)
+	}
+	if decision.Outcome != "recommend" || decision.Rule != "promote" || len(decision.Fired) != 3 || decision.Evaluated != 3 {
+		t.Errorf("Unexpected decision %+v", decision)
+	}
+	fired := decision.Fired[0]
+	if fired.Values["score"] != 0.6 || fired.Values["label"] != "positive" || fired.Values["params.positive_threshold"] != 0.6 {
+		t.Errorf("Expected the values the rule saw, got %v", fired.Values)
+	}
+	explanation := decision.Explanation()
+	for _, part := range []string{"rule promote (priority 30) decided recommend", `label = "positive"`, "rule review (priority 10) also matched"} {
+		if !strings.Contains(explanation, part) {
+			t.Errorf("Expected the explanation to contain %q, got %q", part, explanation)
+		}
+	}
+
+	decision, _ = ruleSet.Decide(map[string]interface{}{"label": "negative", "score": -0.9})
+	if decision.Outcome != "hold" || !decision.Default() || len(decision.Fired) != 0 {
+		t.Errorf("Expected the default outcome, got %+v", decision)
+	}
+	if !strings.Contains(decision.Explanation(), "no rule of 3 matched, default outcome hold") {
+		t.Errorf("Unexpected explanation %q", decision.Explanation())
+	}
+
+	invalid := []map[string]interface{}{
+		{"rules": "score > 1"},
+		{"rules": []interface{}{map[string]interface{}{"when": "score >", "outcome": "x"}}},
+		{"rules": []interface{}{map[string]interface{}{"when": "true"}}},
+		{"rules": []interface{}{map[string]interface{}{"when": "true", "outcome": "x", "priority": 1.5}}},
+		{"rules": []interface{}{map[string]interface{}{"when": "true", "outcome": "x", "then": "y"}}},
+		{"rules": []interface{}{
+			map[string]interface{}{"name": "a", "when": "true", "outcome": "x"},
+			map[string]interface{}{"name": "a", "when": "false", "outcome": "y"},
+		}},
+		{"default": 1},
+	}
+	for _, settings := range invalid {
+		if _, err := rules.FromMap(settings); err == nil {
+			t.Errorf("Expected %v to fail", settings)
+		}
+	}
+}
+
+func TestDecisionMakerRules(t *testing.T) {
+	factory := agents.NewAgentFactory()
+	agent, err := factory.CreateAgent("DecisionMakerAgent", "recommender", map[string]interface{}{
+		"max_retries": 2,
+		"retry_delay": 0,
+		// Nested settings decoded from YAML have interface{} keys
+		"decision_rules": map[interface{}]interface{}{
+			"min_score": 0.5,
+			"rules": []interface{}{
+				map[interface{}]interface{}{"name": "promote", "when": "score >= params.min_score", "outcome": "recommend"},
+				map[interface{}]interface{}{"name": "strict", "when": "score > level", "outcome": "flag", "priority": -1},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	decider := agent.(*agents.DecisionMakerAgent)
+
+	output, err := decider.Run(context.Background(), map[string]interface{}{"score": 0.8})
+	if err != nil {
+		t.Fatalf("Decision failed: %v", err)
+	}
+	if decision, ok := output.(*rules.Decision); !ok || decision.Outcome != "recommend" || decider.GetDecision() != "recommend" || decider.GetDecisionResult() != decision {
+		t.Errorf("Unexpected decision %v", output)
+	}
+
+	// Without a matching rule the default outcome is used
+	decider.Run(context.Background(), map[string]interface{}{"score": 0.1})
+	if decider.GetDecision() != rules.DefaultOutcome {
+		t.Errorf("Expected the default outcome, got %s", decider.GetDecision())
+	}
+
+	// A rule that cannot be evaluated fails the decision without retries
+	finished := make(chan agents.ExecutionFinished, 1)
+	subscription := decider.Subscribe(agents.EventExecutionFinished, func(event events.Event) {
+		finished <- event.(agents.ExecutionFinished)
+	})
+	defer subscription.Cancel()
+	if _, err := decider.Run(context.Background(), map[string]interface{}{"score": 0.1, "level": "high"}); err == nil || !strings.Contains(err.Error(), "rule strict") {
+		t.Errorf("Expected the strict rule to fail, got %v", err)
+	}
+	select {
+	case event := <-finished:
+		if event.Attempts != 1 {
+			t.Errorf("Expected a single attempt, got %d", event.Attempts)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Timed out waiting for the execution to finish")
+	}
+
+	// Conditions see the decisions the agent recalls
+	repeating, err := factory.CreateAgent("DecisionMakerAgent", "repeating", map[string]interface{}{
+		"memory": map[string]interface{}{"type": "in_memory"},
+		"decision_rules": map[string]interface{}{
+			"rules": []interface{}{
+				map[string]interface{}{"name": "again", "when": "len(history) > 0 and history[-1].output.outcome == 'recommend'", "outcome": "repeat", "priority": 1},
+				map[string]interface{}{"name": "promote", "when": "score >= 0.5", "outcome": "recommend"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	for _, want := range []string{"recommend", "repeat"} {
+		if output, err := repeating.(*agents.DecisionMakerAgent).Run(context.Background(), map[string]interface{}{"score": 0.8}); err != nil || output.(*rules.Decision).Outcome != want {
+			t.Errorf("Expected %s, got %v (%v)", want, output, err)
+		}
+	}
+
+	if err := agents.NewDecisionMakerAgent("invalid").Initialize(map[string]interface{}{"decision_rules": "strict"}); err == nil {
+		t.Errorf("Expected invalid decision_rules to fail")
+	}
+}
+
+func TestShippedDecisionRules(t *testing.T) {
+	manager := config.NewConfigManager()
+	if err := manager.LoadConfig("../configs/agents/agents.json"); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	analyzerConfig, err := manager.GetAgentConfig("sentiment_analyzer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	recommenderConfig, err := manager.GetAgentConfig("content_recommender")
+	if err != nil {
+		t.Fatal(err)
+	}
+	analyzer, err := analysis.NewRegistry().New("sentiment", analysis.Config{Settings: analyzerConfig.Settings})
+	if err != nil {
+		t.Fatalf("Failed to create analyzer: %v", err)
+	}
+	recommender := agents.NewDecisionMakerAgent("content_recommender")
+	if err := recommender.Initialize(recommenderConfig.Settings); err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+
+	// What the sentiment analyzer finds is decided on by the shipped rules
+	testCases := []struct {
+		text string
+		rule string
+	}{
+		{"Great news, the launch was an excellent success and users love the wonderful new design", "recommend_positive"},
+		{"A terrible, awful outage ruined the day and customers hate the horrible response", "suppress_negative"},
+		{"The meeting is scheduled for Tuesday in room four", "review_neutral"},
+	}
+	for _, tc := range testCases {
+		result, err := analyzer.Analyze(context.Background(), tc.text)
+		if err != nil {
+			t.Fatalf("Analysis failed: %v", err)
+		}
+		output, err := recommender.Run(context.Background(), result)
+		if err != nil {
+			t.Fatalf("Decision failed: %v", err)
+		}
+		if decision := output.(*rules.Decision); decision.Rule != tc.rule {
+			t.Errorf("Expected rule %s to decide on %+v, got %+v", tc.rule, result, decision)
+		}
+	}
+}
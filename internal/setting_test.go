@@ -0,0 +1,93 @@
+package internal
+
+import (
+	"beluga/pkg/setting"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestSettingHelpers(t *testing.T) {
+	var settings map[string]interface{}
+	err := yaml.Unmarshal([]byte(`
+name: worker
+retries: "3"
+timeout: 30
+ratio: 0.5
+enabled: true
+tags: [a, b]
+headers:
+  X-Id: 7
+`), &settings)
+	if err != nil {
+		t.Fatalf("Failed to decode settings: %v", err)
+	}
+
+	if name, err := setting.String(settings, "name", ""); err != nil || name != "worker" {
+		t.Errorf("Expected name worker, got %q (%v)", name, err)
+	}
+	if retries, err := setting.Int(settings, "retries", 0); err != nil || retries != 3 {
+		t.Errorf("Expected a numeric string to give 3 retries, got %d (%v)", retries, err)
+	}
+	if timeout, err := setting.Seconds(settings, "timeout", 0); err != nil || timeout != 30*time.Second {
+		t.Errorf("Expected a 30s timeout, got %v (%v)", timeout, err)
+	}
+	if ratio, err := setting.Float(settings, "ratio", 0); err != nil || ratio != 0.5 {
+		t.Errorf("Expected ratio 0.5, got %v (%v)", ratio, err)
+	}
+	if enabled, err := setting.Bool(settings, "enabled", false); err != nil || !enabled {
+		t.Errorf("Expected enabled, got %v (%v)", enabled, err)
+	}
+	if tags, err := setting.Strings(settings, "tags"); err != nil || len(tags) != 2 || tags[1] != "b" {
+		t.Errorf("Expected tags [a b], got %v (%v)", tags, err)
+	}
+	if headers, err := setting.StringMap(settings, "headers"); err != nil || headers["X-Id"] != "7" {
+		t.Errorf("Expected YAML map keys and values as strings, got %v (%v)", headers, err)
+	}
+	if missing, err := setting.Int(settings, "missing", 5); err != nil || missing != 5 {
+		t.Errorf("Expected the default for a missing setting, got %d (%v)", missing, err)
+	}
+
+	if _, err := setting.Int(settings, "ratio", 0); err == nil {
+		t.Errorf("Expected a fraction to be rejected as an integer")
+	}
+	if _, err := setting.String(settings, "timeout", ""); err == nil {
+		t.Errorf("Expected a number to be rejected as a string")
+	}
+	if _, ok := setting.StringKeys([]string{"a"}); ok {
+		t.Errorf("Expected a list not to convert to a map")
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	var decoded interface{}
+	if err := yaml.Unmarshal([]byte("outer:\n  inner: [1, 2.5, {key: true}]\n"), &decoded); err != nil {
+		t.Fatalf("Failed to decode YAML: %v", err)
+	}
+	normalized, ok := setting.NormalizeAll(decoded).(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected a string-keyed map, got %T", decoded)
+	}
+	inner := normalized["outer"].(map[string]interface{})["inner"].([]interface{})
+	if inner[0] != int64(1) || inner[1] != 2.5 {
+		t.Errorf("Expected integers as int64 and other numbers as float64, got %v", inner)
+	}
+	if nested, ok := inner[2].(map[string]interface{}); !ok || nested["key"] != true {
+		t.Errorf("Expected nested YAML maps to get string keys, got %T", inner[2])
+	}
+
+	type point struct {
+		X int     `json:"x"`
+		Y float64 `json:"y"`
+	}
+	if object, ok := setting.Normalize(point{X: 1, Y: 0.5}).(map[string]interface{}); !ok || object["x"] != int64(1) || object["y"] != 0.5 {
+		t.Errorf("Expected a struct to convert through JSON, got %v", setting.Normalize(point{X: 1, Y: 0.5}))
+	}
+	if list, ok := setting.Normalize([]int{1, 2}).([]interface{}); !ok || len(list) != 2 || list[0] != 1 {
+		t.Errorf("Expected a typed slice to become a list of its elements, got %v", list)
+	}
+	if value := setting.Normalize(uint64(1) << 63); value != float64(1<<63) {
+		t.Errorf("Expected an out of range integer as float64, got %v (%T)", value, value)
+	}
+}
@@ -1,8 +1,12 @@
 package internal
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 	"beluga/pkg/agents"
+	"beluga/pkg/interfaces"
 )
 
 func TestBaseAgentLifecycle(t *testing.T) {
@@ -102,18 +106,24 @@ func TestAgentIntegration(t *testing.T) {
 			t.Fatalf("Failed to create DecisionMakerAgent: %v", err)
 		}
 
-		// Simulate workflow
-		if err := dataFetcher.Execute(); err != nil {
+		// Simulate workflow, passing each agent's output to the next
+		ctx := context.Background()
+		if _, err := interfaces.AsContextAgent(dataFetcher).Run(ctx, nil); err != nil {
 			t.Errorf("DataFetcherAgent execution failed: %v", err)
 		}
 
-		if err := analyzer.Execute(); err != nil {
+		analysis, err := interfaces.AsContextAgent(analyzer).Run(ctx, "simulated data")
+		if err != nil {
 			t.Errorf("AnalyzerAgent execution failed: %v", err)
 		}
 
-		if err := decisionMaker.Execute(); err != nil {
+		decision, err := interfaces.AsContextAgent(decisionMaker).Run(ctx, analysis)
+		if err != nil {
 			t.Errorf("DecisionMakerAgent execution failed: %v", err)
 		}
+		if decision == nil {
+			t.Errorf("Expected a decision from DecisionMakerAgent")
+		}
 
 		// Shutdown agents
 		if err := dataFetcher.Shutdown(); err != nil {
@@ -128,4 +138,79 @@ func TestAgentIntegration(t *testing.T) {
 			t.Errorf("DecisionMakerAgent shutdown failed: %v", err)
 		}
 	})
-}
\ No newline at end of file
+}
+// legacyAgent only implements the original interfaces.Agent contract.
+type legacyAgent struct {
+	executed bool
+	block    chan struct{}
+}
+
+func (l *legacyAgent) Initialize(config map[string]interface{}) error { return nil }
+func (l *legacyAgent) Shutdown() error                                { return nil }
+func (l *legacyAgent) Execute() error {
+	l.executed = true
+	if l.block != nil {
+		<-l.block
+	}
+	return nil
+}
+
+func TestContextAgentShim(t *testing.T) {
+	t.Run("Legacy agent runs through Execute", func(t *testing.T) {
+		legacy := &legacyAgent{}
+		output, err := interfaces.AsContextAgent(legacy).Run(context.Background(), "ignored")
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if !legacy.executed {
+			t.Errorf("Expected Execute to be called")
+		}
+		if output != nil {
+			t.Errorf("Expected nil output from legacy agent, got %v", output)
+		}
+	})
+
+	t.Run("Legacy agent run is cancellable", func(t *testing.T) {
+		legacy := &legacyAgent{block: make(chan struct{})}
+		defer close(legacy.block)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if _, err := interfaces.AsContextAgent(legacy).Run(ctx, nil); err != context.DeadlineExceeded {
+			t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+		}
+	})
+
+	t.Run("Context agents are returned unchanged", func(t *testing.T) {
+		agent := agents.NewAnalyzerAgent("ShimAnalyzer", "basic")
+		if interfaces.AsContextAgent(agent) != interfaces.ContextAgent(agent) {
+			t.Errorf("Expected AsContextAgent to return the agent itself")
+		}
+	})
+}
+
+func TestBaseAgentRunCancellation(t *testing.T) {
+	agent := agents.NewBaseAgent("CancelAgent")
+	if err := agent.Initialize(map[string]interface{}{"max_retries": 5}); err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	_, err := agent.RunWith(ctx, nil, agents.BehaviorFunc(func(ctx context.Context, input interface{}) (interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if agent.GetState() != agents.StateReady {
+		t.Errorf("Expected agent to return to ready after cancellation, got %s", agent.GetState())
+	}
+}
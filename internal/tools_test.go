@@ -0,0 +1,386 @@
+package internal
+
+import (
+	"beluga/pkg/actions"
+	"beluga/pkg/agents"
+	"beluga/pkg/events"
+	"beluga/pkg/llm"
+	"beluga/pkg/tools"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func addTool() *tools.FuncTool {
+	return tools.NewTool("add", "Adds two integers.",
+		tools.Object(map[string]*tools.Schema{
+			"a": tools.Integer("First operand"),
+			"b": tools.Integer("Second operand"),
+		}, "a", "b"),
+		func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			a, _ := args["a"].(float64)
+			b, _ := args["b"].(float64)
+			return a + b, nil
+		})
+}
+
+func TestSchemaValidation(t *testing.T) {
+	min, maxLength, closed := 1.0, 3, false
+	schema := tools.Object(map[string]*tools.Schema{
+		"name":   {Type: "string", MaxLength: &maxLength, Pattern: "^[a-z]+$"},
+		"count":  {Type: "integer", Minimum: &min},
+		"mode":   {Type: "string", Enum: []interface{}{"fast", "slow"}},
+		"tags":   tools.Array("", tools.String("")),
+		"nested": tools.Object(map[string]*tools.Schema{"flag": tools.Boolean("")}, "flag"),
+	}, "name")
+	schema.AdditionalProperties = &closed
+
+	valid := []map[string]interface{}{
+		{"name": "abc"},
+		{"name": "a", "count": 2, "mode": "fast", "tags": []string{"x", "y"}},
+		{"name": "a", "count": float64(3), "nested": map[string]interface{}{"flag": true}},
+	}
+	for _, args := range valid {
+		if err := schema.Validate(args); err != nil {
+			t.Errorf("Expected %v to be valid, got %v", args, err)
+		}
+	}
+
+	invalid := map[string]map[string]interface{}{
+		"name":        {},
+		"count":       {"name": "a", "count": 1.5},
+		"mode":        {"name": "a", "mode": "medium"},
+		"tags[1]":     {"name": "a", "tags": []interface{}{"x", 2}},
+		"nested.flag": {"name": "a", "nested": map[string]interface{}{}},
+		"extra":       {"name": "a", "extra": true},
+	}
+	for path, args := range invalid {
+		err := schema.Validate(args)
+		var validationErr *tools.ValidationError
+		if !errors.As(err, &validationErr) || validationErr.Path != path {
+			t.Errorf("Expected a validation error at %s for %v, got %v", path, args, err)
+		}
+	}
+	for _, args := range []map[string]interface{}{{"name": "abcd"}, {"name": "ABC"}, {"name": "a", "count": 0}} {
+		if err := schema.Validate(args); err == nil {
+			t.Errorf("Expected %v to be invalid", args)
+		}
+	}
+
+	// The schema marshals to standard JSON Schema
+	data, _ := json.Marshal(tools.Object(map[string]*tools.Schema{"a": tools.Integer("A")}, "a"))
+	if string(data) != `{"type":"object","properties":{"a":{"type":"integer","description":"A"}},"required":["a"]}` {
+		t.Errorf("Unexpected JSON Schema: %s", data)
+	}
+}
+
+func TestToolRegistry(t *testing.T) {
+	registry := tools.NewRegistry()
+	if err := registry.Register(addTool()); err != nil {
+		t.Fatalf("Failed to register tool: %v", err)
+	}
+	if err := registry.Register(addTool()); err == nil {
+		t.Errorf("Expected a duplicate tool name to fail")
+	}
+	if err := registry.Register(tools.NewTool("bad name", "", nil, nil)); err == nil {
+		t.Errorf("Expected an invalid tool name to fail")
+	}
+
+	result, err := registry.Invoke(context.Background(), "add", map[string]interface{}{"a": 2.0, "b": 3.0})
+	if err != nil || result != 5.0 {
+		t.Errorf("Expected 5, got %v (%v)", result, err)
+	}
+	_, err = registry.Invoke(context.Background(), "add", map[string]interface{}{"a": 2.0})
+	var validationErr *tools.ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Path != "b" {
+		t.Errorf("Expected b to be reported missing, got %v", err)
+	}
+	if _, err := registry.Invoke(context.Background(), "missing", nil); !errors.Is(err, tools.ErrUnknownTool) {
+		t.Errorf("Expected an unknown tool error, got %v", err)
+	}
+
+	if _, err := registry.Subset("add", "missing"); !errors.Is(err, tools.ErrUnknownTool) {
+		t.Errorf("Expected a subset with an unknown tool to fail, got %v", err)
+	}
+	definitions := registry.Definitions()
+	if len(definitions) != 1 || definitions[0].Name != "add" || definitions[0].Parameters.(*tools.Schema).Type != "object" {
+		t.Errorf("Unexpected definitions: %+v", definitions)
+	}
+}
+
+// scriptedLLM replays canned responses and records the requests it receives.
+type scriptedLLM struct {
+	mutex     sync.Mutex
+	responses []llm.Message
+	requests  []llm.Request
+}
+
+func (s *scriptedLLM) Chat(ctx context.Context, request llm.Request) (*llm.Response, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.requests = append(s.requests, request)
+	if len(s.responses) == 0 {
+		return nil, errors.New("no more responses")
+	}
+	message := s.responses[0]
+	if len(s.responses) > 1 {
+		s.responses = s.responses[1:]
+	}
+	return &llm.Response{Message: message, Usage: llm.Usage{TotalTokens: 10}}, nil
+}
+
+func (s *scriptedLLM) ChatStream(ctx context.Context, request llm.Request, onChunk func(llm.Chunk) error) (*llm.Response, error) {
+	return s.Chat(ctx, request)
+}
+
+func (s *scriptedLLM) GetUsage() llm.Usage { return llm.Usage{} }
+
+func toolCall(id, name, args string) llm.ToolCall {
+	return llm.ToolCall{ID: id, Type: "function", Function: llm.FunctionCall{Name: name, Arguments: args}}
+}
+
+func TestToolLoop(t *testing.T) {
+	registry := tools.NewRegistry()
+	registry.MustRegister(addTool())
+
+	model := &scriptedLLM{responses: []llm.Message{
+		{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCall{
+			toolCall("1", "add", `{"a": 2, "b": 3}`),
+			toolCall("2", "add", `{"a": 2}`),
+		}},
+		{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCall{toolCall("3", "add", `not json`)}},
+		{Role: llm.RoleAssistant, Content: "The sum is 5."},
+	}}
+	loop := &tools.Loop{LLM: model, Registry: registry}
+	result, err := loop.Run(context.Background(), llm.Request{Messages: []llm.Message{{Role: llm.RoleUser, Content: "What is 2 + 3?"}}})
+	if err != nil {
+		t.Fatalf("Loop failed: %v", err)
+	}
+
+	if result.Response.Message.Content != "The sum is 5." || result.Iterations != 3 || result.Usage.TotalTokens != 30 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if len(result.Invocations) != 3 || result.Invocations[0].Result != 5.0 || result.Invocations[1].Err == nil || result.Invocations[2].Err == nil {
+		t.Errorf("Unexpected invocations: %+v", result.Invocations)
+	}
+
+	// Tool schemas are sent and results fed back as tool messages
+	second := model.requests[1]
+	if len(second.Tools) != 1 || second.Tools[0].Name != "add" {
+		t.Errorf("Expected the add tool to be offered, got %+v", second.Tools)
+	}
+	fed := second.Messages[len(second.Messages)-2:]
+	if fed[0].Role != llm.RoleTool || fed[0].ToolCallID != "1" || fed[0].Content != "5" {
+		t.Errorf("Unexpected tool result message: %+v", fed[0])
+	}
+	if !strings.HasPrefix(fed[1].Content, "error: ") || !strings.Contains(fed[1].Content, "b: is required") {
+		t.Errorf("Expected the validation error to be fed back, got %q", fed[1].Content)
+	}
+	if len(result.Messages) != 7 {
+		t.Errorf("Expected 7 messages in the conversation, got %d", len(result.Messages))
+	}
+
+	// A model that never stops calling tools hits the iteration limit
+	looping := &scriptedLLM{responses: []llm.Message{
+		{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCall{toolCall("1", "add", `{"a": 1, "b": 1}`)}},
+	}}
+	loop = &tools.Loop{LLM: looping, Registry: registry, MaxIterations: 2}
+	if _, err := loop.Run(context.Background(), llm.Request{}); !errors.Is(err, tools.ErrMaxIterations) {
+		t.Errorf("Expected the iteration limit, got %v", err)
+	}
+}
+
+func TestOpenAIClientToolCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Tools []struct {
+				Type     string `json:"type"`
+				Function struct {
+					Name       string                 `json:"name"`
+					Parameters map[string]interface{} `json:"parameters"`
+				} `json:"function"`
+			} `json:"tools"`
+			Messages []llm.Message `json:"messages"`
+			Stream   bool          `json:"stream"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if len(body.Tools) != 1 || body.Tools[0].Type != "function" || body.Tools[0].Function.Parameters["type"] != "object" {
+			t.Errorf("Unexpected tools in request: %+v", body.Tools)
+		}
+
+		if body.Stream {
+			fmt.Fprint(w, `data: {"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"add","arguments":""}}]}}]}`+"\n\n")
+			fmt.Fprint(w, `data: {"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"a\":1,"}}]}}]}`+"\n\n")
+			fmt.Fprint(w, `data: {"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"b\":2}"}}]},"finish_reason":"tool_calls"}]}`+"\n\n")
+			fmt.Fprint(w, "data: [DONE]\n\n")
+			return
+		}
+		last := body.Messages[len(body.Messages)-1]
+		if last.Role == llm.RoleTool {
+			fmt.Fprintf(w, `{"choices":[{"message":{"role":"assistant","content":"result %s for %s"},"finish_reason":"stop"}]}`, last.Content, last.ToolCallID)
+			return
+		}
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":null,"tool_calls":[{"id":"call_1","type":"function","function":{"name":"add","arguments":"{\"a\":1,\"b\":2}"}}]},"finish_reason":"tool_calls"}]}`)
+	}))
+	defer server.Close()
+
+	client, _ := llm.NewOpenAIClient(llm.Config{Model: "m", BaseURL: server.URL, APIKey: "k"})
+	registry := tools.NewRegistry()
+	registry.MustRegister(addTool())
+
+	result, err := (&tools.Loop{LLM: client, Registry: registry}).Run(context.Background(),
+		llm.Request{Messages: []llm.Message{{Role: llm.RoleUser, Content: "1 + 2?"}}})
+	if err != nil {
+		t.Fatalf("Loop failed: %v", err)
+	}
+	if result.Response.Message.Content != "result 3 for call_1" {
+		t.Errorf("Unexpected final answer: %q", result.Response.Message.Content)
+	}
+
+	streamed, err := client.ChatStream(context.Background(), llm.Request{Tools: registry.Definitions()}, func(llm.Chunk) error { return nil })
+	if err != nil {
+		t.Fatalf("ChatStream failed: %v", err)
+	}
+	calls := streamed.Message.ToolCalls
+	if len(calls) != 1 || calls[0].ID != "call_1" || calls[0].Function.Name != "add" || calls[0].Function.Arguments != `{"a":1,"b":2}` {
+		t.Errorf("Expected the streamed tool call to be assembled, got %+v", calls)
+	}
+}
+
+func TestOpenAIClientParallelToolCalls(t *testing.T) {
+	streams := map[string][]string{
+		// Fragments of parallel calls interleave and are told apart by index
+		"interleaved": {
+			`{"index":0,"id":"call_1","type":"function","function":{"name":"add","arguments":""}}`,
+			`{"index":1,"id":"call_2","type":"function","function":{"name":"add","arguments":"{\"a\":"}}`,
+			`{"index":0,"function":{"arguments":"{\"a\":1,"}}`,
+			`{"index":1,"function":{"arguments":"3,\"b\":4}"}}`,
+			`{"index":0,"function":{"arguments":"\"b\":2}"}}`,
+		},
+		// Some providers repeat the ID on every fragment
+		"repeated_ids": {
+			`{"index":0,"id":"call_1","type":"function","function":{"name":"add","arguments":"{\"a\":1,"}}`,
+			`{"index":0,"id":"call_1","function":{"arguments":"\"b\":2}"}}`,
+			`{"index":1,"id":"call_2","type":"function","function":{"name":"add","arguments":"{\"a\":3,\"b\":4}"}}`,
+		},
+	}
+	for name, fragments := range streams {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for _, fragment := range fragments {
+					fmt.Fprintf(w, `data: {"choices":[{"delta":{"tool_calls":[%s]}}]}`+"\n\n", fragment)
+				}
+				fmt.Fprint(w, `data: {"choices":[{"delta":{},"finish_reason":"tool_calls"}]}`+"\n\ndata: [DONE]\n\n")
+			}))
+			defer server.Close()
+
+			client, _ := llm.NewOpenAIClient(llm.Config{Model: "m", BaseURL: server.URL, APIKey: "k"})
+			streamed, err := client.ChatStream(context.Background(), llm.Request{}, func(llm.Chunk) error { return nil })
+			if err != nil {
+				t.Fatalf("ChatStream failed: %v", err)
+			}
+			calls := streamed.Message.ToolCalls
+			if len(calls) != 2 || calls[0].ID != "call_1" || calls[0].Function.Arguments != `{"a":1,"b":2}` ||
+				calls[1].ID != "call_2" || calls[1].Function.Name != "add" || calls[1].Function.Arguments != `{"a":3,"b":4}` {
+				t.Errorf("Expected two assembled calls, got %+v", calls)
+			}
+		})
+	}
+}
+
+func TestExecutorToolDispatch(t *testing.T) {
+	registry := tools.NewRegistry()
+	registry.MustRegister(tools.NewTool("notify", "Sends a notification.",
+		tools.Object(map[string]*tools.Schema{
+			"target":  tools.String("Channel"),
+			"message": tools.String("Text to send"),
+		}, "target", "message"),
+		func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			return fmt.Sprintf("%s <- %s", args["target"], args["message"]), nil
+		}))
+
+	executor := agents.NewExecutorAgent("tool_executor", "notify", "ops")
+	executor.SetTools(registry)
+	if err := executor.Initialize(map[string]interface{}{"max_retries": 0}); err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+	invoked := make(chan agents.ToolInvoked, 4)
+	executor.Subscribe(agents.EventToolInvoked, func(event events.Event) {
+		invoked <- event.(agents.ToolInvoked)
+	})
+
+	output, err := executor.Run(context.Background(), map[string]interface{}{"message": "disk full"})
+	result, ok := output.(*actions.ExecutionResult)
+	if err != nil || !ok || result.Output != "ops <- disk full" || !result.Success || result.Action != "notify" {
+		t.Fatalf("Expected the notify tool to run on the target, got %v (%v)", output, err)
+	}
+	select {
+	case event := <-invoked:
+		if event.Tool != "notify" || event.Result != "ops <- disk full" {
+			t.Errorf("Unexpected ToolInvoked event: %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Expected a ToolInvoked event")
+	}
+
+	// Arguments are validated before the tool runs
+	if _, err := executor.Run(context.Background(), map[string]interface{}{"message": 42}); err == nil {
+		t.Errorf("Expected invalid arguments to fail")
+	}
+
+	unknown := agents.NewExecutorAgent("unknown_executor", "launch", "")
+	unknown.SetTools(registry)
+	unknown.Initialize(map[string]interface{}{"max_retries": 0})
+	if _, err := unknown.Run(context.Background(), nil); !errors.Is(err, tools.ErrUnknownTool) {
+		t.Errorf("Expected an unknown action to fail, got %v", err)
+	}
+}
+
+func TestAgentToolLoop(t *testing.T) {
+	tools.DefaultRegistry().MustRegister(tools.NewTool("test_clock", "Returns a fixed time.", nil,
+		func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			return "12:00", nil
+		}))
+	defer tools.DefaultRegistry().Unregister("test_clock")
+
+	agent := agents.NewBaseAgent("tool_loop_agent")
+	if err := agent.Initialize(map[string]interface{}{"tools": []interface{}{"test_clock"}, "max_tool_iterations": 3}); err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+	if _, err := agent.RunToolLoop(context.Background()); err == nil {
+		t.Errorf("Expected the loop to require an llm")
+	}
+
+	agent.SetLLM(&scriptedLLM{responses: []llm.Message{
+		{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCall{toolCall("1", "test_clock", "")}},
+		{Role: llm.RoleAssistant, Content: "It is noon."},
+	}})
+	result, err := agent.RunToolLoop(context.Background(), llm.Message{Role: llm.RoleUser, Content: "What time is it?"})
+	if err != nil || result.Response.Message.Content != "It is noon." {
+		t.Fatalf("Unexpected loop result: %+v (%v)", result, err)
+	}
+	if len(agent.GetTools().List()) != 1 {
+		t.Errorf("Expected only the selected tool to be available")
+	}
+
+	// Agents without the tools setting get none of the default registry
+	unconfigured := agents.NewBaseAgent("unconfigured_tools_agent")
+	if err := unconfigured.Initialize(map[string]interface{}{}); err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+	if _, err := unconfigured.InvokeTool(context.Background(), "test_clock", nil); !errors.Is(err, tools.ErrUnknownTool) || len(unconfigured.GetTools().List()) != 0 {
+		t.Errorf("Expected no tools without the tools setting, got %v", err)
+	}
+
+	invalid := agents.NewBaseAgent("invalid_tools_agent")
+	if err := invalid.Initialize(map[string]interface{}{"tools": []interface{}{"missing_tool"}}); err == nil {
+		t.Errorf("Expected an unknown tool name to fail")
+	}
+}
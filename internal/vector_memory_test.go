@@ -0,0 +1,241 @@
+package internal
+
+import (
+	"beluga/pkg/agents"
+	"beluga/pkg/memory"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestVectorIndexSearch(t *testing.T) {
+	ctx := context.Background()
+	index := memory.NewVectorIndex(nil, memory.Cosine)
+
+	err := index.Add(ctx,
+		memory.Document{ID: "x", Vector: []float64{1, 0}, Metadata: map[string]interface{}{"kind": "axis", "rank": 1}},
+		memory.Document{ID: "y", Vector: []float64{0, 2}, Metadata: map[string]interface{}{"kind": "axis", "rank": 2}},
+		memory.Document{ID: "xy", Vector: []float64{3, 3}, Metadata: map[string]interface{}{"kind": "diagonal"}},
+	)
+	if err != nil {
+		t.Fatalf("Failed to add documents: %v", err)
+	}
+
+	// Cosine ignores length: x is the closest to a query along the x axis
+	results, err := index.Search(ctx, memory.Query{Vector: []float64{1, 0.1}, TopK: 2})
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if len(results) != 2 || results[0].Document.ID != "x" || results[1].Document.ID != "xy" {
+		t.Fatalf("Expected x then xy, got %v", results)
+	}
+	if math.Abs(results[0].Score-1/math.Sqrt(1.01)) > 1e-9 {
+		t.Errorf("Unexpected cosine score %v", results[0].Score)
+	}
+
+	// The dot product favours longer vectors
+	results, _ = index.Search(ctx, memory.Query{Vector: []float64{1, 0.1}, TopK: 1, Metric: memory.DotProduct})
+	if len(results) != 1 || results[0].Document.ID != "xy" || results[0].Score != 3.3 {
+		t.Errorf("Expected xy with score 3.3, got %v", results)
+	}
+
+	// Filters match numbers by value and slices as alternatives
+	results, _ = index.Search(ctx, memory.Query{
+		Vector: []float64{1, 1},
+		Filter: map[string]interface{}{"kind": "axis", "rank": []interface{}{float64(2), 3}},
+	})
+	if len(results) != 1 || results[0].Document.ID != "y" {
+		t.Errorf("Expected only y to match the filter, got %v", results)
+	}
+	results, _ = index.Search(ctx, memory.Query{Vector: []float64{1, 0}, MinScore: 0.5})
+	if len(results) != 2 {
+		t.Errorf("Expected two results above the minimum score, got %v", results)
+	}
+
+	// Replacing and deleting by ID
+	index.Add(ctx, memory.Document{ID: "x", Vector: []float64{-1, 0}})
+	index.Delete("y", "missing")
+	if index.Len() != 2 {
+		t.Errorf("Expected 2 documents, got %d", index.Len())
+	}
+	results, _ = index.Search(ctx, memory.Query{Vector: []float64{1, 0}, TopK: 1})
+	if results[0].Document.ID != "xy" {
+		t.Errorf("Expected the replaced x to no longer match, got %v", results)
+	}
+
+	if err := index.Add(ctx, memory.Document{Vector: []float64{1, 2, 3}}); !errors.Is(err, memory.ErrDimensionMismatch) {
+		t.Errorf("Expected a dimension mismatch, got %v", err)
+	}
+	if _, err := index.Search(ctx, memory.Query{Vector: []float64{1}}); !errors.Is(err, memory.ErrDimensionMismatch) {
+		t.Errorf("Expected a dimension mismatch for the query, got %v", err)
+	}
+}
+
+func TestVectorIndexEmbedderAndPersistence(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "vectors.json")
+
+	calls := 0
+	embedder := memory.EmbedderFunc(func(ctx context.Context, texts []string) ([][]float64, error) {
+		calls++
+		return memory.NewHashEmbedder(64).Embed(ctx, texts)
+	})
+	index, err := memory.OpenVectorIndex(path, embedder, "")
+	if err != nil {
+		t.Fatalf("Failed to open vector index: %v", err)
+	}
+	index.Add(ctx,
+		memory.Document{Content: "the server is running out of disk space"},
+		memory.Document{Content: "customers love the new recommendation feature"},
+	)
+	if calls != 1 {
+		t.Errorf("Expected documents to be embedded in one batch, got %d calls", calls)
+	}
+
+	// A reopened index has the documents and keeps assigning unique IDs
+	reopened, err := memory.OpenVectorIndex(path, embedder, memory.Cosine)
+	if err != nil {
+		t.Fatalf("Failed to reopen vector index: %v", err)
+	}
+	reopened.Add(ctx, memory.Document{Content: "disk usage alert on the database server"})
+	if reopened.Len() != 3 {
+		t.Fatalf("Expected 3 documents after reopening, got %d", reopened.Len())
+	}
+
+	results, err := reopened.Search(ctx, memory.Query{Text: "Running out of disk space?", TopK: 2})
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if len(results) != 2 || results[0].Document.Content != "the server is running out of disk space" {
+		t.Errorf("Expected the disk space record first, got %v", results)
+	}
+	if results[1].Document.Content != "disk usage alert on the database server" {
+		t.Errorf("Expected the disk usage record second, got %v", results)
+	}
+
+	if _, err := (memory.VectorConfig{Metric: "euclidean"}).New(nil); err == nil {
+		t.Errorf("Expected an unknown metric to fail")
+	}
+}
+
+func TestVectorIndexCapacity(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "vectors.json")
+	config := memory.VectorConfig{Path: path, Capacity: 3}
+
+	index, err := config.New(nil)
+	if err != nil {
+		t.Fatalf("Failed to open vector index: %v", err)
+	}
+	for i := 1; i <= 8; i++ {
+		if err := index.Add(ctx, memory.Document{ID: fmt.Sprint(i), Content: fmt.Sprintf("record %d", i)}); err != nil {
+			t.Fatalf("Failed to add document: %v", err)
+		}
+	}
+	if index.Len() != 3 {
+		t.Fatalf("Expected the index to keep 3 documents, got %d", index.Len())
+	}
+
+	// Adds are appended to the file, which is compacted as evictions pile up
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read vector index: %v", err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines > 6 {
+		t.Errorf("Expected the file to be compacted, got %d lines", lines)
+	}
+
+	// A reopened index has the newest documents
+	if err := index.Delete("7"); err != nil {
+		t.Fatalf("Failed to delete document: %v", err)
+	}
+	reopened, err := config.New(nil)
+	if err != nil {
+		t.Fatalf("Failed to reopen vector index: %v", err)
+	}
+	results, err := reopened.Search(ctx, memory.Query{Text: "record", TopK: 10})
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	var ids []string
+	for _, result := range results {
+		ids = append(ids, result.Document.ID)
+	}
+	sort.Strings(ids)
+	if strings.Join(ids, ",") != "6,8" {
+		t.Errorf("Expected documents 6 and 8 after reopening, got %v", ids)
+	}
+
+	if err := reopened.SetCapacity(1); err != nil || reopened.Len() != 1 {
+		t.Errorf("Expected lowering the capacity to evict, got %d documents (%v)", reopened.Len(), err)
+	}
+}
+
+func TestAgentVectorMemory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vectors.json")
+	settings := map[string]interface{}{
+		"max_retries": 0,
+		"vector_memory": map[string]interface{}{
+			"path":      path,
+			"top_k":     1,
+			"min_score": 0.3,
+			"filter":    map[string]interface{}{"agent": "vector_analyzer"},
+		},
+	}
+
+	analyzer := agents.NewAnalyzerAgent("vector_analyzer", "keyword")
+	if err := analyzer.Initialize(settings); err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+	for _, input := range []string{"shipping delays in europe", "payment failures at checkout"} {
+		if _, err := analyzer.Run(context.Background(), input); err != nil {
+			t.Fatalf("Failed to run agent: %v", err)
+		}
+	}
+	if _, err := analyzer.Run(context.Background(), "more checkout payment failures"); err != nil {
+		t.Fatalf("Failed to run agent: %v", err)
+	}
+
+	analyzer.Mutex.RLock()
+	related := analyzer.Related
+	analyzer.Mutex.RUnlock()
+	if len(related) != 1 || related[0].Document.Content != "payment failures at checkout" {
+		t.Fatalf("Expected the payment record to be retrieved, got %v", related)
+	}
+	if output, _ := related[0].Document.Metadata["output"].(string); !strings.Contains(output, `"term":"payment"`) {
+		t.Errorf("Expected the record to carry its output, got %v", related[0].Document.Metadata)
+	}
+
+	// A decision maker sharing the persisted index only sees the analyzer's
+	// records through the filter, and nothing unrelated
+	decider := agents.NewDecisionMakerAgent("vector_decider")
+	if err := decider.Initialize(settings); err != nil {
+		t.Fatalf("Failed to initialize agent: %v", err)
+	}
+	decider.Run(context.Background(), "europe shipping")
+	decider.Run(context.Background(), "quarterly revenue")
+	decider.Mutex.RLock()
+	related = decider.Related
+	decider.Mutex.RUnlock()
+	if len(related) != 0 {
+		t.Errorf("Expected no related records for an unrelated input, got %v", related)
+	}
+	decider.Run(context.Background(), "shipping delays")
+	decider.Mutex.RLock()
+	related = decider.Related
+	decider.Mutex.RUnlock()
+	if len(related) != 1 || related[0].Document.Content != "shipping delays in europe" {
+		t.Errorf("Expected the shipping record, got %v", related)
+	}
+
+	invalid := agents.NewBaseAgent("invalid_vector_memory")
+	if err := invalid.Initialize(map[string]interface{}{"vector_memory": map[string]interface{}{"top_k": "many"}}); err == nil {
+		t.Errorf("Expected an invalid vector_memory setting to fail")
+	}
+}
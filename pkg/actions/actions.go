@@ -0,0 +1,175 @@
+package actions
+
+import (
+	"beluga/pkg/orchestration"
+	"beluga/pkg/retry"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnknownAction is returned when no action is registered under a name.
+var ErrUnknownAction = errors.New("unknown action")
+
+// Request is what an action is executed with.
+type Request struct {
+	// Action is the name the action was registered under.
+	Action string
+	// Target is the executor's target, which actions may use as their
+	// default destination.
+	Target string
+	// Params are the parameters of this execution.
+	Params map[string]interface{}
+	// Sender names the agent executing the action.
+	Sender string
+	// Messaging is the executor's messaging system, if it has one.
+	Messaging *orchestration.MessagingSystem
+	// MessagingPolicy retries messages that fail to send; nil sends once.
+	MessagingPolicy *retry.Policy
+}
+
+// ExecutionResult describes an execution of an action.
+type ExecutionResult struct {
+	Action  string `json:"action"`
+	Target  string `json:"target,omitempty"`
+	Success bool   `json:"success"`
+	// Output is what the action produced, such as a *CommandOutput. Failed
+	// executions may have output too.
+	Output    interface{}   `json:"output,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
+}
+
+// Action performs an action on behalf of an executor.
+type Action interface {
+	Execute(ctx context.Context, request Request) (interface{}, error)
+}
+
+// ActionFunc is an Action implemented by a function.
+type ActionFunc func(ctx context.Context, request Request) (interface{}, error)
+
+// Execute calls the function.
+func (f ActionFunc) Execute(ctx context.Context, request Request) (interface{}, error) {
+	return f(ctx, request)
+}
+
+// Execute runs action and describes the execution as an ExecutionResult,
+// which is returned together with the action's error.
+func Execute(ctx context.Context, action Action, request Request) (*ExecutionResult, error) {
+	result := &ExecutionResult{Action: request.Action, Target: request.Target, StartedAt: time.Now()}
+	output, err := action.Execute(ctx, request)
+	result.Duration = time.Since(result.StartedAt)
+	result.Output = output
+	result.Success = err == nil
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result, err
+}
+
+// Config is what an action is created from.
+type Config struct {
+	// Settings are the executor's settings; each action reads its own keys.
+	Settings map[string]interface{}
+}
+
+// Factory creates an action from its configuration.
+type Factory func(config Config) (Action, error)
+
+// Registry maps action names to action factories.
+type Registry struct {
+	mutex     sync.RWMutex
+	factories map[string]Factory
+}
+
+// NewRegistry creates a registry holding the built-in actions: command,
+// webhook, file, message and notification.
+func NewRegistry() *Registry {
+	r := &Registry{factories: make(map[string]Factory)}
+	r.MustRegister("command", NewCommandAction)
+	r.MustRegister("webhook", NewWebhookAction)
+	r.MustRegister("file", NewFileAction)
+	r.MustRegister("message", NewMessageAction)
+	r.MustRegister("notification", NewNotificationAction)
+	return r
+}
+
+var defaultRegistry = NewRegistry()
+
+// DefaultRegistry returns the process-wide registry executors create
+// actions from unless given their own.
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
+// Register adds factory under a lower-case action name, which must not be
+// taken.
+func (r *Registry) Register(name string, factory Factory) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid action name %q", name)
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, exists := r.factories[name]; exists {
+		return fmt.Errorf("action %s is already registered", name)
+	}
+	r.factories[name] = factory
+	return nil
+}
+
+// MustRegister is like Register but panics on error.
+func (r *Registry) MustRegister(name string, factory Factory) {
+	if err := r.Register(name, factory); err != nil {
+		panic(err)
+	}
+}
+
+// Unregister removes the named action.
+func (r *Registry) Unregister(name string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.factories, name)
+}
+
+// Has reports whether an action is registered, case-insensitively.
+func (r *Registry) Has(name string) bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	_, ok := r.factories[strings.ToLower(name)]
+	return ok
+}
+
+// Names returns the registered action names in order.
+func (r *Registry) Names() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New creates the named action.
+func (r *Registry) New(name string, config Config) (Action, error) {
+	r.mutex.RLock()
+	factory, ok := r.factories[strings.ToLower(name)]
+	r.mutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAction, name)
+	}
+	action, err := factory(config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid settings for action %s: %w", name, err)
+	}
+	return action, nil
+}
@@ -0,0 +1,235 @@
+package actions
+
+import (
+	"beluga/pkg/retry"
+	"beluga/pkg/setting"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// DefaultCommandTimeout bounds commands unless configured otherwise.
+const DefaultCommandTimeout = 30 * time.Second
+
+// DefaultMaxOutput bounds the output kept of each stream of a command.
+const DefaultMaxOutput = 1 << 20
+
+// CommandOutput is the output of the command action.
+type CommandOutput struct {
+	Command  []string `json:"command"`
+	ExitCode int      `json:"exit_code"`
+	Stdout   string   `json:"stdout"`
+	Stderr   string   `json:"stderr"`
+	// Truncated reports whether output beyond MaxOutput was dropped.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// ExitError is returned for a command that exits with a non-zero status.
+type ExitError struct {
+	ExitCode int
+	Stderr   string
+	retry    bool
+}
+
+func (e *ExitError) Error() string {
+	stderr := strings.TrimSpace(e.Stderr)
+	if len(stderr) > 200 {
+		stderr = "..." + stderr[len(stderr)-200:]
+	}
+	if stderr == "" {
+		return fmt.Sprintf("command exited with status %d", e.ExitCode)
+	}
+	return fmt.Sprintf("command exited with status %d: %s", e.ExitCode, stderr)
+}
+
+// Retryable reports whether the command may be run again, which is only if
+// its action allows retries.
+func (e *ExitError) Retryable() bool {
+	return e.retry
+}
+
+// CommandAction runs a local command without a shell.
+type CommandAction struct {
+	// Command is the program and its arguments.
+	Command []string
+	// Dir is the working directory; the executor's if empty.
+	Dir string
+	// Timeout bounds each run; the command is killed when it expires.
+	Timeout time.Duration
+	// Env lists the environment variables the command inherits and that
+	// params may set. The command sees no others.
+	Env []string
+	// AllowArgs lets params append arguments to Command.
+	AllowArgs bool
+	// MaxOutput bounds the bytes kept of stdout and of stderr.
+	MaxOutput int
+	// Retry lets a command that timed out or failed be run again. Commands
+	// have side effects, so they are not retried by default.
+	Retry bool
+}
+
+// NewCommandAction creates the command action from the settings "command",
+// a string split at spaces or a list; "workdir"; "timeout" in seconds, 30
+// by default; "env", the allowlist of environment variables; "allow_args";
+// "max_output" in bytes, 1 MiB by default; and "retry", which lets the
+// executor retry commands that time out or exit with a non-zero status.
+//
+// At execution the params may hold "args", a list of arguments appended if
+// allowed, "env", a map of variables on the allowlist, and "stdin".
+func NewCommandAction(config Config) (Action, error) {
+	settings := config.Settings
+	action := &CommandAction{}
+	switch command := settings["command"].(type) {
+	case string:
+		action.Command = strings.Fields(command)
+	case nil:
+	default:
+		list, err := setting.Strings(settings, "command")
+		if err != nil {
+			return nil, err
+		}
+		action.Command = list
+	}
+	if len(action.Command) == 0 {
+		return nil, errors.New("command is required")
+	}
+
+	var err error
+	if action.Dir, err = setting.String(settings, "workdir", ""); err != nil {
+		return nil, err
+	}
+	if action.Timeout, err = setting.Seconds(settings, "timeout", DefaultCommandTimeout); err != nil {
+		return nil, err
+	}
+	if action.Env, err = setting.Strings(settings, "env"); err != nil {
+		return nil, err
+	}
+	if action.AllowArgs, err = setting.Bool(settings, "allow_args", false); err != nil {
+		return nil, err
+	}
+	if action.MaxOutput, err = setting.Int(settings, "max_output", DefaultMaxOutput); err != nil {
+		return nil, err
+	}
+	if action.Retry, err = setting.Bool(settings, "retry", false); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+// Execute runs the command. A command that fails to start, times out or
+// exits with a non-zero status fails the action; its output is returned
+// nonetheless. Such failures are permanent unless Retry is set.
+func (a *CommandAction) Execute(ctx context.Context, request Request) (interface{}, error) {
+	params := request.Params
+	command := append([]string(nil), a.Command...)
+	args, err := setting.Strings(params, "args")
+	if err != nil {
+		return nil, retry.Permanent(err)
+	}
+	if len(args) > 0 && !a.AllowArgs {
+		return nil, retry.Permanent(errors.New("the command does not accept args"))
+	}
+	command = append(command, args...)
+
+	env, err := a.environment(params)
+	if err != nil {
+		return nil, retry.Permanent(err)
+	}
+	stdin, err := setting.String(params, "stdin", "")
+	if err != nil {
+		return nil, retry.Permanent(err)
+	}
+
+	if a.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.Timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Dir = a.Dir
+	cmd.Env = env
+	cmd.Stdin = strings.NewReader(stdin)
+	// Children that keep the output open must not block the action
+	cmd.WaitDelay = time.Second
+	stdout := &limitedBuffer{limit: a.MaxOutput}
+	stderr := &limitedBuffer{limit: a.MaxOutput}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err = cmd.Run()
+	output := &CommandOutput{
+		Command:   command,
+		ExitCode:  cmd.ProcessState.ExitCode(),
+		Stdout:    stdout.String(),
+		Stderr:    stderr.String(),
+		Truncated: stdout.truncated || stderr.truncated,
+	}
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return output, nil
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		err = fmt.Errorf("command timed out after %s", a.Timeout)
+		if !a.Retry {
+			err = retry.Permanent(err)
+		}
+		return output, err
+	case ctx.Err() != nil:
+		return output, ctx.Err()
+	case errors.As(err, &exitErr):
+		return output, &ExitError{ExitCode: output.ExitCode, Stderr: output.Stderr, retry: a.Retry}
+	}
+	return output, retry.Permanent(fmt.Errorf("failed to run %s: %w", command[0], err))
+}
+
+// environment returns the allowed variables of the executor's environment
+// with the values params set.
+func (a *CommandAction) environment(params map[string]interface{}) ([]string, error) {
+	overrides, err := setting.StringMap(params, "env")
+	if err != nil {
+		return nil, err
+	}
+	// An empty, non-nil environment keeps the command from inheriting ours
+	env := []string{}
+	for _, name := range a.Env {
+		if value, ok := overrides[name]; ok {
+			env = append(env, name+"="+value)
+			delete(overrides, name)
+		} else if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	for name := range overrides {
+		return nil, fmt.Errorf("environment variable %s is not allowed", name)
+	}
+	return env, nil
+}
+
+// limitedBuffer keeps the first limit bytes written to it.
+type limitedBuffer struct {
+	buffer    bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if room := b.limit - b.buffer.Len(); room < n {
+		b.truncated = true
+		if room < 0 {
+			room = 0
+		}
+		p = p[:room]
+	}
+	b.buffer.Write(p)
+	return n, nil
+}
+
+func (b *limitedBuffer) String() string {
+	return b.buffer.String()
+}
@@ -0,0 +1,166 @@
+package actions
+
+import (
+	"beluga/pkg/retry"
+	"beluga/pkg/setting"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// FileOutput is the output of the file action.
+type FileOutput struct {
+	Path     string `json:"path"`
+	Bytes    int    `json:"bytes"`
+	Appended bool   `json:"appended,omitempty"`
+}
+
+// FileAction writes content to a file.
+type FileAction struct {
+	// Path is the file written; the request's target if empty. If Dir is
+	// set, params may name another file with "path".
+	Path string
+	// Dir, if set, resolves relative paths and confines the files written
+	// to it.
+	Dir string
+	// Append adds to the file instead of replacing it.
+	Append bool
+	// Mode is the permission of created files.
+	Mode os.FileMode
+}
+
+// NewFileAction creates the file action from the settings "path", "dir",
+// "append" and "mode", an octal string such as "0640", "0644" by default.
+//
+// At execution the params may name the file with "path", which is only
+// accepted if "dir" is set, as it confines such files. The content
+// written is "content" if it is a string, or else the JSON of "data" or of
+// the params. Files are replaced atomically, and created with their
+// directories.
+func NewFileAction(config Config) (Action, error) {
+	settings := config.Settings
+	action := &FileAction{Mode: 0o644}
+	var err error
+	if action.Path, err = setting.String(settings, "path", ""); err != nil {
+		return nil, err
+	}
+	if action.Dir, err = setting.String(settings, "dir", ""); err != nil {
+		return nil, err
+	}
+	if action.Append, err = setting.Bool(settings, "append", false); err != nil {
+		return nil, err
+	}
+	mode, err := setting.String(settings, "mode", "")
+	if err != nil {
+		return nil, err
+	}
+	if mode != "" {
+		parsed, err := strconv.ParseUint(mode, 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("mode must be an octal permission, got %q", mode)
+		}
+		action.Mode = os.FileMode(parsed)
+	}
+	return action, nil
+}
+
+// Execute writes the file.
+func (a *FileAction) Execute(ctx context.Context, request Request) (interface{}, error) {
+	params := request.Params
+	path, err := setting.String(params, "path", "")
+	if err != nil {
+		return nil, retry.Permanent(err)
+	}
+	if path != "" && a.Dir == "" {
+		return nil, retry.Permanent(errors.New("the path param requires the file action to have a dir"))
+	}
+	if path == "" {
+		path = a.Path
+	}
+	if path == "" {
+		path = request.Target
+	}
+	path, err = a.resolve(path)
+	if err != nil {
+		return nil, retry.Permanent(err)
+	}
+
+	var content []byte
+	if text, ok := params["content"].(string); ok {
+		content = []byte(text)
+	} else {
+		var data interface{} = params
+		if value, ok := params["data"]; ok {
+			data = value
+		}
+		if content, err = json.MarshalIndent(data, "", "  "); err != nil {
+			return nil, retry.Permanent(fmt.Errorf("failed to encode file content: %w", err))
+		}
+		content = append(content, '\n')
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	if a.Append {
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, a.Mode)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := file.Write(content); err != nil {
+			file.Close()
+			return nil, err
+		}
+		if err := file.Close(); err != nil {
+			return nil, err
+		}
+	} else if err := writeAtomically(path, content, a.Mode); err != nil {
+		return nil, err
+	}
+	return &FileOutput{Path: path, Bytes: len(content), Appended: a.Append}, nil
+}
+
+// resolve joins a relative path to Dir and rejects paths outside it.
+func (a *FileAction) resolve(path string) (string, error) {
+	if path == "" {
+		return "", errors.New("no file path given")
+	}
+	if a.Dir == "" {
+		return filepath.Clean(path), nil
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(a.Dir, path)
+	}
+	path = filepath.Clean(path)
+	relative, err := filepath.Rel(filepath.Clean(a.Dir), path)
+	if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %s is outside %s", path, a.Dir)
+	}
+	return path, nil
+}
+
+// writeAtomically replaces the file at path through a temporary file in
+// the same directory.
+func writeAtomically(path string, content []byte, mode os.FileMode) error {
+	temp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(content); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(temp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
@@ -0,0 +1,90 @@
+package actions
+
+import (
+	"beluga/pkg/orchestration"
+	"beluga/pkg/retry"
+	"beluga/pkg/setting"
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultMessageType is the type of the messages the message action sends
+// unless configured otherwise.
+const DefaultMessageType = "action"
+
+var messageSequence uint64
+
+// MessageAction sends a message on the executor's messaging system.
+type MessageAction struct {
+	// Receiver receives the messages; the request's target if empty.
+	Receiver string
+	Type     string
+}
+
+// NewMessageAction creates the message action from the settings
+// "receiver" and "message_type".
+//
+// At execution the params may override them with "receiver" and "type".
+// The payload is "payload" if the params hold it, or else the params. A
+// message that fails to send is retried with the request's MessagingPolicy.
+func NewMessageAction(config Config) (Action, error) {
+	settings := config.Settings
+	action := &MessageAction{}
+	var err error
+	if action.Receiver, err = setting.String(settings, "receiver", ""); err != nil {
+		return nil, err
+	}
+	if action.Type, err = setting.String(settings, "message_type", DefaultMessageType); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+// Execute sends the message and returns it.
+func (a *MessageAction) Execute(ctx context.Context, request Request) (interface{}, error) {
+	if request.Messaging == nil {
+		return nil, retry.Permanent(errors.New("the executor has no messaging system"))
+	}
+	params := request.Params
+	receiver, err := setting.String(params, "receiver", a.Receiver)
+	if err != nil {
+		return nil, retry.Permanent(err)
+	}
+	if receiver == "" {
+		receiver = request.Target
+	}
+	messageType, err := setting.String(params, "type", a.Type)
+	if err != nil {
+		return nil, retry.Permanent(err)
+	}
+
+	payload := params
+	if raw, ok := params["payload"]; ok {
+		if payload, ok = raw.(map[string]interface{}); !ok {
+			return nil, retry.Permanent(fmt.Errorf("payload must be a map, got %T", raw))
+		}
+	}
+	message := orchestration.Message{
+		ID:        fmt.Sprintf("%s-%d", request.Sender, atomic.AddUint64(&messageSequence, 1)),
+		Timestamp: time.Now(),
+		Sender:    request.Sender,
+		Receiver:  receiver,
+		Type:      messageType,
+		Payload:   payload,
+	}
+	if err := orchestration.ValidateMessage(message); err != nil {
+		return nil, retry.Permanent(err)
+	}
+	if request.MessagingPolicy != nil {
+		err = request.Messaging.SendMessageWithPolicy(ctx, message, request.MessagingPolicy)
+	} else {
+		err = request.Messaging.SendMessage(message)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
@@ -0,0 +1,95 @@
+package actions
+
+import (
+	"beluga/pkg/notify"
+	"beluga/pkg/retry"
+	"beluga/pkg/setting"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// NotificationAction renders a notification template for each recipient and
+// sends it over the notifier's channels.
+type NotificationAction struct {
+	Notifier *notify.Notifier
+	// Template and Recipients are used when the params do not give them.
+	Template   string
+	Recipients []notify.Recipient
+}
+
+// NewNotificationAction creates the notification action. Its notifier is
+// configured as for notify.FromMap by the settings "templates",
+// "templates_path", "channels" and "channel"; "template" and "recipients"
+// are defaults for the params.
+//
+// At execution the params give the "recipients", as addresses or maps with
+// "address", "name", "channel" and "data", the "template", the "subject",
+// the "data" rendered, and the "channel". Without a channel, the channel
+// named by the request's target is used if there is one, or else the
+// default channel. Without a template the params' "text" is sent.
+func NewNotificationAction(config Config) (Action, error) {
+	settings := config.Settings
+	notifier, err := notify.FromMap(settings)
+	if err != nil {
+		return nil, err
+	}
+	action := &NotificationAction{Notifier: notifier}
+	if action.Template, err = setting.String(settings, "template", ""); err != nil {
+		return nil, err
+	}
+	if action.Template != "" {
+		if _, ok := notifier.Templates.Get(action.Template); !ok {
+			return nil, fmt.Errorf("%w: %s", notify.ErrUnknownTemplate, action.Template)
+		}
+	}
+	if action.Recipients, err = notify.ParseRecipients(settings["recipients"]); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+// Execute sends the notification and returns its *notify.Report, which
+// also describes deliveries that failed.
+func (a *NotificationAction) Execute(ctx context.Context, request Request) (interface{}, error) {
+	params := request.Params
+	notification := notify.Notification{Recipients: a.Recipients}
+	var err error
+	if raw, ok := params["recipients"]; ok {
+		if notification.Recipients, err = notify.ParseRecipients(raw); err != nil {
+			return nil, retry.Permanent(err)
+		}
+	}
+	if notification.Template, err = setting.String(params, "template", a.Template); err != nil {
+		return nil, retry.Permanent(err)
+	}
+	if notification.Subject, err = setting.String(params, "subject", ""); err != nil {
+		return nil, retry.Permanent(err)
+	}
+	if notification.Text, err = setting.String(params, "text", ""); err != nil {
+		return nil, retry.Permanent(err)
+	}
+	if raw, ok := params["data"]; ok {
+		if notification.Data, ok = raw.(map[string]interface{}); !ok {
+			return nil, retry.Permanent(fmt.Errorf("data must be a map, got %T", raw))
+		}
+	}
+	if notification.Channel, err = setting.String(params, "channel", ""); err != nil {
+		return nil, retry.Permanent(err)
+	}
+	if notification.Channel == "" {
+		if _, ok := a.Notifier.GetChannel(request.Target); ok {
+			notification.Channel = request.Target
+		}
+	}
+
+	report, err := a.Notifier.Notify(ctx, notification)
+	if err != nil {
+		var delivery *notify.DeliveryError
+		if !errors.As(err, &delivery) {
+			return nil, retry.Permanent(err)
+		}
+		return report, err
+	}
+	return report, nil
+}
@@ -0,0 +1,140 @@
+package actions
+
+import (
+	"beluga/pkg/retry"
+	"beluga/pkg/setting"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultWebhookTimeout bounds webhook requests unless configured otherwise.
+const DefaultWebhookTimeout = 30 * time.Second
+
+// maxResponseBody bounds the response body kept of a webhook.
+const maxResponseBody = 64 << 10
+
+// WebhookResponse is the output of the webhook action.
+type WebhookResponse struct {
+	URL        string            `json:"url"`
+	StatusCode int               `json:"status_code"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body"`
+}
+
+// StatusError is returned for a webhook answered with a non-2xx status.
+type StatusError struct {
+	URL        string
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("webhook %s: http status %d: %s", e.URL, e.StatusCode, e.Body)
+}
+
+// Retryable reports whether the webhook may succeed if sent again: rate
+// limits and server errors are transient, other client errors are not.
+func (e *StatusError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// WebhookAction sends the params as JSON to a URL.
+type WebhookAction struct {
+	// URL receives the webhook; the request's target if empty.
+	URL     string
+	Method  string
+	Headers map[string]string
+	Client  *http.Client
+}
+
+// NewWebhookAction creates the webhook action from the settings "url",
+// "method", POST by default, "headers" and "timeout" in seconds, 30 by
+// default.
+//
+// At execution the params are the JSON body, or "body" if they hold it.
+// A string body is sent as is.
+func NewWebhookAction(config Config) (Action, error) {
+	settings := config.Settings
+	action := &WebhookAction{}
+	var err error
+	if action.URL, err = setting.String(settings, "url", ""); err != nil {
+		return nil, err
+	}
+	if action.Method, err = setting.String(settings, "method", http.MethodPost); err != nil {
+		return nil, err
+	}
+	action.Method = strings.ToUpper(action.Method)
+	if action.Headers, err = setting.StringMap(settings, "headers"); err != nil {
+		return nil, err
+	}
+	timeout, err := setting.Seconds(settings, "timeout", DefaultWebhookTimeout)
+	if err != nil {
+		return nil, err
+	}
+	action.Client = &http.Client{Timeout: timeout}
+	return action, nil
+}
+
+// Execute sends the webhook. Non-2xx responses fail the action with a
+// *StatusError; the response is returned nonetheless.
+func (a *WebhookAction) Execute(ctx context.Context, request Request) (interface{}, error) {
+	target := a.URL
+	if target == "" {
+		target = request.Target
+	}
+	parsed, err := url.Parse(target)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, retry.Permanent(fmt.Errorf("webhook url %q is not an http url", target))
+	}
+
+	var payload interface{} = request.Params
+	if body, ok := request.Params["body"]; ok {
+		payload = body
+	}
+	var body []byte
+	contentType := "application/json"
+	if text, ok := payload.(string); ok {
+		body = []byte(text)
+		contentType = "text/plain; charset=utf-8"
+	} else if body, err = json.Marshal(payload); err != nil {
+		return nil, retry.Permanent(fmt.Errorf("failed to encode webhook body: %w", err))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, a.Method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, retry.Permanent(err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	for name, value := range a.Headers {
+		req.Header.Set(name, value)
+	}
+	client := a.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("webhook %s: %w", target, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	if err != nil {
+		return nil, fmt.Errorf("webhook %s: failed to read response: %w", target, err)
+	}
+
+	response := &WebhookResponse{URL: target, StatusCode: resp.StatusCode, Headers: make(map[string]string), Body: string(data)}
+	for name := range resp.Header {
+		response.Headers[name] = resp.Header.Get(name)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return response, &StatusError{URL: target, StatusCode: resp.StatusCode, Body: strings.TrimSpace(response.Body)}
+	}
+	return response, nil
+}
@@ -3,6 +3,7 @@ package adapter
 import (
 	"beluga/pkg/interfaces"
 	"beluga/pkg/orchestration"
+	"beluga/pkg/retry"
 	"context"
 	"fmt"
 	"sync"
@@ -77,20 +78,44 @@ func (at *AgentTask) ToTask() *orchestration.Task {
 		Execute: func() error {
 			return at.Execute()
 		},
+		ExecuteContext: at.ExecuteContext,
 	}
 }
 
 // Execute runs the agent and captures its output.
 func (at *AgentTask) Execute() error {
+	return at.ExecuteContext(context.Background())
+}
+
+// ExecuteContext runs the agent with the task input and captures its output.
+// The run is cancelled when either ctx is done or Cancel is called. Agents
+// that only implement interfaces.Agent are run through interfaces.AsContextAgent.
+func (at *AgentTask) ExecuteContext(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(at.context, cancel)
+	defer stop()
+	if at.context.Err() != nil {
+		cancel()
+	}
+
+	at.mutex.RLock()
+	input := at.InputData
+	at.mutex.RUnlock()
+
 	// Execute the agent
-	if err := at.Agent.Execute(); err != nil {
+	output, err := interfaces.AsContextAgent(at.Agent).Run(ctx, input)
+	if err != nil {
 		return fmt.Errorf("agent execution failed: %w", err)
 	}
 
+	// Agents that produce no output leave any previously set output in place
+	if output != nil {
+		at.SetOutput(output)
+	}
+
 	// Process results if a handler is specified
 	if at.ResultHandler != nil {
-		// Here we assume that the agent has stored its output somehow
-		// and we can retrieve it. This is a simplified example.
 		if err := at.ResultHandler(at.GetOutput()); err != nil {
 			return fmt.Errorf("result handler failed: %w", err)
 		}
@@ -131,6 +156,12 @@ func (aw *AgentWorkflow) Execute() error {
 	return aw.Scheduler.Run()
 }
 
+// ExecuteContext runs the workflow in dependency order, cancelling the
+// running agent and skipping the remaining tasks once ctx is done.
+func (aw *AgentWorkflow) ExecuteContext(ctx context.Context) error {
+	return aw.Scheduler.RunContext(ctx)
+}
+
 // ExecuteSequential runs the workflow in a strictly sequential manner.
 func (aw *AgentWorkflow) ExecuteSequential() error {
 	return aw.Scheduler.ExecuteSequential()
@@ -185,6 +216,15 @@ func (ama *AgentMessagingAdapter) SendMessage(sender string, receiver string, ms
 		Payload:   payload,
 	}
 
+	// Agents configured with a "messaging_retry_policy" retry with it
+	ama.mutex.RLock()
+	agent := ama.AgentRegistry[sender]
+	ama.mutex.RUnlock()
+	if configured, ok := agent.(interface{ GetMessagingPolicy() *retry.Policy }); ok {
+		if policy := configured.GetMessagingPolicy(); policy != nil {
+			return ama.MessagingSystem.SendMessageWithPolicy(context.Background(), msg, policy)
+		}
+	}
 	return ama.MessagingSystem.SendMessageWithRetry(msg, 3, time.Second)
 }
 
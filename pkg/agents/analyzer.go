@@ -0,0 +1,159 @@
+package agents
+
+import (
+	"beluga/pkg/analysis"
+	"beluga/pkg/memory"
+	"beluga/pkg/retry"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// AnalyzerAgent processes and analyzes data to extract insights.
+type AnalyzerAgent struct {
+	*BaseAgent
+	// AnalysisType selects the analyzer from Analyzers.
+	AnalysisType   string
+	InputData      interface{}
+	AnalysisResult analysis.Result
+	// Analyzers holds the analyzers by type; nil means the default registry.
+	Analyzers *analysis.Registry
+	// Analyzer is created from AnalysisType and the settings on Initialize,
+	// or on the first run.
+	Analyzer analysis.Analyzer
+	// History holds the earlier interactions recalled for the last analysis.
+	History []memory.Entry
+	// Related holds the past records retrieved for the last analysis.
+	Related []memory.SearchResult
+}
+
+// NewAnalyzerAgent creates a new AnalyzerAgent.
+func NewAnalyzerAgent(name string, analysisType string) *AnalyzerAgent {
+	agent := &AnalyzerAgent{
+		BaseAgent:    NewBaseAgent(name),
+		AnalysisType: analysisType,
+	}
+	agent.SetBehavior(BehaviorFunc(agent.doExecute))
+	return agent
+}
+
+// SetInputData sets the data analyzed when Run is called without an input.
+func (a *AnalyzerAgent) SetInputData(data interface{}) {
+	a.Mutex.Lock()
+	defer a.Mutex.Unlock()
+	a.InputData = data
+}
+
+// GetAnalysisResult returns the result of the last analysis.
+func (a *AnalyzerAgent) GetAnalysisResult() analysis.Result {
+	a.Mutex.RLock()
+	defer a.Mutex.RUnlock()
+	return a.AnalysisResult
+}
+
+func (a *AnalyzerAgent) doExecute(ctx context.Context, input interface{}) (interface{}, error) {
+	inputData := input
+	if inputData == nil {
+		a.Mutex.RLock()
+		inputData = a.InputData
+		a.Mutex.RUnlock()
+	}
+
+	if inputData == nil {
+		return nil, errors.New("no input data provided for analysis")
+	}
+
+	history, err := a.Recall()
+	if err != nil {
+		a.Logger.Warning("Analyzing without earlier context: %v", err)
+	}
+	related, err := a.Retrieve(ctx, inputData)
+	if err != nil {
+		a.Logger.Warning("Analyzing without related records: %v", err)
+	}
+
+	analyzer, err := a.getAnalyzer()
+	if err != nil {
+		return nil, retry.Permanent(err)
+	}
+	a.Logger.Info("Analyzing data using %s method with %d earlier interactions and %d related records",
+		a.AnalysisType, len(history), len(related))
+	result, err := analysis.AnalyzeWith(ctx, analyzer, inputData, analysis.Recalled{History: history, Related: related})
+	if err != nil {
+		return nil, err
+	}
+
+	// Store analysis result
+	a.Mutex.Lock()
+	a.AnalysisResult = result
+	a.History = history
+	a.Related = related
+	a.Mutex.Unlock()
+
+	return result, nil
+}
+
+// Initialize sets up the agent. In addition to the BaseAgent settings it
+// reads "analysis_type" and creates the analyzer of that type, which reads
+// its own settings such as "language" and "threshold" for sentiment
+// analysis. An analysis type that is not registered fails with
+// analysis.ErrUnknownAnalysis.
+func (a *AnalyzerAgent) Initialize(config map[string]interface{}) error {
+	if err := a.BaseAgent.Initialize(config); err != nil {
+		return err
+	}
+
+	a.Mutex.Lock()
+	defer a.Mutex.Unlock()
+	a.AnalysisType = getStringParam(config, "analysis_type", a.AnalysisType)
+	analyzer, err := a.newAnalyzer(config)
+	if err != nil {
+		return err
+	}
+	a.Analyzer = analyzer
+	return nil
+}
+
+// GetAnalyzers returns the registry the agent creates its analyzer from.
+func (a *AnalyzerAgent) GetAnalyzers() *analysis.Registry {
+	a.Mutex.RLock()
+	defer a.Mutex.RUnlock()
+	return a.analyzers()
+}
+
+// analyzers returns the agent's analyzer registry. The caller must hold a.Mutex.
+func (a *AnalyzerAgent) analyzers() *analysis.Registry {
+	if a.Analyzers == nil {
+		return analysis.DefaultRegistry()
+	}
+	return a.Analyzers
+}
+
+// getAnalyzer returns the agent's analyzer, creating it without settings if
+// the agent was not initialized.
+func (a *AnalyzerAgent) getAnalyzer() (analysis.Analyzer, error) {
+	a.Mutex.Lock()
+	defer a.Mutex.Unlock()
+	if a.Analyzer == nil {
+		analyzer, err := a.newAnalyzer(nil)
+		if err != nil {
+			return nil, err
+		}
+		a.Analyzer = analyzer
+	}
+	return a.Analyzer, nil
+}
+
+// newAnalyzer creates the analyzer for the agent's analysis type. The caller
+// must hold a.Mutex.
+func (a *AnalyzerAgent) newAnalyzer(settings map[string]interface{}) (analysis.Analyzer, error) {
+	config := analysis.Config{Settings: settings, LLM: a.LLM}
+	if a.PromptTemplate != "" {
+		config.Prompt = a.RenderPrompt
+	}
+	analyzer, err := a.analyzers().New(a.AnalysisType, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create analyzer: %w", err)
+	}
+	return analyzer, nil
+}
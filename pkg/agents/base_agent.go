@@ -6,8 +6,14 @@ import (
 	"fmt"
 	"sync"
 	"time"
+	"beluga/pkg/events"
 	"beluga/pkg/interfaces"
+	"beluga/pkg/llm"
+	"beluga/pkg/memory"
 	"beluga/pkg/monitoring"
+	"beluga/pkg/prompts"
+	"beluga/pkg/retry"
+	"beluga/pkg/tools"
 )
 
 // AgentState represents the current state of an agent.
@@ -24,34 +30,91 @@ const (
 
 // BaseAgent provides common functionality for all agents.
 type BaseAgent struct {
-	Name           string
-	Config         map[string]interface{}
-	State          AgentState
-	CreatedAt      time.Time
-	LastActiveTime time.Time
-	Mutex          sync.RWMutex
-	Context        context.Context
-	CancelFunc     context.CancelFunc
-	Logger         *monitoring.Logger
-	ErrorCount     int
-	MaxRetries     int
-	RetryDelay     time.Duration
-	EventHandlers  map[string][]func(interface{}) error
+	Name              string
+	Config            map[string]interface{}
+	State             AgentState
+	CreatedAt         time.Time
+	LastActiveTime    time.Time
+	Mutex             sync.RWMutex
+	Context           context.Context
+	CancelFunc        context.CancelFunc
+	Logger            *monitoring.Logger
+	Metrics           *monitoring.AgentMetrics
+	ErrorCount        int
+	MaxRetries        int
+	RetryDelay        time.Duration
+	RetryPolicy       *retry.Policy
+	// HealthCheckPolicy retries the failed checks of the agent's health
+	// check; nil uses the health check's own retries.
+	HealthCheckPolicy *retry.Policy
+	// MessagingPolicy retries the messages the agent fails to send; nil
+	// sends each message once.
+	MessagingPolicy   *retry.Policy
+	CircuitBreaker    *CircuitBreaker
+	Limiter           *ExecutionLimiter
+	Events            *events.Bus
+	// Memory records the agent's executions; nil, unless the "memory"
+	// setting configures a store, keeps none.
+	Memory            memory.Memory
+	MemoryContext     int
+	VectorMemory      memory.VectorStore
+	Retrieval         memory.Query
+	LLM               llm.LLM
+	Tools             *tools.Registry
+	MaxToolIterations int
+	Prompts           *prompts.Library
+	PromptTemplate    string
+	StateHistory      []StateTransition
+	StateHistorySize  int
+	PausePolicy       PausePolicy
+	behavior          Behavior
+	pauseCh           chan struct{}
+	inFlight          int
+	idleCh            chan struct{}
+	work              map[uint64]WorkItem
+	nextWorkID        uint64
+	workCh            chan struct{}
+	draining          bool
+	workerCtx         context.Context
+	stopWorkers       context.CancelFunc
+}
+
+// Behavior implements the task-specific logic of an agent. BaseAgent runs the
+// behavior inside its retry, state and event handling, so agents customise what
+// they do by supplying a Behavior rather than overriding Execute.
+type Behavior interface {
+	Perform(ctx context.Context, input interface{}) (interface{}, error)
+}
+
+// BehaviorFunc adapts an ordinary function to the Behavior interface.
+type BehaviorFunc func(ctx context.Context, input interface{}) (interface{}, error)
+
+// Perform calls f(ctx, input).
+func (f BehaviorFunc) Perform(ctx context.Context, input interface{}) (interface{}, error) {
+	return f(ctx, input)
 }
 
 // NewBaseAgent creates a new BaseAgent with default values.
 func NewBaseAgent(name string) *BaseAgent {
 	ctx, cancel := context.WithCancel(context.Background())
+	workerCtx, stopWorkers := context.WithCancel(ctx)
 	return &BaseAgent{
-		Name:          name,
-		State:         StateInitializing,
-		CreatedAt:     time.Now(),
-		Context:       ctx,
-		CancelFunc:    cancel,
-		Logger:        monitoring.NewLogger(name),
-		MaxRetries:    3,
-		RetryDelay:    time.Second * 2,
-		EventHandlers: make(map[string][]func(interface{}) error),
+		Name:             name,
+		State:            StateInitializing,
+		CreatedAt:        time.Now(),
+		Context:          ctx,
+		CancelFunc:       cancel,
+		Logger:           monitoring.NewLogger(name),
+		Metrics:          monitoring.NewAgentMetrics(monitoring.DefaultMetricsRegistry(), name),
+		MaxRetries:       3,
+		RetryDelay:       time.Second * 2,
+		Events:           events.NewBus(),
+		MemoryContext:    DefaultMemoryContext,
+		StateHistorySize: DefaultStateHistorySize,
+		PausePolicy:      PauseBlock,
+		work:             make(map[uint64]WorkItem),
+		workerCtx:        workerCtx,
+		stopWorkers:      stopWorkers,
 	}
 }
 
@@ -64,423 +127,504 @@ func (b *BaseAgent) Initialize(config map[string]interface{}) error {
 		return errors.New("config cannot be nil")
 	}
 
+	b.ensureDefaults()
+	if err := b.setState(StateReady, "initialized"); err != nil {
+		return err
+	}
 	b.Config = config
-	b.setState(StateReady)
 	b.Logger.Info("Agent initialized with config: %v", b.Config)
 
 	// Handle specific configuration options
-	if maxRetries, ok := config["max_retries"].(int); ok {
-		b.MaxRetries = maxRetries
+	b.MaxRetries = getIntParam(config, "max_retries", b.MaxRetries)
+	b.RetryDelay = time.Duration(getIntParam(config, "retry_delay", int(b.RetryDelay/time.Second))) * time.Second
+	if rawPolicy, ok := config["retry_policy"]; ok {
+		policy, err := parseRetryPolicy(rawPolicy)
+		if err != nil {
+			return fmt.Errorf("invalid retry_policy: %w", err)
+		}
+		b.RetryPolicy = policy
+	}
+	if rawPolicy, ok := config["health_check_retry_policy"]; ok {
+		policy, err := parseRetryPolicy(rawPolicy)
+		if err != nil {
+			return fmt.Errorf("invalid health_check_retry_policy: %w", err)
+		}
+		b.HealthCheckPolicy = policy
+	}
+	if rawPolicy, ok := config["messaging_retry_policy"]; ok {
+		policy, err := parseRetryPolicy(rawPolicy)
+		if err != nil {
+			return fmt.Errorf("invalid messaging_retry_policy: %w", err)
+		}
+		b.MessagingPolicy = policy
+	}
+	limiter, err := newExecutionLimiterFromSettings(config)
+	if err != nil {
+		return err
+	}
+	if limiter != nil {
+		b.Limiter = limiter
+	}
+	if rawBreaker, ok := config["circuit_breaker"]; ok {
+		breaker, err := newCircuitBreakerFromSettings(rawBreaker)
+		if err != nil {
+			return fmt.Errorf("invalid circuit_breaker: %w", err)
+		}
+		b.setCircuitBreaker(breaker)
+	}
+	if _, ok := config["event_buffer_size"]; ok {
+		b.Events.BufferSize = getIntParam(config, "event_buffer_size", events.DefaultBufferSize)
 	}
-	if retryDelay, ok := config["retry_delay"].(int); ok {
-		b.RetryDelay = time.Duration(retryDelay) * time.Second
+	if rawMemory, ok := config["memory"]; ok {
+		store, err := parseMemory(rawMemory)
+		if err != nil {
+			return fmt.Errorf("invalid memory: %w", err)
+		}
+		b.Memory = store
+	}
+	b.MemoryContext = getIntParam(config, "memory_context", b.MemoryContext)
+	if rawVector, ok := config["vector_memory"]; ok {
+		store, retrieval, err := parseVectorMemory(rawVector)
+		if err != nil {
+			return fmt.Errorf("invalid vector_memory: %w", err)
+		}
+		b.VectorMemory, b.Retrieval = store, retrieval
+	}
+	if rawLLM, ok := config["llm"]; ok {
+		model, err := parseLLM(rawLLM, config)
+		if err != nil {
+			return fmt.Errorf("invalid llm: %w", err)
+		}
+		b.LLM = model
+	}
+	if rawTools, ok := config["tools"]; ok {
+		registry, err := parseTools(rawTools)
+		if err != nil {
+			return fmt.Errorf("invalid tools: %w", err)
+		}
+		b.Tools = registry
+	}
+	b.MaxToolIterations = getIntParam(config, "max_tool_iterations", b.MaxToolIterations)
+	if rawPrompt, ok := config["prompt"]; ok {
+		if err := b.setPromptTemplate(rawPrompt); err != nil {
+			return fmt.Errorf("invalid prompt: %w", err)
+		}
+	}
+	if _, ok := config["state_history_size"]; ok {
+		historySize := getIntParam(config, "state_history_size", 0)
+		if historySize <= 0 {
+			return fmt.Errorf("state_history_size must be a positive number, got %v", config["state_history_size"])
+		}
+		b.StateHistorySize = historySize
+	}
+	if pausePolicy, ok := config["pause_policy"].(string); ok {
+		switch PausePolicy(pausePolicy) {
+		case PauseBlock, PauseReject:
+			b.PausePolicy = PausePolicy(pausePolicy)
+		default:
+			return fmt.Errorf("unknown pause policy: %s", pausePolicy)
+		}
 	}
 
 	return nil
 }
 
+// ensureDefaults fills in the fields NewBaseAgent would have set, so that a
+// zero-value BaseAgent can be used. The caller must hold b.Mutex.
+func (b *BaseAgent) ensureDefaults() {
+	if b.Logger == nil {
+		b.Logger = monitoring.NewLogger(b.Name)
+	}
+	if b.Metrics == nil {
+		b.Metrics = monitoring.NewAgentMetrics(monitoring.DefaultMetricsRegistry(), b.Name)
+	}
+	if b.Context == nil {
+		b.Context, b.CancelFunc = context.WithCancel(context.Background())
+	}
+	if b.workerCtx == nil {
+		b.workerCtx, b.stopWorkers = context.WithCancel(b.Context)
+	}
+	if b.Events == nil {
+		b.Events = events.NewBus()
+	}
+	if b.CreatedAt.IsZero() {
+		b.CreatedAt = time.Now()
+	}
+}
+
 // Execute performs the main task of the agent.
 func (b *BaseAgent) Execute() error {
 	b.Mutex.Lock()
-	b.setState(StateRunning)
-	b.LastActiveTime = time.Now()
+	b.ensureDefaults()
+	ctx := b.Context
 	b.Mutex.Unlock()
 
-	b.Logger.Info("Executing agent task")
-
-	// Implement retry logic
-	var err error
-	for attempt := 0; attempt <= b.MaxRetries; attempt++ {
-		if attempt > 0 {
-			b.Logger.Warning("Retrying execution (attempt %d of %d)", attempt, b.MaxRetries)
-			time.Sleep(b.RetryDelay)
-		}
-
-		err = b.doExecute()
-		if err == nil {
-			break
-		}
-
-		b.Mutex.Lock()
-		b.ErrorCount++
-		b.Mutex.Unlock()
-		b.Logger.Error("Execution failed: %v", err)
-	}
-
-	b.Mutex.Lock()
-	defer b.Mutex.Unlock()
-
-	if err != nil {
-		b.setState(StateError)
-		return fmt.Errorf("agent %s execution failed after %d attempts: %w", b.Name, b.MaxRetries+1, err)
-	}
-
-	b.setState(StateReady)
-	return nil
+	_, err := b.Run(ctx, nil)
+	return err
 }
 
-// doExecute is the internal execution method that should be overridden by subclasses.
-func (b *BaseAgent) doExecute() error {
-	// This method should be overridden by specific agent implementations
-	return nil
+// Run performs the main task of the agent on the given input and returns its output.
+func (b *BaseAgent) Run(ctx context.Context, input interface{}) (interface{}, error) {
+	return b.RunWith(ctx, input, b.GetBehavior())
 }
 
-// Shutdown gracefully stops the agent and cleans up resources.
-func (b *BaseAgent) Shutdown() error {
+// RunWith executes behavior with the agent's state tracking and retry logic. The
+// execution is cancelled when either ctx or the agent's own context is done.
+func (b *BaseAgent) RunWith(ctx context.Context, input interface{}, behavior Behavior) (interface{}, error) {
 	b.Mutex.Lock()
-	defer b.Mutex.Unlock()
+	b.ensureDefaults()
+	agentCtx := b.Context
+	breaker := b.CircuitBreaker
+	limiter := b.Limiter
+	b.Mutex.Unlock()
 
-	if b.State == StateShutdown {
-		return nil // Already shut down
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(agentCtx, cancel)
+	defer stop()
+	if agentCtx.Err() != nil {
+		cancel()
 	}
 
-	b.Logger.Info("Shutting down")
-	b.CancelFunc() // Cancel the context to signal all goroutines to stop
-	b.setState(StateShutdown)
-
-	// Perform resource cleanup here, such as closing files or connections.
-	return nil
-}
-
-// GracefulShutdown ensures that ongoing tasks are completed or safely terminated.
-func (b *BaseAgent) GracefulShutdown(timeout time.Duration) error {
-	b.Logger.Info("Performing graceful shutdown (timeout: %v)", timeout)
+	// An open circuit breaker rejects the execution before it waits for the
+	// pause policy or uses up a concurrency slot or rate token
+	if breaker != nil {
+		if err := breaker.Allow(); err != nil {
+			return nil, fmt.Errorf("agent %s: %w", b.Name, err)
+		}
+	}
 
-	// Signal that we're shutting down but don't stop current tasks yet
 	b.Mutex.Lock()
-	if b.State == StateRunning {
-		b.setState(StatePaused)
+	executionID, release, err := b.beginExecution(ctx, limiter)
+	if err != nil {
+		b.Mutex.Unlock()
+		if breaker != nil {
+			breaker.Release()
+		}
+		return nil, err
 	}
+	defer release()
+	started := b.newAgentEvent()
+	b.publish(ExecutionStarted{AgentEvent: started, ExecutionID: executionID, Input: input})
+	metrics := b.Metrics
+	store, vectors := b.Memory, b.VectorMemory
 	b.Mutex.Unlock()
+	metrics.ExecutionStarted()
 
-	// Create a timeout context
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
+	b.Logger.Info("Executing agent task")
 
-	// Wait for running tasks to complete or timeout
-	shutdownComplete := make(chan struct{})
-	go func() {
-		// Add logic to wait for tasks to complete
-		// This is a simplified example
-		time.Sleep(100 * time.Millisecond) // Simulate waiting for tasks
-		close(shutdownComplete)
-	}()
-
-	// Wait for completion or timeout
-	select {
-	case <-shutdownComplete:
-		return b.Shutdown()
-	case <-ctx.Done():
-		b.Logger.Warning("Graceful shutdown timed out, forcing shutdown")
-		return b.Shutdown()
+	policy := b.executionPolicy()
+	var output interface{}
+	attempts := 0
+	_, err = policy.Do(ctx, func(ctx context.Context) error {
+		// The first attempt was admitted above; retries wait for the rate limit
+		// like new executions do and stop once the breaker opens
+		if attempts > 0 {
+			if limiter != nil {
+				if err := limiter.Wait(ctx); err != nil {
+					return err
+				}
+			}
+			if breaker != nil {
+				if err := breaker.Allow(); err != nil {
+					return retry.Permanent(err)
+				}
+			}
+		}
+		attempts++
+
+		var performErr error
+		output, performErr = behavior.Perform(ctx, input)
+		if performErr != nil {
+			b.Mutex.Lock()
+			b.ErrorCount++
+			b.Mutex.Unlock()
+			b.Logger.Error("Execution failed: %v", performErr)
+		}
+		if breaker != nil {
+			b.recordOutcome(breaker, ctx, performErr)
+		}
+		return performErr
+	})
+	if breaker != nil && attempts == 0 {
+		breaker.Release()
+	}
+	if err == nil {
+		b.remember(store, executionID, started.Timestamp, input, output)
+		b.index(ctx, vectors, executionID, started.Timestamp, input, output)
 	}
-}
-
-// GetState returns the current state of the agent.
-func (b *BaseAgent) GetState() AgentState {
-	b.Mutex.RLock()
-	defer b.Mutex.RUnlock()
-	return b.State
-}
 
-// setState updates the agent's state.
-func (b *BaseAgent) setState(state AgentState) {
-	b.State = state
-	b.LastActiveTime = time.Now()
-	b.Logger.Info("State changed to: %s", state)
+	b.Mutex.Lock()
+	defer b.Mutex.Unlock()
+	b.endExecution(executionID)
+
+	// A paused agent stays paused, and a running agent stays running while
+	// other executions are still in flight.
+	settled := b.State == StateRunning && b.inFlight == 0
+
+	cancelled := false
+	switch ctxErr := ctx.Err(); {
+	case ctxErr != nil && errors.Is(err, ctxErr):
+		cancelled = true
+		if settled {
+			b.setState(StateReady, "execution cancelled")
+		}
+		output, err = nil, fmt.Errorf("agent %s execution cancelled: %w", b.Name, err)
+	case err != nil:
+		if b.State != StateShutdown {
+			b.setState(StateError, fmt.Sprintf("execution failed after %d attempts: %v", attempts, err))
+		}
+		output, err = nil, fmt.Errorf("agent %s execution failed after %d attempts: %w", b.Name, attempts, err)
+	case settled:
+		b.setState(StateReady, "execution succeeded")
+	}
 
-	// Trigger state change event
-	b.triggerEvent("state_change", state)
+	metrics.ExecutionFinished(started.Timestamp, err == nil, cancelled)
+	b.publish(ExecutionFinished{
+		AgentEvent:  b.newAgentEvent(),
+		ExecutionID: executionID,
+		Duration:    time.Since(started.Timestamp),
+		Attempts:    attempts,
+		Output:      output,
+		Err:         err,
+	})
+	return output, err
+}
+
+// recordOutcome reports the result of an attempt to the circuit breaker. An
+// attempt cut short by cancellation says nothing about the operation's health.
+func (b *BaseAgent) recordOutcome(breaker *CircuitBreaker, ctx context.Context, err error) {
+	switch {
+	case err == nil:
+		breaker.RecordSuccess()
+	case ctx.Err() != nil && errors.Is(err, ctx.Err()):
+		breaker.Release()
+	default:
+		breaker.RecordFailure(err)
+	}
 }
 
-// RegisterEventHandler registers a handler function for a specific event type.
-func (b *BaseAgent) RegisterEventHandler(eventType string, handler func(interface{}) error) {
+// SetCircuitBreaker protects the agent's executions with breaker. Passing nil
+// removes the circuit breaker.
+func (b *BaseAgent) SetCircuitBreaker(breaker *CircuitBreaker) {
 	b.Mutex.Lock()
 	defer b.Mutex.Unlock()
+	b.setCircuitBreaker(breaker)
+}
 
-	if _, exists := b.EventHandlers[eventType]; !exists {
-		b.EventHandlers[eventType] = make([]func(interface{}) error, 0)
+// setCircuitBreaker installs breaker and routes its state changes to the
+// agent's event handlers. The caller must hold b.Mutex.
+func (b *BaseAgent) setCircuitBreaker(breaker *CircuitBreaker) {
+	if breaker != nil {
+		breaker.OnStateChange = b.circuitStateChanged
 	}
-
-	b.EventHandlers[eventType] = append(b.EventHandlers[eventType], handler)
+	b.CircuitBreaker = breaker
 }
 
-// triggerEvent calls all registered handlers for the given event type.
-func (b *BaseAgent) triggerEvent(eventType string, payload interface{}) {
+// circuitStateChanged logs a circuit breaker state change and publishes a
+// CircuitStateChanged event, whose type is "circuit_open", "circuit_half_open"
+// or "circuit_closed".
+func (b *BaseAgent) circuitStateChanged(from, to CircuitState) {
 	b.Mutex.RLock()
-	handlers := b.EventHandlers[eventType]
-	b.Mutex.RUnlock()
+	defer b.Mutex.RUnlock()
 
-	for _, handler := range handlers {
-		if err := handler(payload); err != nil {
-			b.Logger.Error("Event handler for %s failed: %v", eventType, err)
-		}
+	breaker := b.CircuitBreaker
+	if breaker == nil {
+		return
 	}
-}
-
-// CheckHealth returns the health status of the agent.
-func (b *BaseAgent) CheckHealth() map[string]interface{} {
+	if to == CircuitOpen {
+		b.Logger.Warning("Circuit breaker opened: %s -> %s", from, to)
+	} else {
+		b.Logger.Info("Circuit breaker state changed: %s -> %s", from, to)
+	}
+	b.publish(CircuitStateChanged{
+		AgentEvent: b.newAgentEvent(),
+		From:       from,
+		To:         to,
+		Stats:      breaker.GetStats(),
+	})
+}
+
+// executionPolicy returns a copy of the agent's retry policy that logs each
+// retry. Agents without a RetryPolicy retry MaxRetries times, waiting
+// RetryDelay between attempts.
+func (b *BaseAgent) executionPolicy() retry.Policy {
 	b.Mutex.RLock()
 	defer b.Mutex.RUnlock()
 
-	return map[string]interface{}{
-		"name":             b.Name,
-		"state":            b.State,
-		"up_time":          time.Since(b.CreatedAt).String(),
-		"last_active_time": b.LastActiveTime,
-		"error_count":      b.ErrorCount,
+	var policy retry.Policy
+	if b.RetryPolicy != nil {
+		policy = *b.RetryPolicy
+	} else {
+		policy = *retry.Constant(b.MaxRetries, b.RetryDelay)
 	}
-}
-
-// Ensure BaseAgent implements the Agent interface.
-var _ interfaces.Agent = (*BaseAgent)(nil)
 
-// DataFetcherAgent is responsible for retrieving data from various sources.
-type DataFetcherAgent struct {
-	*BaseAgent
-	DataSource string
-	DataFormat string
-}
-
-// NewDataFetcherAgent creates a new DataFetcherAgent.
-func NewDataFetcherAgent(name string, dataSource string, dataFormat string) *DataFetcherAgent {
-	return &DataFetcherAgent{
-		BaseAgent:  NewBaseAgent(name),
-		DataSource: dataSource,
-		DataFormat: dataFormat,
+	metrics := b.Metrics
+	onRetry := policy.OnRetry
+	policy.OnRetry = func(attempt int, err error, delay time.Duration) {
+		b.Logger.Warning("Retrying execution in %v (attempt %d of %d)", delay, attempt, policy.MaxRetries)
+		metrics.Retries.Inc()
+		if onRetry != nil {
+			onRetry(attempt, err, delay)
+		}
+	}
+	return policy
+}
+
+// parseRetryPolicy builds a retry policy from a "retry_policy" setting, which
+// may be a *retry.Policy, a retry.Config or a settings map.
+func parseRetryPolicy(raw interface{}) (*retry.Policy, error) {
+	switch v := raw.(type) {
+	case *retry.Policy:
+		return v, nil
+	case retry.Config:
+		return v.Policy()
+	case *retry.Config:
+		return v.Policy()
+	case map[string]interface{}:
+		cfg, err := retry.ConfigFromMap(v)
+		if err != nil {
+			return nil, err
+		}
+		return cfg.Policy()
+	default:
+		return nil, fmt.Errorf("unsupported type %T", raw)
 	}
 }
 
-func (d *DataFetcherAgent) doExecute() error {
-	d.Logger.Info("Fetching data from %s in format %s", d.DataSource, d.DataFormat)
-	// Add data fetching logic here.
-	return nil
+// SetBehavior replaces the logic the agent runs on Execute and Run.
+func (b *BaseAgent) SetBehavior(behavior Behavior) {
+	b.Mutex.Lock()
+	defer b.Mutex.Unlock()
+	b.behavior = behavior
 }
 
-// AnalyzerAgent processes and analyzes data to extract insights.
-type AnalyzerAgent struct {
-	*BaseAgent
-	AnalysisType   string
-	InputData      interface{}
-	AnalysisResult interface{}
-}
+// GetBehavior returns the agent's behavior. An agent without a behavior
+// performs no work and produces no output.
+func (b *BaseAgent) GetBehavior() Behavior {
+	b.Mutex.RLock()
+	defer b.Mutex.RUnlock()
 
-// NewAnalyzerAgent creates a new AnalyzerAgent.
-func NewAnalyzerAgent(name string, analysisType string) *AnalyzerAgent {
-	return &AnalyzerAgent{
-		BaseAgent:    NewBaseAgent(name),
-		AnalysisType: analysisType,
+	if b.behavior == nil {
+		return BehaviorFunc(func(ctx context.Context, input interface{}) (interface{}, error) {
+			return nil, nil
+		})
 	}
+	return b.behavior
 }
 
-func (a *AnalyzerAgent) SetInputData(data interface{}) {
-	a.Mutex.Lock()
-	defer a.Mutex.Unlock()
-	a.InputData = data
-}
-
-func (a *AnalyzerAgent) GetAnalysisResult() interface{} {
-	a.Mutex.RLock()
-	defer a.Mutex.RUnlock()
-	return a.AnalysisResult
-}
-
-func (a *AnalyzerAgent) doExecute() error {
-	a.Mutex.RLock()
-	inputData := a.InputData
-	a.Mutex.RUnlock()
+// Shutdown gracefully stops the agent and cleans up resources.
+func (b *BaseAgent) Shutdown() error {
+	b.Mutex.Lock()
+	defer b.Mutex.Unlock()
 
-	if inputData == nil {
-		return errors.New("no input data provided for analysis")
+	if b.State == StateShutdown {
+		return nil // Already shut down
 	}
 
-	a.Logger.Info("Analyzing data using %s method", a.AnalysisType)
-	// Add data analysis logic here.
+	b.ensureDefaults()
+	b.Logger.Info("Shutting down")
+	b.draining = true
+	b.CancelFunc() // Cancel the context to signal all goroutines to stop
+	b.setState(StateShutdown, "shutdown requested")
 
-	// Store analysis result
-	a.Mutex.Lock()
-	a.AnalysisResult = "Sample analysis result"
-	a.Mutex.Unlock()
+	// Subscribers still receive the events already published, including the
+	// shutdown itself
+	b.Events.Close()
 
+	// Perform resource cleanup here, such as closing files or connections.
 	return nil
 }
 
-// DecisionMakerAgent makes decisions based on analyzed data.
-type DecisionMakerAgent struct {
-	*BaseAgent
-	AnalysisData  interface{}
-	Decision      string
-	DecisionRules map[string]interface{}
-}
-
-// NewDecisionMakerAgent creates a new DecisionMakerAgent.
-func NewDecisionMakerAgent(name string) *DecisionMakerAgent {
-	return &DecisionMakerAgent{
-		BaseAgent:     NewBaseAgent(name),
-		DecisionRules: make(map[string]interface{}),
+// GracefulShutdown stops the agent from accepting new work, cancels its
+// background goroutines and waits up to timeout for in-flight executions and
+// goroutines to finish before shutting down. If work is still in flight when
+// the timeout expires, the agent is shut down anyway and an *ErrWorkAbandoned
+// listing that work is returned.
+func (b *BaseAgent) GracefulShutdown(timeout time.Duration) error {
+	b.Mutex.Lock()
+	b.ensureDefaults()
+	alreadyShutdown := b.State == StateShutdown
+	b.Mutex.Unlock()
+	if alreadyShutdown {
+		return nil
 	}
-}
-
-func (d *DecisionMakerAgent) SetAnalysisData(data interface{}) {
-	d.Mutex.Lock()
-	defer d.Mutex.Unlock()
-	d.AnalysisData = data
-}
-
-func (d *DecisionMakerAgent) GetDecision() string {
-	d.Mutex.RLock()
-	defer d.Mutex.RUnlock()
-	return d.Decision
-}
-
-func (d *DecisionMakerAgent) doExecute() error {
-	d.Mutex.RLock()
-	analysisData := d.AnalysisData
-	d.Mutex.RUnlock()
 
-	if analysisData == nil {
-		return errors.New("no analysis data provided for decision making")
+	b.Logger.Info("Performing graceful shutdown (timeout: %v)", timeout)
+	abandoned := b.drain(timeout)
+	if len(abandoned) == 0 {
+		return b.Shutdown()
 	}
 
-	d.Logger.Info("Making decision based on analysis data")
-	// Add decision-making logic here.
-
-	// Store decision
-	d.Mutex.Lock()
-	d.Decision = "Sample decision"
-	d.Mutex.Unlock()
-
-	return nil
-}
-
-// ExecutorAgent executes actions or commands based on decisions.
-type ExecutorAgent struct {
-	*BaseAgent
-	Action   string
-	Target   string
-	Params   map[string]interface{}
-	Results  interface{}
-}
-
-// NewExecutorAgent creates a new ExecutorAgent.
-func NewExecutorAgent(name string, action string, target string) *ExecutorAgent {
-	return &ExecutorAgent{
-		BaseAgent: NewBaseAgent(name),
-		Action:    action,
-		Target:    target,
-		Params:    make(map[string]interface{}),
+	for _, item := range abandoned {
+		b.Logger.Warning("Graceful shutdown timed out, abandoning %s", item)
 	}
-}
-
-func (e *ExecutorAgent) SetParams(params map[string]interface{}) {
-	e.Mutex.Lock()
-	defer e.Mutex.Unlock()
-	e.Params = params
-}
-
-func (e *ExecutorAgent) GetResults() interface{} {
-	e.Mutex.RLock()
-	defer e.Mutex.RUnlock()
-	return e.Results
-}
-
-func (e *ExecutorAgent) doExecute() error {
-	e.Logger.Info("Executing action %s on target %s", e.Action, e.Target)
-
-	// Add action execution logic here.
-
-	// Store results
-	e.Mutex.Lock()
-	e.Results = "Sample execution results"
-	e.Mutex.Unlock()
-
-	return nil
-}
-
-// MonitorAgent monitors the performance and health of the system.
-type MonitorAgent struct {
-	*BaseAgent
-	MonitorTargets []string
-	MonitorResults map[string]interface{}
-	Interval       time.Duration
-	stopMonitoring chan struct{}
-}
-
-// NewMonitorAgent creates a new MonitorAgent.
-func NewMonitorAgent(name string, interval time.Duration) *MonitorAgent {
-	return &MonitorAgent{
-		BaseAgent:      NewBaseAgent(name),
-		MonitorTargets: make([]string, 0),
-		MonitorResults: make(map[string]interface{}),
-		Interval:       interval,
-		stopMonitoring: make(chan struct{}),
+	if err := b.Shutdown(); err != nil {
+		return err
 	}
+	return &ErrWorkAbandoned{Agent: b.Name, Abandoned: abandoned}
 }
 
-func (m *MonitorAgent) AddMonitorTarget(target string) {
-	m.Mutex.Lock()
-	defer m.Mutex.Unlock()
-	m.MonitorTargets = append(m.MonitorTargets, target)
-}
+// Restart cancels the agent's in-flight executions and background goroutines,
+// waits up to timeout for them to finish and returns the agent to the ready
+// state with a fresh context, a cleared error count and a reset circuit
+// breaker. A paused agent is resumed. Work still in flight when the timeout
+// expires is abandoned. An agent that has been shut down cannot be restarted.
+// Background goroutines are not started again; agents that run them, such as
+// MonitorAgent, override Restart to do so.
+func (b *BaseAgent) Restart(timeout time.Duration) error {
+	b.Mutex.Lock()
+	b.ensureDefaults()
+	if b.State == StateShutdown {
+		b.Mutex.Unlock()
+		return fmt.Errorf("agent %s cannot be restarted after shutdown", b.Name)
+	}
+	b.Logger.Info("Restarting agent (timeout: %v)", timeout)
+	b.CancelFunc()
+	b.Mutex.Unlock()
 
-func (m *MonitorAgent) GetMonitorResults() map[string]interface{} {
-	m.Mutex.RLock()
-	defer m.Mutex.RUnlock()
+	for _, item := range b.drain(timeout) {
+		b.Logger.Warning("Restart timed out, abandoning %s", item)
+	}
 
-	// Create a copy to avoid external modification
-	results := make(map[string]interface{})
-	for k, v := range m.MonitorResults {
-		results[k] = v
+	b.Mutex.Lock()
+	b.Context, b.CancelFunc = context.WithCancel(context.Background())
+	b.workerCtx, b.stopWorkers = context.WithCancel(b.Context)
+	b.draining = false
+	b.ErrorCount = 0
+	if b.pauseCh != nil {
+		close(b.pauseCh)
+		b.pauseCh = nil
 	}
+	err := b.setState(StateReady, "restarted")
+	breaker := b.CircuitBreaker
+	b.Mutex.Unlock()
 
-	return results
+	if breaker != nil {
+		breaker.Reset()
+	}
+	return err
 }
 
-func (m *MonitorAgent) doExecute() error {
-	m.Logger.Info("Starting continuous monitoring")
-
-	// Start continuous monitoring in a goroutine
-	go func() {
-		ticker := time.NewTicker(m.Interval)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ticker.C:
-				m.collectMetrics()
-			case <-m.stopMonitoring:
-				m.Logger.Info("Stopping monitoring")
-				return
-			case <-m.Context.Done():
-				m.Logger.Info("Context cancelled, stopping monitoring")
-				return
-			}
-		}
-	}()
-
-	return nil
+// GetState returns the current state of the agent.
+func (b *BaseAgent) GetState() AgentState {
+	b.Mutex.RLock()
+	defer b.Mutex.RUnlock()
+	return b.State
 }
 
-func (m *MonitorAgent) collectMetrics() {
-	m.Mutex.Lock()
-	defer m.Mutex.Unlock()
-
-	for _, target := range m.MonitorTargets {
-		// Simulate metric collection
-		m.Logger.Debug("Collecting metrics for %s", target)
-		m.MonitorResults[target] = map[string]interface{}{
-			"status":     "healthy",
-			"timestamp":  time.Now(),
-			"cpu_usage":  30.5,
-			"memory_use": 512,
+// RegisterEventHandler registers a handler function for a specific event type.
+// The handler is called asynchronously, in order, with the event's payload:
+// the new AgentState for "state_change", the metrics map for
+// "metrics_updated" and the breaker stats for circuit breaker events. Use
+// Subscribe to receive the typed events and to be able to unsubscribe.
+func (b *BaseAgent) RegisterEventHandler(eventType string, handler func(interface{}) error) *events.Subscription {
+	return b.Subscribe(eventType, func(event events.Event) {
+		if err := handler(legacyPayload(event)); err != nil {
+			b.Logger.Error("Event handler for %s failed: %v", eventType, err)
 		}
-	}
-
-	// Trigger metrics updated event
-	m.triggerEvent("metrics_updated", m.MonitorResults)
+	})
 }
 
-func (m *MonitorAgent) Shutdown() error {
-	close(m.stopMonitoring)
-	return m.BaseAgent.Shutdown()
-}
\ No newline at end of file
+// Ensure BaseAgent implements the ContextAgent interface.
+var _ interfaces.ContextAgent = (*BaseAgent)(nil)
@@ -0,0 +1,250 @@
+package agents
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of a circuit breaker.
+type CircuitState string
+
+const (
+	// CircuitClosed lets every execution through.
+	CircuitClosed CircuitState = "closed"
+	// CircuitOpen rejects executions until the cool-down has passed.
+	CircuitOpen CircuitState = "open"
+	// CircuitHalfOpen lets a limited number of trial executions through to
+	// find out whether the failure has cleared.
+	CircuitHalfOpen CircuitState = "half_open"
+)
+
+// Circuit breaker defaults used when the configuration leaves them unset.
+const (
+	DefaultFailureThreshold = 5
+	DefaultCoolDown         = 30 * time.Second
+)
+
+// ErrCircuitOpen is returned when an execution is rejected by an open circuit breaker.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitBreaker stops calls to a failing operation after FailureThreshold
+// consecutive failures. Once open it rejects calls for CoolDown, then moves to
+// half-open and lets HalfOpenMaxCalls trial calls through: a success closes the
+// breaker again and a failure reopens it.
+type CircuitBreaker struct {
+	FailureThreshold int
+	CoolDown         time.Duration
+	HalfOpenMaxCalls int
+	// OnStateChange, if set, is called after every state change. It is called
+	// without the breaker's lock held.
+	OnStateChange func(from, to CircuitState)
+
+	mutex     sync.Mutex
+	state     CircuitState
+	failures  int
+	trials    int
+	openedAt  time.Time
+	lastError string
+}
+
+// CircuitBreakerStats is a snapshot of a circuit breaker.
+type CircuitBreakerStats struct {
+	State               CircuitState `json:"state"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	FailureThreshold    int          `json:"failure_threshold"`
+	CoolDown            string       `json:"cool_down"`
+	OpenedAt            time.Time    `json:"opened_at,omitempty"`
+	LastError           string       `json:"last_error,omitempty"`
+}
+
+// NewCircuitBreaker creates a closed circuit breaker.
+func NewCircuitBreaker(failureThreshold int, coolDown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		FailureThreshold: failureThreshold,
+		CoolDown:         coolDown,
+		HalfOpenMaxCalls: 1,
+		state:            CircuitClosed,
+	}
+}
+
+// Allow reports whether a call may proceed. It returns ErrCircuitOpen while the
+// breaker is open, or half-open with all trial calls taken. Every successful
+// call to Allow must be followed by RecordSuccess, RecordFailure or Release.
+func (c *CircuitBreaker) Allow() error {
+	c.mutex.Lock()
+	from := c.currentState()
+	to := from
+	if from == CircuitOpen && time.Since(c.openedAt) >= c.coolDown() {
+		to = CircuitHalfOpen
+		c.state = CircuitHalfOpen
+		c.trials = 0
+	}
+
+	var err error
+	switch to {
+	case CircuitOpen:
+		err = fmt.Errorf("%w (retry in %v)", ErrCircuitOpen, (c.coolDown() - time.Since(c.openedAt)).Round(time.Millisecond))
+	case CircuitHalfOpen:
+		if c.trials >= c.halfOpenMaxCalls() {
+			err = fmt.Errorf("%w (half-open trial in progress)", ErrCircuitOpen)
+		} else {
+			c.trials++
+		}
+	}
+	c.mutex.Unlock()
+
+	c.notify(from, to)
+	return err
+}
+
+// RecordSuccess records a successful call, closing a half-open breaker.
+func (c *CircuitBreaker) RecordSuccess() {
+	c.mutex.Lock()
+	from := c.currentState()
+	c.failures = 0
+	c.lastError = ""
+	if from == CircuitHalfOpen {
+		c.state = CircuitClosed
+		c.trials = 0
+	}
+	to := c.currentState()
+	c.mutex.Unlock()
+
+	c.notify(from, to)
+}
+
+// RecordFailure records a failed call. The breaker opens when the failure
+// threshold is reached, or immediately if a half-open trial call failed.
+func (c *CircuitBreaker) RecordFailure(err error) {
+	c.mutex.Lock()
+	from := c.currentState()
+	c.failures++
+	if err != nil {
+		c.lastError = err.Error()
+	}
+	if from == CircuitHalfOpen || (from == CircuitClosed && c.failures >= c.failureThreshold()) {
+		c.state = CircuitOpen
+		c.openedAt = time.Now()
+		c.trials = 0
+	}
+	to := c.currentState()
+	c.mutex.Unlock()
+
+	c.notify(from, to)
+}
+
+// Release gives back a call allowed by Allow that ended without telling
+// whether the operation works, such as a cancelled call.
+func (c *CircuitBreaker) Release() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.state == CircuitHalfOpen && c.trials > 0 {
+		c.trials--
+	}
+}
+
+// Reset closes the breaker and clears its failure count.
+func (c *CircuitBreaker) Reset() {
+	c.mutex.Lock()
+	from := c.currentState()
+	c.state = CircuitClosed
+	c.failures = 0
+	c.trials = 0
+	c.lastError = ""
+	c.mutex.Unlock()
+
+	c.notify(from, CircuitClosed)
+}
+
+// GetState returns the state of the breaker. An open breaker whose cool-down
+// has passed is reported as half-open.
+func (c *CircuitBreaker) GetState() CircuitState {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.effectiveState()
+}
+
+// GetStats returns a snapshot of the breaker.
+func (c *CircuitBreaker) GetStats() CircuitBreakerStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	stats := CircuitBreakerStats{
+		State:               c.effectiveState(),
+		ConsecutiveFailures: c.failures,
+		FailureThreshold:    c.failureThreshold(),
+		CoolDown:            c.coolDown().String(),
+		LastError:           c.lastError,
+	}
+	if c.currentState() != CircuitClosed {
+		stats.OpenedAt = c.openedAt
+	}
+	return stats
+}
+
+// currentState returns the stored state, treating the zero value as closed.
+// The caller must hold c.mutex.
+func (c *CircuitBreaker) currentState() CircuitState {
+	if c.state == "" {
+		return CircuitClosed
+	}
+	return c.state
+}
+
+// effectiveState returns the state a call would see. The caller must hold c.mutex.
+func (c *CircuitBreaker) effectiveState() CircuitState {
+	state := c.currentState()
+	if state == CircuitOpen && time.Since(c.openedAt) >= c.coolDown() {
+		return CircuitHalfOpen
+	}
+	return state
+}
+
+func (c *CircuitBreaker) failureThreshold() int {
+	if c.FailureThreshold <= 0 {
+		return DefaultFailureThreshold
+	}
+	return c.FailureThreshold
+}
+
+func (c *CircuitBreaker) coolDown() time.Duration {
+	if c.CoolDown <= 0 {
+		return DefaultCoolDown
+	}
+	return c.CoolDown
+}
+
+func (c *CircuitBreaker) halfOpenMaxCalls() int {
+	if c.HalfOpenMaxCalls <= 0 {
+		return 1
+	}
+	return c.HalfOpenMaxCalls
+}
+
+func (c *CircuitBreaker) notify(from, to CircuitState) {
+	if from != to && c.OnStateChange != nil {
+		c.OnStateChange(from, to)
+	}
+}
+
+// newCircuitBreakerFromSettings builds a circuit breaker from the
+// "circuit_breaker" settings map, which accepts "failure_threshold",
+// "cool_down" (in seconds or as a duration string) and "half_open_max_calls".
+func newCircuitBreakerFromSettings(raw interface{}) (*CircuitBreaker, error) {
+	settings, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a settings map, got %T", raw)
+	}
+
+	breaker := NewCircuitBreaker(
+		getIntParam(settings, "failure_threshold", DefaultFailureThreshold),
+		getDurationParam(settings, "cool_down", DefaultCoolDown),
+	)
+	breaker.HalfOpenMaxCalls = getIntParam(settings, "half_open_max_calls", 1)
+	if breaker.FailureThreshold <= 0 || breaker.CoolDown <= 0 || breaker.HalfOpenMaxCalls <= 0 {
+		return nil, errors.New("failure_threshold, cool_down and half_open_max_calls must be positive")
+	}
+	return breaker, nil
+}
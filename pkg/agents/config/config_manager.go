@@ -1,6 +1,10 @@
 package config
 
 import (
+	"beluga/pkg/notify"
+	"beluga/pkg/prompts"
+	"beluga/pkg/retry"
+	"beluga/pkg/rules"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -13,14 +17,19 @@ import (
 
 // AgentConfig represents the configuration for a single agent.
 type AgentConfig struct {
-	Type         string                 `json:"type" yaml:"type"`
-	Name         string                 `json:"name" yaml:"name"`
-	Role         string                 `json:"role" yaml:"role"`
-	Settings     map[string]interface{} `json:"settings" yaml:"settings"`
-	MaxRetries   int                    `json:"max_retries" yaml:"max_retries"`
-	RetryDelay   int                    `json:"retry_delay" yaml:"retry_delay"`
-	Dependencies []string               `json:"dependencies" yaml:"dependencies"`
-	Description  string                 `json:"description" yaml:"description"`
+	Type        string                 `json:"type" yaml:"type"`
+	Name        string                 `json:"name" yaml:"name"`
+	Role        string                 `json:"role" yaml:"role"`
+	Settings    map[string]interface{} `json:"settings" yaml:"settings"`
+	MaxRetries  int                    `json:"max_retries" yaml:"max_retries"`
+	RetryDelay  int                    `json:"retry_delay" yaml:"retry_delay"`
+	RetryPolicy *retry.Config          `json:"retry_policy" yaml:"retry_policy"`
+	// HealthCheckRetryPolicy retries the failed checks of the agent's health check.
+	HealthCheckRetryPolicy *retry.Config `json:"health_check_retry_policy" yaml:"health_check_retry_policy"`
+	// MessagingRetryPolicy retries the messages the agent fails to send.
+	MessagingRetryPolicy *retry.Config `json:"messaging_retry_policy" yaml:"messaging_retry_policy"`
+	Dependencies         []string      `json:"dependencies" yaml:"dependencies"`
+	Description          string        `json:"description" yaml:"description"`
 }
 
 // AgentConfigMap maps agent names to their configurations.
@@ -33,6 +42,10 @@ type AgentModuleConfig struct {
 	LoggingConfig     map[string]interface{} `json:"logging" yaml:"logging"`
 	HealthCheckConfig map[string]interface{} `json:"health_check" yaml:"health_check"`
 	WorkflowConfig    map[string]interface{} `json:"workflow" yaml:"workflow"`
+	// PromptDir is a directory of prompt templates, relative to the config file.
+	PromptDir string `json:"prompt_dir,omitempty" yaml:"prompt_dir,omitempty"`
+	// Prompts defines prompt templates inline, by name.
+	Prompts map[string]interface{} `json:"prompts,omitempty" yaml:"prompts,omitempty"`
 }
 
 // ConfigManager handles loading and accessing agent configurations.
@@ -41,6 +54,7 @@ type ConfigManager struct {
 	agentConfigMap  AgentConfigMap
 	envVarOverrides map[string]string
 	configPath      string
+	prompts         *prompts.Library
 }
 
 // NewConfigManager creates a new configuration manager instance.
@@ -49,6 +63,7 @@ func NewConfigManager() *ConfigManager {
 		config:          &AgentModuleConfig{},
 		agentConfigMap:  make(AgentConfigMap),
 		envVarOverrides: make(map[string]string),
+		prompts:         prompts.DefaultLibrary(),
 	}
 }
 
@@ -89,9 +104,149 @@ func (cm *ConfigManager) LoadConfig(filePath string) error {
 	// Load environment variable overrides
 	cm.loadEnvVarOverrides()
 
+	// Load the prompt templates agent settings refer to
+	if err := cm.loadPrompts(); err != nil {
+		return err
+	}
+
+	// Check the decision tables agent settings refer to
+	if err := cm.loadDecisionTables(); err != nil {
+		return err
+	}
+
+	// Check the notification templates agent settings refer to
+	if err := cm.loadNotificationTemplates(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// loadPrompts adds the configured prompt directory and inline prompts to the
+// prompt library, then checks that every agent's "prompt" setting names a
+// template in it.
+func (cm *ConfigManager) loadPrompts() error {
+	if cm.config.PromptDir != "" {
+		dir := cm.config.PromptDir
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(filepath.Dir(cm.configPath), dir)
+		}
+		if err := cm.prompts.LoadDir(dir); err != nil {
+			return err
+		}
+	}
+	if len(cm.config.Prompts) > 0 {
+		if err := cm.prompts.LoadConfig(cm.config.Prompts); err != nil {
+			return fmt.Errorf("failed to load prompts: %w", err)
+		}
+	}
+
+	for _, agent := range cm.config.Agents {
+		name, ok := agent.Settings["prompt"].(string)
+		if !ok {
+			continue
+		}
+		if _, exists := cm.prompts.Get(name); !exists {
+			return fmt.Errorf("agent %s refers to unknown prompt template %s", agent.Name, name)
+		}
+	}
+	return nil
+}
+
+// loadDecisionTables loads the "decision_table" of every agent and checks it
+// for gaps and overlaps. Relative table paths are resolved against the
+// directory of the config file and replaced by the resolved paths, so that
+// agents find the tables wherever they run.
+func (cm *ConfigManager) loadDecisionTables() error {
+	dir := filepath.Dir(cm.configPath)
+	for _, agent := range cm.config.Agents {
+		setting, ok := agent.Settings["decision_table"]
+		if !ok {
+			continue
+		}
+		table, err := rules.TableFromSetting(setting, dir)
+		if err != nil {
+			return fmt.Errorf("agent %s has an invalid decision table: %w", agent.Name, err)
+		}
+		if err := table.Validate(); err != nil {
+			return fmt.Errorf("agent %s: %w", agent.Name, err)
+		}
+		agent.Settings["decision_table"] = resolveTablePath(setting, dir)
+	}
 	return nil
 }
 
+// loadNotificationTemplates parses the "templates_path" directory of every
+// agent, resolving relative paths against the directory of the config file
+// as for decision tables.
+func (cm *ConfigManager) loadNotificationTemplates() error {
+	dir := filepath.Dir(cm.configPath)
+	for _, agent := range cm.config.Agents {
+		path, ok := agent.Settings["templates_path"].(string)
+		if !ok || path == "" {
+			continue
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		if err := notify.NewTemplates().LoadDir(path); err != nil {
+			return fmt.Errorf("agent %s has invalid notification templates: %w", agent.Name, err)
+		}
+		agent.Settings["templates_path"] = path
+	}
+	return nil
+}
+
+// resolveTablePath returns a decision_table setting with a relative path
+// joined to dir.
+func resolveTablePath(setting interface{}, dir string) interface{} {
+	resolve := func(path string) string {
+		if filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(dir, path)
+	}
+	switch v := setting.(type) {
+	case string:
+		return resolve(v)
+	case map[string]interface{}:
+		if path, ok := v["path"].(string); ok {
+			resolved := make(map[string]interface{}, len(v))
+			for key, value := range v {
+				resolved[key] = value
+			}
+			resolved["path"] = resolve(path)
+			return resolved
+		}
+	case map[interface{}]interface{}:
+		if path, ok := v["path"].(string); ok {
+			resolved := make(map[interface{}]interface{}, len(v))
+			for key, value := range v {
+				resolved[key] = value
+			}
+			resolved["path"] = resolve(path)
+			return resolved
+		}
+	}
+	return setting
+}
+
+// LoadPromptDir adds the prompt templates in dir to the prompt library.
+func (cm *ConfigManager) LoadPromptDir(dir string) error {
+	return cm.prompts.LoadDir(dir)
+}
+
+// GetPrompts returns the prompt library templates are loaded into.
+func (cm *ConfigManager) GetPrompts() *prompts.Library {
+	return cm.prompts
+}
+
+// SetPromptLibrary makes the manager load templates into library instead of
+// the default library. It must be called before LoadConfig.
+func (cm *ConfigManager) SetPromptLibrary(library *prompts.Library) {
+	cm.prompts = library
+}
+
 // loadEnvVarOverrides loads configuration overrides from environment variables.
 // The format is BELUGA_AGENT_[AGENT_NAME]_[SETTING]
 func (cm *ConfigManager) loadEnvVarOverrides() {
@@ -106,19 +261,10 @@ func (cm *ConfigManager) loadEnvVarOverrides() {
 			continue
 		}
 
-		// Strip the prefix
+		// Strip the prefix. Agent names may themselves contain underscores,
+		// so the key is split into agent and setting in GetAgentConfig.
 		key = strings.TrimPrefix(key, "BELUGA_AGENT_")
-		parts = strings.Split(key, "_")
-
-		if len(parts) < 2 {
-			continue
-		}
-
-		// Extract agent name and setting name
-		agentName := parts[0]
-		settingName := strings.Join(parts[1:], "_")
-
-		cm.envVarOverrides[agentName+"_"+settingName] = value
+		cm.envVarOverrides[key] = value
 	}
 }
 
@@ -133,10 +279,10 @@ func (cm *ConfigManager) GetAgentConfig(agentName string) (*AgentConfig, error)
 	configCopy := *config
 
 	// Apply environment variable overrides
+	prefix := strings.ToUpper(agentName) + "_"
 	for key, value := range cm.envVarOverrides {
-		parts := strings.SplitN(key, "_", 2)
-		if len(parts) == 2 && parts[0] == agentName {
-			settingName := parts[1]
+		if strings.HasPrefix(strings.ToUpper(key), prefix) && len(key) > len(prefix) {
+			settingName := strings.ToUpper(key[len(prefix):])
 			
 			// Handle different settings
 			switch settingName {
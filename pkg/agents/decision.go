@@ -0,0 +1,168 @@
+package agents
+
+import (
+	"beluga/pkg/memory"
+	"beluga/pkg/retry"
+	"beluga/pkg/rules"
+	"beluga/pkg/setting"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// DecisionMakerAgent makes decisions based on analyzed data.
+type DecisionMakerAgent struct {
+	*BaseAgent
+	AnalysisData interface{}
+	Decision     string
+	// DecisionRules are the "decision_rules" settings Rules is read from.
+	DecisionRules map[string]interface{}
+	// DecisionTable is the "decision_table" setting, a file path or table
+	// definition, Rules is read from instead.
+	DecisionTable interface{}
+	// Rules decides on the analysis data, by a rule set or a decision table;
+	// nil means no rules, so that every decision is the default outcome.
+	Rules rules.Decider
+	// DecisionResult is the last decision with the rules that led to it.
+	DecisionResult *rules.Decision
+	// History holds the earlier interactions recalled for the last decision.
+	History []memory.Entry
+	// Related holds the past records retrieved for the last decision.
+	Related []memory.SearchResult
+}
+
+// NewDecisionMakerAgent creates a new DecisionMakerAgent.
+func NewDecisionMakerAgent(name string) *DecisionMakerAgent {
+	agent := &DecisionMakerAgent{
+		BaseAgent:     NewBaseAgent(name),
+		DecisionRules: make(map[string]interface{}),
+	}
+	agent.SetBehavior(BehaviorFunc(agent.doExecute))
+	return agent
+}
+
+// SetAnalysisData sets the data decided on when Run is called without an input.
+func (d *DecisionMakerAgent) SetAnalysisData(data interface{}) {
+	d.Mutex.Lock()
+	defer d.Mutex.Unlock()
+	d.AnalysisData = data
+}
+
+// GetDecision returns the outcome of the last decision.
+func (d *DecisionMakerAgent) GetDecision() string {
+	d.Mutex.RLock()
+	defer d.Mutex.RUnlock()
+	return d.Decision
+}
+
+func (d *DecisionMakerAgent) doExecute(ctx context.Context, input interface{}) (interface{}, error) {
+	analysisData := input
+	if analysisData == nil {
+		d.Mutex.RLock()
+		analysisData = d.AnalysisData
+		d.Mutex.RUnlock()
+	}
+
+	if analysisData == nil {
+		return nil, errors.New("no analysis data provided for decision making")
+	}
+
+	history, err := d.Recall()
+	if err != nil {
+		d.Logger.Warning("Deciding without earlier context: %v", err)
+	}
+	related, err := d.Retrieve(ctx, analysisData)
+	if err != nil {
+		d.Logger.Warning("Deciding without related records: %v", err)
+	}
+
+	d.Logger.Info("Making decision based on analysis data, %d earlier interactions and %d related records",
+		len(history), len(related))
+	decision, err := d.getRules().Decide(rules.Extend(analysisData, map[string]interface{}{
+		rules.HistoryField: history,
+		rules.RelatedField: related,
+	}))
+	if err != nil {
+		return nil, retry.Permanent(fmt.Errorf("decision failed: %w", err))
+	}
+	d.Logger.Info("Decided %s: %s", decision.Outcome, decision.Explanation())
+
+	// Store decision
+	d.Mutex.Lock()
+	d.Decision = decision.Outcome
+	d.DecisionResult = decision
+	d.History = history
+	d.Related = related
+	d.Mutex.Unlock()
+
+	return decision, nil
+}
+
+// Initialize sets up the agent. In addition to the BaseAgent settings it
+// reads "decision_rules", the rules, default outcome and parameters of
+// rules.FromMap, or "decision_table", the path of a decision table file or
+// the table settings of rules.TableFromMap. Tables are checked for gaps and
+// overlaps when the configuration is loaded, not here. Besides the fields of
+// the analysis data, conditions see what the agent recalled as "history"
+// and "related".
+func (d *DecisionMakerAgent) Initialize(config map[string]interface{}) error {
+	if err := d.BaseAgent.Initialize(config); err != nil {
+		return err
+	}
+
+	d.Mutex.Lock()
+	defer d.Mutex.Unlock()
+	if table, ok := config["decision_table"]; ok {
+		if _, ok := config["decision_rules"]; ok {
+			return fmt.Errorf("decision_rules and decision_table cannot both be set")
+		}
+		decisionTable, err := rules.TableFromSetting(table, "")
+		if err != nil {
+			return fmt.Errorf("invalid decision_table: %w", err)
+		}
+		d.DecisionTable = table
+		d.Rules = decisionTable
+		return nil
+	}
+	raw, ok := config["decision_rules"]
+	if !ok {
+		return nil
+	}
+	settings, ok := setting.StringKeys(raw)
+	if !ok {
+		return fmt.Errorf("decision_rules must be a map, got %T", raw)
+	}
+	ruleSet, err := rules.FromMap(settings)
+	if err != nil {
+		return fmt.Errorf("invalid decision_rules: %w", err)
+	}
+	d.DecisionRules = settings
+	d.Rules = ruleSet
+	return nil
+}
+
+// GetDecisionResult returns the last decision together with the rules that
+// led to it.
+func (d *DecisionMakerAgent) GetDecisionResult() *rules.Decision {
+	d.Mutex.RLock()
+	defer d.Mutex.RUnlock()
+	return d.DecisionResult
+}
+
+// SetRules replaces the rule set or decision table the agent decides by.
+func (d *DecisionMakerAgent) SetRules(decider rules.Decider) {
+	d.Mutex.Lock()
+	defer d.Mutex.Unlock()
+	d.Rules = decider
+}
+
+// getRules returns the agent's rules, or an empty rule set if it has none.
+func (d *DecisionMakerAgent) getRules() rules.Decider {
+	d.Mutex.RLock()
+	decider := d.Rules
+	d.Mutex.RUnlock()
+	if decider == nil {
+		decider, _ = rules.NewRuleSet(nil, "", nil)
+	}
+	return decider
+}
@@ -0,0 +1,130 @@
+package agents
+
+import (
+	"beluga/pkg/events"
+	"time"
+)
+
+// Event types published on an agent's event bus.
+const (
+	EventStateChanged      = "state_change"
+	EventExecutionStarted  = "execution_started"
+	EventExecutionFinished = "execution_finished"
+	EventMetricsUpdated    = "metrics_updated"
+	EventToolInvoked       = "tool_invoked"
+	EventReasoningStep     = "reasoning_step"
+)
+
+// AgentEvent holds the fields common to every agent event.
+type AgentEvent struct {
+	Agent     string    `json:"agent"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// StateChanged is published whenever the agent changes state.
+type StateChanged struct {
+	AgentEvent
+	From   AgentState `json:"from"`
+	To     AgentState `json:"to"`
+	Reason string     `json:"reason"`
+}
+
+// EventType implements events.Event.
+func (StateChanged) EventType() string { return EventStateChanged }
+
+// ExecutionStarted is published when an execution has been admitted and starts running.
+type ExecutionStarted struct {
+	AgentEvent
+	ExecutionID uint64      `json:"execution_id"`
+	Input       interface{} `json:"input,omitempty"`
+}
+
+// EventType implements events.Event.
+func (ExecutionStarted) EventType() string { return EventExecutionStarted }
+
+// ExecutionFinished is published when an execution ends, successfully or not.
+type ExecutionFinished struct {
+	AgentEvent
+	ExecutionID uint64        `json:"execution_id"`
+	Duration    time.Duration `json:"duration"`
+	Attempts    int           `json:"attempts"`
+	Output      interface{}   `json:"output,omitempty"`
+	Err         error         `json:"-"`
+}
+
+// EventType implements events.Event.
+func (ExecutionFinished) EventType() string { return EventExecutionFinished }
+
+// MetricsUpdated is published when an agent has collected new metrics.
+type MetricsUpdated struct {
+	AgentEvent
+	Metrics map[string]interface{} `json:"metrics"`
+}
+
+// EventType implements events.Event.
+func (MetricsUpdated) EventType() string { return EventMetricsUpdated }
+
+// ToolInvoked is published after the agent has invoked a tool.
+type ToolInvoked struct {
+	AgentEvent
+	Tool   string                 `json:"tool"`
+	Args   map[string]interface{} `json:"args,omitempty"`
+	Result interface{}            `json:"result,omitempty"`
+	Err    error                  `json:"-"`
+}
+
+// EventType implements events.Event.
+func (ToolInvoked) EventType() string { return EventToolInvoked }
+
+// CircuitStateChanged is published when the agent's circuit breaker changes
+// state. Its type is "circuit_" followed by the new state, such as "circuit_open".
+type CircuitStateChanged struct {
+	AgentEvent
+	From  CircuitState        `json:"from"`
+	To    CircuitState        `json:"to"`
+	Stats CircuitBreakerStats `json:"stats"`
+}
+
+// EventType implements events.Event.
+func (e CircuitStateChanged) EventType() string { return "circuit_" + string(e.To) }
+
+// Subscribe calls handler asynchronously with every event of the agent whose
+// type matches pattern ("*" for all events). Cancel the returned subscription
+// to stop receiving events.
+func (b *BaseAgent) Subscribe(pattern string, handler events.Handler) *events.Subscription {
+	b.Mutex.Lock()
+	b.ensureDefaults()
+	bus := b.Events
+	b.Mutex.Unlock()
+	return bus.Subscribe(pattern, handler)
+}
+
+// newAgentEvent returns the common fields for an event of this agent.
+func (b *BaseAgent) newAgentEvent() AgentEvent {
+	return AgentEvent{Agent: b.Name, Timestamp: time.Now()}
+}
+
+// publish sends event to the agent's bus and the global bus. Delivery is
+// asynchronous, so it is safe to call with b.Mutex held.
+func (b *BaseAgent) publish(event events.Event) {
+	if b.Events != nil {
+		b.Events.Publish(event)
+	}
+	events.Global().Publish(event)
+}
+
+// legacyPayload returns the payload RegisterEventHandler handlers receive for
+// an event: the new state for state changes, the metrics for metric updates,
+// the breaker stats for circuit breaker changes and the event itself otherwise.
+func legacyPayload(event events.Event) interface{} {
+	switch e := event.(type) {
+	case StateChanged:
+		return e.To
+	case MetricsUpdated:
+		return e.Metrics
+	case CircuitStateChanged:
+		return e.Stats
+	default:
+		return event
+	}
+}
@@ -0,0 +1,199 @@
+package agents
+
+import (
+	"beluga/pkg/actions"
+	"beluga/pkg/orchestration"
+	"beluga/pkg/retry"
+	"beluga/pkg/tools"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+)
+
+// ExecutorAgent executes actions or commands based on decisions. Its Action
+// names a registered action, such as command or webhook, or else the tool
+// it invokes, and its Target is the action's default destination, passed
+// to tools as the "target" argument.
+type ExecutorAgent struct {
+	*BaseAgent
+	Action string
+	Target string
+	Params map[string]interface{}
+	// Results is the *actions.ExecutionResult of the last execution.
+	Results interface{}
+	// Actions is the registry Action is created from; nil means the default
+	// registry.
+	Actions *actions.Registry
+	// Executor performs Action; it is created from the agent's settings if
+	// Action is registered.
+	Executor actions.Action
+	// Messaging carries the messages of the message action.
+	Messaging *orchestration.MessagingSystem
+}
+
+// NewExecutorAgent creates a new ExecutorAgent.
+func NewExecutorAgent(name string, action string, target string) *ExecutorAgent {
+	agent := &ExecutorAgent{
+		BaseAgent: NewBaseAgent(name),
+		Action:    action,
+		Target:    target,
+		Params:    make(map[string]interface{}),
+	}
+	agent.SetBehavior(BehaviorFunc(agent.doExecute))
+	return agent
+}
+
+// SetParams sets the action parameters used when Run is called without an input.
+func (e *ExecutorAgent) SetParams(params map[string]interface{}) {
+	e.Mutex.Lock()
+	defer e.Mutex.Unlock()
+	e.Params = params
+}
+
+func (e *ExecutorAgent) GetResults() interface{} {
+	e.Mutex.RLock()
+	defer e.Mutex.RUnlock()
+	return e.Results
+}
+
+func (e *ExecutorAgent) doExecute(ctx context.Context, input interface{}) (interface{}, error) {
+	// Read the settings of the execution at once, and copy the params, so
+	// that concurrent setters cannot change them while the action runs
+	params, ok := input.(map[string]interface{})
+	e.Mutex.RLock()
+	if !ok {
+		params = maps.Clone(e.Params)
+	}
+	request := actions.Request{
+		Action:          e.Action,
+		Target:          e.Target,
+		Params:          params,
+		Sender:          e.Name,
+		Messaging:       e.Messaging,
+		MessagingPolicy: e.MessagingPolicy,
+	}
+	e.Mutex.RUnlock()
+	if request.Action == "" {
+		return nil, errors.New("no action configured")
+	}
+
+	e.Logger.Info("Executing action %s on target %s with %d params", request.Action, request.Target, len(request.Params))
+	action, err := e.getAction()
+	if err != nil {
+		return nil, retry.Permanent(err)
+	}
+	result, err := actions.Execute(ctx, action, request)
+
+	// Store results
+	e.Mutex.Lock()
+	e.Results = result
+	e.Mutex.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+	e.Logger.Info("Action %s succeeded in %s", request.Action, result.Duration)
+	return result, nil
+}
+
+// invokeTool performs an action that is not registered by invoking the tool
+// of the same name.
+func (e *ExecutorAgent) invokeTool(ctx context.Context, request actions.Request) (interface{}, error) {
+	tool, ok := e.GetTools().Get(request.Action)
+	if !ok {
+		return nil, fmt.Errorf("%w %s: %w", actions.ErrUnknownAction, request.Action, tools.ErrUnknownTool)
+	}
+	args := make(map[string]interface{}, len(request.Params)+1)
+	for key, value := range request.Params {
+		args[key] = value
+	}
+	if _, set := args["target"]; !set && request.Target != "" && acceptsArgument(tool, "target") {
+		args["target"] = request.Target
+	}
+	return e.InvokeTool(ctx, request.Action, args)
+}
+
+// acceptsArgument reports whether the tool's parameters allow the named argument.
+func acceptsArgument(tool tools.Tool, name string) bool {
+	schema := tool.Parameters()
+	if schema == nil {
+		return true
+	}
+	if _, declared := schema.Properties[name]; declared {
+		return true
+	}
+	return schema.AdditionalProperties == nil || *schema.AdditionalProperties
+}
+
+// Initialize sets up the agent. In addition to the BaseAgent settings it
+// creates the agent's action if Action is registered, which reads its own
+// settings such as "command" and "timeout" for the command action. Other
+// actions are dispatched to the tool of the same name.
+func (e *ExecutorAgent) Initialize(config map[string]interface{}) error {
+	if err := e.BaseAgent.Initialize(config); err != nil {
+		return err
+	}
+
+	e.Mutex.Lock()
+	defer e.Mutex.Unlock()
+	if !e.actions().Has(e.Action) {
+		return nil
+	}
+	action, err := e.actions().New(e.Action, actions.Config{Settings: config})
+	if err != nil {
+		return fmt.Errorf("failed to create action: %w", err)
+	}
+	e.Executor = action
+	return nil
+}
+
+// GetExecutionResult returns the result of the last execution.
+func (e *ExecutorAgent) GetExecutionResult() *actions.ExecutionResult {
+	e.Mutex.RLock()
+	defer e.Mutex.RUnlock()
+	result, _ := e.Results.(*actions.ExecutionResult)
+	return result
+}
+
+// GetActions returns the registry the agent creates its action from.
+func (e *ExecutorAgent) GetActions() *actions.Registry {
+	e.Mutex.RLock()
+	defer e.Mutex.RUnlock()
+	return e.actions()
+}
+
+// SetMessaging sets the messaging system the message action sends on.
+func (e *ExecutorAgent) SetMessaging(messaging *orchestration.MessagingSystem) {
+	e.Mutex.Lock()
+	defer e.Mutex.Unlock()
+	e.Messaging = messaging
+}
+
+// actions returns the agent's action registry. The caller must hold e.Mutex.
+func (e *ExecutorAgent) actions() *actions.Registry {
+	if e.Actions == nil {
+		return actions.DefaultRegistry()
+	}
+	return e.Actions
+}
+
+// getAction returns the action the agent performs: its Executor, created
+// without settings if the agent was not initialized, or the tool named by
+// Action.
+func (e *ExecutorAgent) getAction() (actions.Action, error) {
+	e.Mutex.Lock()
+	defer e.Mutex.Unlock()
+	if e.Executor != nil {
+		return e.Executor, nil
+	}
+	if !e.actions().Has(e.Action) {
+		return actions.ActionFunc(e.invokeTool), nil
+	}
+	action, err := e.actions().New(e.Action, actions.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create action: %w", err)
+	}
+	e.Executor = action
+	return action, nil
+}
@@ -2,35 +2,45 @@ package agents
 
 import (
 	"beluga/pkg/interfaces"
+	"beluga/pkg/retry"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
-	"os"
 	"path/filepath"
+	"sort"
 	"time"
 )
 
 // AgentConfig represents the configuration structure for an agent.
 type AgentConfig struct {
-	Type           string                 `json:"type"`
-	Name           string                 `json:"name"`
-	Role           string                 `json:"role"`
-	Settings       map[string]interface{} `json:"settings"`
-	MaxRetries     int                    `json:"max_retries"`
-	RetryDelay     int                    `json:"retry_delay"`
-	Dependencies   []string               `json:"dependencies"`
-	Description    string                 `json:"description"`
+	Type        string                 `json:"type"`
+	Name        string                 `json:"name"`
+	Role        string                 `json:"role"`
+	Settings    map[string]interface{} `json:"settings"`
+	MaxRetries  int                    `json:"max_retries"`
+	RetryDelay  int                    `json:"retry_delay"`
+	RetryPolicy *retry.Config          `json:"retry_policy"`
+	// HealthCheckRetryPolicy retries the failed checks of the agent's health
+	// check, see NewHealthCheck.
+	HealthCheckRetryPolicy *retry.Config `json:"health_check_retry_policy"`
+	// MessagingRetryPolicy retries the messages the agent fails to send.
+	MessagingRetryPolicy *retry.Config `json:"messaging_retry_policy"`
+	Dependencies         []string      `json:"dependencies"`
+	Description          string        `json:"description"`
 }
 
 // AgentRegistry maintains a registry of all created agents for reference and management.
 type AgentRegistry struct {
-	Agents map[string]interfaces.Agent
+	Agents       map[string]interfaces.Agent
+	Dependencies map[string][]string
 }
 
 // NewAgentRegistry creates a new AgentRegistry.
 func NewAgentRegistry() *AgentRegistry {
 	return &AgentRegistry{
-		Agents: make(map[string]interfaces.Agent),
+		Agents:       make(map[string]interfaces.Agent),
+		Dependencies: make(map[string][]string),
 	}
 }
 
@@ -39,6 +49,104 @@ func (r *AgentRegistry) RegisterAgent(name string, agent interfaces.Agent) {
 	r.Agents[name] = agent
 }
 
+// RegisterAgentWithDependencies adds an agent to the registry together with the
+// names of the agents it depends on.
+func (r *AgentRegistry) RegisterAgentWithDependencies(name string, agent interfaces.Agent, dependencies []string) {
+	r.Agents[name] = agent
+	if r.Dependencies == nil {
+		r.Dependencies = make(map[string][]string)
+	}
+	r.Dependencies[name] = append([]string(nil), dependencies...)
+}
+
+// ShutdownOrder returns the registered agent names ordered so that every agent
+// comes before the agents it depends on, i.e. the reverse of start-up order.
+// Dependencies on unregistered agents are ignored. An error is returned if the
+// dependencies contain a cycle.
+func (r *AgentRegistry) ShutdownOrder() ([]string, error) {
+	names := r.ListAgents()
+	sort.Strings(names)
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	marks := make(map[string]int, len(names))
+	startOrder := make([]string, 0, len(names))
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch marks[name] {
+		case visiting:
+			return fmt.Errorf("dependency cycle detected at agent %s", name)
+		case visited:
+			return nil
+		}
+		marks[name] = visiting
+		for _, dep := range r.Dependencies[name] {
+			if _, exists := r.Agents[dep]; !exists {
+				continue
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		marks[name] = visited
+		startOrder = append(startOrder, name)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+
+	order := make([]string, len(startOrder))
+	for i, name := range startOrder {
+		order[len(startOrder)-1-i] = name
+	}
+	return order, nil
+}
+
+// ShutdownAll shuts down every registered agent in reverse dependency order, so
+// that no agent is stopped while agents depending on it are still running.
+// Agents that support GracefulShutdown are given the time remaining until the
+// overall timeout to drain their work. All agents are shut down even if some
+// fail; the collected errors are returned together.
+func (r *AgentRegistry) ShutdownAll(timeout time.Duration) error {
+	order, err := r.ShutdownOrder()
+	if err != nil {
+		// Fall back to name order so that every agent is still shut down
+		order = r.ListAgents()
+		sort.Strings(order)
+	}
+
+	errs := []error{err}
+	deadline := time.Now().Add(timeout)
+	for _, name := range order {
+		agent := r.Agents[name]
+
+		var shutdownErr error
+		if graceful, ok := agent.(interface{ GracefulShutdown(time.Duration) error }); ok {
+			remaining := time.Until(deadline)
+			if remaining < 0 {
+				remaining = 0
+			}
+			shutdownErr = graceful.GracefulShutdown(remaining)
+		} else {
+			shutdownErr = agent.Shutdown()
+		}
+
+		if shutdownErr != nil {
+			errs = append(errs, fmt.Errorf("failed to shut down agent %s: %w", name, shutdownErr))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
 // GetAgent retrieves an agent from the registry by name.
 func (r *AgentRegistry) GetAgent(name string) (interfaces.Agent, bool) {
 	agent, exists := r.Agents[name]
@@ -68,13 +176,34 @@ func NewAgentFactory() *AgentFactory {
 
 // CreateAgentFromConfig creates an agent based on the provided configuration.
 func (f *AgentFactory) CreateAgentFromConfig(config *AgentConfig) (interfaces.Agent, error) {
-	agent, err := f.CreateAgent(config.Type, config.Name, config.Settings)
+	// Retry options given outside Settings apply unless Settings override them
+	settings := make(map[string]interface{}, len(config.Settings)+5)
+	if config.MaxRetries > 0 {
+		settings["max_retries"] = config.MaxRetries
+	}
+	if config.RetryDelay > 0 {
+		settings["retry_delay"] = config.RetryDelay
+	}
+	if config.RetryPolicy != nil {
+		settings["retry_policy"] = *config.RetryPolicy
+	}
+	if config.HealthCheckRetryPolicy != nil {
+		settings["health_check_retry_policy"] = *config.HealthCheckRetryPolicy
+	}
+	if config.MessagingRetryPolicy != nil {
+		settings["messaging_retry_policy"] = *config.MessagingRetryPolicy
+	}
+	for key, value := range config.Settings {
+		settings[key] = value
+	}
+
+	agent, err := f.CreateAgent(config.Type, config.Name, settings)
 	if err != nil {
 		return nil, err
 	}
 	
-	// Register the agent
-	f.Registry.RegisterAgent(config.Name, agent)
+	// Register the agent with its dependencies
+	f.Registry.RegisterAgentWithDependencies(config.Name, agent, config.Dependencies)
 	return agent, nil
 }
 
@@ -123,6 +252,7 @@ func (f *AgentFactory) CreateAgent(agentType, name string, config map[string]int
 		interval := time.Duration(getIntParam(config, "interval_seconds", 60)) * time.Second
 		
 		monitor := NewMonitorAgent(name, interval)
+		monitor.Agents = f.Registry
 		if err := monitor.Initialize(config); err != nil {
 			return nil, fmt.Errorf("failed to initialize MonitorAgent: %w", err)
 		}
@@ -138,11 +268,23 @@ func (f *AgentFactory) CreateAgent(agentType, name string, config map[string]int
 		
 		agent = monitor
 		
+	case "ReasoningAgent":
+		goal := getStringParam(config, "goal", "")
+		
+		reasoner := NewReasoningAgent(name, goal)
+		if err := reasoner.Initialize(config); err != nil {
+			return nil, fmt.Errorf("failed to initialize ReasoningAgent: %w", err)
+		}
+		agent = reasoner
+		
 	default:
 		return nil, fmt.Errorf("unknown agent type: %s", agentType)
 	}
 	
 	// Register the created agent
+	if f.Registry == nil {
+		f.Registry = NewAgentRegistry()
+	}
 	f.Registry.RegisterAgent(name, agent)
 	
 	return agent, nil
@@ -218,4 +360,19 @@ func getIntParam(config map[string]interface{}, key string, defaultValue int) in
 		return val
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+// getDurationParam reads a duration given either as a number of seconds or as
+// a string such as "500ms".
+func getDurationParam(config map[string]interface{}, key string, defaultValue time.Duration) time.Duration {
+	switch val := config[key].(type) {
+	case float64:
+		return time.Duration(val * float64(time.Second))
+	case int:
+		return time.Duration(val) * time.Second
+	case string:
+		if d, err := time.ParseDuration(val); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,273 @@
+package agents
+
+import (
+	"beluga/pkg/codec"
+	"beluga/pkg/fetch"
+	"beluga/pkg/retry"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// DataFetcherAgent is responsible for retrieving data from various sources.
+type DataFetcherAgent struct {
+	*BaseAgent
+	DataSource string
+	DataFormat string
+	// FormatOptions configures the codec of DataFormat, such as the CSV delimiter.
+	FormatOptions map[string]interface{}
+	// Codecs holds the codecs DataFormat names; the default registry if nil.
+	Codecs *codec.Registry
+	// Source configures the driver reading DataSource, see Initialize.
+	Source fetch.Config
+	// Data holds the records of the last fetch.
+	Data *codec.Dataset
+}
+
+// NewDataFetcherAgent creates a new DataFetcherAgent.
+func NewDataFetcherAgent(name string, dataSource string, dataFormat string) *DataFetcherAgent {
+	agent := &DataFetcherAgent{
+		BaseAgent:  NewBaseAgent(name),
+		DataSource: dataSource,
+		DataFormat: dataFormat,
+	}
+	agent.SetBehavior(BehaviorFunc(agent.doExecute))
+	return agent
+}
+
+func (d *DataFetcherAgent) doExecute(ctx context.Context, input interface{}) (interface{}, error) {
+	dataset := &codec.Dataset{Records: []codec.Record{}, Schema: codec.NewSchema()}
+	fetched, err := d.fetch(ctx, input, func(record codec.Record) error {
+		dataset.Add(record)
+		return nil
+	})
+	if err != nil || !fetched {
+		return nil, err
+	}
+
+	d.Mutex.Lock()
+	d.Data = dataset
+	d.Mutex.Unlock()
+
+	return dataset, nil
+}
+
+// Initialize sets up the agent. In addition to the BaseAgent settings it
+// reads "data_source", "data_format", "format_options" and the source options
+// of fetch.ConfigFromMap: "url", "base_url", "path", "paths", "method",
+// "headers", "query", "body", "timeout" and "pagination". Without "url",
+// "base_url" or a path setting, DataSource is read if it is a location, see
+// fetch.IsLocation; a DataSource that merely names a source without a
+// configured location fetches nothing.
+func (d *DataFetcherAgent) Initialize(config map[string]interface{}) error {
+	if err := d.BaseAgent.Initialize(config); err != nil {
+		return err
+	}
+
+	d.Mutex.Lock()
+	defer d.Mutex.Unlock()
+	d.DataSource = getStringParam(config, "data_source", d.DataSource)
+	d.DataFormat = getStringParam(config, "data_format", d.DataFormat)
+	if raw, ok := config["format_options"]; ok {
+		options, isMap := raw.(map[string]interface{})
+		if !isMap {
+			return fmt.Errorf("format_options must be a map, got %T", raw)
+		}
+		d.FormatOptions = options
+	}
+
+	source, err := fetch.ConfigFromMap(config)
+	if err != nil {
+		return err
+	}
+	source.Stdin = d.Source.Stdin
+	d.Source = source
+	if _, err := d.codecFor(d.DataFormat, d.FormatOptions, source); err != nil {
+		return err
+	}
+	if located, ok := d.locate(source); ok {
+		if _, err := located.New(); err != nil {
+			return fmt.Errorf("invalid data source %s: %w", d.DataSource, err)
+		}
+	}
+	return nil
+}
+
+// GetData returns the records of the last fetch.
+func (d *DataFetcherAgent) GetData() *codec.Dataset {
+	d.Mutex.RLock()
+	defer d.Mutex.RUnlock()
+	return d.Data
+}
+
+// Stream fetches like Run but passes each record to handle as it is decoded
+// instead of collecting them, so that inputs larger than memory can be
+// processed. It returns the schema of the records. The fetch is retried
+// according to the agent's policy only until the first record was handled.
+func (d *DataFetcherAgent) Stream(ctx context.Context, input interface{}, handle func(codec.Record) error) (*codec.Schema, error) {
+	output, err := d.RunWith(ctx, input, BehaviorFunc(func(ctx context.Context, input interface{}) (interface{}, error) {
+		schema := codec.NewSchema()
+		emitted := false
+		_, err := d.fetch(ctx, input, func(record codec.Record) error {
+			schema.Observe(record)
+			emitted = true
+			if err := handle(record); err != nil {
+				return retry.Permanent(err)
+			}
+			return nil
+		})
+		if err != nil && emitted {
+			return nil, retry.Permanent(err)
+		}
+		return schema, err
+	}))
+	if err != nil {
+		return nil, err
+	}
+	schema, _ := output.(*codec.Schema)
+	return schema, nil
+}
+
+// fetch reads the source for input and passes its records to handle. It
+// reports false if the source has no location.
+func (d *DataFetcherAgent) fetch(ctx context.Context, input interface{}, handle func(codec.Record) error) (bool, error) {
+	source, format, options, located, err := d.sourceFor(input)
+	if err != nil {
+		return false, err
+	}
+	if !located {
+		d.Logger.Warning("Data source %s has no location configured, nothing fetched", d.DataSource)
+		return false, nil
+	}
+	decoding, err := d.codecFor(format, options, source)
+	if err != nil {
+		return false, err
+	}
+	driver, err := source.New()
+	if err != nil {
+		return false, err
+	}
+
+	d.Logger.Info("Fetching data from %s in format %s", d.DataSource, format)
+	err = driver.Fetch(ctx, func(document fetch.Document) error {
+		decoder, err := decoding.NewDecoder(document.Body)
+		if err != nil {
+			return fmt.Errorf("failed to decode %s: %w", document.Source, err)
+		}
+		return codec.Each(decoder, func(record codec.Record) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return handle(record)
+		})
+	})
+	return true, err
+}
+
+// codecFor returns the codec decoding format with options. JSON pages of a
+// paginated source are decoded from the pagination's items path unless the
+// options set "records_path".
+func (d *DataFetcherAgent) codecFor(format string, options map[string]interface{}, source fetch.Config) (codec.Codec, error) {
+	registry := d.Codecs
+	if registry == nil {
+		registry = codec.DefaultRegistry()
+	}
+	if source.Pagination != nil && source.Pagination.ItemsPath != "" && strings.EqualFold(format, "json") {
+		if _, ok := options["records_path"]; !ok {
+			merged := map[string]interface{}{"records_path": source.Pagination.ItemsPath}
+			for key, value := range options {
+				merged[key] = value
+			}
+			options = merged
+		}
+	}
+	return registry.Lookup(format, options)
+}
+
+// locate returns source with DataSource as its location if it has none, and
+// whether it has a location at all.
+func (d *DataFetcherAgent) locate(source fetch.Config) (fetch.Config, bool) {
+	if source.Source != "" || len(source.Paths) > 0 {
+		return source, true
+	}
+	if !fetch.IsLocation(d.DataSource) {
+		return source, false
+	}
+	source.Source = d.DataSource
+	return source, true
+}
+
+// sourceFor returns the source, format and format options a run with input
+// reads, and whether the source has a location. A string input replaces the
+// configured source location; a map input overrides the configured options
+// it sets, including "data_format" and "format_options".
+func (d *DataFetcherAgent) sourceFor(input interface{}) (fetch.Config, string, map[string]interface{}, bool, error) {
+	d.Mutex.RLock()
+	source, format, options := d.Source, d.DataFormat, d.FormatOptions
+	source, located := d.locate(source)
+	d.Mutex.RUnlock()
+
+	if values, ok := input.(map[string]string); ok {
+		settings := make(map[string]interface{}, len(values))
+		for key, value := range values {
+			settings[key] = value
+		}
+		input = settings
+	}
+
+	switch v := input.(type) {
+	case nil:
+	case string:
+		located = true
+		source.Source, source.Paths = v, nil
+	case map[string]interface{}:
+		override, err := fetch.ConfigFromMap(v)
+		if err != nil {
+			return source, format, options, located, err
+		}
+		if override.Source != "" || len(override.Paths) > 0 {
+			source.Source, source.Paths = override.Source, override.Paths
+			located = true
+		}
+		if override.Method != "" {
+			source.Method = override.Method
+		}
+		if override.Body != "" {
+			source.Body = override.Body
+		}
+		if override.Timeout > 0 {
+			source.Timeout = override.Timeout
+		}
+		if override.Pagination != nil {
+			source.Pagination = override.Pagination
+		}
+		source.Headers = mergeStrings(source.Headers, override.Headers)
+		source.Query = mergeStrings(source.Query, override.Query)
+		if _, ok := v["data_format"]; ok {
+			format, options = getStringParam(v, "data_format", format), nil
+		}
+		if raw, ok := v["format_options"]; ok {
+			if options, ok = raw.(map[string]interface{}); !ok {
+				return source, format, options, located, fmt.Errorf("format_options must be a map, got %T", raw)
+			}
+		}
+	default:
+		return source, format, options, located, fmt.Errorf("unsupported fetch input %T", input)
+	}
+	return source, format, options, located, nil
+}
+
+// mergeStrings returns base with the entries of override added or replaced.
+func mergeStrings(base, override map[string]string) map[string]string {
+	if len(override) == 0 {
+		return base
+	}
+	merged := make(map[string]string, len(base)+len(override))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range override {
+		merged[key] = value
+	}
+	return merged
+}
@@ -0,0 +1,80 @@
+package agents
+
+import (
+	"beluga/pkg/interfaces"
+	"beluga/pkg/monitoring"
+	"beluga/pkg/retry"
+	"time"
+)
+
+// CheckHealth returns the health status of the agent.
+func (b *BaseAgent) CheckHealth() map[string]interface{} {
+	b.Mutex.RLock()
+	defer b.Mutex.RUnlock()
+
+	history := make([]StateTransition, len(b.StateHistory))
+	copy(history, b.StateHistory)
+
+	stateReason := ""
+	if len(history) > 0 {
+		stateReason = history[len(history)-1].Reason
+	}
+
+	health := map[string]interface{}{
+		"name":             b.Name,
+		"state":            b.State,
+		"state_reason":     stateReason,
+		"state_history":    history,
+		"up_time":          time.Since(b.CreatedAt).String(),
+		"last_active_time": b.LastActiveTime,
+		"error_count":      b.ErrorCount,
+		"in_flight":        len(b.work),
+		"metrics":          b.Metrics.Snapshot(),
+	}
+	if b.Limiter != nil {
+		health["limits"] = b.Limiter.GetStats()
+	}
+	if b.CircuitBreaker != nil {
+		stats := b.CircuitBreaker.GetStats()
+		health["circuit_state"] = stats.State
+		health["circuit_breaker"] = stats
+	}
+	return health
+}
+
+// GetHealthCheckPolicy returns the policy retrying the failed checks of the
+// agent's health check, or nil if it has none.
+func (b *BaseAgent) GetHealthCheckPolicy() *retry.Policy {
+	b.Mutex.RLock()
+	defer b.Mutex.RUnlock()
+	return b.HealthCheckPolicy
+}
+
+// GetMessagingPolicy returns the policy retrying the messages the agent fails
+// to send, or nil if it has none.
+func (b *BaseAgent) GetMessagingPolicy() *retry.Policy {
+	b.Mutex.RLock()
+	defer b.Mutex.RUnlock()
+	return b.MessagingPolicy
+}
+
+// NewHealthCheck creates a health check of agent reporting its CheckHealth,
+// as for monitoring.NewHealthCheck. Failed checks are retried with the
+// agent's "health_check_retry_policy" setting if it has one.
+func NewHealthCheck(name, componentID string, interval time.Duration, agent interfaces.Agent) *monitoring.HealthCheck {
+	check := monitoring.CreateAgentHealthCheckFunc(func() map[string]interface{} {
+		if reporter, ok := agent.(interface{ CheckHealth() map[string]interface{} }); ok {
+			return reporter.CheckHealth()
+		}
+		return map[string]interface{}{
+			"name":  componentID,
+			"state": "unknown",
+		}
+	})
+
+	healthCheck := monitoring.NewHealthCheck(name, componentID, interval, check)
+	if configured, ok := agent.(interface{ GetHealthCheckPolicy() *retry.Policy }); ok {
+		healthCheck.RetryPolicy = configured.GetHealthCheckPolicy()
+	}
+	return healthCheck
+}
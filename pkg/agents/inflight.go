@@ -0,0 +1,252 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WorkKind distinguishes the kinds of work an agent tracks.
+type WorkKind string
+
+const (
+	// WorkExecution is a call to Execute or Run.
+	WorkExecution WorkKind = "execution"
+	// WorkGoroutine is a background goroutine started with Go.
+	WorkGoroutine WorkKind = "goroutine"
+)
+
+// ErrAgentShuttingDown is returned when new work is requested from an agent
+// that has started shutting down.
+var ErrAgentShuttingDown = errors.New("agent is shutting down")
+
+// WorkItem describes a unit of work in flight on an agent.
+type WorkItem struct {
+	ID        uint64    `json:"id"`
+	Kind      WorkKind  `json:"kind"`
+	Name      string    `json:"name"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// String returns a short description of the work item.
+func (w WorkItem) String() string {
+	return fmt.Sprintf("%s %s#%d (running %v)", w.Kind, w.Name, w.ID, time.Since(w.StartedAt).Round(time.Millisecond))
+}
+
+// ErrWorkAbandoned is returned by GracefulShutdown when work was still in
+// flight when the timeout expired and had to be abandoned.
+type ErrWorkAbandoned struct {
+	Agent     string
+	Abandoned []WorkItem
+}
+
+// Error implements the error interface.
+func (e *ErrWorkAbandoned) Error() string {
+	items := make([]string, len(e.Abandoned))
+	for i, item := range e.Abandoned {
+		items[i] = item.String()
+	}
+	return fmt.Sprintf("agent %s shut down with %d work item(s) abandoned: %s", e.Agent, len(e.Abandoned), strings.Join(items, ", "))
+}
+
+// Go runs fn in a background goroutine tracked by the agent. The context passed
+// to fn is cancelled as soon as the agent starts shutting down, and
+// GracefulShutdown waits for fn to return.
+func (b *BaseAgent) Go(name string, fn func(ctx context.Context)) error {
+	b.Mutex.Lock()
+	b.ensureDefaults()
+	if b.draining || b.State == StateShutdown {
+		b.Mutex.Unlock()
+		return fmt.Errorf("agent %s cannot start %s: %w", b.Name, name, ErrAgentShuttingDown)
+	}
+	id := b.trackWork(WorkGoroutine, name)
+	ctx := b.workerCtx
+	b.Mutex.Unlock()
+
+	go func() {
+		defer func() {
+			b.Mutex.Lock()
+			b.untrackWork(id)
+			b.Mutex.Unlock()
+		}()
+		fn(ctx)
+	}()
+	return nil
+}
+
+// GetInFlightWork returns the work currently in flight on the agent, oldest first.
+func (b *BaseAgent) GetInFlightWork() []WorkItem {
+	b.Mutex.RLock()
+	defer b.Mutex.RUnlock()
+	return b.inFlightWork()
+}
+
+// beginExecution applies the pause policy, refuses new work while shutting
+// down, takes a slot from limiter and registers a new in-flight execution. The
+// slot is only taken once the agent is not paused, and is given back if the
+// agent is paused while the caller waits for it, so callers blocked on a paused
+// agent do not hold slots. On success the returned function releases the slot.
+// The caller must hold b.Mutex; it may be released while blocked.
+func (b *BaseAgent) beginExecution(ctx context.Context, limiter *ExecutionLimiter) (uint64, func(), error) {
+	var release func()
+	giveBack := func() {
+		if release != nil {
+			release()
+			release = nil
+		}
+	}
+
+	for {
+		// An agent that has finished shutting down rejects the transition to
+		// running instead, reporting an invalid transition.
+		if b.draining && b.State != StateShutdown {
+			giveBack()
+			return 0, nil, fmt.Errorf("agent %s: %w", b.Name, ErrAgentShuttingDown)
+		}
+		if b.pauseCh != nil {
+			giveBack()
+			if b.PausePolicy == PauseReject {
+				return 0, nil, fmt.Errorf("agent %s: %w", b.Name, ErrAgentPaused)
+			}
+
+			pauseCh := b.pauseCh
+			drainCh := b.workerCtx.Done()
+			b.Mutex.Unlock()
+			select {
+			case <-pauseCh:
+			case <-drainCh:
+			case <-ctx.Done():
+			}
+			b.Mutex.Lock()
+
+			if err := ctx.Err(); err != nil {
+				return 0, nil, fmt.Errorf("agent %s execution cancelled while paused: %w", b.Name, err)
+			}
+			continue
+		}
+		if limiter == nil || release != nil {
+			break
+		}
+
+		// Wait for, or be refused, a concurrency slot and a rate token, then
+		// check again whether the agent was paused or drained meanwhile
+		b.Mutex.Unlock()
+		acquired, err := limiter.Acquire(ctx)
+		b.Mutex.Lock()
+		if err != nil {
+			b.Logger.Warning("Execution not admitted: %v", err)
+			return 0, nil, fmt.Errorf("agent %s: %w", b.Name, err)
+		}
+		release = acquired
+	}
+
+	if b.State != StateRunning {
+		if err := b.setState(StateRunning, "execution started"); err != nil {
+			giveBack()
+			return 0, nil, err
+		}
+	}
+	if release == nil {
+		release = func() {}
+	}
+	b.inFlight++
+	return b.trackWork(WorkExecution, "execution"), release, nil
+}
+
+// endExecution unregisters an in-flight execution and wakes callers waiting
+// for the agent to become idle. The caller must hold b.Mutex.
+func (b *BaseAgent) endExecution(id uint64) {
+	b.untrackWork(id)
+	b.inFlight--
+	if b.inFlight == 0 && b.idleCh != nil {
+		close(b.idleCh)
+		b.idleCh = nil
+	}
+}
+
+// idleChan returns a channel that is closed once no executions are in flight.
+// The caller must hold b.Mutex.
+func (b *BaseAgent) idleChan() <-chan struct{} {
+	if b.inFlight == 0 {
+		closed := make(chan struct{})
+		close(closed)
+		return closed
+	}
+	if b.idleCh == nil {
+		b.idleCh = make(chan struct{})
+	}
+	return b.idleCh
+}
+
+// trackWork records a new work item and returns its ID. The caller must hold b.Mutex.
+func (b *BaseAgent) trackWork(kind WorkKind, name string) uint64 {
+	if b.work == nil {
+		b.work = make(map[uint64]WorkItem)
+	}
+	b.nextWorkID++
+	b.work[b.nextWorkID] = WorkItem{
+		ID:        b.nextWorkID,
+		Kind:      kind,
+		Name:      name,
+		StartedAt: time.Now(),
+	}
+	return b.nextWorkID
+}
+
+// untrackWork removes a finished work item and wakes callers waiting for work
+// to drain. The caller must hold b.Mutex.
+func (b *BaseAgent) untrackWork(id uint64) {
+	delete(b.work, id)
+	if b.workCh != nil {
+		close(b.workCh)
+		b.workCh = nil
+	}
+}
+
+// inFlightWork returns the tracked work items ordered by ID. The caller must hold b.Mutex.
+func (b *BaseAgent) inFlightWork() []WorkItem {
+	items := make([]WorkItem, 0, len(b.work))
+	for _, item := range b.work {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	return items
+}
+
+// drain stops the agent from accepting new work, cancels its background
+// goroutines and waits until all tracked work has finished or the timeout
+// expires. It returns the work still in flight at that point.
+func (b *BaseAgent) drain(timeout time.Duration) []WorkItem {
+	b.Mutex.Lock()
+	b.ensureDefaults()
+	b.draining = true
+	b.stopWorkers()
+	b.Mutex.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		b.Mutex.Lock()
+		if len(b.work) == 0 {
+			b.Mutex.Unlock()
+			return nil
+		}
+		if b.workCh == nil {
+			b.workCh = make(chan struct{})
+		}
+		workCh := b.workCh
+		b.Mutex.Unlock()
+
+		select {
+		case <-workCh:
+		case <-timer.C:
+			b.Mutex.RLock()
+			defer b.Mutex.RUnlock()
+			return b.inFlightWork()
+		}
+	}
+}
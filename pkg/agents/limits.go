@@ -0,0 +1,300 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// LimitPolicy determines what happens to executions that exceed an agent's
+// concurrency or rate limit.
+type LimitPolicy string
+
+const (
+	// LimitQueue makes Execute and Run wait until a slot or token is free.
+	LimitQueue LimitPolicy = "queue"
+	// LimitReject makes Execute and Run fail immediately with
+	// ErrConcurrencyLimit or ErrRateLimited.
+	LimitReject LimitPolicy = "reject"
+)
+
+var (
+	// ErrConcurrencyLimit is returned when an execution is rejected because the
+	// agent is already running its maximum number of concurrent executions.
+	ErrConcurrencyLimit = errors.New("agent concurrency limit reached")
+	// ErrRateLimited is returned when an execution is rejected because the
+	// agent's rate limit has been used up.
+	ErrRateLimited = errors.New("agent rate limit exceeded")
+)
+
+// TokenBucket is a token-bucket rate limiter. The bucket holds up to Burst
+// tokens and refills at Rate tokens per second; every call takes one token.
+type TokenBucket struct {
+	rate   float64
+	burst  float64
+	mutex  sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket creates a full token bucket. A burst below 1 is treated as 1.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// TryTake takes a token if one is available and reports whether it did.
+func (t *TokenBucket) TryTake() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.refill()
+	if t.tokens >= 1 {
+		t.tokens--
+		return true
+	}
+	return false
+}
+
+// Take waits until a token is available and takes it, or returns the context
+// error if ctx is done first.
+func (t *TokenBucket) Take(ctx context.Context) error {
+	for {
+		t.mutex.Lock()
+		t.refill()
+		if t.tokens >= 1 {
+			t.tokens--
+			t.mutex.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - t.tokens) / t.rate * float64(time.Second))
+		t.mutex.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// Tokens returns the number of tokens currently in the bucket.
+func (t *TokenBucket) Tokens() float64 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.refill()
+	return t.tokens
+}
+
+// refill adds the tokens earned since the last refill. The caller must hold t.mutex.
+func (t *TokenBucket) refill() {
+	now := time.Now()
+	t.tokens = math.Min(t.burst, t.tokens+now.Sub(t.last).Seconds()*t.rate)
+	t.last = now
+}
+
+// ExecutionLimiter bounds how many executions of an agent run at once and how
+// often they may start.
+type ExecutionLimiter struct {
+	// MaxConcurrent is the maximum number of concurrent executions; zero means unlimited.
+	MaxConcurrent int
+	// RateLimit, if set, limits how often executions and their retries start.
+	RateLimit *TokenBucket
+	// Policy decides whether executions over the limit wait or are rejected.
+	Policy LimitPolicy
+
+	once     sync.Once
+	slots    chan struct{}
+	mutex    sync.Mutex
+	queued   int
+	rejected int
+}
+
+// ExecutionLimiterStats is a snapshot of an execution limiter.
+type ExecutionLimiterStats struct {
+	MaxConcurrent int         `json:"max_concurrent"`
+	Active        int         `json:"active"`
+	Queued        int         `json:"queued"`
+	Rejected      int         `json:"rejected"`
+	Policy        LimitPolicy `json:"policy"`
+	RateLimit     float64     `json:"rate_limit,omitempty"`
+	Tokens        float64     `json:"tokens,omitempty"`
+}
+
+// NewExecutionLimiter creates a limiter allowing maxConcurrent concurrent
+// executions (zero for no limit) at up to rate executions per second with the
+// given burst (zero rate for no limit).
+func NewExecutionLimiter(maxConcurrent int, rate float64, burst int, policy LimitPolicy) *ExecutionLimiter {
+	limiter := &ExecutionLimiter{
+		MaxConcurrent: maxConcurrent,
+		Policy:        policy,
+	}
+	if rate > 0 {
+		limiter.RateLimit = NewTokenBucket(rate, burst)
+	}
+	return limiter
+}
+
+// Acquire admits a new execution, waiting for a free slot and a rate token or
+// rejecting the execution according to the policy. On success the returned
+// function must be called once the execution has finished.
+func (l *ExecutionLimiter) Acquire(ctx context.Context) (func(), error) {
+	slots := l.slotChan()
+	reject := l.Policy == LimitReject
+
+	if slots != nil {
+		select {
+		case slots <- struct{}{}:
+		default:
+			if reject {
+				l.countRejection()
+				return nil, fmt.Errorf("%w (%d running)", ErrConcurrencyLimit, l.MaxConcurrent)
+			}
+			l.setQueued(1)
+			select {
+			case slots <- struct{}{}:
+				l.setQueued(-1)
+			case <-ctx.Done():
+				l.setQueued(-1)
+				return nil, ctx.Err()
+			}
+		}
+	}
+	release := func() {
+		if slots != nil {
+			<-slots
+		}
+	}
+
+	if err := l.takeToken(ctx, reject); err != nil {
+		release()
+		return nil, err
+	}
+
+	var once sync.Once
+	return func() { once.Do(release) }, nil
+}
+
+// Wait waits for a rate token without taking a concurrency slot. It is used
+// for the retries of an execution that has already been admitted.
+func (l *ExecutionLimiter) Wait(ctx context.Context) error {
+	return l.takeToken(ctx, false)
+}
+
+// GetStats returns a snapshot of the limiter.
+func (l *ExecutionLimiter) GetStats() ExecutionLimiterStats {
+	l.mutex.Lock()
+	stats := ExecutionLimiterStats{
+		MaxConcurrent: l.MaxConcurrent,
+		Queued:        l.queued,
+		Rejected:      l.rejected,
+		Policy:        l.Policy,
+	}
+	l.mutex.Unlock()
+
+	if slots := l.slotChan(); slots != nil {
+		stats.Active = len(slots)
+	}
+	if l.RateLimit != nil {
+		stats.RateLimit = l.RateLimit.rate
+		stats.Tokens = l.RateLimit.Tokens()
+	}
+	return stats
+}
+
+func (l *ExecutionLimiter) takeToken(ctx context.Context, reject bool) error {
+	if l.RateLimit == nil {
+		return nil
+	}
+	if reject {
+		if !l.RateLimit.TryTake() {
+			l.countRejection()
+			return ErrRateLimited
+		}
+		return nil
+	}
+
+	l.setQueued(1)
+	defer l.setQueued(-1)
+	return l.RateLimit.Take(ctx)
+}
+
+func (l *ExecutionLimiter) slotChan() chan struct{} {
+	l.once.Do(func() {
+		if l.MaxConcurrent > 0 {
+			l.slots = make(chan struct{}, l.MaxConcurrent)
+		}
+	})
+	return l.slots
+}
+
+func (l *ExecutionLimiter) setQueued(delta int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.queued += delta
+}
+
+func (l *ExecutionLimiter) countRejection() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.rejected++
+}
+
+// newExecutionLimiterFromSettings builds an execution limiter from an agent's
+// settings. It returns nil if neither "max_concurrent_executions" nor
+// "rate_limit" is set. "rate_limit" is either a number of executions per
+// second or a map with "rate" and "burst"; "limit_policy" is "queue" (the
+// default) or "reject".
+func newExecutionLimiterFromSettings(config map[string]interface{}) (*ExecutionLimiter, error) {
+	maxConcurrent := getIntParam(config, "max_concurrent_executions", 0)
+	if maxConcurrent < 0 {
+		return nil, fmt.Errorf("max_concurrent_executions cannot be negative: %d", maxConcurrent)
+	}
+
+	var rate float64
+	burst := 1
+	switch v := config["rate_limit"].(type) {
+	case nil:
+	case float64:
+		rate = v
+	case int:
+		rate = float64(v)
+	case map[string]interface{}:
+		switch r := v["rate"].(type) {
+		case float64:
+			rate = r
+		case int:
+			rate = float64(r)
+		default:
+			return nil, fmt.Errorf("rate_limit.rate must be a number, got %T", v["rate"])
+		}
+		burst = getIntParam(v, "burst", 1)
+	default:
+		return nil, fmt.Errorf("rate_limit must be a number or a settings map, got %T", v)
+	}
+	if rate < 0 {
+		return nil, fmt.Errorf("rate_limit cannot be negative: %v", rate)
+	}
+
+	policy := LimitPolicy(getStringParam(config, "limit_policy", string(LimitQueue)))
+	if policy != LimitQueue && policy != LimitReject {
+		return nil, fmt.Errorf("unknown limit policy: %s", policy)
+	}
+
+	if maxConcurrent == 0 && rate == 0 {
+		return nil, nil
+	}
+	return NewExecutionLimiter(maxConcurrent, rate, burst, policy), nil
+}
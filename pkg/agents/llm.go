@@ -0,0 +1,47 @@
+package agents
+
+import (
+	"beluga/pkg/llm"
+	"fmt"
+)
+
+// parseLLM builds the model described by the "llm" setting: an llm.LLM, an
+// llm.Config or a map with the keys read by llm.ConfigFromMap. The agent's
+// "nlp_model" setting names the model when the llm setting does not.
+func parseLLM(raw interface{}, config map[string]interface{}) (llm.LLM, error) {
+	var llmConfig llm.Config
+	switch v := raw.(type) {
+	case llm.LLM:
+		return v, nil
+	case llm.Config:
+		llmConfig = v
+	case *llm.Config:
+		llmConfig = *v
+	case map[string]interface{}:
+		var err error
+		if llmConfig, err = llm.ConfigFromMap(v); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported llm setting type %T", raw)
+	}
+
+	if llmConfig.Model == "" {
+		llmConfig.Model = getStringParam(config, "nlp_model", "")
+	}
+	return llm.New(llmConfig)
+}
+
+// GetLLM returns the language model the agent was configured with, or nil.
+func (b *BaseAgent) GetLLM() llm.LLM {
+	b.Mutex.RLock()
+	defer b.Mutex.RUnlock()
+	return b.LLM
+}
+
+// SetLLM replaces the agent's language model.
+func (b *BaseAgent) SetLLM(model llm.LLM) {
+	b.Mutex.Lock()
+	defer b.Mutex.Unlock()
+	b.LLM = model
+}
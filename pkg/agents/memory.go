@@ -0,0 +1,168 @@
+package agents
+
+import (
+	"beluga/pkg/memory"
+	"context"
+	"fmt"
+	"time"
+)
+
+// DefaultMemoryContext is the number of earlier interactions analyzer and
+// decision agents recall before each run.
+const DefaultMemoryContext = 5
+
+// parseMemory builds the store described by the "memory" setting: a
+// memory.Memory, a memory.Config or a map with the keys "type" ("in_memory"
+// or "file"), "path" and "capacity".
+func parseMemory(raw interface{}) (memory.Memory, error) {
+	switch v := raw.(type) {
+	case memory.Memory:
+		return v, nil
+	case memory.Config:
+		return v.New()
+	case *memory.Config:
+		return v.New()
+	case map[string]interface{}:
+		config, err := memory.ConfigFromMap(v)
+		if err != nil {
+			return nil, err
+		}
+		return config.New()
+	default:
+		return nil, fmt.Errorf("unsupported memory setting type %T", raw)
+	}
+}
+
+// remember records a successful execution in store. Failing to remember does
+// not fail the execution.
+func (b *BaseAgent) remember(store memory.Memory, executionID uint64, started time.Time, input, output interface{}) {
+	if store == nil {
+		return
+	}
+	entry := memory.Entry{
+		Timestamp: started,
+		Input:     input,
+		Output:    output,
+		Metadata: map[string]interface{}{
+			"agent":        b.Name,
+			"execution_id": executionID,
+		},
+	}
+	if err := store.Append(entry); err != nil {
+		b.Logger.Warning("Failed to remember execution %d: %v", executionID, err)
+	}
+}
+
+// GetMemory returns the agent's memory store.
+func (b *BaseAgent) GetMemory() memory.Memory {
+	b.Mutex.RLock()
+	defer b.Mutex.RUnlock()
+	return b.Memory
+}
+
+// SetMemory replaces the agent's memory store. Passing nil disables memory.
+func (b *BaseAgent) SetMemory(store memory.Memory) {
+	b.Mutex.Lock()
+	defer b.Mutex.Unlock()
+	b.Memory = store
+}
+
+// Recall returns the last MemoryContext interactions of the agent, oldest
+// first, or nothing if the agent has no memory or MemoryContext is zero.
+func (b *BaseAgent) Recall() ([]memory.Entry, error) {
+	b.Mutex.RLock()
+	store, n := b.Memory, b.MemoryContext
+	b.Mutex.RUnlock()
+
+	if store == nil || n <= 0 {
+		return nil, nil
+	}
+	entries, err := store.Last(n)
+	if err != nil {
+		return nil, fmt.Errorf("agent %s failed to recall memory: %w", b.Name, err)
+	}
+	return entries, nil
+}
+
+// parseVectorMemory builds the store described by the "vector_memory"
+// setting and the query template used to retrieve from it. The setting is a
+// memory.VectorStore, a memory.VectorConfig or a map with the keys "path",
+// "metric", "capacity", "dimensions", "top_k", "min_score" and "filter".
+func parseVectorMemory(raw interface{}) (memory.VectorStore, memory.Query, error) {
+	var config memory.VectorConfig
+	switch v := raw.(type) {
+	case memory.VectorStore:
+		return v, memory.Query{}, nil
+	case memory.VectorConfig:
+		config = v
+	case *memory.VectorConfig:
+		config = *v
+	case map[string]interface{}:
+		var err error
+		if config, err = memory.VectorConfigFromMap(v); err != nil {
+			return nil, memory.Query{}, err
+		}
+	default:
+		return nil, memory.Query{}, fmt.Errorf("unsupported vector_memory setting type %T", raw)
+	}
+
+	index, err := config.New(nil)
+	if err != nil {
+		return nil, memory.Query{}, err
+	}
+	return index, memory.Query{TopK: config.TopK, MinScore: config.MinScore, Filter: config.Filter}, nil
+}
+
+// index adds a successful execution to the vector store, keyed by the text of
+// its input. Failing to index does not fail the execution.
+func (b *BaseAgent) index(ctx context.Context, store memory.VectorStore, executionID uint64, started time.Time, input, output interface{}) {
+	if store == nil {
+		return
+	}
+	content := memory.ContentOf(input)
+	if content == "" {
+		return
+	}
+	document := memory.Document{
+		Content: content,
+		Metadata: map[string]interface{}{
+			"agent":        b.Name,
+			"execution_id": executionID,
+			"timestamp":    started.Format(time.RFC3339Nano),
+			"output":       memory.ContentOf(output),
+		},
+	}
+	if err := store.Add(ctx, document); err != nil {
+		b.Logger.Warning("Failed to index execution %d: %v", executionID, err)
+	}
+}
+
+// SetVectorMemory replaces the agent's vector store and the query template
+// used by Retrieve. Passing a nil store disables retrieval.
+func (b *BaseAgent) SetVectorMemory(store memory.VectorStore, retrieval memory.Query) {
+	b.Mutex.Lock()
+	defer b.Mutex.Unlock()
+	b.VectorMemory, b.Retrieval = store, retrieval
+}
+
+// Retrieve returns the records of the agent's vector store most similar to
+// the text of input, using Retrieval for the number of results, minimum score
+// and metadata filter. It returns nothing if the agent has no vector store.
+func (b *BaseAgent) Retrieve(ctx context.Context, input interface{}) ([]memory.SearchResult, error) {
+	b.Mutex.RLock()
+	store, query := b.VectorMemory, b.Retrieval
+	b.Mutex.RUnlock()
+
+	if store == nil {
+		return nil, nil
+	}
+	query.Text = memory.ContentOf(input)
+	if query.Text == "" && len(query.Vector) == 0 {
+		return nil, nil
+	}
+	results, err := store.Search(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("agent %s failed to retrieve related records: %w", b.Name, err)
+	}
+	return results, nil
+}
@@ -0,0 +1,318 @@
+package agents
+
+import (
+	"beluga/pkg/monitoring"
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MonitorAgent monitors the performance and health of the system. Its
+// targets are measured by the collector of their URI scheme, such as
+// "proc://self", "https://example.com/health", "tcp://db:5432" or
+// "disk:///var/lib"; targets without a scheme name agents.
+type MonitorAgent struct {
+	*BaseAgent
+	MonitorTargets []string
+	MonitorResults map[string]interface{}
+	Interval       time.Duration
+	// Collectors measures the targets; nil means the default collectors.
+	Collectors *monitoring.Collectors
+	// Agents holds the agents targets without a scheme name.
+	Agents *AgentRegistry
+	// History keeps the samples collected of every target.
+	History *monitoring.History
+	// Thresholds degrade targets whose metrics exceed them.
+	Thresholds map[string]float64
+	// Timeout bounds the collection of a target.
+	Timeout time.Duration
+}
+
+// NewMonitorAgent creates a new MonitorAgent.
+func NewMonitorAgent(name string, interval time.Duration) *MonitorAgent {
+	agent := &MonitorAgent{
+		BaseAgent:      NewBaseAgent(name),
+		MonitorTargets: make([]string, 0),
+		MonitorResults: make(map[string]interface{}),
+		Interval:       interval,
+		History:        monitoring.NewHistory(monitoring.DefaultHistorySize),
+		Timeout:        monitoring.DefaultProbeTimeout,
+	}
+	agent.SetBehavior(BehaviorFunc(agent.doExecute))
+	return agent
+}
+
+func (m *MonitorAgent) AddMonitorTarget(target string) {
+	m.Mutex.Lock()
+	defer m.Mutex.Unlock()
+	m.MonitorTargets = append(m.MonitorTargets, target)
+}
+
+func (m *MonitorAgent) GetMonitorResults() map[string]interface{} {
+	m.Mutex.RLock()
+	defer m.Mutex.RUnlock()
+
+	// Create a copy to avoid external modification
+	results := make(map[string]interface{})
+	for k, v := range m.MonitorResults {
+		results[k] = v
+	}
+
+	return results
+}
+
+func (m *MonitorAgent) doExecute(ctx context.Context, input interface{}) (interface{}, error) {
+	m.Logger.Info("Starting continuous monitoring")
+
+	// Start continuous monitoring in a tracked goroutine, which is stopped
+	// when the agent shuts down
+	err := m.Go(monitorLoop, func(ctx context.Context) {
+		ticker := time.NewTicker(m.Interval)
+		defer ticker.Stop()
+
+		m.collectMetrics(ctx)
+		for {
+			select {
+			case <-ticker.C:
+				// Suspend collection while the agent is paused
+				if err := m.WaitWhilePaused(ctx); err != nil {
+					m.Logger.Info("Stopping monitoring")
+					return
+				}
+				m.collectMetrics(ctx)
+			case <-ctx.Done():
+				m.Logger.Info("Stopping monitoring")
+				return
+			}
+		}
+	})
+
+	return nil, err
+}
+
+// collectMetrics samples every target, records the samples in the history
+// and publishes the latest results.
+func (m *MonitorAgent) collectMetrics(ctx context.Context) {
+	m.Mutex.RLock()
+	targets := append([]string(nil), m.MonitorTargets...)
+	m.Mutex.RUnlock()
+
+	// Probes may be slow, so targets are collected concurrently and without
+	// holding the lock
+	samples := make([]*monitoring.Sample, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target string) {
+			defer wg.Done()
+			m.Logger.Debug("Collecting metrics for %s", target)
+			samples[i] = m.collect(ctx, target)
+		}(i, target)
+	}
+	wg.Wait()
+
+	m.Mutex.Lock()
+	for _, sample := range samples {
+		sample.ApplyThresholds(m.Thresholds)
+		m.History.Add(sample)
+		m.MonitorResults[sample.Target] = sampleResult(sample)
+	}
+
+	// Publish a copy, since subscribers read it after the lock is released
+	metrics := make(map[string]interface{}, len(m.MonitorResults))
+	for target, result := range m.MonitorResults {
+		metrics[target] = result
+	}
+	m.Mutex.Unlock()
+	m.publish(MetricsUpdated{AgentEvent: m.newAgentEvent(), Metrics: metrics})
+}
+
+// monitorLoop names the goroutine that collects the targets' metrics.
+const monitorLoop = "monitor_loop"
+
+// Initialize sets up the agent. In addition to the BaseAgent settings it
+// reads "alert_thresholds", the values of metrics such as "cpu_usage" above
+// which targets are degraded, "history_size", the number of samples kept
+// per target, and "timeout_seconds", which bounds the collection of a
+// target.
+func (m *MonitorAgent) Initialize(config map[string]interface{}) error {
+	if err := m.BaseAgent.Initialize(config); err != nil {
+		return err
+	}
+
+	thresholds, err := parseThresholds(config["alert_thresholds"])
+	if err != nil {
+		return err
+	}
+	m.Mutex.Lock()
+	defer m.Mutex.Unlock()
+	if thresholds != nil {
+		m.Thresholds = thresholds
+	}
+	if _, ok := config["history_size"]; ok {
+		m.History = monitoring.NewHistory(getIntParam(config, "history_size", monitoring.DefaultHistorySize))
+	}
+	m.Timeout = getDurationParam(config, "timeout_seconds", m.Timeout)
+	return nil
+}
+
+// parseThresholds reads a map of metric thresholds.
+func parseThresholds(raw interface{}) (map[string]float64, error) {
+	var values map[string]interface{}
+	switch v := raw.(type) {
+	case nil:
+		return nil, nil
+	case map[string]float64:
+		return v, nil
+	case map[string]interface{}:
+		values = v
+	case map[interface{}]interface{}:
+		values = make(map[string]interface{}, len(v))
+		for key, value := range v {
+			values[fmt.Sprint(key)] = value
+		}
+	default:
+		return nil, fmt.Errorf("alert_thresholds must be a map, got %T", raw)
+	}
+	thresholds := make(map[string]float64, len(values))
+	for name, value := range values {
+		switch v := value.(type) {
+		case int:
+			thresholds[name] = float64(v)
+		case int64:
+			thresholds[name] = float64(v)
+		case float64:
+			thresholds[name] = v
+		default:
+			return nil, fmt.Errorf("alert threshold %s must be a number, got %T", name, value)
+		}
+	}
+	return thresholds, nil
+}
+
+// Restart restarts the agent as BaseAgent.Restart does and starts the
+// monitor loop again if it was running, so that supervised monitors keep
+// collecting after a restart.
+func (m *MonitorAgent) Restart(timeout time.Duration) error {
+	running := false
+	for _, item := range m.GetInFlightWork() {
+		if item.Kind == WorkGoroutine && item.Name == monitorLoop {
+			running = true
+		}
+	}
+	if err := m.BaseAgent.Restart(timeout); err != nil {
+		return err
+	}
+	if !running {
+		return nil
+	}
+	return m.Execute()
+}
+
+// GetHistory returns the samples collected of the agent's targets.
+func (m *MonitorAgent) GetHistory() *monitoring.History {
+	m.Mutex.RLock()
+	defer m.Mutex.RUnlock()
+	return m.History
+}
+
+// SetCollectors sets the registry targets are collected with.
+func (m *MonitorAgent) SetCollectors(collectors *monitoring.Collectors) {
+	m.Mutex.Lock()
+	defer m.Mutex.Unlock()
+	m.Collectors = collectors
+}
+
+// RegisterHealthChecks adds a health check named after the agent for each
+// of its targets to manager, reporting the latest sample of the target.
+func (m *MonitorAgent) RegisterHealthChecks(manager *monitoring.HealthCheckManager) error {
+	m.Mutex.RLock()
+	defer m.Mutex.RUnlock()
+	for _, target := range m.MonitorTargets {
+		if err := manager.AddCheck(m.History.HealthCheck(m.Name, target, m.Interval)); err != nil {
+			return fmt.Errorf("failed to add health check for %s: %w", target, err)
+		}
+	}
+	return nil
+}
+
+// collect samples a target with the collector of its scheme, or from the
+// agent it names.
+func (m *MonitorAgent) collect(ctx context.Context, target string) *monitoring.Sample {
+	m.Mutex.RLock()
+	collectors := m.Collectors
+	timeout := m.Timeout
+	m.Mutex.RUnlock()
+	if collectors == nil {
+		collectors = monitoring.DefaultCollectors()
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	parsed, err := monitoring.ParseTarget(target)
+	if err == nil && parsed.Scheme == monitoring.AgentScheme {
+		return monitoring.CollectWith(ctx, monitoring.CollectorFunc(m.collectAgent), target, parsed)
+	}
+	return collectors.Collect(ctx, target)
+}
+
+// collectAgent samples the health of the agent a target names.
+func (m *MonitorAgent) collectAgent(ctx context.Context, target *url.URL) (*monitoring.Sample, error) {
+	name := target.Opaque
+	m.Mutex.RLock()
+	registry := m.Agents
+	m.Mutex.RUnlock()
+	var agent interface{}
+	if registry != nil {
+		agent, _ = registry.GetAgent(name)
+	}
+	reporter, ok := agent.(interface{ CheckHealth() map[string]interface{} })
+	if !ok {
+		return &monitoring.Sample{Status: monitoring.StatusUnknown, Message: fmt.Sprintf("Agent %s is not registered", name)}, nil
+	}
+
+	health := reporter.CheckHealth()
+	result := monitoring.CreateAgentHealthCheckFunc(func() map[string]interface{} { return health })()
+	sample := &monitoring.Sample{
+		Status:    result.Status,
+		Message:   result.Message,
+		Timestamp: time.Now(),
+		Metrics:   make(map[string]float64),
+		Details:   map[string]interface{}{"state": fmt.Sprint(health["state"])},
+	}
+	for _, key := range []string{"error_count", "in_flight"} {
+		if value, ok := health[key].(int); ok {
+			sample.Metrics[key] = float64(value)
+		}
+	}
+	if metrics, ok := health["metrics"].(map[string]interface{}); ok {
+		for key, value := range metrics {
+			if number, err := strconv.ParseFloat(fmt.Sprint(value), 64); err == nil {
+				sample.Metrics[key] = number
+			}
+		}
+	}
+	return sample, nil
+}
+
+// sampleResult is the monitor result of a sample: its status, timestamp and
+// message with its metrics.
+func sampleResult(sample *monitoring.Sample) map[string]interface{} {
+	result := map[string]interface{}{
+		"status":    string(sample.Status),
+		"timestamp": sample.Timestamp,
+	}
+	if sample.Message != "" {
+		result["message"] = sample.Message
+	}
+	for name, value := range sample.Metrics {
+		result[name] = value
+	}
+	return result
+}
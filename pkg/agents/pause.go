@@ -0,0 +1,98 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// PausePolicy determines what happens to executions requested while an agent is paused.
+type PausePolicy string
+
+const (
+	// PauseBlock makes Execute and Run wait until the agent is resumed.
+	PauseBlock PausePolicy = "block"
+	// PauseReject makes Execute and Run fail immediately with ErrAgentPaused.
+	PauseReject PausePolicy = "reject"
+)
+
+// ErrAgentPaused is returned by Execute and Run when the agent is paused and
+// its PausePolicy is PauseReject.
+var ErrAgentPaused = errors.New("agent is paused")
+
+// Pause stops the agent from starting new executions and waits until the
+// executions already in flight have finished. If ctx is done first, Pause
+// returns the context error; the agent stays paused either way.
+func (b *BaseAgent) Pause(ctx context.Context) error {
+	b.Mutex.Lock()
+	b.ensureDefaults()
+	if b.pauseCh == nil {
+		if err := b.setState(StatePaused, "pause requested"); err != nil {
+			b.Mutex.Unlock()
+			return err
+		}
+		b.pauseCh = make(chan struct{})
+	}
+	idle := b.idleChan()
+	b.Mutex.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("agent %s paused with executions still in flight: %w", b.Name, ctx.Err())
+	}
+}
+
+// Resume lets a paused agent start executions again and releases callers
+// blocked by the PauseBlock policy.
+func (b *BaseAgent) Resume() error {
+	b.Mutex.Lock()
+	defer b.Mutex.Unlock()
+
+	if b.pauseCh == nil {
+		return fmt.Errorf("agent %s is not paused", b.Name)
+	}
+
+	if b.State == StatePaused {
+		next := StateReady
+		if b.inFlight > 0 {
+			next = StateRunning
+		}
+		if err := b.setState(next, "resumed"); err != nil {
+			return err
+		}
+	}
+
+	close(b.pauseCh)
+	b.pauseCh = nil
+	return nil
+}
+
+// IsPaused reports whether the agent has been paused and not yet resumed.
+func (b *BaseAgent) IsPaused() bool {
+	b.Mutex.RLock()
+	defer b.Mutex.RUnlock()
+	return b.pauseCh != nil
+}
+
+// WaitWhilePaused blocks until the agent is resumed or ctx is done. It returns
+// immediately when the agent is not paused. Long-running loops call it to
+// suspend their work while the agent is paused.
+func (b *BaseAgent) WaitWhilePaused(ctx context.Context) error {
+	for {
+		b.Mutex.RLock()
+		pauseCh := b.pauseCh
+		b.Mutex.RUnlock()
+
+		if pauseCh == nil {
+			return nil
+		}
+
+		select {
+		case <-pauseCh:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
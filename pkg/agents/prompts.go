@@ -0,0 +1,63 @@
+package agents
+
+import (
+	"beluga/pkg/llm"
+	"beluga/pkg/prompts"
+	"errors"
+	"fmt"
+)
+
+// GetPrompts returns the prompt templates available to the agent: its own
+// library, or the default library if it was not given one.
+func (b *BaseAgent) GetPrompts() *prompts.Library {
+	b.Mutex.RLock()
+	defer b.Mutex.RUnlock()
+	return b.promptLibrary()
+}
+
+// promptLibrary returns the agent's prompt library. The caller must hold b.Mutex.
+func (b *BaseAgent) promptLibrary() *prompts.Library {
+	if b.Prompts == nil {
+		return prompts.DefaultLibrary()
+	}
+	return b.Prompts
+}
+
+// SetPrompts replaces the agent's prompt library. Passing nil makes the agent
+// use the default library.
+func (b *BaseAgent) SetPrompts(library *prompts.Library) {
+	b.Mutex.Lock()
+	defer b.Mutex.Unlock()
+	b.Prompts = library
+}
+
+// setPromptTemplate selects the template named by the "prompt" setting,
+// which must exist in the agent's library. The caller must hold b.Mutex.
+func (b *BaseAgent) setPromptTemplate(raw interface{}) error {
+	name, ok := raw.(string)
+	if !ok {
+		return fmt.Errorf("prompt must be a template name, got %T", raw)
+	}
+	if _, exists := b.promptLibrary().Get(name); !exists {
+		return fmt.Errorf("%w: %s", prompts.ErrUnknownTemplate, name)
+	}
+	b.PromptTemplate = name
+	return nil
+}
+
+// RenderPrompt renders the agent's prompt template, selected by the "prompt"
+// setting, to chat messages.
+func (b *BaseAgent) RenderPrompt(vars map[string]interface{}) ([]llm.Message, error) {
+	b.Mutex.RLock()
+	name, library := b.PromptTemplate, b.promptLibrary()
+	b.Mutex.RUnlock()
+
+	if name == "" {
+		return nil, errors.New("agent " + b.Name + " has no prompt template configured")
+	}
+	messages, err := library.RenderMessages(name, vars)
+	if err != nil {
+		return nil, fmt.Errorf("agent %s: %w", b.Name, err)
+	}
+	return messages, nil
+}
@@ -0,0 +1,346 @@
+package agents
+
+import (
+	"beluga/pkg/llm"
+	"beluga/pkg/retry"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultMaxSteps bounds the steps of a ReasoningAgent run unless configured otherwise.
+const DefaultMaxSteps = 10
+
+const (
+	finalAnswerMarker    = "Final Answer:"
+	observationMarker    = "Observation:"
+	defaultReActTemplate = `You are %s, an agent that accomplishes goals by reasoning step by step and using tools.
+
+You can use these tools:
+%s
+Use exactly this format:
+
+Thought: what you should do next
+Action: the tool to use, one of [%s]
+Action Input: the tool arguments as a JSON object
+Observation: the result of the tool, which will be provided to you
+
+Repeat Thought, Action, Action Input and Observation as often as needed, then finish with:
+
+Thought: I know the final answer
+Final Answer: the answer to the goal
+
+Never write the Observation yourself.`
+)
+
+var (
+	// ErrMaxStepsExceeded is returned when a reasoning agent has not reached a
+	// final answer within its step limit.
+	ErrMaxStepsExceeded = errors.New("reasoning step limit reached without a final answer")
+	// ErrTokenBudgetExceeded is returned when a reasoning agent has used its
+	// token budget without reaching a final answer.
+	ErrTokenBudgetExceeded = errors.New("reasoning token budget exhausted without a final answer")
+)
+
+// ReasoningStep is one thought, action and observation of a reasoning agent.
+type ReasoningStep struct {
+	Index       int                    `json:"index"`
+	Thought     string                 `json:"thought,omitempty"`
+	Action      string                 `json:"action,omitempty"`
+	ActionInput map[string]interface{} `json:"action_input,omitempty"`
+	Observation string                 `json:"observation,omitempty"`
+	FinalAnswer string                 `json:"final_answer,omitempty"`
+	// Output is the raw model output the step was parsed from.
+	Output    string    `json:"output"`
+	Tokens    int       `json:"tokens"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ReasoningStepCompleted is published after each step of a reasoning agent.
+type ReasoningStepCompleted struct {
+	AgentEvent
+	Goal string        `json:"goal"`
+	Step ReasoningStep `json:"step"`
+}
+
+// EventType implements events.Event.
+func (ReasoningStepCompleted) EventType() string { return EventReasoningStep }
+
+// ReasoningAgent pursues a goal in ReAct style: its language model alternates
+// thoughts and tool actions, each action's result being fed back as an
+// observation, until the model gives a final answer.
+type ReasoningAgent struct {
+	*BaseAgent
+	Goal string
+	// MaxSteps bounds the model requests of a run.
+	MaxSteps int
+	// TokenBudget bounds the tokens used by a run; zero means no limit.
+	TokenBudget int
+	// SystemPrompt replaces the default ReAct instructions when set. A prompt
+	// template selected by the "prompt" setting takes precedence.
+	SystemPrompt string
+	// FinalAnswerDetector extracts the final answer from a model output. The
+	// default looks for a "Final Answer:" line.
+	FinalAnswerDetector func(output string) (answer string, ok bool)
+	FinalAnswer         string
+	Transcript          []ReasoningStep
+	TokensUsed          int
+}
+
+// NewReasoningAgent creates a new ReasoningAgent pursuing goal when run
+// without an input.
+func NewReasoningAgent(name string, goal string) *ReasoningAgent {
+	agent := &ReasoningAgent{
+		BaseAgent:           NewBaseAgent(name),
+		Goal:                goal,
+		MaxSteps:            DefaultMaxSteps,
+		FinalAnswerDetector: DetectFinalAnswer,
+	}
+	agent.SetBehavior(BehaviorFunc(agent.doExecute))
+	return agent
+}
+
+// Initialize sets up the agent, reading "goal", "max_steps", "token_budget"
+// and "system_prompt" in addition to the BaseAgent settings.
+func (r *ReasoningAgent) Initialize(config map[string]interface{}) error {
+	if err := r.BaseAgent.Initialize(config); err != nil {
+		return err
+	}
+
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+	r.Goal = getStringParam(config, "goal", r.Goal)
+	r.MaxSteps = getIntParam(config, "max_steps", r.MaxSteps)
+	r.TokenBudget = getIntParam(config, "token_budget", r.TokenBudget)
+	r.SystemPrompt = getStringParam(config, "system_prompt", r.SystemPrompt)
+	if r.MaxSteps <= 0 {
+		return fmt.Errorf("max_steps must be positive, got %d", r.MaxSteps)
+	}
+	return nil
+}
+
+// GetFinalAnswer returns the answer of the last successful run.
+func (r *ReasoningAgent) GetFinalAnswer() string {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+	return r.FinalAnswer
+}
+
+// GetTranscript returns the steps of the last run, successful or not.
+func (r *ReasoningAgent) GetTranscript() []ReasoningStep {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+	return append([]ReasoningStep(nil), r.Transcript...)
+}
+
+func (r *ReasoningAgent) doExecute(ctx context.Context, input interface{}) (interface{}, error) {
+	r.Mutex.RLock()
+	goal := r.Goal
+	model := r.LLM
+	maxSteps, budget := r.MaxSteps, r.TokenBudget
+	systemPrompt := r.SystemPrompt
+	detect := r.FinalAnswerDetector
+	r.Mutex.RUnlock()
+
+	if text, ok := input.(string); ok && text != "" {
+		goal = text
+	}
+	if goal == "" {
+		return nil, retry.Permanent(errors.New("no goal provided for reasoning"))
+	}
+	if model == nil {
+		return nil, retry.Permanent(errors.New("reasoning requires an llm"))
+	}
+	if detect == nil {
+		detect = DetectFinalAnswer
+	}
+	if maxSteps <= 0 {
+		maxSteps = DefaultMaxSteps
+	}
+
+	r.Logger.Info("Reasoning towards goal: %s", goal)
+	messages, err := r.initialMessages(goal, systemPrompt)
+	if err != nil {
+		return nil, err
+	}
+	var transcript []ReasoningStep
+	tokens := 0
+	defer func() {
+		r.Mutex.Lock()
+		r.Transcript = transcript
+		r.TokensUsed = tokens
+		r.Mutex.Unlock()
+	}()
+
+	for index := 1; index <= maxSteps; index++ {
+		response, err := model.Chat(ctx, llm.Request{Messages: messages, Stop: []string{"\n" + observationMarker}})
+		if err != nil {
+			return nil, fmt.Errorf("reasoning step %d: %w", index, err)
+		}
+		tokens += response.Usage.TotalTokens
+
+		output := strings.TrimSpace(response.Message.Content)
+		step := ReasoningStep{Index: index, Output: output, Tokens: response.Usage.TotalTokens, Timestamp: time.Now()}
+		messages = append(messages, llm.Message{Role: llm.RoleAssistant, Content: output})
+
+		if answer, ok := detect(output); ok {
+			step.Thought = parseField(output, "Thought:")
+			step.FinalAnswer = answer
+			transcript = append(transcript, step)
+			r.stepCompleted(goal, step)
+
+			r.Mutex.Lock()
+			r.FinalAnswer = answer
+			r.Mutex.Unlock()
+			r.Logger.Info("Reached final answer after %d steps", index)
+			return answer, nil
+		}
+
+		step.Thought, step.Action, step.ActionInput, err = ParseReActAction(output)
+		if err != nil {
+			step.Observation = "Invalid format: " + err.Error() + ". Reply with an Action and Action Input, or a Final Answer."
+		} else {
+			result, err := r.InvokeTool(ctx, step.Action, step.ActionInput)
+			step.Observation = formatObservation(result, err)
+		}
+		transcript = append(transcript, step)
+		r.stepCompleted(goal, step)
+		messages = append(messages, llm.Message{Role: llm.RoleUser, Content: observationMarker + " " + step.Observation})
+
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if budget > 0 && tokens >= budget {
+			return nil, retry.Permanent(fmt.Errorf("%w (used %d of %d tokens)", ErrTokenBudgetExceeded, tokens, budget))
+		}
+	}
+	return nil, retry.Permanent(fmt.Errorf("%w (%d steps)", ErrMaxStepsExceeded, maxSteps))
+}
+
+// initialMessages returns the conversation a run starts from: the agent's
+// prompt template rendered with the variables "agent", "goal", "tools" (a
+// list of maps with "name", "description" and "parameters") and
+// "tool_names" if the "prompt" setting selects one, and otherwise the system
+// prompt, by default the ReAct instructions, followed by the goal.
+func (r *ReasoningAgent) initialMessages(goal, systemPrompt string) ([]llm.Message, error) {
+	var descriptions strings.Builder
+	var names []string
+	var toolVars []interface{}
+	for _, tool := range r.GetTools().List() {
+		names = append(names, tool.Name())
+		schema := "{}"
+		if parameters := tool.Parameters(); parameters != nil {
+			if data, err := json.Marshal(parameters); err == nil {
+				schema = string(data)
+			}
+		}
+		fmt.Fprintf(&descriptions, "- %s: %s Arguments: %s\n", tool.Name(), tool.Description(), schema)
+		toolVars = append(toolVars, map[string]interface{}{
+			"name":        tool.Name(),
+			"description": tool.Description(),
+			"parameters":  schema,
+		})
+	}
+
+	r.Mutex.RLock()
+	template := r.PromptTemplate
+	r.Mutex.RUnlock()
+	if template != "" {
+		return r.RenderPrompt(map[string]interface{}{
+			"agent":      r.Name,
+			"goal":       goal,
+			"tools":      toolVars,
+			"tool_names": names,
+		})
+	}
+
+	if systemPrompt == "" {
+		systemPrompt = fmt.Sprintf(defaultReActTemplate, r.Name, descriptions.String(), strings.Join(names, ", "))
+	}
+	return []llm.Message{
+		{Role: llm.RoleSystem, Content: systemPrompt},
+		{Role: llm.RoleUser, Content: "Goal: " + goal},
+	}, nil
+}
+
+func (r *ReasoningAgent) stepCompleted(goal string, step ReasoningStep) {
+	r.Logger.Debug("Reasoning step %d: action=%q observation=%q", step.Index, step.Action, step.Observation)
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+	r.publish(ReasoningStepCompleted{AgentEvent: r.newAgentEvent(), Goal: goal, Step: step})
+}
+
+// DetectFinalAnswer is the default final-answer detector: it returns the text
+// following a "Final Answer:" marker.
+func DetectFinalAnswer(output string) (string, bool) {
+	index := strings.LastIndex(output, finalAnswerMarker)
+	if index < 0 {
+		return "", false
+	}
+	return strings.TrimSpace(output[index+len(finalAnswerMarker):]), true
+}
+
+var actionPattern = regexp.MustCompile(`(?m)^\s*Action\s*:\s*(.+?)\s*$`)
+
+// ParseReActAction parses a ReAct model output into its thought, the tool
+// named by its "Action:" line and the JSON object following "Action Input:".
+// An empty or missing action input means no arguments.
+func ParseReActAction(output string) (thought, action string, input map[string]interface{}, err error) {
+	thought = parseField(output, "Thought:")
+	match := actionPattern.FindStringSubmatch(output)
+	if match == nil {
+		return thought, "", nil, errors.New("missing action")
+	}
+	action = strings.Trim(match[1], "`\"' ")
+
+	input = map[string]interface{}{}
+	raw := parseField(output, "Action Input:")
+	raw = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(raw, "```json"), "```"), "```"))
+	if raw == "" || raw == "{}" {
+		return thought, action, input, nil
+	}
+	if start, end := strings.Index(raw, "{"), strings.LastIndex(raw, "}"); start >= 0 && end > start {
+		raw = raw[start : end+1]
+	}
+	if err := json.Unmarshal([]byte(raw), &input); err != nil {
+		return thought, action, nil, fmt.Errorf("action input is not a JSON object: %v", err)
+	}
+	return thought, action, input, nil
+}
+
+// parseField returns the text following marker up to the next ReAct marker.
+func parseField(output, marker string) string {
+	index := strings.Index(output, marker)
+	if index < 0 {
+		return ""
+	}
+	rest := output[index+len(marker):]
+	end := len(rest)
+	for _, next := range []string{"\nThought:", "\nAction:", "\nAction Input:", "\n" + observationMarker, "\n" + finalAnswerMarker} {
+		if i := strings.Index(rest, next); i >= 0 && i < end {
+			end = i
+		}
+	}
+	return strings.TrimSpace(rest[:end])
+}
+
+// formatObservation renders a tool result, or its error, for the model.
+func formatObservation(result interface{}, err error) string {
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	switch v := result.(type) {
+	case nil:
+		return "(no result)"
+	case string:
+		return v
+	}
+	if data, err := json.Marshal(result); err == nil {
+		return string(data)
+	}
+	return fmt.Sprint(result)
+}
@@ -0,0 +1,109 @@
+package agents
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultStateHistorySize is the number of state transitions an agent keeps
+// unless configured otherwise with the "state_history_size" setting.
+const DefaultStateHistorySize = 50
+
+// validTransitions lists, for each state, the states an agent may move to.
+// StateShutdown is terminal.
+var validTransitions = map[AgentState][]AgentState{
+	StateInitializing: {StateReady, StateError, StateShutdown},
+	StateReady:        {StateReady, StateRunning, StatePaused, StateError, StateShutdown},
+	StateRunning:      {StateRunning, StateReady, StatePaused, StateError, StateShutdown},
+	StatePaused:       {StateReady, StateRunning, StateError, StateShutdown},
+	StateError:        {StateReady, StateRunning, StateShutdown},
+	StateShutdown:     {},
+}
+
+// ErrInvalidTransition is returned when an agent is asked to move to a state
+// that is not reachable from its current state.
+type ErrInvalidTransition struct {
+	Agent string
+	From  AgentState
+	To    AgentState
+}
+
+// Error implements the error interface.
+func (e *ErrInvalidTransition) Error() string {
+	return fmt.Sprintf("agent %s: invalid state transition from %s to %s", e.Agent, e.From, e.To)
+}
+
+// StateTransition records a single change of an agent's state.
+type StateTransition struct {
+	From      AgentState `json:"from"`
+	To        AgentState `json:"to"`
+	Timestamp time.Time  `json:"timestamp"`
+	Reason    string     `json:"reason"`
+}
+
+// CanTransition reports whether an agent may move from one state to another.
+func CanTransition(from, to AgentState) bool {
+	for _, allowed := range validTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// setState validates and applies a state transition, records it in the
+// agent's history and publishes a StateChanged event. The caller must hold b.Mutex.
+func (b *BaseAgent) setState(state AgentState, reason string) error {
+	from := b.State
+	if from == "" {
+		from = StateInitializing
+	}
+
+	if !CanTransition(from, state) {
+		b.Logger.Warning("Rejected state change from %s to %s (%s)", from, state, reason)
+		return &ErrInvalidTransition{Agent: b.Name, From: from, To: state}
+	}
+
+	b.State = state
+	b.LastActiveTime = time.Now()
+	b.recordTransition(StateTransition{
+		From:      from,
+		To:        state,
+		Timestamp: b.LastActiveTime,
+		Reason:    reason,
+	})
+	b.Logger.Info("State changed to: %s (%s)", state, reason)
+
+	b.publish(StateChanged{
+		AgentEvent: AgentEvent{Agent: b.Name, Timestamp: b.LastActiveTime},
+		From:       from,
+		To:         state,
+		Reason:     reason,
+	})
+	return nil
+}
+
+// recordTransition appends a transition to the bounded history, dropping the
+// oldest entries once StateHistorySize is exceeded. The caller must hold b.Mutex.
+func (b *BaseAgent) recordTransition(transition StateTransition) {
+	size := b.StateHistorySize
+	if size <= 0 {
+		size = DefaultStateHistorySize
+	}
+
+	b.StateHistory = append(b.StateHistory, transition)
+	if overflow := len(b.StateHistory) - size; overflow > 0 {
+		b.StateHistory = append([]StateTransition(nil), b.StateHistory[overflow:]...)
+	}
+}
+
+// GetStateHistory returns a copy of the agent's recorded state transitions,
+// oldest first.
+func (b *BaseAgent) GetStateHistory() []StateTransition {
+	b.Mutex.RLock()
+	defer b.Mutex.RUnlock()
+
+	history := make([]StateTransition, len(b.StateHistory))
+	copy(history, b.StateHistory)
+	return history
+}
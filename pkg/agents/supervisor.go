@@ -0,0 +1,401 @@
+package agents
+
+import (
+	"beluga/pkg/events"
+	"beluga/pkg/monitoring"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RestartStrategy determines which children a Supervisor restarts when one of
+// them fails.
+type RestartStrategy string
+
+const (
+	// OneForOne restarts only the child that failed.
+	OneForOne RestartStrategy = "one_for_one"
+	// OneForAll restarts every child when one of them fails.
+	OneForAll RestartStrategy = "one_for_all"
+	// RestForOne restarts the child that failed and every child added after it.
+	RestForOne RestartStrategy = "rest_for_one"
+)
+
+// Supervisor defaults used when the fields are left unset.
+const (
+	DefaultMaxRestarts    = 3
+	DefaultRestartPeriod  = 5 * time.Second
+	DefaultCheckInterval  = time.Second
+	DefaultRestartTimeout = 5 * time.Second
+)
+
+// Restartable is implemented by agents a Supervisor can look after. BaseAgent
+// and the agents built on it implement it, and so does Supervisor itself, which
+// is how supervisors are nested.
+type Restartable interface {
+	GetState() AgentState
+	Restart(timeout time.Duration) error
+}
+
+// ErrRestartIntensity is reported by a Supervisor that gave up because its
+// children failed more than MaxRestarts times within Period.
+type ErrRestartIntensity struct {
+	Supervisor  string
+	MaxRestarts int
+	Period      time.Duration
+}
+
+// Error implements the error interface.
+func (e *ErrRestartIntensity) Error() string {
+	return fmt.Sprintf("supervisor %s exceeded %d restarts in %v", e.Supervisor, e.MaxRestarts, e.Period)
+}
+
+// supervisedChild is a child of a Supervisor.
+type supervisedChild struct {
+	name  string
+	agent Restartable
+}
+
+// Supervisor watches a set of agents and restarts them when they enter
+// StateError, following its RestartStrategy. If the children fail more than
+// MaxRestarts times within Period the supervisor gives up: it enters
+// StateError itself, which escalates the failure to its parent supervisor, if
+// any, so that the parent can restart the whole subtree.
+type Supervisor struct {
+	Name           string
+	Strategy       RestartStrategy
+	MaxRestarts    int
+	Period         time.Duration
+	CheckInterval  time.Duration
+	RestartTimeout time.Duration
+	Logger         *monitoring.Logger
+
+	registry *AgentRegistry
+	parent   *Supervisor
+	mutex    sync.Mutex
+	children []supervisedChild
+	state    AgentState
+	restarts []time.Time
+	failure  error
+	wake     chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewSupervisor creates a supervisor for agents held in registry. Children are
+// added with Supervise, AddChild or AddSupervisor, and watched once Start is called.
+func NewSupervisor(name string, registry *AgentRegistry, strategy RestartStrategy) *Supervisor {
+	return &Supervisor{
+		Name:           name,
+		Strategy:       strategy,
+		MaxRestarts:    DefaultMaxRestarts,
+		Period:         DefaultRestartPeriod,
+		CheckInterval:  DefaultCheckInterval,
+		RestartTimeout: DefaultRestartTimeout,
+		Logger:         monitoring.NewLogger("supervisor_" + name),
+		registry:       registry,
+		state:          StateReady,
+		wake:           make(chan struct{}, 1),
+	}
+}
+
+// Supervise adds the named agents from the supervisor's registry as children,
+// in order. The order matters for the rest_for_one strategy.
+func (s *Supervisor) Supervise(names ...string) error {
+	if s.registry == nil {
+		return fmt.Errorf("supervisor %s has no agent registry", s.Name)
+	}
+	for _, name := range names {
+		agent, exists := s.registry.GetAgent(name)
+		if !exists {
+			return fmt.Errorf("agent %s not found in registry", name)
+		}
+		child, ok := agent.(Restartable)
+		if !ok {
+			return fmt.Errorf("agent %s (%T) cannot be supervised: it does not implement Restart", name, agent)
+		}
+		s.AddChild(name, child)
+	}
+	return nil
+}
+
+// SuperviseRegistry adds every agent in the supervisor's registry that can be
+// restarted, dependencies before the agents that depend on them, so that
+// rest_for_one restarts an agent together with everything that depends on it.
+func (s *Supervisor) SuperviseRegistry() error {
+	if s.registry == nil {
+		return fmt.Errorf("supervisor %s has no agent registry", s.Name)
+	}
+	order, err := s.registry.ShutdownOrder()
+	if err != nil {
+		return err
+	}
+	for i := len(order) - 1; i >= 0; i-- {
+		agent, _ := s.registry.GetAgent(order[i])
+		if child, ok := agent.(Restartable); ok {
+			s.AddChild(order[i], child)
+		}
+	}
+	return nil
+}
+
+// AddChild adds an agent as the supervisor's last child.
+func (s *Supervisor) AddChild(name string, agent Restartable) {
+	s.mutex.Lock()
+	s.children = append(s.children, supervisedChild{name: name, agent: agent})
+	s.mutex.Unlock()
+
+	// Agents that report state changes are checked as soon as they fail
+	// rather than at the next poll.
+	if notifier, ok := agent.(interface {
+		Subscribe(pattern string, handler events.Handler) *events.Subscription
+	}); ok {
+		notifier.Subscribe(EventStateChanged, func(event events.Event) {
+			if changed, ok := event.(StateChanged); ok && changed.To == StateError {
+				s.notify()
+			}
+		})
+	}
+}
+
+// AddSupervisor adds child as a nested supervisor. When child gives up it
+// escalates to s, which restarts it according to s's strategy.
+func (s *Supervisor) AddSupervisor(child *Supervisor) {
+	child.mutex.Lock()
+	child.parent = s
+	child.mutex.Unlock()
+	s.AddChild(child.Name, child)
+}
+
+// Start begins watching the children.
+func (s *Supervisor) Start() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.state == StateShutdown {
+		return fmt.Errorf("supervisor %s has been stopped", s.Name)
+	}
+	if s.stop != nil {
+		return nil // Already watching
+	}
+	s.startLocked()
+	s.Logger.Info("Supervising %d agents with strategy %s", len(s.children), s.Strategy)
+	return nil
+}
+
+// Stop stops watching the children and waits for the supervisor to finish any
+// restart in progress. The children themselves keep running.
+func (s *Supervisor) Stop() {
+	s.mutex.Lock()
+	stop, done := s.stop, s.done
+	s.stop, s.done = nil, nil
+	s.state = StateShutdown
+	s.mutex.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+// GetState returns StateRunning while the supervisor watches its children,
+// StateError once it has given up and StateShutdown after Stop.
+func (s *Supervisor) GetState() AgentState {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.state
+}
+
+// Err returns the reason the supervisor gave up, or nil.
+func (s *Supervisor) Err() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.failure
+}
+
+// Restart restarts every child in order, clears the restart history and
+// resumes watching the children. Parent supervisors call it to recover a
+// supervisor that has given up.
+func (s *Supervisor) Restart(timeout time.Duration) error {
+	s.mutex.Lock()
+	if s.state == StateShutdown {
+		s.mutex.Unlock()
+		return fmt.Errorf("supervisor %s has been stopped", s.Name)
+	}
+	children := make([]supervisedChild, len(s.children))
+	copy(children, s.children)
+	s.mutex.Unlock()
+
+	s.Logger.Info("Restarting supervision tree")
+	var errs []error
+	for _, child := range children {
+		if err := child.agent.Restart(timeout); err != nil {
+			errs = append(errs, fmt.Errorf("failed to restart %s: %w", child.name, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.restarts = nil
+	s.failure = nil
+	if s.stop == nil && s.state != StateShutdown {
+		s.startLocked()
+	}
+	return nil
+}
+
+// GetRestartCount returns the number of restarts within the current period.
+func (s *Supervisor) GetRestartCount() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.recentRestarts(time.Now()))
+}
+
+// startLocked starts the watch loop. The caller must hold s.mutex.
+func (s *Supervisor) startLocked() {
+	s.state = StateRunning
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	go s.watch(s.stop, s.done)
+}
+
+// notify wakes the watch loop without blocking.
+func (s *Supervisor) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Supervisor) watch(stop, done chan struct{}) {
+	defer close(done)
+
+	interval := s.CheckInterval
+	if interval <= 0 {
+		interval = DefaultCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if !s.check() {
+			return
+		}
+		select {
+		case <-stop:
+			return
+		case <-s.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// check restarts failed children according to the strategy. It returns false
+// if the supervisor gave up.
+func (s *Supervisor) check() bool {
+	s.mutex.Lock()
+	children := make([]supervisedChild, len(s.children))
+	copy(children, s.children)
+	s.mutex.Unlock()
+
+	failed := -1
+	for i, child := range children {
+		if child.agent.GetState() == StateError {
+			failed = i
+			break
+		}
+	}
+	if failed < 0 {
+		return true
+	}
+
+	var toRestart []supervisedChild
+	switch s.Strategy {
+	case OneForAll:
+		toRestart = children
+	case RestForOne:
+		toRestart = children[failed:]
+	default:
+		for _, child := range children[failed:] {
+			if child.agent.GetState() == StateError {
+				toRestart = append(toRestart, child)
+			}
+		}
+	}
+
+	if !s.recordRestart() {
+		s.giveUp()
+		return false
+	}
+
+	s.Logger.Warning("Agent %s failed, restarting %d agent(s) (%s)", children[failed].name, len(toRestart), s.Strategy)
+	timeout := s.RestartTimeout
+	if timeout <= 0 {
+		timeout = DefaultRestartTimeout
+	}
+	for _, child := range toRestart {
+		if err := child.agent.Restart(timeout); err != nil {
+			s.Logger.Error("Failed to restart %s: %v", child.name, err)
+		}
+	}
+	return true
+}
+
+// recordRestart records a restart and reports whether it is within the
+// restart intensity.
+func (s *Supervisor) recordRestart() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	s.restarts = append(s.recentRestarts(now), now)
+	return len(s.restarts) <= s.maxRestarts()
+}
+
+// recentRestarts returns the restarts within the period ending at now. The
+// caller must hold s.mutex.
+func (s *Supervisor) recentRestarts(now time.Time) []time.Time {
+	period := s.period()
+	var recent []time.Time
+	for _, at := range s.restarts {
+		if now.Sub(at) < period {
+			recent = append(recent, at)
+		}
+	}
+	return recent
+}
+
+func (s *Supervisor) period() time.Duration {
+	if s.Period <= 0 {
+		return DefaultRestartPeriod
+	}
+	return s.Period
+}
+
+func (s *Supervisor) maxRestarts() int {
+	if s.MaxRestarts < 0 {
+		return 0
+	}
+	return s.MaxRestarts
+}
+
+// giveUp stops the supervisor and escalates to its parent.
+func (s *Supervisor) giveUp() {
+	s.mutex.Lock()
+	s.failure = &ErrRestartIntensity{Supervisor: s.Name, MaxRestarts: s.maxRestarts(), Period: s.period()}
+	s.state = StateError
+	s.stop, s.done = nil, nil
+	parent := s.parent
+	s.mutex.Unlock()
+
+	if parent != nil {
+		s.Logger.Error("%v, escalating to supervisor %s", s.failure, parent.Name)
+		parent.notify()
+	} else {
+		s.Logger.Error("%v, giving up", s.failure)
+	}
+}
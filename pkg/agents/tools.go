@@ -0,0 +1,96 @@
+package agents
+
+import (
+	"beluga/pkg/llm"
+	"beluga/pkg/tools"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// parseTools returns the registry described by the "tools" setting: a
+// *tools.Registry, or a list of names of tools in the default registry.
+func parseTools(raw interface{}) (*tools.Registry, error) {
+	switch v := raw.(type) {
+	case *tools.Registry:
+		return v, nil
+	case []string:
+		return tools.DefaultRegistry().Subset(v...)
+	case []interface{}:
+		names := make([]string, len(v))
+		for i, name := range v {
+			s, ok := name.(string)
+			if !ok {
+				return nil, fmt.Errorf("tool names must be strings, got %T", name)
+			}
+			names[i] = s
+		}
+		return tools.DefaultRegistry().Subset(names...)
+	default:
+		return nil, fmt.Errorf("unsupported tools setting type %T", raw)
+	}
+}
+
+// GetTools returns the tools available to the agent: its own registry, or
+// an empty registry if it was not given one. Tools of the default registry
+// are only available if the "tools" setting lists them.
+func (b *BaseAgent) GetTools() *tools.Registry {
+	b.Mutex.RLock()
+	defer b.Mutex.RUnlock()
+	if b.Tools == nil {
+		return tools.NewRegistry()
+	}
+	return b.Tools
+}
+
+// SetTools replaces the agent's tool registry. Passing nil leaves the agent
+// without tools.
+func (b *BaseAgent) SetTools(registry *tools.Registry) {
+	b.Mutex.Lock()
+	defer b.Mutex.Unlock()
+	b.Tools = registry
+}
+
+// InvokeTool validates args and invokes the named tool of the agent's
+// registry, publishing a ToolInvoked event.
+func (b *BaseAgent) InvokeTool(ctx context.Context, name string, args map[string]interface{}) (interface{}, error) {
+	b.Logger.Info("Invoking tool %s", name)
+	result, err := b.GetTools().Invoke(ctx, name, args)
+	b.toolInvoked(name, args, result, err)
+	return result, err
+}
+
+func (b *BaseAgent) toolInvoked(name string, args map[string]interface{}, result interface{}, err error) {
+	if err != nil {
+		b.Logger.Warning("Tool %s failed: %v", name, err)
+	}
+	b.Mutex.RLock()
+	defer b.Mutex.RUnlock()
+	b.publish(ToolInvoked{AgentEvent: b.newAgentEvent(), Tool: name, Args: args, Result: result, Err: err})
+}
+
+// RunToolLoop lets the agent's language model answer the conversation using
+// the agent's tools, invoking the tools it calls until it gives a final
+// response. Every invocation is published as a ToolInvoked event.
+func (b *BaseAgent) RunToolLoop(ctx context.Context, messages ...llm.Message) (*tools.LoopResult, error) {
+	b.Mutex.RLock()
+	model, maxIterations := b.LLM, b.MaxToolIterations
+	b.Mutex.RUnlock()
+	if model == nil {
+		return nil, errors.New("agent " + b.Name + " has no llm configured")
+	}
+
+	loop := &tools.Loop{
+		LLM:           model,
+		Registry:      b.GetTools(),
+		MaxIterations: maxIterations,
+		OnInvocation: func(invocation tools.Invocation) {
+			b.toolInvoked(invocation.Call.Function.Name, invocation.Args, invocation.Result, invocation.Err)
+		},
+	}
+	result, err := loop.Run(ctx, llm.Request{Messages: messages})
+	if err != nil {
+		return result, fmt.Errorf("agent %s: %w", b.Name, err)
+	}
+	return result, nil
+}
@@ -0,0 +1,273 @@
+package analysis
+
+import (
+	"beluga/pkg/codec"
+	"beluga/pkg/llm"
+	"beluga/pkg/memory"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrUnknownAnalysis is returned when no analyzer is registered for an
+// analysis type.
+var ErrUnknownAnalysis = errors.New("unknown analysis type")
+
+// Result is the structured outcome of an analysis. Type names the analysis
+// that produced it.
+type Result interface {
+	Type() string
+}
+
+// Analyzer analyzes input data. Inputs are strings, numbers, records such as
+// codec.Record or map[string]interface{}, lists of these or a *codec.Dataset.
+type Analyzer interface {
+	Analyze(ctx context.Context, input interface{}) (Result, error)
+}
+
+// Recalled is what an agent recalls before an analysis: its earlier
+// interactions, oldest first, and the records most related to the input.
+type Recalled struct {
+	History []memory.Entry
+	Related []memory.SearchResult
+}
+
+// String describes what was recalled to a model, or is empty if nothing was.
+func (r Recalled) String() string {
+	var b strings.Builder
+	if len(r.History) > 0 {
+		b.WriteString("Earlier interactions, oldest first:")
+		for _, entry := range r.History {
+			fmt.Fprintf(&b, "\n- input: %s\n  output: %s", memory.ContentOf(entry.Input), memory.ContentOf(entry.Output))
+		}
+	}
+	if len(r.Related) > 0 {
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+		}
+		b.WriteString("Related records, most similar first:")
+		for _, result := range r.Related {
+			fmt.Fprintf(&b, "\n- %s (similarity %.2f)", result.Document.Content, result.Score)
+		}
+	}
+	return b.String()
+}
+
+// RecallAnalyzer is an Analyzer that takes what the agent recalled into
+// account.
+type RecallAnalyzer interface {
+	Analyzer
+	AnalyzeWith(ctx context.Context, input interface{}, recalled Recalled) (Result, error)
+}
+
+// AnalyzeWith analyzes input with what the agent recalled if analyzer is a
+// RecallAnalyzer, and without it otherwise.
+func AnalyzeWith(ctx context.Context, analyzer Analyzer, input interface{}, recalled Recalled) (Result, error) {
+	if recaller, ok := analyzer.(RecallAnalyzer); ok {
+		return recaller.AnalyzeWith(ctx, input, recalled)
+	}
+	return analyzer.Analyze(ctx, input)
+}
+
+// Config is what an analyzer is created from.
+type Config struct {
+	// Settings are the agent's settings; each analyzer reads its own keys.
+	Settings map[string]interface{}
+	// LLM is the agent's language model, if it has one.
+	LLM llm.LLM
+	// Prompt, if set, renders the agent's prompt template to the messages a
+	// model-backed analyzer sends.
+	Prompt func(vars map[string]interface{}) ([]llm.Message, error)
+}
+
+// Factory creates an analyzer from its configuration.
+type Factory func(config Config) (Analyzer, error)
+
+// Registry maps analysis types to analyzer factories.
+type Registry struct {
+	mutex     sync.RWMutex
+	factories map[string]Factory
+}
+
+// NewRegistry creates a registry holding the built-in analyzers: summary
+// (also registered as statistical and basic), keyword (also tfidf),
+// sentiment and llm.
+func NewRegistry() *Registry {
+	r := &Registry{factories: make(map[string]Factory)}
+	builtins := []struct {
+		names   []string
+		factory Factory
+	}{
+		{[]string{"summary", "statistical", "basic"}, NewSummaryAnalyzer},
+		{[]string{"keyword", "tfidf"}, NewKeywordAnalyzer},
+		{[]string{"sentiment"}, NewSentimentAnalyzer},
+		{[]string{"llm"}, NewLLMAnalyzer},
+	}
+	for _, builtin := range builtins {
+		for _, name := range builtin.names {
+			r.MustRegister(name, builtin.factory)
+		}
+	}
+	return r
+}
+
+var defaultRegistry = NewRegistry()
+
+// DefaultRegistry returns the process-wide registry agents create analyzers
+// from unless given their own.
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
+// Register adds factory under a lower-case analysis type, which must not be
+// taken.
+func (r *Registry) Register(name string, factory Factory) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid analysis type %q", name)
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, exists := r.factories[name]; exists {
+		return fmt.Errorf("analysis type %s is already registered", name)
+	}
+	r.factories[name] = factory
+	return nil
+}
+
+// MustRegister is like Register but panics on error.
+func (r *Registry) MustRegister(name string, factory Factory) {
+	if err := r.Register(name, factory); err != nil {
+		panic(err)
+	}
+}
+
+// Has reports whether an analysis type is registered, case-insensitively.
+func (r *Registry) Has(name string) bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	_, ok := r.factories[strings.ToLower(name)]
+	return ok
+}
+
+// Names returns the registered analysis types in order.
+func (r *Registry) Names() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New creates the analyzer for an analysis type, case-insensitively.
+func (r *Registry) New(name string, config Config) (Analyzer, error) {
+	r.mutex.RLock()
+	factory, ok := r.factories[strings.ToLower(name)]
+	r.mutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAnalysis, name)
+	}
+	analyzer, err := factory(config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s analysis settings: %w", name, err)
+	}
+	return analyzer, nil
+}
+
+// records returns the records of input: the records of a dataset or list,
+// a map as a single record and any other value v as {"value": v}.
+func records(input interface{}) []map[string]interface{} {
+	switch v := input.(type) {
+	case nil:
+		return nil
+	case *codec.Dataset:
+		return records(v.Records)
+	case []codec.Record:
+		list := make([]map[string]interface{}, len(v))
+		for i, record := range v {
+			list[i] = record
+		}
+		return list
+	case []map[string]interface{}:
+		return v
+	case []interface{}:
+		var list []map[string]interface{}
+		for _, item := range v {
+			list = append(list, records(item)...)
+		}
+		return list
+	case []float64:
+		list := make([]map[string]interface{}, len(v))
+		for i, item := range v {
+			list[i] = map[string]interface{}{"value": item}
+		}
+		return list
+	case []int:
+		list := make([]map[string]interface{}, len(v))
+		for i, item := range v {
+			list[i] = map[string]interface{}{"value": item}
+		}
+		return list
+	case []string:
+		list := make([]map[string]interface{}, len(v))
+		for i, item := range v {
+			list[i] = map[string]interface{}{"value": item}
+		}
+		return list
+	case codec.Record:
+		return []map[string]interface{}{v}
+	case map[string]interface{}:
+		return []map[string]interface{}{v}
+	}
+	return []map[string]interface{}{{"value": input}}
+}
+
+// texts returns the documents of input: a string, every string of a list or
+// one document per record. A record's document is the string at field, a
+// dotted path, or if field is empty all of its string values in key order.
+func texts(input interface{}, field string) []string {
+	switch v := input.(type) {
+	case string:
+		return []string{v}
+	case []byte:
+		return []string{string(v)}
+	case []string:
+		return v
+	case fmt.Stringer:
+		return []string{v.String()}
+	}
+
+	var documents []string
+	for _, record := range records(input) {
+		if field != "" {
+			if text, ok := codec.Lookup(record, field).(string); ok {
+				documents = append(documents, text)
+			}
+			continue
+		}
+		keys := make([]string, 0, len(record))
+		for key, value := range record {
+			if _, ok := value.(string); ok {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		sort.Strings(keys)
+		parts := make([]string, len(keys))
+		for i, key := range keys {
+			parts[i] = record[key].(string)
+		}
+		documents = append(documents, strings.Join(parts, "\n"))
+	}
+	return documents
+}
@@ -0,0 +1,163 @@
+package analysis
+
+import (
+	"beluga/pkg/setting"
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"unicode"
+)
+
+// Keyword is a term ranked by its TF-IDF score.
+type Keyword struct {
+	Term  string  `json:"term"`
+	Score float64 `json:"score"`
+	// Count is the number of occurrences and Documents the number of
+	// documents the term occurs in.
+	Count     int `json:"count"`
+	Documents int `json:"documents"`
+}
+
+// KeywordResult holds the keywords of a set of documents.
+type KeywordResult struct {
+	Documents int `json:"documents"`
+	// Keywords are the highest scoring terms across all documents, best
+	// first.
+	Keywords []Keyword `json:"keywords"`
+	// PerDocument holds the keywords of each document if there are several.
+	PerDocument [][]Keyword `json:"per_document,omitempty"`
+}
+
+// Type implements Result.
+func (*KeywordResult) Type() string { return "keyword" }
+
+// KeywordAnalyzer extracts keywords by TF-IDF: a term scores high in a
+// document if it is frequent there and rare in the other documents.
+type KeywordAnalyzer struct {
+	// MaxKeywords limits the number of keywords reported.
+	MaxKeywords int
+	// MinLength is the minimum number of letters of a term.
+	MinLength int
+	// TextField is the dotted path of the text in record inputs; if empty,
+	// all string values of a record are analyzed.
+	TextField string
+	stopwords map[string]bool
+}
+
+// NewKeywordAnalyzer creates a KeywordAnalyzer from the settings "language",
+// whose stop words are ignored (default "en"), "max_keywords" (default 10),
+// "min_length" (default 3) and "text_field".
+func NewKeywordAnalyzer(config Config) (Analyzer, error) {
+	code, err := setting.String(config.Settings, "language", "en")
+	if err != nil {
+		return nil, err
+	}
+	lang, ok := supportedLanguage(code)
+	if !ok {
+		return nil, fmt.Errorf("unsupported language %s", code)
+	}
+	analyzer := &KeywordAnalyzer{stopwords: lang.stopwords}
+	if analyzer.MaxKeywords, err = setting.Int(config.Settings, "max_keywords", 10); err != nil {
+		return nil, err
+	}
+	if analyzer.MinLength, err = setting.Int(config.Settings, "min_length", 3); err != nil {
+		return nil, err
+	}
+	if analyzer.TextField, err = setting.String(config.Settings, "text_field", ""); err != nil {
+		return nil, err
+	}
+	return analyzer, nil
+}
+
+// Analyze implements Analyzer.
+func (k *KeywordAnalyzer) Analyze(ctx context.Context, input interface{}) (Result, error) {
+	documents := texts(input, k.TextField)
+	if len(documents) == 0 {
+		return nil, fmt.Errorf("no text to extract keywords from in %T", input)
+	}
+
+	// Term frequencies per document, and the number of documents per term
+	counts := make([]map[string]int, len(documents))
+	lengths := make([]int, len(documents))
+	frequency := make(map[string]int)
+	for i, document := range documents {
+		counts[i] = make(map[string]int)
+		for _, term := range tokenize(document) {
+			if !k.isTerm(term) {
+				continue
+			}
+			if counts[i][term] == 0 {
+				frequency[term]++
+			}
+			counts[i][term]++
+			lengths[i]++
+		}
+	}
+
+	// Inverse document frequency, smoothed so that terms of a single
+	// document or found in every document still count a little
+	n := float64(len(documents))
+	idf := make(map[string]float64, len(frequency))
+	for term, df := range frequency {
+		idf[term] = math.Log((1 + n) / float64(df))
+	}
+
+	result := &KeywordResult{Documents: len(documents)}
+	totals := make(map[string]*Keyword, len(frequency))
+	for i, terms := range counts {
+		var keywords []Keyword
+		for term, count := range terms {
+			score := float64(count) / float64(lengths[i]) * idf[term]
+			keywords = append(keywords, Keyword{Term: term, Score: score, Count: count, Documents: 1})
+
+			total, ok := totals[term]
+			if !ok {
+				total = &Keyword{Term: term}
+				totals[term] = total
+			}
+			total.Score += score / n
+			total.Count += count
+			total.Documents++
+		}
+		if len(documents) > 1 {
+			result.PerDocument = append(result.PerDocument, k.top(keywords))
+		}
+	}
+
+	keywords := make([]Keyword, 0, len(totals))
+	for _, keyword := range totals {
+		keywords = append(keywords, *keyword)
+	}
+	result.Keywords = k.top(keywords)
+	return result, nil
+}
+
+// isTerm reports whether a token is a candidate keyword.
+func (k *KeywordAnalyzer) isTerm(token string) bool {
+	if k.stopwords[token] {
+		return false
+	}
+	letters := 0
+	for _, r := range token {
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	return letters > 0 && letters >= k.MinLength
+}
+
+// top sorts keywords by descending score, then term, and keeps the first
+// MaxKeywords.
+func (k *KeywordAnalyzer) top(keywords []Keyword) []Keyword {
+	sort.Slice(keywords, func(i, j int) bool {
+		if keywords[i].Score != keywords[j].Score {
+			return keywords[i].Score > keywords[j].Score
+		}
+		return keywords[i].Term < keywords[j].Term
+	})
+	if k.MaxKeywords > 0 && len(keywords) > k.MaxKeywords {
+		keywords = keywords[:k.MaxKeywords]
+	}
+	return keywords
+}
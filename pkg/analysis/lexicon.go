@@ -0,0 +1,127 @@
+package analysis
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Lexicon maps words to sentiment weights between -4 (most negative) and 4
+// (most positive).
+type Lexicon map[string]float64
+
+// language holds the word lists of a supported language.
+type language struct {
+	stopwords map[string]bool
+	negators  map[string]bool
+	lexicon   Lexicon
+}
+
+// languages are the languages keyword and sentiment analysis support.
+var languages = map[string]*language{
+	"en": {
+		stopwords: wordSet(`a about above after again against all am an and any are as at be because been
+			before being below between both but by can could did do does doing down during each few for
+			from further had has have having he her here hers herself him himself his how i if in into is
+			it its itself just me more most my myself no nor not now of off on once only or other our ours
+			ourselves out over own same she should so some such than that the their theirs them themselves
+			then there these they this those through to too under until up very was we were what when where
+			which while who whom why will with would you your yours yourself yourselves also may might must
+			shall us it's i'm that's don't isn't wasn't aren't can't won't`),
+		negators: wordSet(`not no never none nobody nothing neither nor without cannot can't don't doesn't
+			didn't isn't wasn't aren't weren't won't wouldn't couldn't shouldn't hardly`),
+		lexicon: Lexicon{
+			"amazing": 4, "awesome": 4, "excellent": 3, "outstanding": 4, "perfect": 3, "wonderful": 4,
+			"fantastic": 4, "superb": 4, "brilliant": 4, "love": 3, "loved": 3, "loves": 3, "great": 3,
+			"delighted": 3, "happy": 3, "enjoy": 2, "enjoyed": 2, "enjoyable": 2, "good": 2, "nice": 2,
+			"positive": 2, "pleased": 2, "glad": 2, "like": 2, "liked": 2, "engaging": 2, "helpful": 2,
+			"recommend": 2, "recommended": 2, "satisfied": 2, "reliable": 2, "fast": 1, "easy": 1,
+			"useful": 2, "interesting": 2, "fun": 2, "beautiful": 3, "best": 3, "better": 2, "thanks": 2,
+			"thank": 2, "win": 2, "success": 2, "successful": 2, "improved": 2, "fine": 1, "ok": 1,
+			"terrible": -3, "awful": -3, "horrible": -3, "worst": -3, "hate": -3, "hated": -3, "hates": -3,
+			"bad": -3, "poor": -2, "disappointed": -2, "disappointing": -2, "annoying": -2, "angry": -3,
+			"sad": -2, "unhappy": -2, "broken": -2, "slow": -1, "fail": -2, "failed": -2, "failure": -2,
+			"failures": -2, "problem": -2, "problems": -2, "issue": -1, "issues": -1, "bug": -2, "bugs": -2,
+			"error": -2, "errors": -2, "crash": -2, "crashes": -2, "delay": -1, "delays": -1, "late": -1,
+			"useless": -2, "boring": -3, "confusing": -2, "difficult": -1, "expensive": -1, "worse": -3,
+			"negative": -2, "refund": -1, "complaint": -2, "wrong": -2, "lost": -2, "pain": -2,
+		},
+	},
+	"de": {
+		stopwords: wordSet(`aber alle als also am an auch auf aus bei bin bis bist da damit dann das dass
+			dem den der des die dies diese dieser doch dort du durch ein eine einem einen einer eines er es
+			für hat hatte haben hier ich ihr im in ist ja jetzt kann mit nach noch nur oder sehr sich sie
+			sind so über um und uns unter vom von vor war was weil wenn wie wir wird zu zum zur`),
+		negators: wordSet(`nicht kein keine keinen keiner keinem nie niemals nichts ohne weder`),
+		lexicon: Lexicon{
+			"ausgezeichnet": 3, "hervorragend": 4, "toll": 3, "super": 3, "großartig": 4, "wunderbar": 4,
+			"perfekt": 3, "gut": 2, "schön": 2, "liebe": 3, "lieben": 3, "empfehlen": 2, "zufrieden": 2,
+			"freude": 2, "froh": 2, "hilfreich": 2, "schnell": 1, "einfach": 1, "danke": 2, "positiv": 2,
+			"schlecht": -3, "schrecklich": -3, "furchtbar": -3, "hasse": -3, "enttäuscht": -2,
+			"enttäuschend": -2, "langsam": -1, "fehler": -2, "problem": -2, "probleme": -2, "kaputt": -2,
+			"ärgerlich": -2, "traurig": -2, "teuer": -1, "negativ": -2, "verspätung": -1, "falsch": -2,
+		},
+	},
+	"es": {
+		stopwords: wordSet(`a al algo como con de del el ella ellos en entre era es esa ese eso esta este
+			esto ha hay la las le lo los más me mi muy no nos o para pero por que se si sin sobre su sus
+			también te tu un una uno unos y ya yo`),
+		negators: wordSet(`no nunca jamás nada nadie ningún ninguna ninguno sin tampoco ni`),
+		lexicon: Lexicon{
+			"excelente": 3, "genial": 3, "increíble": 4, "maravilloso": 4, "perfecto": 3, "bueno": 2,
+			"buena": 2, "bien": 2, "encanta": 3, "amor": 3, "feliz": 3, "contento": 2, "recomiendo": 2,
+			"útil": 2, "rápido": 1, "fácil": 1, "gracias": 2, "positivo": 2, "mejor": 2,
+			"malo": -3, "mala": -3, "terrible": -3, "horrible": -3, "odio": -3, "peor": -3,
+			"decepcionado": -2, "decepcionante": -2, "lento": -1, "error": -2, "problema": -2,
+			"problemas": -2, "roto": -2, "triste": -2, "caro": -1, "negativo": -2, "retraso": -1,
+		},
+	},
+	"fr": {
+		stopwords: wordSet(`au aux avec ce ces dans de des du elle en est et eux il ils je la le les leur
+			lui ma mais me même mes moi mon ne nos notre nous on ou par pas pour qu que qui sa se ses son
+			sur ta te tes toi ton tu un une vos votre vous très`),
+		negators: wordSet(`ne pas jamais rien personne aucun aucune sans ni`),
+		lexicon: Lexicon{
+			"excellent": 3, "génial": 3, "incroyable": 4, "merveilleux": 4, "parfait": 3, "bon": 2,
+			"bonne": 2, "bien": 2, "adore": 3, "aime": 2, "heureux": 3, "content": 2, "recommande": 2,
+			"utile": 2, "rapide": 1, "facile": 1, "merci": 2, "positif": 2, "meilleur": 2,
+			"mauvais": -3, "mauvaise": -3, "terrible": -3, "horrible": -3, "déteste": -3, "pire": -3,
+			"déçu": -2, "décevant": -2, "lent": -1, "erreur": -2, "problème": -2, "problèmes": -2,
+			"cassé": -2, "triste": -2, "cher": -1, "négatif": -2, "retard": -1,
+		},
+	},
+}
+
+// supportedLanguage returns the word lists of a language code such as "en"
+// or "en-US".
+func supportedLanguage(code string) (*language, bool) {
+	code = strings.ToLower(code)
+	if i := strings.IndexAny(code, "-_"); i >= 0 {
+		code = code[:i]
+	}
+	lang, ok := languages[code]
+	return lang, ok
+}
+
+func wordSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(words) {
+		set[word] = true
+	}
+	return set
+}
+
+// tokenize splits text into lower-case words. Apostrophes within a word, as
+// in "don't", are kept.
+func tokenize(text string) []string {
+	text = strings.ToLower(strings.ReplaceAll(text, "’", "'"))
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+	tokens := words[:0]
+	for _, word := range words {
+		if word = strings.Trim(word, "'"); word != "" {
+			tokens = append(tokens, word)
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,136 @@
+package analysis
+
+import (
+	"beluga/pkg/llm"
+	"beluga/pkg/setting"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultInstructions tell the model what to do unless configured otherwise.
+const DefaultInstructions = "Analyze the data given by the user. Answer with a single JSON object " +
+	"holding your findings, without any text around it."
+
+// LLMResult is the answer of a language model to an analysis request.
+type LLMResult struct {
+	Model string `json:"model"`
+	// Text is the model's answer and Data the JSON object it holds, if any.
+	Text  string                 `json:"text"`
+	Data  map[string]interface{} `json:"data,omitempty"`
+	Usage llm.Usage              `json:"usage"`
+}
+
+// Type implements Result.
+func (*LLMResult) Type() string { return "llm" }
+
+// LLMAnalyzer asks a language model to analyze the input.
+type LLMAnalyzer struct {
+	Model llm.LLM
+	// Instructions are sent as the system message.
+	Instructions string
+	// RequireJSON fails the analysis if the answer holds no JSON object.
+	RequireJSON bool
+	// Prompt, if set, renders the messages instead, given the variables
+	// "input", "instructions", "language", "history", the recalled
+	// []memory.Entry, and "related", the recalled []memory.SearchResult.
+	Prompt   func(vars map[string]interface{}) ([]llm.Message, error)
+	language string
+}
+
+// NewLLMAnalyzer creates an LLMAnalyzer using the agent's model from the
+// settings "instructions", "require_json" and "language". The agent's prompt
+// template, if it has one, replaces the instructions.
+func NewLLMAnalyzer(config Config) (Analyzer, error) {
+	if config.LLM == nil {
+		return nil, errors.New("the llm analysis requires the llm setting")
+	}
+	analyzer := &LLMAnalyzer{Model: config.LLM, Prompt: config.Prompt}
+	var err error
+	if analyzer.Instructions, err = setting.String(config.Settings, "instructions", DefaultInstructions); err != nil {
+		return nil, err
+	}
+	if analyzer.language, err = setting.String(config.Settings, "language", ""); err != nil {
+		return nil, err
+	}
+	if raw, ok := config.Settings["require_json"]; ok {
+		if analyzer.RequireJSON, ok = raw.(bool); !ok {
+			return nil, fmt.Errorf("require_json must be a boolean, got %T", raw)
+		}
+	}
+	return analyzer, nil
+}
+
+// Analyze implements Analyzer. Inputs other than text are sent as JSON.
+func (l *LLMAnalyzer) Analyze(ctx context.Context, input interface{}) (Result, error) {
+	return l.AnalyzeWith(ctx, input, Recalled{})
+}
+
+// AnalyzeWith implements RecallAnalyzer. Without a prompt template, what was
+// recalled is sent as a system message after the instructions.
+func (l *LLMAnalyzer) AnalyzeWith(ctx context.Context, input interface{}, recalled Recalled) (Result, error) {
+	text, ok := input.(string)
+	if !ok {
+		data, err := json.MarshalIndent(input, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode the input for the model: %w", err)
+		}
+		text = string(data)
+	}
+
+	var messages []llm.Message
+	if l.Prompt != nil {
+		var err error
+		messages, err = l.Prompt(map[string]interface{}{
+			"input":        text,
+			"instructions": l.Instructions,
+			"language":     l.language,
+			"history":      recalled.History,
+			"related":      recalled.Related,
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		instructions := l.Instructions
+		if l.language != "" {
+			instructions += " Answer in the language " + l.language + "."
+		}
+		messages = []llm.Message{{Role: llm.RoleSystem, Content: instructions}}
+		if recollection := recalled.String(); recollection != "" {
+			messages = append(messages, llm.Message{Role: llm.RoleSystem, Content: recollection})
+		}
+		messages = append(messages, llm.Message{Role: llm.RoleUser, Content: text})
+	}
+
+	response, err := l.Model.Chat(ctx, llm.Request{Messages: messages})
+	if err != nil {
+		return nil, fmt.Errorf("analysis request failed: %w", err)
+	}
+	result := &LLMResult{
+		Model: response.Model,
+		Text:  response.Message.Content,
+		Data:  jsonObject(response.Message.Content),
+		Usage: response.Usage,
+	}
+	if l.RequireJSON && result.Data == nil {
+		return nil, fmt.Errorf("the model answered without a JSON object: %q", result.Text)
+	}
+	return result, nil
+}
+
+// jsonObject returns the JSON object in a model's answer, which may be
+// surrounded by text such as a Markdown code fence, or nil if there is none.
+func jsonObject(text string) map[string]interface{} {
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return nil
+	}
+	var object map[string]interface{}
+	if err := json.Unmarshal([]byte(text[start:end+1]), &object); err != nil {
+		return nil
+	}
+	return object
+}
@@ -0,0 +1,164 @@
+package analysis
+
+import (
+	"beluga/pkg/setting"
+	"context"
+	"fmt"
+	"math"
+)
+
+// Sentiment labels.
+const (
+	Positive = "positive"
+	Negative = "negative"
+	Neutral  = "neutral"
+)
+
+// negatedWeight scales the weight of a word preceded by a negation, so that
+// "not good" is mildly negative rather than as negative as "bad".
+const negatedWeight = -0.74
+
+// negationWindow is the number of words before a word a negation applies from.
+const negationWindow = 3
+
+// Sentiment is the sentiment of one document.
+type Sentiment struct {
+	Label string `json:"label"`
+	// Score is between -1 (most negative) and 1 (most positive).
+	Score float64 `json:"score"`
+}
+
+// SentimentResult holds the sentiment of a set of documents.
+type SentimentResult struct {
+	// Label and Score are the sentiment of all documents, the mean of their
+	// scores.
+	Label    string  `json:"label"`
+	Score    float64 `json:"score"`
+	Language string  `json:"language"`
+	// Positive and Negative list the words that contributed, in order.
+	Positive []string `json:"positive,omitempty"`
+	Negative []string `json:"negative,omitempty"`
+	// Documents holds the sentiment of each document if there are several.
+	Documents []Sentiment `json:"documents,omitempty"`
+}
+
+// Type implements Result.
+func (*SentimentResult) Type() string { return "sentiment" }
+
+// SentimentAnalyzer scores text against a sentiment lexicon, taking negations
+// into account. The sum of the weights of a document's words is normalized
+// to a score between -1 and 1; scores of at least Threshold are positive and
+// of at most -Threshold negative.
+type SentimentAnalyzer struct {
+	Language  string
+	Threshold float64
+	// TextField is the dotted path of the text in record inputs; if empty,
+	// all string values of a record are analyzed.
+	TextField string
+	lexicon   Lexicon
+	negators  map[string]bool
+}
+
+// NewSentimentAnalyzer creates a SentimentAnalyzer from the settings
+// "language" (default "en"), "threshold" (default 0.05), "text_field" and
+// "lexicon", a map of words to weights between -4 and 4 that adds to or
+// replaces entries of the language's lexicon.
+func NewSentimentAnalyzer(config Config) (Analyzer, error) {
+	code, err := setting.String(config.Settings, "language", "en")
+	if err != nil {
+		return nil, err
+	}
+	lang, ok := supportedLanguage(code)
+	if !ok {
+		return nil, fmt.Errorf("unsupported language %s", code)
+	}
+	analyzer := &SentimentAnalyzer{Language: code, lexicon: lang.lexicon, negators: lang.negators}
+	if analyzer.Threshold, err = setting.Float(config.Settings, "threshold", 0.05); err != nil {
+		return nil, err
+	}
+	if analyzer.Threshold < 0 || analyzer.Threshold > 1 {
+		return nil, fmt.Errorf("threshold must be between 0 and 1, got %v", analyzer.Threshold)
+	}
+	if analyzer.TextField, err = setting.String(config.Settings, "text_field", ""); err != nil {
+		return nil, err
+	}
+
+	if raw, ok := config.Settings["lexicon"]; ok {
+		custom, isMap := raw.(map[string]interface{})
+		if !isMap {
+			return nil, fmt.Errorf("lexicon must be a map of words to weights, got %T", raw)
+		}
+		analyzer.lexicon = make(Lexicon, len(lang.lexicon)+len(custom))
+		for word, weight := range lang.lexicon {
+			analyzer.lexicon[word] = weight
+		}
+		for word, value := range custom {
+			weight, ok := setting.Number(value)
+			if !ok || weight < -4 || weight > 4 {
+				return nil, fmt.Errorf("lexicon weight of %s must be a number between -4 and 4, got %v", word, value)
+			}
+			for _, token := range tokenize(word) {
+				analyzer.lexicon[token] = weight
+			}
+		}
+	}
+	return analyzer, nil
+}
+
+// Analyze implements Analyzer.
+func (s *SentimentAnalyzer) Analyze(ctx context.Context, input interface{}) (Result, error) {
+	documents := texts(input, s.TextField)
+	if len(documents) == 0 {
+		return nil, fmt.Errorf("no text to analyze sentiment of in %T", input)
+	}
+
+	result := &SentimentResult{Language: s.Language}
+	total := 0.0
+	for _, document := range documents {
+		score := s.score(document, result)
+		total += score
+		if len(documents) > 1 {
+			result.Documents = append(result.Documents, Sentiment{Label: s.label(score), Score: score})
+		}
+	}
+	result.Score = total / float64(len(documents))
+	result.Label = s.label(result.Score)
+	return result, nil
+}
+
+// score returns the normalized score of a document and records the words
+// that contributed to it in result.
+func (s *SentimentAnalyzer) score(document string, result *SentimentResult) float64 {
+	tokens := tokenize(document)
+	sum := 0.0
+	for i, token := range tokens {
+		weight, ok := s.lexicon[token]
+		if !ok {
+			continue
+		}
+		for j := i - 1; j >= 0 && j >= i-negationWindow; j-- {
+			if s.negators[tokens[j]] {
+				weight *= negatedWeight
+				break
+			}
+		}
+		if weight > 0 {
+			result.Positive = append(result.Positive, token)
+		} else if weight < 0 {
+			result.Negative = append(result.Negative, token)
+		}
+		sum += weight
+	}
+	// Normalize as VADER does, approaching ±1 as the evidence grows
+	return sum / math.Sqrt(sum*sum+15)
+}
+
+func (s *SentimentAnalyzer) label(score float64) string {
+	switch {
+	case score >= s.Threshold && score > 0:
+		return Positive
+	case score <= -s.Threshold && score < 0:
+		return Negative
+	}
+	return Neutral
+}
@@ -0,0 +1,154 @@
+package analysis
+
+import (
+	"beluga/pkg/setting"
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// DefaultPercentiles are the percentiles a summary reports unless configured.
+var DefaultPercentiles = []float64{25, 50, 75, 90, 95, 99}
+
+// Stats summarizes the numeric values of one field.
+type Stats struct {
+	// Count is the number of records with a numeric value for the field and
+	// Missing the number without.
+	Count   int     `json:"count"`
+	Missing int     `json:"missing"`
+	Sum     float64 `json:"sum"`
+	Mean    float64 `json:"mean"`
+	Min     float64 `json:"min"`
+	Max     float64 `json:"max"`
+	// StdDev is the sample standard deviation.
+	StdDev float64 `json:"std_dev"`
+	// Percentiles maps names such as "p50" and "p99.9" to the percentile,
+	// interpolated linearly between the closest values.
+	Percentiles map[string]float64 `json:"percentiles"`
+}
+
+// SummaryResult is the statistical summary of a set of records.
+type SummaryResult struct {
+	Records int `json:"records"`
+	// Fields holds the statistics of every numeric field by name. Fields of
+	// nested records are named by their dotted path, and a bare number is
+	// the field "value".
+	Fields map[string]*Stats `json:"fields"`
+}
+
+// Type implements Result.
+func (*SummaryResult) Type() string { return "summary" }
+
+// SummaryAnalyzer computes count, mean, spread and percentiles of the numeric
+// fields of its input.
+type SummaryAnalyzer struct {
+	Percentiles []float64
+	// Fields restricts the summary to these fields if set.
+	Fields []string
+}
+
+// NewSummaryAnalyzer creates a SummaryAnalyzer from the settings
+// "percentiles", a list of numbers between 0 and 100, and "fields".
+func NewSummaryAnalyzer(config Config) (Analyzer, error) {
+	percentiles, err := setting.Floats(config.Settings, "percentiles", DefaultPercentiles)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range percentiles {
+		if p < 0 || p > 100 {
+			return nil, fmt.Errorf("percentile %v is not between 0 and 100", p)
+		}
+	}
+
+	analyzer := &SummaryAnalyzer{Percentiles: percentiles}
+	if raw, ok := config.Settings["fields"]; ok {
+		switch v := raw.(type) {
+		case []string:
+			analyzer.Fields = v
+		case []interface{}:
+			for _, item := range v {
+				name, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("fields must be a list of strings, got %T", item)
+				}
+				analyzer.Fields = append(analyzer.Fields, name)
+			}
+		default:
+			return nil, fmt.Errorf("fields must be a list of strings, got %T", raw)
+		}
+	}
+	return analyzer, nil
+}
+
+// Analyze implements Analyzer. Numeric strings such as CSV cells that were
+// kept as text are not counted as numbers.
+func (s *SummaryAnalyzer) Analyze(ctx context.Context, input interface{}) (Result, error) {
+	list := records(input)
+	values := make(map[string][]float64)
+	for _, record := range list {
+		collect(record, "", values)
+	}
+	if len(s.Fields) > 0 {
+		selected := make(map[string][]float64, len(s.Fields))
+		for _, name := range s.Fields {
+			selected[name] = values[name]
+		}
+		values = selected
+	}
+
+	result := &SummaryResult{Records: len(list), Fields: make(map[string]*Stats, len(values))}
+	for name, numbers := range values {
+		result.Fields[name] = s.stats(numbers, len(list))
+	}
+	return result, nil
+}
+
+// collect adds the numeric values of record to values, by dotted path.
+func collect(record map[string]interface{}, prefix string, values map[string][]float64) {
+	for key, value := range record {
+		name := prefix + key
+		if nested, ok := value.(map[string]interface{}); ok {
+			collect(nested, name+".", values)
+			continue
+		}
+		if f, ok := setting.Number(value); ok && !math.IsNaN(f) {
+			values[name] = append(values[name], f)
+		}
+	}
+}
+
+func (s *SummaryAnalyzer) stats(values []float64, records int) *Stats {
+	stats := &Stats{Count: len(values), Missing: records - len(values), Percentiles: make(map[string]float64)}
+	if len(values) == 0 {
+		return stats
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	for _, v := range sorted {
+		stats.Sum += v
+	}
+	stats.Mean = stats.Sum / float64(len(sorted))
+	stats.Min, stats.Max = sorted[0], sorted[len(sorted)-1]
+	if len(sorted) > 1 {
+		squares := 0.0
+		for _, v := range sorted {
+			squares += (v - stats.Mean) * (v - stats.Mean)
+		}
+		stats.StdDev = math.Sqrt(squares / float64(len(sorted)-1))
+	}
+	for _, p := range s.Percentiles {
+		stats.Percentiles["p"+strconv.FormatFloat(p, 'f', -1, 64)] = percentile(sorted, p)
+	}
+	return stats
+}
+
+// percentile returns the p-th percentile of sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
@@ -0,0 +1,257 @@
+package codec
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrUnknownCodec is returned when no codec is registered for a format.
+var ErrUnknownCodec = errors.New("unknown data format")
+
+// Record is a decoded record. Values are nil, bool, int64, float64, string,
+// []interface{} or map[string]interface{}.
+type Record map[string]interface{}
+
+// Decoder reads records one at a time. Next returns io.EOF after the last
+// record.
+type Decoder interface {
+	Next() (Record, error)
+}
+
+// Codec decodes raw bytes in one data format into records.
+type Codec interface {
+	Name() string
+	NewDecoder(r io.Reader) (Decoder, error)
+}
+
+// Configurable is implemented by codecs that accept options, such as the
+// delimiter of CSV. WithOptions returns a copy of the codec configured with
+// options.
+type Configurable interface {
+	WithOptions(options map[string]interface{}) (Codec, error)
+}
+
+// Columns is implemented by decoders that know the fields of their records
+// in order before decoding them, such as CSV with a header row.
+type Columns interface {
+	Columns() []string
+}
+
+// Dataset is a list of records together with their schema.
+type Dataset struct {
+	Records []Record `json:"records"`
+	Schema  *Schema  `json:"schema"`
+}
+
+// Add appends record to the dataset and its schema.
+func (d *Dataset) Add(record Record) {
+	if d.Schema == nil {
+		d.Schema = NewSchema()
+	}
+	d.Records = append(d.Records, record)
+	d.Schema.Observe(record)
+}
+
+// ReadAll reads the records of decoder into a dataset.
+func ReadAll(decoder Decoder) (*Dataset, error) {
+	dataset := &Dataset{Records: []Record{}, Schema: NewSchema()}
+	err := Each(decoder, func(record Record) error {
+		dataset.Add(record)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if columns, ok := decoder.(Columns); ok {
+		dataset.Schema.order(columns.Columns())
+	}
+	return dataset, nil
+}
+
+// Each calls handle with every record of decoder until the records are
+// exhausted or decoding or handle fail.
+func Each(decoder Decoder, handle func(Record) error) error {
+	for {
+		record, err := decoder.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := handle(record); err != nil {
+			return err
+		}
+	}
+}
+
+// Registry maps format names to codecs.
+type Registry struct {
+	mutex  sync.RWMutex
+	codecs map[string]Codec
+}
+
+// NewRegistry creates a registry holding the built-in codecs: json, ndjson,
+// csv, xml, yaml, text and lines.
+func NewRegistry() *Registry {
+	r := &Registry{codecs: make(map[string]Codec)}
+	for _, codec := range []Codec{JSONCodec{}, NDJSONCodec{}, CSVCodec{}, XMLCodec{}, YAMLCodec{}, TextCodec{}, LinesCodec{}} {
+		r.MustRegister(codec)
+	}
+	return r
+}
+
+var defaultRegistry = NewRegistry()
+
+// DefaultRegistry returns the process-wide registry agents decode with unless
+// given their own.
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9_.+-]*$`)
+
+// Register adds codec under its lower-case name, which must not be taken.
+func (r *Registry) Register(codec Codec) error {
+	name := codec.Name()
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid codec name %q", name)
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, exists := r.codecs[name]; exists {
+		return fmt.Errorf("codec %s is already registered", name)
+	}
+	r.codecs[name] = codec
+	return nil
+}
+
+// MustRegister is like Register but panics on error.
+func (r *Registry) MustRegister(codec Codec) {
+	if err := r.Register(codec); err != nil {
+		panic(err)
+	}
+}
+
+// Get returns the codec for a format name, case-insensitively.
+func (r *Registry) Get(name string) (Codec, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	codec, ok := r.codecs[strings.ToLower(name)]
+	return codec, ok
+}
+
+// Names returns the registered format names in order.
+func (r *Registry) Names() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	names := make([]string, 0, len(r.codecs))
+	for name := range r.codecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the codec for format configured with options, if any.
+func (r *Registry) Lookup(format string, options map[string]interface{}) (Codec, error) {
+	codec, ok := r.Get(format)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCodec, format)
+	}
+	if len(options) == 0 {
+		return codec, nil
+	}
+	configurable, ok := codec.(Configurable)
+	if !ok {
+		return nil, fmt.Errorf("format %s takes no options", format)
+	}
+	configured, err := configurable.WithOptions(options)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s options: %w", format, err)
+	}
+	return configured, nil
+}
+
+// Decode reads all records of r in the named format.
+func (r *Registry) Decode(format string, reader io.Reader) (*Dataset, error) {
+	codec, err := r.Lookup(format, nil)
+	if err != nil {
+		return nil, err
+	}
+	decoder, err := codec.NewDecoder(reader)
+	if err != nil {
+		return nil, err
+	}
+	return ReadAll(decoder)
+}
+
+// Lookup returns the value at a dotted path such as "data.items" within a
+// decoded value, or nil if there is none. The empty path gives value itself.
+func Lookup(value interface{}, path string) interface{} {
+	if path == "" {
+		return value
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch object := value.(type) {
+		case map[string]interface{}:
+			value = object[key]
+		case Record:
+			value = object[key]
+		default:
+			return nil
+		}
+	}
+	return value
+}
+
+// recordOf returns value as a record, wrapping values that are not objects
+// as the field "value".
+func recordOf(value interface{}) Record {
+	if object, ok := value.(map[string]interface{}); ok {
+		return Record(object)
+	}
+	return Record{"value": value}
+}
+
+// parseScalar converts text to an int64, float64 or bool if it is the
+// canonical spelling of one, so that values such as zip codes with leading
+// zeros stay strings.
+func parseScalar(text string) interface{} {
+	switch text {
+	case "true", "TRUE", "True":
+		return true
+	case "false", "FALSE", "False":
+		return false
+	}
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil && strconv.FormatInt(n, 10) == text {
+		return n
+	}
+	if isDecimal(text) {
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			return f
+		}
+	}
+	return text
+}
+
+// isDecimal reports whether text is a plain decimal number without
+// superfluous leading zeros, such as "-0.5" or "1e6".
+func isDecimal(text string) bool {
+	digits := strings.TrimPrefix(text, "-")
+	if digits == "" || digits[0] < '0' || digits[0] > '9' {
+		return false
+	}
+	if len(digits) > 1 && digits[0] == '0' && digits[1] != '.' {
+		return false
+	}
+	return strings.IndexFunc(digits, func(r rune) bool {
+		return !(r >= '0' && r <= '9' || r == '.' || r == 'e' || r == 'E' || r == '+' || r == '-')
+	}) < 0
+}
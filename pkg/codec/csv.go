@@ -0,0 +1,285 @@
+package codec
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Header modes of the CSV codec.
+const (
+	// HeaderAuto treats the first row as a header unless it looks like data:
+	// a header has distinct, non-empty cells none of which is a number or
+	// boolean.
+	HeaderAuto = "auto"
+	// HeaderPresent always reads the column names from the first row.
+	HeaderPresent = "present"
+	// HeaderAbsent names the columns column_1, column_2 and so on.
+	HeaderAbsent = "absent"
+)
+
+// DefaultInferRows is the number of rows the CSV codec reads ahead to infer
+// column types unless configured otherwise.
+const DefaultInferRows = 100
+
+// CSVCodec decodes comma-separated values, one record per row. Column types
+// are inferred from the first InferRows rows: a column whose non-empty cells
+// are all integers, numbers or booleans is converted to that type, and empty
+// cells become nil. A later cell that does not fit the column type is kept
+// as a string.
+type CSVCodec struct {
+	// Delimiter separates fields; ',' if zero.
+	Delimiter rune
+	// Comment starts lines that are ignored; none if zero.
+	Comment rune
+	// Header is HeaderAuto, HeaderPresent or HeaderAbsent; HeaderAuto if empty.
+	Header string
+	// InferRows is the number of rows read ahead to infer column types;
+	// DefaultInferRows if zero. Negative keeps every cell a string.
+	InferRows int
+	// Types sets the type of named columns, skipping inference for them.
+	Types map[string]string
+}
+
+// Name implements Codec.
+func (CSVCodec) Name() string { return "csv" }
+
+// WithOptions implements Configurable, reading "delimiter", "comment",
+// "header", "infer_rows" and "types".
+func (c CSVCodec) WithOptions(options map[string]interface{}) (Codec, error) {
+	for key, value := range options {
+		switch key {
+		case "delimiter", "comment":
+			s, ok := value.(string)
+			if !ok || utf8.RuneCountInString(s) != 1 {
+				return nil, fmt.Errorf("%s must be a single character, got %v", key, value)
+			}
+			r, _ := utf8.DecodeRuneInString(s)
+			if key == "delimiter" {
+				c.Delimiter = r
+			} else {
+				c.Comment = r
+			}
+		case "header":
+			switch v := value.(type) {
+			case bool:
+				c.Header = HeaderAbsent
+				if v {
+					c.Header = HeaderPresent
+				}
+			case string:
+				c.Header = v
+			default:
+				return nil, fmt.Errorf("header must be a boolean or a mode, got %T", value)
+			}
+		case "infer_rows":
+			switch v := value.(type) {
+			case int:
+				c.InferRows = v
+			case float64:
+				c.InferRows = int(v)
+			default:
+				return nil, fmt.Errorf("infer_rows must be a number, got %T", value)
+			}
+		case "types":
+			types, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("types must be a map, got %T", value)
+			}
+			c.Types = make(map[string]string, len(types))
+			for column, t := range types {
+				s, ok := t.(string)
+				if !ok {
+					return nil, fmt.Errorf("type of column %s must be a string, got %T", column, t)
+				}
+				c.Types[column] = s
+			}
+		default:
+			return nil, fmt.Errorf("unknown option %s", key)
+		}
+	}
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c CSVCodec) validate() error {
+	switch c.Header {
+	case "", HeaderAuto, HeaderPresent, HeaderAbsent:
+	default:
+		return fmt.Errorf("unknown header mode %q", c.Header)
+	}
+	for column, t := range c.Types {
+		switch t {
+		case TypeString, TypeInteger, TypeNumber, TypeBoolean:
+		default:
+			return fmt.Errorf("unsupported type %q for column %s", t, column)
+		}
+	}
+	return nil
+}
+
+// NewDecoder implements Codec. It reads the header and the rows used for
+// type inference.
+func (c CSVCodec) NewDecoder(r io.Reader) (Decoder, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	reader := csv.NewReader(r)
+	if c.Delimiter != 0 {
+		reader.Comma = c.Delimiter
+	}
+	reader.Comment = c.Comment
+	reader.TrimLeadingSpace = true
+
+	d := &csvDecoder{reader: reader}
+	first, err := reader.Read()
+	if err == io.EOF {
+		return d, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	first[0] = strings.TrimPrefix(first[0], "\ufeff")
+
+	header := c.Header
+	if header == "" || header == HeaderAuto {
+		header = HeaderAbsent
+		if looksLikeHeader(first) {
+			header = HeaderPresent
+		}
+	}
+	if header == HeaderPresent {
+		d.columns = first
+	} else {
+		d.columns = make([]string, len(first))
+		for i := range first {
+			d.columns[i] = "column_" + strconv.Itoa(i+1)
+		}
+		d.buffered = append(d.buffered, first)
+	}
+
+	inferRows := c.InferRows
+	if inferRows == 0 {
+		inferRows = DefaultInferRows
+	}
+	for len(d.buffered) < inferRows {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		d.buffered = append(d.buffered, row)
+	}
+
+	d.types = make([]string, len(d.columns))
+	for i, column := range d.columns {
+		switch {
+		case c.Types[column] != "":
+			d.types[i] = c.Types[column]
+		case inferRows < 0:
+			d.types[i] = TypeString
+		default:
+			d.types[i] = inferColumn(d.buffered, i)
+		}
+	}
+	return d, nil
+}
+
+type csvDecoder struct {
+	reader   *csv.Reader
+	columns  []string
+	types    []string
+	buffered [][]string
+}
+
+func (d *csvDecoder) Columns() []string {
+	return d.columns
+}
+
+func (d *csvDecoder) Next() (Record, error) {
+	if d.columns == nil {
+		return nil, io.EOF
+	}
+	var row []string
+	if len(d.buffered) > 0 {
+		row, d.buffered = d.buffered[0], d.buffered[1:]
+	} else {
+		var err error
+		if row, err = d.reader.Read(); err != nil {
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+	}
+
+	record := make(Record, len(d.columns))
+	for i, column := range d.columns {
+		if i < len(row) {
+			record[column] = convertCell(row[i], d.types[i])
+		} else {
+			record[column] = nil
+		}
+	}
+	return record, nil
+}
+
+// looksLikeHeader reports whether a first row is a header rather than data.
+func looksLikeHeader(row []string) bool {
+	seen := make(map[string]bool, len(row))
+	for _, cell := range row {
+		if cell == "" || seen[cell] {
+			return false
+		}
+		seen[cell] = true
+		if _, isText := parseScalar(cell).(string); !isText {
+			return false
+		}
+	}
+	return true
+}
+
+// inferColumn returns the type fitting all non-empty cells of column i.
+func inferColumn(rows [][]string, i int) string {
+	inferred := TypeNull
+	for _, row := range rows {
+		if i >= len(row) || row[i] == "" {
+			continue
+		}
+		inferred = widen(inferred, TypeOf(parseScalar(row[i])))
+	}
+	if inferred == TypeNull {
+		return TypeString
+	}
+	return inferred
+}
+
+// convertCell converts a cell to the column type, keeping it as a string if
+// it does not fit.
+func convertCell(cell, columnType string) interface{} {
+	if cell == "" {
+		return nil
+	}
+	switch columnType {
+	case TypeInteger:
+		if n, err := strconv.ParseInt(cell, 10, 64); err == nil {
+			return n
+		}
+	case TypeNumber:
+		if f, err := strconv.ParseFloat(cell, 64); err == nil {
+			return f
+		}
+	case TypeBoolean:
+		if b, err := strconv.ParseBool(cell); err == nil {
+			return b
+		}
+	}
+	return cell
+}
@@ -0,0 +1,173 @@
+package codec
+
+import (
+	"beluga/pkg/setting"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// JSONCodec decodes JSON. The elements of a top-level array are streamed as
+// records one at a time; otherwise every top-level value is a record.
+// Values that are not objects become records with the single field "value".
+type JSONCodec struct {
+	// RecordsPath is the dotted path of the array holding the records within
+	// each top-level value, such as "data.items". The enclosing value is
+	// decoded whole.
+	RecordsPath string
+}
+
+// Name implements Codec.
+func (JSONCodec) Name() string { return "json" }
+
+// WithOptions implements Configurable, reading "records_path".
+func (c JSONCodec) WithOptions(options map[string]interface{}) (Codec, error) {
+	for key, value := range options {
+		switch key {
+		case "records_path":
+			path, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("records_path must be a string, got %T", value)
+			}
+			c.RecordsPath = path
+		default:
+			return nil, fmt.Errorf("unknown option %s", key)
+		}
+	}
+	return c, nil
+}
+
+// NewDecoder implements Codec.
+func (c JSONCodec) NewDecoder(r io.Reader) (Decoder, error) {
+	buffered := bufio.NewReader(r)
+	decoder := json.NewDecoder(buffered)
+	decoder.UseNumber()
+	d := &jsonDecoder{decoder: decoder, recordsPath: c.RecordsPath}
+
+	// Stream the elements of a top-level array instead of decoding it whole
+	if c.RecordsPath == "" {
+		first, err := firstByte(buffered)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if first == '[' {
+			if _, err := decoder.Token(); err != nil {
+				return nil, fmt.Errorf("invalid JSON: %w", err)
+			}
+			d.inArray = true
+		}
+	}
+	return d, nil
+}
+
+type jsonDecoder struct {
+	decoder     *json.Decoder
+	recordsPath string
+	inArray     bool
+	pending     []interface{}
+}
+
+func (d *jsonDecoder) Next() (Record, error) {
+	for {
+		if len(d.pending) > 0 {
+			value := d.pending[0]
+			d.pending = d.pending[1:]
+			return recordOf(setting.NormalizeAll(value)), nil
+		}
+
+		if d.inArray {
+			if d.decoder.More() {
+				var value interface{}
+				if err := d.decoder.Decode(&value); err != nil {
+					return nil, fmt.Errorf("invalid JSON: %w", err)
+				}
+				return recordOf(setting.NormalizeAll(value)), nil
+			}
+			if _, err := d.decoder.Token(); err != nil {
+				return nil, fmt.Errorf("invalid JSON: %w", err)
+			}
+			d.inArray = false
+			continue
+		}
+
+		var value interface{}
+		if err := d.decoder.Decode(&value); err != nil {
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		if d.recordsPath == "" {
+			return recordOf(setting.NormalizeAll(value)), nil
+		}
+		switch records := Lookup(value, d.recordsPath).(type) {
+		case nil:
+		case []interface{}:
+			d.pending = records
+		default:
+			d.pending = []interface{}{records}
+		}
+	}
+}
+
+// NDJSONCodec decodes newline-delimited JSON, one record per line, reading a
+// line at a time however long the input. Blank lines are skipped.
+type NDJSONCodec struct{}
+
+// Name implements Codec.
+func (NDJSONCodec) Name() string { return "ndjson" }
+
+// NewDecoder implements Codec.
+func (NDJSONCodec) NewDecoder(r io.Reader) (Decoder, error) {
+	return &ndjsonDecoder{reader: bufio.NewReader(r)}, nil
+}
+
+type ndjsonDecoder struct {
+	reader *bufio.Reader
+	line   int
+}
+
+func (d *ndjsonDecoder) Next() (Record, error) {
+	for {
+		line, err := d.reader.ReadBytes('\n')
+		if len(line) == 0 && err != nil {
+			return nil, err
+		}
+		d.line++
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.UseNumber()
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return nil, fmt.Errorf("invalid JSON on line %d: %w", d.line, err)
+		}
+		if decoder.More() {
+			return nil, fmt.Errorf("invalid JSON on line %d: more than one value", d.line)
+		}
+		return recordOf(setting.NormalizeAll(value)), nil
+	}
+}
+
+// firstByte returns the first byte of r that is not white space, leaving it unread.
+func firstByte(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b, r.UnreadByte()
+	}
+}
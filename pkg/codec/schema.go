@@ -0,0 +1,169 @@
+package codec
+
+import "sort"
+
+// Field types of a schema.
+const (
+	TypeNull    = "null"
+	TypeBoolean = "boolean"
+	TypeInteger = "integer"
+	TypeNumber  = "number"
+	TypeString  = "string"
+	TypeArray   = "array"
+	TypeObject  = "object"
+	TypeAny     = "any"
+)
+
+// Field describes a field of the records of a dataset.
+type Field struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Nullable is set if some record lacks the field or has it set to nil.
+	Nullable bool `json:"nullable"`
+	present  int
+}
+
+// Schema describes the fields of a set of records, inferred from the
+// records themselves.
+type Schema struct {
+	Fields  []Field `json:"fields"`
+	records int
+	index   map[string]int
+}
+
+// NewSchema creates an empty schema.
+func NewSchema() *Schema {
+	return &Schema{Fields: []Field{}, index: make(map[string]int)}
+}
+
+// InferSchema returns the schema of records.
+func InferSchema(records []Record) *Schema {
+	s := NewSchema()
+	for _, record := range records {
+		s.Observe(record)
+	}
+	return s
+}
+
+// Observe widens the schema to cover record. Fields are kept in the order
+// they first appear; the new fields of a record are added by name.
+func (s *Schema) Observe(record Record) {
+	if s.index == nil {
+		s.index = make(map[string]int)
+	}
+	s.records++
+
+	names := make([]string, 0, len(record))
+	for name := range record {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		valueType := TypeOf(record[name])
+		i, exists := s.index[name]
+		if !exists {
+			i = len(s.Fields)
+			s.index[name] = i
+			// Records observed before lacked the field
+			s.Fields = append(s.Fields, Field{Name: name, Type: TypeNull, Nullable: s.records > 1})
+		}
+		field := &s.Fields[i]
+		field.present++
+		if valueType == TypeNull {
+			field.Nullable = true
+		}
+		field.Type = widen(field.Type, valueType)
+	}
+	for i := range s.Fields {
+		if s.Fields[i].present < s.records {
+			s.Fields[i].Nullable = true
+		}
+	}
+}
+
+// Field returns the named field.
+func (s *Schema) Field(name string) (Field, bool) {
+	i, ok := s.index[name]
+	if !ok {
+		return Field{}, false
+	}
+	return s.Fields[i], true
+}
+
+// Names returns the field names in order.
+func (s *Schema) Names() []string {
+	names := make([]string, len(s.Fields))
+	for i, field := range s.Fields {
+		names[i] = field.Name
+	}
+	return names
+}
+
+// order puts the named fields first, in the given order.
+func (s *Schema) order(names []string) {
+	ordered := make([]Field, 0, len(s.Fields))
+	taken := make(map[string]bool, len(names))
+	for _, name := range names {
+		if i, ok := s.index[name]; ok && !taken[name] {
+			ordered = append(ordered, s.Fields[i])
+			taken[name] = true
+		}
+	}
+	for _, field := range s.Fields {
+		if !taken[field.Name] {
+			ordered = append(ordered, field)
+		}
+	}
+	s.Fields = ordered
+	for i, field := range s.Fields {
+		s.index[field.Name] = i
+	}
+}
+
+// TypeOf returns the schema type of a record value.
+func TypeOf(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return TypeNull
+	case bool:
+		return TypeBoolean
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return TypeInteger
+	case float32, float64:
+		return TypeNumber
+	case string:
+		return TypeString
+	case []interface{}:
+		return TypeArray
+	case map[string]interface{}, Record:
+		return TypeObject
+	}
+	return TypeAny
+}
+
+// widen returns the narrowest type covering values of both types: integers
+// widen to numbers and scalars mixed with strings to strings.
+func widen(a, b string) string {
+	switch {
+	case a == b || b == TypeNull:
+		return a
+	case a == TypeNull:
+		return b
+	case a == TypeAny || b == TypeAny:
+		return TypeAny
+	case (a == TypeInteger && b == TypeNumber) || (a == TypeNumber && b == TypeInteger):
+		return TypeNumber
+	case isScalar(a) && isScalar(b):
+		return TypeString
+	}
+	return TypeAny
+}
+
+func isScalar(t string) bool {
+	switch t {
+	case TypeBoolean, TypeInteger, TypeNumber, TypeString:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package codec
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// TextCodec decodes text as a single record with the field "text".
+type TextCodec struct{}
+
+// Name implements Codec.
+func (TextCodec) Name() string { return "text" }
+
+// NewDecoder implements Codec.
+func (TextCodec) NewDecoder(r io.Reader) (Decoder, error) {
+	return &textDecoder{reader: r}, nil
+}
+
+type textDecoder struct {
+	reader io.Reader
+	done   bool
+}
+
+func (d *textDecoder) Next() (Record, error) {
+	if d.done {
+		return nil, io.EOF
+	}
+	d.done = true
+	data, err := io.ReadAll(d.reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read text: %w", err)
+	}
+	return Record{"text": string(data)}, nil
+}
+
+// LinesCodec decodes text one line at a time, as records with the fields
+// "line", the line number from 1, and "text". Blank lines are skipped.
+type LinesCodec struct{}
+
+// Name implements Codec.
+func (LinesCodec) Name() string { return "lines" }
+
+// NewDecoder implements Codec.
+func (LinesCodec) NewDecoder(r io.Reader) (Decoder, error) {
+	return &linesDecoder{reader: bufio.NewReader(r)}, nil
+}
+
+type linesDecoder struct {
+	reader *bufio.Reader
+	line   int64
+}
+
+func (d *linesDecoder) Next() (Record, error) {
+	for {
+		line, err := d.reader.ReadString('\n')
+		if line == "" && err != nil {
+			return nil, err
+		}
+		d.line++
+		if text := strings.TrimRight(line, "\r\n"); strings.TrimSpace(text) != "" {
+			return Record{"line": d.line, "text": text}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
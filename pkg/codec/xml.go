@@ -0,0 +1,138 @@
+package codec
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// XMLCodec decodes XML, one record per element, streaming element by
+// element. Within a record, attributes become fields prefixed with "@",
+// child elements become fields named after them, repeated children become
+// lists and the text of an element with attributes or children becomes the
+// field "#text". Text that spells a number or boolean is converted to it.
+type XMLCodec struct {
+	// RecordElement is the name of the elements that are records, at any
+	// depth. If empty, the children of the root element are records.
+	RecordElement string
+	// KeepStrings disables the conversion of numbers and booleans.
+	KeepStrings bool
+}
+
+// Name implements Codec.
+func (XMLCodec) Name() string { return "xml" }
+
+// WithOptions implements Configurable, reading "record_element" and "keep_strings".
+func (c XMLCodec) WithOptions(options map[string]interface{}) (Codec, error) {
+	for key, value := range options {
+		switch key {
+		case "record_element":
+			name, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("record_element must be a string, got %T", value)
+			}
+			c.RecordElement = name
+		case "keep_strings":
+			keep, ok := value.(bool)
+			if !ok {
+				return nil, fmt.Errorf("keep_strings must be a boolean, got %T", value)
+			}
+			c.KeepStrings = keep
+		default:
+			return nil, fmt.Errorf("unknown option %s", key)
+		}
+	}
+	return c, nil
+}
+
+// NewDecoder implements Codec.
+func (c XMLCodec) NewDecoder(r io.Reader) (Decoder, error) {
+	return &xmlDecoder{decoder: xml.NewDecoder(r), codec: c}, nil
+}
+
+type xmlDecoder struct {
+	decoder *xml.Decoder
+	codec   XMLCodec
+	depth   int
+}
+
+// xmlNode is an element decoded generically.
+type xmlNode struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Text     string     `xml:",chardata"`
+	Children []xmlNode  `xml:",any"`
+}
+
+func (d *xmlDecoder) Next() (Record, error) {
+	for {
+		token, err := d.decoder.Token()
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid XML: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			isRecord := d.depth == 1
+			if d.codec.RecordElement != "" {
+				isRecord = t.Name.Local == d.codec.RecordElement
+			}
+			if !isRecord {
+				d.depth++
+				continue
+			}
+			var node xmlNode
+			if err := d.decoder.DecodeElement(&node, &t); err != nil {
+				return nil, fmt.Errorf("invalid XML: %w", err)
+			}
+			return recordOf(d.value(node)), nil
+		case xml.EndElement:
+			d.depth--
+		}
+	}
+}
+
+// value converts an element to a record value.
+func (d *xmlDecoder) value(node xmlNode) interface{} {
+	text := strings.TrimSpace(node.Text)
+	if len(node.Attrs) == 0 && len(node.Children) == 0 {
+		if text == "" {
+			return nil
+		}
+		return d.scalar(text)
+	}
+
+	object := make(map[string]interface{}, len(node.Attrs)+len(node.Children)+1)
+	for _, attr := range node.Attrs {
+		object["@"+attr.Name.Local] = d.scalar(attr.Value)
+	}
+	// Repeated children are collected into a list
+	counts := make(map[string]int, len(node.Children))
+	for _, child := range node.Children {
+		counts[child.XMLName.Local]++
+	}
+	for _, child := range node.Children {
+		name := child.XMLName.Local
+		if counts[name] == 1 {
+			object[name] = d.value(child)
+			continue
+		}
+		list, _ := object[name].([]interface{})
+		object[name] = append(list, d.value(child))
+	}
+	if text != "" {
+		object["#text"] = d.scalar(text)
+	}
+	return object
+}
+
+func (d *xmlDecoder) scalar(text string) interface{} {
+	if d.codec.KeepStrings {
+		return text
+	}
+	return parseScalar(text)
+}
@@ -0,0 +1,49 @@
+package codec
+
+import (
+	"beluga/pkg/setting"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v2"
+)
+
+// YAMLCodec decodes YAML. Each document of a multi-document stream is
+// decoded in turn; the elements of a document that is a list are records,
+// and any other document is a record itself.
+type YAMLCodec struct{}
+
+// Name implements Codec.
+func (YAMLCodec) Name() string { return "yaml" }
+
+// NewDecoder implements Codec.
+func (YAMLCodec) NewDecoder(r io.Reader) (Decoder, error) {
+	return &yamlDecoder{decoder: yaml.NewDecoder(r)}, nil
+}
+
+type yamlDecoder struct {
+	decoder *yaml.Decoder
+	pending []interface{}
+}
+
+func (d *yamlDecoder) Next() (Record, error) {
+	for len(d.pending) == 0 {
+		var document interface{}
+		if err := d.decoder.Decode(&document); err != nil {
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("invalid YAML: %w", err)
+		}
+		switch v := setting.NormalizeAll(document).(type) {
+		case nil:
+		case []interface{}:
+			d.pending = v
+		default:
+			d.pending = []interface{}{v}
+		}
+	}
+	value := d.pending[0]
+	d.pending = d.pending[1:]
+	return recordOf(value), nil
+}
@@ -0,0 +1,189 @@
+package events
+
+import (
+	"path"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBufferSize is the number of undelivered events a subscription holds
+// before further events are dropped.
+const DefaultBufferSize = 256
+
+// Event is implemented by everything published on a Bus.
+type Event interface {
+	// EventType names the kind of event, such as "state_change". Subscription
+	// patterns are matched against it.
+	EventType() string
+}
+
+// Handler receives the events of a subscription.
+type Handler func(Event)
+
+// Bus delivers published events to its subscribers asynchronously. Each
+// subscription has its own bounded buffer and goroutine, so a slow handler
+// never blocks the publisher or other subscribers; when a buffer is full,
+// further events for that subscription are dropped and counted.
+type Bus struct {
+	// BufferSize is the buffer size of new subscriptions. Zero means DefaultBufferSize.
+	BufferSize int
+
+	mutex  sync.RWMutex
+	subs   map[uint64]*Subscription
+	nextID uint64
+	closed bool
+}
+
+// Subscription is a handle on a subscription to a Bus.
+type Subscription struct {
+	pattern   string
+	bus       *Bus
+	id        uint64
+	ch        chan Event
+	done      chan struct{}
+	cancelled atomic.Bool
+	dropped   atomic.Uint64
+}
+
+// NewBus creates an event bus.
+func NewBus() *Bus {
+	return &Bus{
+		subs: make(map[uint64]*Subscription),
+	}
+}
+
+var global = NewBus()
+
+// Global returns the process-wide bus that aggregates the events of every agent.
+func Global() *Bus {
+	return global
+}
+
+// Subscribe calls handler with every event whose type matches pattern. The
+// pattern uses path.Match syntax, so "*" matches every event and
+// "execution_*" matches both execution events. Subscribing to a closed bus
+// returns a subscription that never receives events.
+func (b *Bus) Subscribe(pattern string, handler Handler) *Subscription {
+	size := b.BufferSize
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+
+	sub := &Subscription{
+		pattern: pattern,
+		bus:     b,
+		ch:      make(chan Event, size),
+		done:    make(chan struct{}),
+	}
+
+	b.mutex.Lock()
+	if b.closed {
+		b.mutex.Unlock()
+		close(sub.ch)
+		close(sub.done)
+		return sub
+	}
+	if b.subs == nil {
+		b.subs = make(map[uint64]*Subscription)
+	}
+	b.nextID++
+	sub.id = b.nextID
+	b.subs[sub.id] = sub
+	b.mutex.Unlock()
+
+	go func() {
+		defer close(sub.done)
+		for event := range sub.ch {
+			if !sub.cancelled.Load() {
+				handler(event)
+			}
+		}
+	}()
+	return sub
+}
+
+// SubscribeTyped calls handler with every event of type T published on b.
+func SubscribeTyped[T Event](b *Bus, handler func(T)) *Subscription {
+	return b.Subscribe("*", func(event Event) {
+		if typed, ok := event.(T); ok {
+			handler(typed)
+		}
+	})
+}
+
+// Publish delivers event to every matching subscription without blocking.
+func (b *Bus) Publish(event Event) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	if b.closed {
+		return
+	}
+	for _, sub := range b.subs {
+		if !sub.matches(event.EventType()) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
+
+// Close stops the bus. Events already buffered are still delivered, after
+// which every subscription ends.
+func (b *Bus) Close() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for id, sub := range b.subs {
+		close(sub.ch)
+		delete(b.subs, id)
+	}
+}
+
+// SubscriberCount returns the number of active subscriptions.
+func (b *Bus) SubscriberCount() int {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return len(b.subs)
+}
+
+// Cancel ends the subscription. Events not yet delivered are discarded. It is
+// safe to call Cancel from the subscription's own handler and more than once.
+func (s *Subscription) Cancel() {
+	if s.cancelled.Swap(true) {
+		return
+	}
+
+	s.bus.mutex.Lock()
+	defer s.bus.mutex.Unlock()
+	if _, ok := s.bus.subs[s.id]; ok {
+		close(s.ch)
+		delete(s.bus.subs, s.id)
+	}
+}
+
+// Done returns a channel that is closed once the subscription has ended and
+// its handler has returned for the last time.
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Dropped returns the number of events dropped because the buffer was full.
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+func (s *Subscription) matches(eventType string) bool {
+	if s.pattern == "*" || s.pattern == eventType {
+		return true
+	}
+	matched, err := path.Match(s.pattern, eventType)
+	return err == nil && matched
+}
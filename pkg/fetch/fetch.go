@@ -0,0 +1,227 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// StdinSource is the source reading standard input; "-" is accepted as well.
+const StdinSource = "stdin"
+
+// Document is the content read from a source: an HTTP response page, a file
+// or standard input.
+type Document struct {
+	// Source is the URL or path the document was read from.
+	Source      string
+	ContentType string
+	// Body streams the content. It is only valid until the handler the
+	// document was passed to returns.
+	Body io.Reader
+}
+
+// Driver reads documents from a data source.
+type Driver interface {
+	// Fetch calls handle with each document of the source in turn, stopping
+	// at the first error.
+	Fetch(ctx context.Context, handle func(Document) error) error
+}
+
+// Config describes a data source and how to read it.
+type Config struct {
+	// Source is an http or https URL, StdinSource, or a file path or glob,
+	// optionally prefixed with "file://".
+	Source string
+	// Paths lists file paths or globs read instead of Source.
+	Paths []string
+	// Method, Headers, Query, Body, Timeout and Pagination apply to HTTP sources.
+	Method     string
+	Headers    map[string]string
+	Query      map[string]string
+	Body       string
+	Timeout    time.Duration
+	Pagination *Pagination
+	// Stdin replaces os.Stdin for StdinSource.
+	Stdin io.Reader
+}
+
+// ConfigFromMap reads a config from settings. The source is given by "url",
+// "base_url" or "path"; "paths" lists several files or globs. "timeout" is in
+// seconds or a duration string such as "500ms".
+func ConfigFromMap(settings map[string]interface{}) (Config, error) {
+	var c Config
+
+	for _, key := range []string{"url", "base_url", "path"} {
+		if raw, ok := settings[key]; ok {
+			s, isString := raw.(string)
+			if !isString {
+				return c, fmt.Errorf("fetch %s must be a string, got %T", key, raw)
+			}
+			if c.Source == "" {
+				c.Source = s
+			}
+		}
+	}
+	if raw, ok := settings["paths"]; ok {
+		paths, err := toStrings(raw)
+		if err != nil {
+			return c, fmt.Errorf("fetch paths: %w", err)
+		}
+		c.Paths = paths
+	}
+	for key, target := range map[string]*string{"method": &c.Method, "body": &c.Body} {
+		if raw, ok := settings[key]; ok {
+			s, isString := raw.(string)
+			if !isString {
+				return c, fmt.Errorf("fetch %s must be a string, got %T", key, raw)
+			}
+			*target = s
+		}
+	}
+	for key, target := range map[string]*map[string]string{"headers": &c.Headers, "query": &c.Query} {
+		if raw, ok := settings[key]; ok {
+			values, err := toStringMap(raw)
+			if err != nil {
+				return c, fmt.Errorf("fetch %s: %w", key, err)
+			}
+			*target = values
+		}
+	}
+	if raw, ok := settings["timeout"]; ok {
+		timeout, err := toDuration(raw)
+		if err != nil {
+			return c, fmt.Errorf("invalid fetch timeout: %w", err)
+		}
+		c.Timeout = timeout
+	}
+	if raw, ok := settings["pagination"]; ok {
+		pagination, err := PaginationFromMap(raw)
+		if err != nil {
+			return c, fmt.Errorf("invalid pagination: %w", err)
+		}
+		c.Pagination = pagination
+	}
+
+	return c, nil
+}
+
+// New creates the driver for the configured source.
+func (c Config) New() (Driver, error) {
+	if len(c.Paths) > 0 {
+		return &FileDriver{Patterns: c.Paths}, nil
+	}
+
+	source := c.Source
+	switch {
+	case source == "":
+		return nil, errors.New("no data source configured")
+	case strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://"):
+		method := strings.ToUpper(c.Method)
+		if method == "" {
+			method = http.MethodGet
+		}
+		if c.Pagination != nil {
+			if err := c.Pagination.validate(); err != nil {
+				return nil, err
+			}
+		}
+		return &HTTPDriver{
+			URL:        source,
+			Method:     method,
+			Headers:    c.Headers,
+			Query:      c.Query,
+			Body:       c.Body,
+			Pagination: c.Pagination,
+			Client:     &http.Client{Timeout: c.Timeout},
+		}, nil
+	case source == StdinSource || source == "-":
+		return &StdinDriver{Reader: c.Stdin}, nil
+	case strings.Contains(source, "://") && !strings.HasPrefix(source, "file://"):
+		return nil, fmt.Errorf("unsupported data source: %s", source)
+	default:
+		return &FileDriver{Patterns: []string{strings.TrimPrefix(source, "file://")}}, nil
+	}
+}
+
+// IsLocation reports whether source locates data, as opposed to naming a
+// source whose location is configured separately: a URL, StdinSource, or a
+// path that has a directory, glob pattern or file extension.
+func IsLocation(source string) bool {
+	switch {
+	case source == StdinSource || source == "-":
+		return true
+	case strings.Contains(source, "://"):
+		return true
+	case strings.ContainsAny(source, "/*?[") || strings.ContainsRune(source, filepath.Separator):
+		return true
+	}
+	return filepath.Ext(source) != ""
+}
+
+func toStrings(raw interface{}) ([]string, error) {
+	switch v := raw.(type) {
+	case string:
+		return []string{v}, nil
+	case []string:
+		return v, nil
+	case []interface{}:
+		values := make([]string, len(v))
+		for i, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected strings, got %T", item)
+			}
+			values[i] = s
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("expected a list of strings, got %T", raw)
+}
+
+func toStringMap(raw interface{}) (map[string]string, error) {
+	switch v := raw.(type) {
+	case map[string]string:
+		return v, nil
+	case map[string]interface{}:
+		values := make(map[string]string, len(v))
+		for key, item := range v {
+			switch item := item.(type) {
+			case string:
+				values[key] = item
+			case float64, int, bool:
+				values[key] = fmt.Sprint(item)
+			default:
+				return nil, fmt.Errorf("%s must be a string, got %T", key, item)
+			}
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("expected a map, got %T", raw)
+}
+
+func toDuration(raw interface{}) (time.Duration, error) {
+	switch v := raw.(type) {
+	case float64:
+		return time.Duration(v * float64(time.Second)), nil
+	case int:
+		return time.Duration(v) * time.Second, nil
+	case string:
+		return time.ParseDuration(v)
+	}
+	return 0, fmt.Errorf("expected seconds or a duration, got %T", raw)
+}
+
+func toInt(raw interface{}) (int, error) {
+	switch v := raw.(type) {
+	case float64:
+		return int(v), nil
+	case int:
+		return v, nil
+	}
+	return 0, fmt.Errorf("expected a number, got %T", raw)
+}
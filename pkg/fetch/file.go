@@ -0,0 +1,83 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// FileDriver reads local files named by paths or glob patterns.
+type FileDriver struct {
+	Patterns []string
+}
+
+// Fetch passes every file matching the patterns to handle, in pattern order
+// and sorted within a pattern, each at most once. Directories are skipped. A
+// pattern matching no file is an error.
+func (f *FileDriver) Fetch(ctx context.Context, handle func(Document) error) error {
+	seen := make(map[string]bool)
+	for _, pattern := range f.Patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid glob %s: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return fmt.Errorf("no files match %s: %w", pattern, os.ErrNotExist)
+		}
+		sort.Strings(matches)
+
+		for _, path := range matches {
+			if seen[path] {
+				continue
+			}
+			seen[path] = true
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := readFile(path, handle); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readFile passes the file at path to handle unless it is a directory.
+func readFile(path string, handle func(Document) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if info.IsDir() {
+		return nil
+	}
+	return handle(Document{
+		Source:      path,
+		ContentType: mime.TypeByExtension(filepath.Ext(path)),
+		Body:        file,
+	})
+}
+
+// StdinDriver reads standard input, or Reader if set, to its end.
+type StdinDriver struct {
+	Reader io.Reader
+}
+
+// Fetch passes the input to handle as a single document.
+func (s *StdinDriver) Fetch(ctx context.Context, handle func(Document) error) error {
+	reader := s.Reader
+	if reader == nil {
+		reader = os.Stdin
+	}
+	return handle(Document{Source: StdinSource, Body: reader})
+}
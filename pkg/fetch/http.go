@@ -0,0 +1,338 @@
+package fetch
+
+import (
+	"beluga/pkg/codec"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Pagination types.
+const (
+	// PaginateLink follows the rel="next" URL of the Link response header.
+	PaginateLink = "link"
+	// PaginatePage increments a page number query parameter until a page has
+	// no items.
+	PaginatePage = "page"
+	// PaginateCursor passes the cursor found in each JSON page to the next request.
+	PaginateCursor = "cursor"
+)
+
+// DefaultMaxPages bounds the pages fetched unless configured otherwise.
+const DefaultMaxPages = 100
+
+// maxErrorBody bounds the response body kept in a StatusError.
+const maxErrorBody = 512
+
+// StatusError is returned for an HTTP response with a non-2xx status.
+type StatusError struct {
+	URL        string
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("fetch %s: http status %d: %s", e.URL, e.StatusCode, e.Body)
+}
+
+// Retryable reports whether the request may succeed if sent again: rate
+// limits and server errors are transient, other client errors are not.
+func (e *StatusError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// Pagination describes how to request the pages of an HTTP source.
+type Pagination struct {
+	// Type is PaginateLink, PaginatePage or PaginateCursor.
+	Type string
+	// Param is the query parameter carrying the page number, "page" by
+	// default, or the cursor, "cursor" by default.
+	Param string
+	// Start is the first page number, 1 by default.
+	Start int
+	// SizeParam and Size request pages of Size items. A page with fewer items
+	// is the last one.
+	SizeParam string
+	Size      int
+	// ItemsPath is the dotted path of the items within a JSON page; the page
+	// itself if empty.
+	ItemsPath string
+	// CursorPath is the dotted path of the next cursor within a JSON page,
+	// "next" by default. A cursor that is a URL is requested as is.
+	CursorPath string
+	// MaxPages bounds the pages fetched; DefaultMaxPages if not positive.
+	MaxPages int
+}
+
+// PaginationFromMap reads pagination from settings with the snake_case
+// names of the Pagination fields.
+func PaginationFromMap(raw interface{}) (*Pagination, error) {
+	settings, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a map, got %T", raw)
+	}
+
+	p := &Pagination{}
+	fields := map[string]*string{
+		"type":        &p.Type,
+		"param":       &p.Param,
+		"size_param":  &p.SizeParam,
+		"items_path":  &p.ItemsPath,
+		"cursor_path": &p.CursorPath,
+	}
+	for key, target := range fields {
+		if value, ok := settings[key]; ok {
+			s, isString := value.(string)
+			if !isString {
+				return nil, fmt.Errorf("%s must be a string, got %T", key, value)
+			}
+			*target = s
+		}
+	}
+	for key, target := range map[string]*int{"start": &p.Start, "size": &p.Size, "max_pages": &p.MaxPages} {
+		if value, ok := settings[key]; ok {
+			n, err := toInt(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			*target = n
+		}
+	}
+	return p, p.validate()
+}
+
+func (p *Pagination) validate() error {
+	switch p.Type {
+	case PaginateLink, PaginatePage, PaginateCursor:
+		return nil
+	}
+	return fmt.Errorf("unknown pagination type: %q", p.Type)
+}
+
+// HTTPDriver fetches a URL, following its pages if paginated.
+type HTTPDriver struct {
+	URL        string
+	Method     string
+	Headers    map[string]string
+	Query      map[string]string
+	Body       string
+	Pagination *Pagination
+	Client     *http.Client
+}
+
+// Fetch requests the URL and passes each page to handle. Responses are
+// streamed, except for page and cursor pagination, whose pages are read
+// whole to find the next one.
+func (h *HTTPDriver) Fetch(ctx context.Context, handle func(Document) error) error {
+	next, err := h.firstURL()
+	if err != nil {
+		return err
+	}
+
+	maxPages := 1
+	if h.Pagination != nil {
+		maxPages = h.Pagination.MaxPages
+		if maxPages <= 0 {
+			maxPages = DefaultMaxPages
+		}
+	}
+
+	for page := 0; next != nil && page < maxPages; page++ {
+		if next, err = h.fetchPage(ctx, next, page, handle); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetchPage requests one page, passes it to handle and returns the URL of
+// the next page, or nil if there is none.
+func (h *HTTPDriver) fetchPage(ctx context.Context, u *url.URL, page int, handle func(Document) error) (*url.URL, error) {
+	response, err := h.get(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	document := Document{
+		Source:      u.String(),
+		ContentType: response.Header.Get("Content-Type"),
+		Body:        response.Body,
+	}
+	p := h.Pagination
+	if p == nil || p.Type == PaginateLink {
+		if err := handle(document); err != nil {
+			return nil, err
+		}
+		if p == nil {
+			return nil, nil
+		}
+		return h.nextURL(u, page, nil, response.Header)
+	}
+
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: failed to read response: %w", u, err)
+	}
+	document.Body = bytes.NewReader(data)
+	if err := handle(document); err != nil {
+		return nil, err
+	}
+	return h.nextURL(u, page, data, response.Header)
+}
+
+// firstURL returns the URL with the configured query and, for page
+// pagination, the first page parameters.
+func (h *HTTPDriver) firstURL() (*url.URL, error) {
+	u, err := url.Parse(h.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url %s: %w", h.URL, err)
+	}
+	query := u.Query()
+	for key, value := range h.Query {
+		query.Set(key, value)
+	}
+	if p := h.Pagination; p != nil {
+		if p.SizeParam != "" && p.Size > 0 {
+			query.Set(p.SizeParam, strconv.Itoa(p.Size))
+		}
+		if p.Type == PaginatePage {
+			query.Set(p.param(), strconv.Itoa(p.start()))
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u, nil
+}
+
+// nextURL returns the URL of the page after the one with the given data, or
+// nil if it was the last.
+func (h *HTTPDriver) nextURL(current *url.URL, page int, data []byte, header http.Header) (*url.URL, error) {
+	p := h.Pagination
+	switch p.Type {
+	case PaginateLink:
+		link := nextLink(header.Get("Link"))
+		if link == "" {
+			return nil, nil
+		}
+		return current.Parse(link)
+
+	case PaginatePage:
+		var value interface{}
+		if err := json.Unmarshal(data, &value); err != nil {
+			return nil, fmt.Errorf("page pagination of %s: invalid JSON page: %w", h.URL, err)
+		}
+		items, _ := codec.Lookup(value, p.ItemsPath).([]interface{})
+		if len(items) == 0 || (p.Size > 0 && len(items) < p.Size) {
+			return nil, nil
+		}
+		next := *current
+		query := next.Query()
+		query.Set(p.param(), strconv.Itoa(p.start()+page+1))
+		next.RawQuery = query.Encode()
+		return &next, nil
+
+	case PaginateCursor:
+		var value interface{}
+		if err := json.Unmarshal(data, &value); err != nil {
+			return nil, fmt.Errorf("cursor pagination of %s: invalid JSON page: %w", h.URL, err)
+		}
+		path := p.CursorPath
+		if path == "" {
+			path = "next"
+		}
+		var cursor string
+		switch c := codec.Lookup(value, path).(type) {
+		case string:
+			cursor = c
+		case float64:
+			cursor = strconv.FormatFloat(c, 'f', -1, 64)
+		}
+		if cursor == "" {
+			return nil, nil
+		}
+		if strings.HasPrefix(cursor, "/") || strings.Contains(cursor, "://") {
+			return current.Parse(cursor)
+		}
+		next := *current
+		query := next.Query()
+		query.Set(p.param(), cursor)
+		next.RawQuery = query.Encode()
+		return &next, nil
+	}
+	return nil, nil
+}
+
+// get performs one request, returning the response if it has a 2xx status.
+func (h *HTTPDriver) get(ctx context.Context, u *url.URL) (*http.Response, error) {
+	var body io.Reader
+	if h.Body != "" {
+		body = strings.NewReader(h.Body)
+	}
+	request, err := http.NewRequestWithContext(ctx, h.Method, u.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, value := range h.Headers {
+		request.Header.Set(name, value)
+	}
+
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", u, err)
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		defer response.Body.Close()
+		data, _ := io.ReadAll(io.LimitReader(response.Body, maxErrorBody))
+		return nil, &StatusError{URL: u.String(), StatusCode: response.StatusCode, Body: string(data)}
+	}
+	return response, nil
+}
+
+func (p *Pagination) param() string {
+	switch {
+	case p.Param != "":
+		return p.Param
+	case p.Type == PaginateCursor:
+		return "cursor"
+	}
+	return "page"
+}
+
+func (p *Pagination) start() int {
+	if p.Start == 0 {
+		return 1
+	}
+	return p.Start
+}
+
+// nextLink returns the rel="next" URL of a Link header.
+func nextLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		parts := strings.Split(link, ";")
+		target := strings.TrimSpace(parts[0])
+		if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+			continue
+		}
+		for _, param := range parts[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(name, "rel") {
+				for _, rel := range strings.Fields(strings.Trim(value, `"`)) {
+					if strings.EqualFold(rel, "next") {
+						return target[1 : len(target)-1]
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
@@ -1,5 +1,9 @@
 package interfaces
 
+import (
+	"context"
+)
+
 // Agent defines the interface for all agents in the system.
 type Agent interface {
 	// Initialize sets up the agent with necessary configurations.
@@ -10,4 +14,49 @@ type Agent interface {
 
 	// Shutdown gracefully stops the agent and cleans up resources.
 	Shutdown() error
-}
\ No newline at end of file
+}
+
+// ContextAgent is the context-aware agent contract. It extends Agent with Run,
+// which receives an input, returns an output and honours cancellation of ctx.
+type ContextAgent interface {
+	Agent
+
+	// Run performs the main task of the agent on the given input and returns its output.
+	Run(ctx context.Context, input interface{}) (interface{}, error)
+}
+
+// AsContextAgent returns agent as a ContextAgent. Agents that already implement
+// ContextAgent are returned unchanged; legacy agents are wrapped so that Run
+// delegates to Execute, ignores the input and returns a nil output.
+func AsContextAgent(agent Agent) ContextAgent {
+	if ca, ok := agent.(ContextAgent); ok {
+		return ca
+	}
+	return &legacyAgent{Agent: agent}
+}
+
+// legacyAgent adapts an Agent that only implements Execute to ContextAgent.
+type legacyAgent struct {
+	Agent
+}
+
+// Run executes the wrapped agent. If ctx is cancelled before Execute returns,
+// Run returns the context error without waiting; Execute keeps running in the
+// background because a legacy agent cannot be interrupted.
+func (l *legacyAgent) Run(ctx context.Context, input interface{}) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- l.Agent.Execute()
+	}()
+
+	select {
+	case err := <-done:
+		return nil, err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,244 @@
+package llm
+
+import (
+	"beluga/pkg/setting"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Role is the author of a chat message.
+type Role string
+
+const (
+	RoleSystem    Role = "system"
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+	RoleTool      Role = "tool"
+)
+
+// Message is a single chat message. An assistant message may request tool
+// calls, whose results are sent back as tool messages naming the call.
+type Message struct {
+	Role       Role       `json:"role"`
+	Content    string     `json:"content"`
+	Name       string     `json:"name,omitempty"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+}
+
+// ToolCall is a request from the model to invoke a tool.
+type ToolCall struct {
+	ID       string       `json:"id"`
+	Type     string       `json:"type"`
+	Function FunctionCall `json:"function"`
+}
+
+// FunctionCall names the tool to invoke and its arguments as a JSON object.
+type FunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// ToolDefinition describes a tool the model may call. Parameters is a JSON
+// Schema for the tool's arguments.
+type ToolDefinition struct {
+	Name        string
+	Description string
+	Parameters  interface{}
+}
+
+// Request is a chat completion request.
+type Request struct {
+	// Model overrides the model the LLM was configured with.
+	Model    string
+	Messages []Message
+	// Temperature, if set, overrides the configured sampling temperature.
+	Temperature *float64
+	// MaxTokens limits the completion length; zero uses the configured limit.
+	MaxTokens int
+	Stop      []string
+	// Tools lists the tools the model may call in its response.
+	Tools []ToolDefinition
+}
+
+// Usage counts the tokens consumed by a request.
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// Add adds other to u.
+func (u *Usage) Add(other Usage) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalTokens += other.TotalTokens
+}
+
+// Response is a completed chat response.
+type Response struct {
+	Model        string
+	Message      Message
+	FinishReason string
+	Usage        Usage
+}
+
+// Chunk is an incremental piece of a streamed response.
+type Chunk struct {
+	Content      string
+	FinishReason string
+}
+
+// LLM is a chat language model.
+type LLM interface {
+	// Chat sends the request and returns the complete response.
+	Chat(ctx context.Context, request Request) (*Response, error)
+	// ChatStream sends the request and calls onChunk as the response is
+	// generated. It returns the assembled response once the stream ends, or
+	// the first error returned by onChunk.
+	ChatStream(ctx context.Context, request Request, onChunk func(Chunk) error) (*Response, error)
+	// GetUsage returns the tokens consumed by every request so far.
+	GetUsage() Usage
+}
+
+// DefaultProvider is the provider used when a Config names none.
+const DefaultProvider = "openai"
+
+// Config selects and configures an LLM provider, as found in agent settings.
+type Config struct {
+	Provider string `json:"provider" yaml:"provider"`
+	Model    string `json:"model" yaml:"model"`
+	BaseURL  string `json:"base_url,omitempty" yaml:"base_url,omitempty"`
+	// APIKey is used as is; APIKeyEnv names an environment variable to read it
+	// from instead, so that keys stay out of configuration files.
+	APIKey      string            `json:"api_key,omitempty" yaml:"api_key,omitempty"`
+	APIKeyEnv   string            `json:"api_key_env,omitempty" yaml:"api_key_env,omitempty"`
+	Temperature *float64          `json:"temperature,omitempty" yaml:"temperature,omitempty"`
+	MaxTokens   int               `json:"max_tokens,omitempty" yaml:"max_tokens,omitempty"`
+	Timeout     time.Duration     `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+}
+
+// GetAPIKey returns the configured API key, reading APIKeyEnv if APIKey is empty.
+func (c Config) GetAPIKey() string {
+	if c.APIKey == "" && c.APIKeyEnv != "" {
+		return os.Getenv(c.APIKeyEnv)
+	}
+	return c.APIKey
+}
+
+// ConfigFromMap reads a Config from a settings map with the keys "provider",
+// "model", "base_url", "api_key", "api_key_env", "temperature", "max_tokens",
+// "timeout" (seconds) and "headers".
+func ConfigFromMap(settings map[string]interface{}) (Config, error) {
+	var c Config
+
+	fields := map[string]*string{
+		"provider":    &c.Provider,
+		"model":       &c.Model,
+		"base_url":    &c.BaseURL,
+		"api_key":     &c.APIKey,
+		"api_key_env": &c.APIKeyEnv,
+	}
+	for key, target := range fields {
+		if raw, ok := settings[key]; ok {
+			s, isString := raw.(string)
+			if !isString {
+				return c, fmt.Errorf("llm %s must be a string, got %T", key, raw)
+			}
+			*target = s
+		}
+	}
+	if raw, ok := settings["temperature"]; ok {
+		value, isNumber := setting.Number(raw)
+		if !isNumber {
+			return c, fmt.Errorf("llm temperature must be a number, got %T", raw)
+		}
+		c.Temperature = &value
+	}
+	if raw, ok := settings["max_tokens"]; ok {
+		value, isNumber := setting.Number(raw)
+		if !isNumber {
+			return c, fmt.Errorf("llm max_tokens must be a number, got %T", raw)
+		}
+		c.MaxTokens = int(value)
+	}
+	if raw, ok := settings["timeout"]; ok {
+		value, isNumber := setting.Number(raw)
+		if !isNumber {
+			return c, fmt.Errorf("llm timeout must be a number, got %T", raw)
+		}
+		c.Timeout = time.Duration(value * float64(time.Second))
+	}
+	if raw, ok := settings["headers"]; ok {
+		headers, isMap := raw.(map[string]interface{})
+		if !isMap {
+			return c, fmt.Errorf("llm headers must be a map, got %T", raw)
+		}
+		c.Headers = make(map[string]string, len(headers))
+		for name, value := range headers {
+			s, isString := value.(string)
+			if !isString {
+				return c, fmt.Errorf("llm header %s must be a string, got %T", name, value)
+			}
+			c.Headers[name] = s
+		}
+	}
+
+	return c, nil
+}
+
+// Factory creates an LLM from its configuration.
+type Factory func(config Config) (LLM, error)
+
+var (
+	providersMutex sync.RWMutex
+	providers      = map[string]Factory{
+		"openai": func(config Config) (LLM, error) { return NewOpenAIClient(config) },
+	}
+)
+
+// RegisterProvider makes a provider available to New under name, replacing
+// any provider already registered with that name.
+func RegisterProvider(name string, factory Factory) {
+	providersMutex.Lock()
+	defer providersMutex.Unlock()
+	providers[name] = factory
+}
+
+// GetProviders returns the names of the registered providers, sorted.
+func GetProviders() []string {
+	providersMutex.RLock()
+	defer providersMutex.RUnlock()
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New creates an LLM with the provider named in config, DefaultProvider if none.
+func New(config Config) (LLM, error) {
+	provider := config.Provider
+	if provider == "" {
+		provider = DefaultProvider
+	}
+
+	providersMutex.RLock()
+	factory, ok := providers[provider]
+	providersMutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown llm provider: %s", provider)
+	}
+
+	model, err := factory(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s llm: %w", provider, err)
+	}
+	return model, nil
+}
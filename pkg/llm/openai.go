@@ -0,0 +1,371 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultOpenAIBaseURL is the base URL of the OpenAI API.
+const DefaultOpenAIBaseURL = "https://api.openai.com/v1"
+
+// DefaultTimeout bounds a request, including a streamed response.
+const DefaultTimeout = 60 * time.Second
+
+// APIError is an error response from a chat completions API.
+type APIError struct {
+	StatusCode int
+	Message    string
+	Type       string
+	Code       string
+}
+
+func (e *APIError) Error() string {
+	if e.Type != "" {
+		return fmt.Sprintf("llm api error %d (%s): %s", e.StatusCode, e.Type, e.Message)
+	}
+	return fmt.Sprintf("llm api error %d: %s", e.StatusCode, e.Message)
+}
+
+// Retryable reports whether the request may succeed if sent again: rate
+// limits and server errors are transient, other client errors are not.
+func (e *APIError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// OpenAIClient is an LLM speaking the OpenAI chat completions API, which many
+// other providers and local model servers also implement.
+type OpenAIClient struct {
+	BaseURL     string
+	APIKey      string
+	Model       string
+	Temperature *float64
+	MaxTokens   int
+	Headers     map[string]string
+	HTTPClient  *http.Client
+	mutex       sync.Mutex
+	usage       Usage
+}
+
+// NewOpenAIClient creates a client from config. BaseURL defaults to
+// DefaultOpenAIBaseURL and the API key to the OPENAI_API_KEY environment
+// variable.
+func NewOpenAIClient(config Config) (*OpenAIClient, error) {
+	if config.Model == "" {
+		return nil, errors.New("model is required")
+	}
+	if config.APIKey == "" && config.APIKeyEnv == "" {
+		config.APIKeyEnv = "OPENAI_API_KEY"
+	}
+	baseURL := config.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultOpenAIBaseURL
+	}
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &OpenAIClient{
+		BaseURL:     strings.TrimRight(baseURL, "/"),
+		APIKey:      config.GetAPIKey(),
+		Model:       config.Model,
+		Temperature: config.Temperature,
+		MaxTokens:   config.MaxTokens,
+		Headers:     config.Headers,
+		HTTPClient:  &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// chatRequest is the JSON body of a chat completions request.
+type chatRequest struct {
+	Model         string         `json:"model"`
+	Messages      []Message      `json:"messages"`
+	Temperature   *float64       `json:"temperature,omitempty"`
+	MaxTokens     int            `json:"max_tokens,omitempty"`
+	Stop          []string       `json:"stop,omitempty"`
+	Tools         []chatTool     `json:"tools,omitempty"`
+	Stream        bool           `json:"stream,omitempty"`
+	StreamOptions *streamOptions `json:"stream_options,omitempty"`
+}
+
+type chatTool struct {
+	Type     string       `json:"type"`
+	Function chatFunction `json:"function"`
+}
+
+type chatFunction struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Parameters  interface{} `json:"parameters,omitempty"`
+}
+
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// chatDelta is the part of a message one event of a streamed response holds.
+type chatDelta struct {
+	Content   string          `json:"content"`
+	ToolCalls []toolCallDelta `json:"tool_calls"`
+}
+
+// toolCallDelta is a fragment of a streamed tool call. Index identifies the
+// call the fragment belongs to, as fragments of parallel calls interleave.
+type toolCallDelta struct {
+	Index *int `json:"index"`
+	ToolCall
+}
+
+// chatResponse is the JSON body of a chat completions response, or of one
+// event of a streamed response.
+type chatResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message      Message   `json:"message"`
+		Delta        chatDelta `json:"delta"`
+		FinishReason string    `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *Usage `json:"usage"`
+}
+
+// Chat sends the request and returns the complete response.
+func (c *OpenAIClient) Chat(ctx context.Context, request Request) (*Response, error) {
+	resp, err := c.send(ctx, c.buildRequest(request, false))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body chatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode chat response: %w", err)
+	}
+	if len(body.Choices) == 0 {
+		return nil, errors.New("chat response has no choices")
+	}
+
+	response := &Response{
+		Model:        body.Model,
+		Message:      body.Choices[0].Message,
+		FinishReason: body.Choices[0].FinishReason,
+	}
+	if body.Usage != nil {
+		response.Usage = *body.Usage
+	}
+	c.addUsage(response.Usage)
+	return response, nil
+}
+
+// ChatStream sends the request with streaming enabled and calls onChunk for
+// each content delta received as a server-sent event. Tool calls streamed in
+// fragments are assembled into the returned message.
+func (c *OpenAIClient) ChatStream(ctx context.Context, request Request, onChunk func(Chunk) error) (*Response, error) {
+	resp, err := c.send(ctx, c.buildRequest(request, true))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	response := &Response{Message: Message{Role: RoleAssistant}}
+	var content strings.Builder
+	var toolCalls []ToolCall
+	positions := make(map[int]int)
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var event chatResponse
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return nil, fmt.Errorf("failed to decode stream event: %w", err)
+		}
+		if event.Model != "" {
+			response.Model = event.Model
+		}
+		if event.Usage != nil {
+			response.Usage = *event.Usage
+		}
+		for _, choice := range event.Choices {
+			if choice.FinishReason != "" {
+				response.FinishReason = choice.FinishReason
+			}
+			toolCalls = mergeToolCallDeltas(toolCalls, positions, choice.Delta.ToolCalls)
+			if choice.Delta.Content == "" && choice.FinishReason == "" {
+				continue
+			}
+			content.WriteString(choice.Delta.Content)
+			if err := onChunk(Chunk{Content: choice.Delta.Content, FinishReason: choice.FinishReason}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read chat stream: %w", err)
+	}
+
+	response.Message.Content = content.String()
+	response.Message.ToolCalls = toolCalls
+	c.addUsage(response.Usage)
+	return response, nil
+}
+
+// mergeToolCallDeltas adds the tool call fragments of a stream event to the
+// calls received so far, positions mapping the index of each call to its
+// position in calls. A fragment with a new index starts a call; the others
+// continue the call of their index, their arguments being appended.
+// Fragments without an index start a call if they have an ID and continue
+// the last call otherwise.
+func mergeToolCallDeltas(calls []ToolCall, positions map[int]int, deltas []toolCallDelta) []ToolCall {
+	for _, delta := range deltas {
+		position, known := 0, false
+		if delta.Index != nil {
+			position, known = positions[*delta.Index]
+		} else if delta.ID == "" && len(calls) > 0 {
+			position, known = len(calls)-1, true
+		}
+		if !known {
+			call := delta.ToolCall
+			if call.Type == "" {
+				call.Type = "function"
+			}
+			if delta.Index != nil {
+				positions[*delta.Index] = len(calls)
+			}
+			calls = append(calls, call)
+			continue
+		}
+		call := &calls[position]
+		if delta.ID != "" {
+			call.ID = delta.ID
+		}
+		if delta.Function.Name != "" {
+			call.Function.Name = delta.Function.Name
+		}
+		call.Function.Arguments += delta.Function.Arguments
+	}
+	return calls
+}
+
+// GetUsage returns the tokens consumed by every request so far.
+func (c *OpenAIClient) GetUsage() Usage {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.usage
+}
+
+func (c *OpenAIClient) addUsage(usage Usage) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.usage.Add(usage)
+}
+
+func (c *OpenAIClient) buildRequest(request Request, stream bool) chatRequest {
+	body := chatRequest{
+		Model:       request.Model,
+		Messages:    request.Messages,
+		Temperature: request.Temperature,
+		MaxTokens:   request.MaxTokens,
+		Stop:        request.Stop,
+		Stream:      stream,
+	}
+	if body.Model == "" {
+		body.Model = c.Model
+	}
+	if body.Temperature == nil {
+		body.Temperature = c.Temperature
+	}
+	if body.MaxTokens == 0 {
+		body.MaxTokens = c.MaxTokens
+	}
+	for _, tool := range request.Tools {
+		body.Tools = append(body.Tools, chatTool{
+			Type:     "function",
+			Function: chatFunction{Name: tool.Name, Description: tool.Description, Parameters: tool.Parameters},
+		})
+	}
+	if stream {
+		body.StreamOptions = &streamOptions{IncludeUsage: true}
+	}
+	return body
+}
+
+// send posts body to the chat completions endpoint and returns the response
+// if its status is successful.
+func (c *OpenAIClient) send(ctx context.Context, body chatRequest) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode chat request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/chat/completions", bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chat request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if body.Stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+	for name, value := range c.Headers {
+		req.Header.Set(name, value)
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("chat request failed: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, decodeAPIError(resp)
+	}
+	return resp, nil
+}
+
+func decodeAPIError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+
+	var body struct {
+		Error struct {
+			Message string      `json:"message"`
+			Type    string      `json:"type"`
+			Code    interface{} `json:"code"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(data, &body) == nil && body.Error.Message != "" {
+		apiErr.Message = body.Error.Message
+		apiErr.Type = body.Error.Type
+		if body.Error.Code != nil {
+			apiErr.Code = fmt.Sprint(body.Error.Code)
+		}
+	} else {
+		apiErr.Message = strings.TrimSpace(string(data))
+		if apiErr.Message == "" {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+	}
+	return apiErr
+}
+
+var _ LLM = (*OpenAIClient)(nil)
@@ -0,0 +1,102 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// DefaultDimensions is the vector size of the default HashEmbedder.
+const DefaultDimensions = 256
+
+// Embedder turns texts into embedding vectors. Implementations wrap an
+// embedding model; every vector an embedder returns has the same length.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float64, error)
+}
+
+// EmbedderFunc adapts an ordinary function to the Embedder interface.
+type EmbedderFunc func(ctx context.Context, texts []string) ([][]float64, error)
+
+// Embed calls f(ctx, texts).
+func (f EmbedderFunc) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	return f(ctx, texts)
+}
+
+// HashEmbedder is a model-free embedder that hashes the lower-cased words of
+// a text into a fixed number of buckets and normalizes the counts to unit
+// length. Texts sharing words get similar vectors, which is enough for
+// keyword-level recall without an external model.
+type HashEmbedder struct {
+	Dimensions int
+}
+
+// NewHashEmbedder creates a HashEmbedder producing vectors of the given size,
+// or DefaultDimensions if dimensions is not positive.
+func NewHashEmbedder(dimensions int) *HashEmbedder {
+	if dimensions <= 0 {
+		dimensions = DefaultDimensions
+	}
+	return &HashEmbedder{Dimensions: dimensions}
+}
+
+// Embed returns one unit vector per text. An empty text gets a zero vector.
+func (e *HashEmbedder) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	dimensions := e.Dimensions
+	if dimensions <= 0 {
+		dimensions = DefaultDimensions
+	}
+
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		vector := make([]float64, dimensions)
+		words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+		})
+		for _, word := range words {
+			h := fnv.New64a()
+			h.Write([]byte(word))
+			vector[h.Sum64()%uint64(dimensions)]++
+		}
+		vectors[i] = normalize(vector)
+	}
+	return vectors, nil
+}
+
+func normalize(vector []float64) []float64 {
+	norm := math.Sqrt(dot(vector, vector))
+	if norm == 0 {
+		return vector
+	}
+	for i := range vector {
+		vector[i] /= norm
+	}
+	return vector
+}
+
+// ContentOf returns the text used to embed an agent input or output: strings
+// as they are, byte slices as text, Stringers through String and anything
+// else as JSON.
+func ContentOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case fmt.Stringer:
+		return v.String()
+	}
+	if data, err := json.Marshal(value); err == nil {
+		return string(data)
+	}
+	return fmt.Sprint(value)
+}
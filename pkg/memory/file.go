@@ -0,0 +1,166 @@
+package memory
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileMemory is a Memory persisted to a file as one JSON entry per line, so
+// that an agent keeps its context across restarts. Inputs and outputs read
+// back from the file are decoded as generic JSON values (maps, slices,
+// strings, float64 numbers, booleans).
+//
+// The most recent entries are cached in memory. Appends go to the end of the
+// file, which is rewritten with only the cached entries once it holds twice
+// the capacity.
+type FileMemory struct {
+	mutex     sync.RWMutex
+	path      string
+	capacity  int
+	entries   []Entry
+	fileLines int
+}
+
+// NewFileMemory opens the store backed by path, creating the file and its
+// directory if needed and loading the entries already written. A capacity of
+// zero means no limit.
+func NewFileMemory(path string, capacity int) (*FileMemory, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create memory directory: %w", err)
+	}
+
+	m := &FileMemory{path: path, capacity: capacity}
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// load reads the entries in the file. A truncated last line, left by an
+// interrupted write, is ignored.
+func (m *FileMemory) load() error {
+	data, err := os.ReadFile(m.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read memory file: %w", err)
+	}
+
+	lines := bytes.Split(data, []byte("\n"))
+	for i, line := range lines {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			if i == len(lines)-1 {
+				break
+			}
+			return fmt.Errorf("invalid memory entry on line %d of %s: %w", i+1, m.path, err)
+		}
+		m.entries = trim(append(m.entries, entry), m.capacity)
+		m.fileLines++
+	}
+	return nil
+}
+
+// Append records an interaction and writes it to the file. The entry must be
+// serializable as JSON.
+func (m *FileMemory) Append(entry Entry) error {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode memory entry: %w", err)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	file, err := os.OpenFile(m.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open memory file: %w", err)
+	}
+	_, err = file.Write(append(line, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write memory entry: %w", err)
+	}
+
+	m.entries = trim(append(m.entries, entry), m.capacity)
+	m.fileLines++
+	if m.capacity > 0 && m.fileLines > 2*m.capacity {
+		return m.compact()
+	}
+	return nil
+}
+
+// compact rewrites the file with only the cached entries. The caller must
+// hold m.mutex.
+func (m *FileMemory) compact() error {
+	tmp, err := os.CreateTemp(filepath.Dir(m.path), filepath.Base(m.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to compact memory file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	for _, entry := range m.entries {
+		if err = encoder.Encode(entry); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), m.path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to compact memory file: %w", err)
+	}
+
+	m.fileLines = len(m.entries)
+	return nil
+}
+
+// Last returns up to n of the most recent entries, oldest first.
+func (m *FileMemory) Last(n int) ([]Entry, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return last(m.entries, n), nil
+}
+
+// Clear forgets every entry and empties the file.
+func (m *FileMemory) Clear() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if err := os.Truncate(m.path, 0); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to clear memory file: %w", err)
+	}
+	m.entries = nil
+	m.fileLines = 0
+	return nil
+}
+
+// GetPath returns the path of the backing file.
+func (m *FileMemory) GetPath() string {
+	return m.path
+}
+
+var _ Memory = (*FileMemory)(nil)
@@ -0,0 +1,175 @@
+package memory
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultCapacity is the number of entries a memory store holds unless its
+// config sets a capacity.
+const DefaultCapacity = 100
+
+// Memory types accepted in the "type" setting.
+const (
+	InMemoryType = "in_memory"
+	FileType     = "file"
+)
+
+// Entry is a single remembered interaction.
+type Entry struct {
+	Timestamp time.Time              `json:"timestamp"`
+	Input     interface{}            `json:"input,omitempty"`
+	Output    interface{}            `json:"output,omitempty"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Memory stores the recent interactions of an agent.
+type Memory interface {
+	// Append records an interaction. A zero Timestamp is set to the current time.
+	Append(entry Entry) error
+	// Last returns up to n of the most recent entries, oldest first. A
+	// non-positive n returns every entry.
+	Last(n int) ([]Entry, error)
+	// Clear forgets every entry.
+	Clear() error
+}
+
+// InMemory is a Memory kept in process memory. It is lost when the process exits.
+type InMemory struct {
+	mutex    sync.RWMutex
+	capacity int
+	entries  []Entry
+}
+
+// NewInMemory creates an in-memory store holding up to capacity entries, the
+// oldest being dropped first. A capacity of zero means no limit.
+func NewInMemory(capacity int) *InMemory {
+	return &InMemory{capacity: capacity}
+}
+
+// Append records an interaction.
+func (m *InMemory) Append(entry Entry) error {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.entries = trim(append(m.entries, entry), m.capacity)
+	return nil
+}
+
+// Last returns up to n of the most recent entries, oldest first.
+func (m *InMemory) Last(n int) ([]Entry, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return last(m.entries, n), nil
+}
+
+// Clear forgets every entry.
+func (m *InMemory) Clear() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.entries = nil
+	return nil
+}
+
+// Len returns the number of entries held.
+func (m *InMemory) Len() int {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return len(m.entries)
+}
+
+// trim drops the oldest entries beyond capacity, reusing the backing array.
+func trim(entries []Entry, capacity int) []Entry {
+	if capacity <= 0 || len(entries) <= capacity {
+		return entries
+	}
+	excess := len(entries) - capacity
+	copy(entries, entries[excess:])
+	for i := capacity; i < len(entries); i++ {
+		entries[i] = Entry{}
+	}
+	return entries[:capacity]
+}
+
+// last returns a copy of the last n entries.
+func last(entries []Entry, n int) []Entry {
+	if n <= 0 || n > len(entries) {
+		n = len(entries)
+	}
+	result := make([]Entry, n)
+	copy(result, entries[len(entries)-n:])
+	return result
+}
+
+// Config describes a memory store, as found in agent settings.
+type Config struct {
+	// Type is InMemoryType (the default) or FileType.
+	Type string `json:"type" yaml:"type"`
+	// Path is the file backing a FileType store.
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+	// Capacity is the number of entries kept; zero means DefaultCapacity and a
+	// negative value means no limit.
+	Capacity int `json:"capacity,omitempty" yaml:"capacity,omitempty"`
+}
+
+// ConfigFromMap reads a Config from a settings map with the keys "type",
+// "path" and "capacity".
+func ConfigFromMap(settings map[string]interface{}) (Config, error) {
+	var c Config
+
+	if raw, ok := settings["type"]; ok {
+		s, isString := raw.(string)
+		if !isString {
+			return c, fmt.Errorf("memory type must be a string, got %T", raw)
+		}
+		c.Type = s
+	}
+	if raw, ok := settings["path"]; ok {
+		s, isString := raw.(string)
+		if !isString {
+			return c, fmt.Errorf("memory path must be a string, got %T", raw)
+		}
+		c.Path = s
+	}
+	if raw, ok := settings["capacity"]; ok {
+		switch v := raw.(type) {
+		case int:
+			c.Capacity = v
+		case float64:
+			c.Capacity = int(v)
+		default:
+			return c, fmt.Errorf("memory capacity must be a number, got %T", raw)
+		}
+	}
+
+	return c, nil
+}
+
+// New creates the memory store described by the config.
+func (c Config) New() (Memory, error) {
+	capacity := c.Capacity
+	switch {
+	case capacity == 0:
+		capacity = DefaultCapacity
+	case capacity < 0:
+		capacity = 0
+	}
+
+	switch c.Type {
+	case "", InMemoryType:
+		return NewInMemory(capacity), nil
+	case FileType:
+		if c.Path == "" {
+			return nil, fmt.Errorf("file memory requires a path")
+		}
+		return NewFileMemory(c.Path, capacity)
+	default:
+		return nil, fmt.Errorf("unknown memory type: %s", c.Type)
+	}
+}
+
+var _ Memory = (*InMemory)(nil)
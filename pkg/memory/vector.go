@@ -0,0 +1,649 @@
+package memory
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Metric is the similarity measure used to rank documents.
+type Metric string
+
+const (
+	// Cosine compares the direction of vectors, ignoring their length.
+	Cosine Metric = "cosine"
+	// DotProduct scores by the inner product of vectors.
+	DotProduct Metric = "dot"
+)
+
+// DefaultTopK is the number of results a query returns unless it asks otherwise.
+const DefaultTopK = 5
+
+// DefaultVectorCapacity is the number of documents a vector memory holds
+// unless its config sets a capacity.
+const DefaultVectorCapacity = 10000
+
+// ErrDimensionMismatch is returned when a vector does not have the length of
+// the vectors already in an index.
+var ErrDimensionMismatch = errors.New("vector dimension mismatch")
+
+// Document is a record stored in a vector memory.
+type Document struct {
+	ID       string                 `json:"id"`
+	Content  string                 `json:"content"`
+	Vector   []float64              `json:"vector"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Query describes a similarity search.
+type Query struct {
+	// Text is embedded to give the query vector when Vector is empty.
+	Text   string
+	Vector []float64
+	// TopK is the maximum number of results; DefaultTopK if not positive.
+	TopK int
+	// Metric overrides the index's metric.
+	Metric Metric
+	// MinScore excludes results scoring below it.
+	MinScore float64
+	// Filter keeps documents whose metadata has every key with an equal value.
+	// A slice value matches any of its elements. Numbers compare by value.
+	Filter map[string]interface{}
+}
+
+// SearchResult is a document matching a query with its similarity score.
+type SearchResult struct {
+	Document Document `json:"document"`
+	Score    float64  `json:"score"`
+}
+
+// VectorStore is a long-term semantic memory.
+type VectorStore interface {
+	// Add stores documents, embedding those without a vector. A document with
+	// the ID of a stored document replaces it; an empty ID is assigned.
+	Add(ctx context.Context, documents ...Document) error
+	// Search returns the documents most similar to the query, best first.
+	Search(ctx context.Context, query Query) ([]SearchResult, error)
+	// Delete removes the documents with the given IDs.
+	Delete(ids ...string) error
+	// Len returns the number of stored documents.
+	Len() int
+}
+
+// VectorIndex is an in-process VectorStore that scores every document on
+// each query. It can be persisted to a file of one JSON record per line.
+//
+// Changes are appended to the file, which is rewritten with only the stored
+// documents once it holds twice as many records. An index with a capacity
+// drops its oldest documents beyond it.
+type VectorIndex struct {
+	mutex      sync.RWMutex
+	embedder   Embedder
+	metric     Metric
+	capacity   int
+	dimensions int
+	documents  []Document
+	positions  map[string]int
+	nextID     uint64
+	path       string
+	fileLines  int
+}
+
+// NewVectorIndex creates an empty index that embeds with embedder (a
+// HashEmbedder if nil) and ranks by metric (Cosine if empty).
+func NewVectorIndex(embedder Embedder, metric Metric) *VectorIndex {
+	if embedder == nil {
+		embedder = NewHashEmbedder(0)
+	}
+	if metric == "" {
+		metric = Cosine
+	}
+	return &VectorIndex{
+		embedder:  embedder,
+		metric:    metric,
+		positions: make(map[string]int),
+	}
+}
+
+// OpenVectorIndex creates an index persisted to path: the documents already
+// saved there are loaded, and every change is appended to the file.
+func OpenVectorIndex(path string, embedder Embedder, metric Metric) (*VectorIndex, error) {
+	index := NewVectorIndex(embedder, metric)
+	if err := index.open(path); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// open loads the documents saved at path and persists later changes there.
+func (x *VectorIndex) open(path string) error {
+	if err := x.Load(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	x.path = path
+	return nil
+}
+
+// SetCapacity limits the index to capacity documents, dropping the oldest
+// beyond it. A capacity of zero means no limit.
+func (x *VectorIndex) SetCapacity(capacity int) error {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	x.capacity = capacity
+	if x.evict() == 0 {
+		return nil
+	}
+	return x.compact()
+}
+
+// Add stores documents, embedding those without a vector. The documents are
+// appended to the index's file in a single write.
+func (x *VectorIndex) Add(ctx context.Context, documents ...Document) error {
+	documents = append([]Document(nil), documents...)
+	var texts []string
+	var missing []int
+	for i, doc := range documents {
+		if len(doc.Vector) == 0 {
+			texts = append(texts, doc.Content)
+			missing = append(missing, i)
+		}
+	}
+	if len(texts) > 0 {
+		vectors, err := x.embedder.Embed(ctx, texts)
+		if err != nil {
+			return fmt.Errorf("failed to embed documents: %w", err)
+		}
+		if len(vectors) != len(texts) {
+			return fmt.Errorf("embedder returned %d vectors for %d documents", len(vectors), len(texts))
+		}
+		for i, position := range missing {
+			documents[position].Vector = vectors[i]
+		}
+	}
+
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	dimensions := x.dimensions
+	for _, doc := range documents {
+		if dimensions == 0 {
+			dimensions = len(doc.Vector)
+		}
+		if len(doc.Vector) != dimensions {
+			return fmt.Errorf("document %q has %d dimensions, expected %d: %w", doc.ID, len(doc.Vector), dimensions, ErrDimensionMismatch)
+		}
+	}
+	x.dimensions = dimensions
+
+	records := make([]vectorRecord, len(documents))
+	for i := range documents {
+		if documents[i].ID == "" {
+			documents[i].ID = x.newID()
+		}
+		x.store(documents[i])
+		records[i] = vectorRecord{Document: &documents[i]}
+	}
+	x.evict()
+	return x.persist(records)
+}
+
+// store adds doc, replacing the document with its ID in place. The caller
+// must hold x.mutex.
+func (x *VectorIndex) store(doc Document) {
+	if position, ok := x.positions[doc.ID]; ok {
+		x.documents[position] = doc
+		return
+	}
+	x.positions[doc.ID] = len(x.documents)
+	x.documents = append(x.documents, doc)
+}
+
+// evict drops the oldest documents beyond the capacity and returns how many
+// it dropped. The caller must hold x.mutex.
+func (x *VectorIndex) evict() int {
+	excess := len(x.documents) - x.capacity
+	if x.capacity <= 0 || excess <= 0 {
+		return 0
+	}
+	for _, doc := range x.documents[:excess] {
+		delete(x.positions, doc.ID)
+	}
+	x.remove(excess)
+	return excess
+}
+
+// remove drops the first skip documents and those no longer in x.positions,
+// reusing the backing array. The caller must hold x.mutex.
+func (x *VectorIndex) remove(skip int) {
+	kept := x.documents[:0]
+	for _, doc := range x.documents[skip:] {
+		if _, ok := x.positions[doc.ID]; ok {
+			x.positions[doc.ID] = len(kept)
+			kept = append(kept, doc)
+		}
+	}
+	for i := len(kept); i < len(x.documents); i++ {
+		x.documents[i] = Document{}
+	}
+	x.documents = kept
+}
+
+// newID returns an ID not used by any document. The caller must hold x.mutex.
+func (x *VectorIndex) newID() string {
+	for {
+		x.nextID++
+		id := strconv.FormatUint(x.nextID, 10)
+		if _, taken := x.positions[id]; !taken {
+			return id
+		}
+	}
+}
+
+// Search returns the documents most similar to the query, best first.
+func (x *VectorIndex) Search(ctx context.Context, query Query) ([]SearchResult, error) {
+	vector := query.Vector
+	if len(vector) == 0 {
+		vectors, err := x.embedder.Embed(ctx, []string{query.Text})
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed query: %w", err)
+		}
+		if len(vectors) != 1 {
+			return nil, fmt.Errorf("embedder returned %d vectors for 1 query", len(vectors))
+		}
+		vector = vectors[0]
+	}
+	topK := query.TopK
+	if topK <= 0 {
+		topK = DefaultTopK
+	}
+
+	x.mutex.RLock()
+	defer x.mutex.RUnlock()
+
+	metric := query.Metric
+	if metric == "" {
+		metric = x.metric
+	}
+	if metric != Cosine && metric != DotProduct {
+		return nil, fmt.Errorf("unknown similarity metric: %s", metric)
+	}
+	if x.dimensions != 0 && len(vector) != x.dimensions {
+		return nil, fmt.Errorf("query has %d dimensions, expected %d: %w", len(vector), x.dimensions, ErrDimensionMismatch)
+	}
+
+	var results []SearchResult
+	for _, doc := range x.documents {
+		if !matches(doc.Metadata, query.Filter) {
+			continue
+		}
+		score := similarity(metric, vector, doc.Vector)
+		if score < query.MinScore {
+			continue
+		}
+		results = append(results, SearchResult{Document: doc, Score: score})
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if len(results) > topK {
+		results = results[:topK]
+	}
+	return results, nil
+}
+
+// Delete removes the documents with the given IDs. Unknown IDs are ignored.
+func (x *VectorIndex) Delete(ids ...string) error {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	var removed []string
+	for _, id := range ids {
+		if _, ok := x.positions[id]; ok {
+			delete(x.positions, id)
+			removed = append(removed, id)
+		}
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+	x.remove(0)
+	return x.persist([]vectorRecord{{Deleted: removed}})
+}
+
+// Len returns the number of stored documents.
+func (x *VectorIndex) Len() int {
+	x.mutex.RLock()
+	defer x.mutex.RUnlock()
+	return len(x.documents)
+}
+
+// vectorRecord is a line of a vector index file: a stored document or the
+// IDs of deleted documents.
+type vectorRecord struct {
+	Document *Document `json:"document,omitempty"`
+	Deleted  []string  `json:"deleted,omitempty"`
+}
+
+// Save writes the stored documents to path, replacing the file atomically.
+func (x *VectorIndex) Save(path string) error {
+	x.mutex.RLock()
+	defer x.mutex.RUnlock()
+	return x.save(path)
+}
+
+// persist appends records to the index's own file, if it has one, and
+// compacts the file once it holds twice as many records as documents. The
+// caller must hold x.mutex.
+func (x *VectorIndex) persist(records []vectorRecord) error {
+	if x.path == "" {
+		return nil
+	}
+
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return fmt.Errorf("failed to encode vector index: %w", err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(x.path), 0755); err != nil {
+		return fmt.Errorf("failed to create vector index directory: %w", err)
+	}
+	file, err := os.OpenFile(x.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open vector index: %w", err)
+	}
+	_, err = file.Write(data.Bytes())
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save vector index: %w", err)
+	}
+
+	x.fileLines += len(records)
+	if x.fileLines > 2*len(x.documents) {
+		return x.compact()
+	}
+	return nil
+}
+
+// compact rewrites the index's own file, if it has one, with only the stored
+// documents. The caller must hold x.mutex.
+func (x *VectorIndex) compact() error {
+	if x.path == "" {
+		return nil
+	}
+	if err := x.save(x.path); err != nil {
+		return err
+	}
+	x.fileLines = len(x.documents)
+	return nil
+}
+
+// save writes the stored documents to path. The caller must hold x.mutex.
+func (x *VectorIndex) save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create vector index directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to save vector index: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	for i := range x.documents {
+		if err = encoder.Encode(vectorRecord{Document: &x.documents[i]}); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save vector index: %w", err)
+	}
+	return nil
+}
+
+// Load replaces the documents of the index with those saved at path, keeping
+// at most the capacity of the index. The index keeps its own metric. A
+// truncated last line, left by an interrupted write, is ignored.
+func (x *VectorIndex) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read vector index: %w", err)
+	}
+
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	loaded := &VectorIndex{capacity: x.capacity, positions: make(map[string]int)}
+	lines := bytes.Split(data, []byte("\n"))
+	for i, line := range lines {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var record vectorRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			if i == len(lines)-1 {
+				break
+			}
+			return fmt.Errorf("invalid vector index record on line %d of %s: %w", i+1, path, err)
+		}
+		loaded.fileLines++
+
+		if doc := record.Document; doc != nil {
+			if loaded.dimensions == 0 {
+				loaded.dimensions = len(doc.Vector)
+			}
+			if len(doc.Vector) != loaded.dimensions {
+				return fmt.Errorf("invalid vector index %s: document %q: %w", path, doc.ID, ErrDimensionMismatch)
+			}
+			loaded.store(*doc)
+			loaded.evict()
+		}
+		for _, id := range record.Deleted {
+			delete(loaded.positions, id)
+		}
+		if len(record.Deleted) > 0 {
+			loaded.remove(0)
+		}
+	}
+
+	x.dimensions = loaded.dimensions
+	x.documents = loaded.documents
+	x.positions = loaded.positions
+	x.fileLines = loaded.fileLines
+	return nil
+}
+
+func similarity(metric Metric, a, b []float64) float64 {
+	score := dot(a, b)
+	if metric == DotProduct {
+		return score
+	}
+	norms := math.Sqrt(dot(a, a)) * math.Sqrt(dot(b, b))
+	if norms == 0 {
+		return 0
+	}
+	return score / norms
+}
+
+func dot(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// matches reports whether metadata satisfies every condition of filter.
+func matches(metadata, filter map[string]interface{}) bool {
+	for key, want := range filter {
+		got, ok := metadata[key]
+		if !ok {
+			return false
+		}
+		if options, isSlice := want.([]interface{}); isSlice {
+			found := false
+			for _, option := range options {
+				if equal(got, option) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+			continue
+		}
+		if !equal(got, want) {
+			return false
+		}
+	}
+	return true
+}
+
+// equal compares metadata values, treating numbers of any type by value so
+// that filters keep matching after a round trip through JSON.
+func equal(a, b interface{}) bool {
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func number(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// VectorConfig describes a vector memory, as found in agent settings.
+type VectorConfig struct {
+	// Path persists the index to a file when set.
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+	// Metric is Cosine (the default) or DotProduct.
+	Metric Metric `json:"metric,omitempty" yaml:"metric,omitempty"`
+	// Capacity is the number of documents kept; zero means
+	// DefaultVectorCapacity and a negative value means no limit.
+	Capacity int `json:"capacity,omitempty" yaml:"capacity,omitempty"`
+	// Dimensions sizes the vectors of the default HashEmbedder.
+	Dimensions int `json:"dimensions,omitempty" yaml:"dimensions,omitempty"`
+	// TopK is the number of records an agent retrieves before it runs.
+	TopK int `json:"top_k,omitempty" yaml:"top_k,omitempty"`
+	// MinScore excludes retrieved records scoring below it.
+	MinScore float64 `json:"min_score,omitempty" yaml:"min_score,omitempty"`
+	// Filter restricts retrieved records by metadata, as in Query.
+	Filter map[string]interface{} `json:"filter,omitempty" yaml:"filter,omitempty"`
+}
+
+// VectorConfigFromMap reads a VectorConfig from a settings map with the keys
+// "path", "metric", "capacity", "dimensions", "top_k", "min_score" and
+// "filter".
+func VectorConfigFromMap(settings map[string]interface{}) (VectorConfig, error) {
+	var c VectorConfig
+
+	if raw, ok := settings["path"]; ok {
+		s, isString := raw.(string)
+		if !isString {
+			return c, fmt.Errorf("vector memory path must be a string, got %T", raw)
+		}
+		c.Path = s
+	}
+	if raw, ok := settings["metric"]; ok {
+		s, isString := raw.(string)
+		if !isString {
+			return c, fmt.Errorf("vector memory metric must be a string, got %T", raw)
+		}
+		c.Metric = Metric(s)
+	}
+	for key, target := range map[string]*int{"capacity": &c.Capacity, "dimensions": &c.Dimensions, "top_k": &c.TopK} {
+		if raw, ok := settings[key]; ok {
+			value, isNumber := number(raw)
+			if !isNumber {
+				return c, fmt.Errorf("vector memory %s must be a number, got %T", key, raw)
+			}
+			*target = int(value)
+		}
+	}
+	if raw, ok := settings["min_score"]; ok {
+		value, isNumber := number(raw)
+		if !isNumber {
+			return c, fmt.Errorf("vector memory min_score must be a number, got %T", raw)
+		}
+		c.MinScore = value
+	}
+	if raw, ok := settings["filter"]; ok {
+		filter, isMap := raw.(map[string]interface{})
+		if !isMap {
+			return c, fmt.Errorf("vector memory filter must be a map, got %T", raw)
+		}
+		c.Filter = filter
+	}
+
+	return c, nil
+}
+
+// New creates the index described by the config, embedding with embedder or,
+// if nil, a HashEmbedder of the configured dimensions.
+func (c VectorConfig) New(embedder Embedder) (*VectorIndex, error) {
+	switch c.Metric {
+	case "", Cosine, DotProduct:
+	default:
+		return nil, fmt.Errorf("unknown similarity metric: %s", c.Metric)
+	}
+	if embedder == nil {
+		embedder = NewHashEmbedder(c.Dimensions)
+	}
+	capacity := c.Capacity
+	switch {
+	case capacity == 0:
+		capacity = DefaultVectorCapacity
+	case capacity < 0:
+		capacity = 0
+	}
+
+	index := NewVectorIndex(embedder, c.Metric)
+	index.capacity = capacity
+	if c.Path != "" {
+		if err := index.open(c.Path); err != nil {
+			return nil, err
+		}
+	}
+	return index, nil
+}
+
+var _ VectorStore = (*VectorIndex)(nil)
@@ -0,0 +1,86 @@
+package monitoring
+
+import "time"
+
+// Names of the metrics reported for every agent, each labelled with "agent".
+const (
+	AgentExecutionsMetric    = "beluga_agent_executions_total"
+	AgentSuccessesMetric     = "beluga_agent_execution_successes_total"
+	AgentFailuresMetric      = "beluga_agent_execution_failures_total"
+	AgentCancellationsMetric = "beluga_agent_execution_cancellations_total"
+	AgentRetriesMetric       = "beluga_agent_execution_retries_total"
+	AgentDurationMetric      = "beluga_agent_execution_duration_seconds"
+	AgentInFlightMetric      = "beluga_agent_executions_in_flight"
+)
+
+// AgentMetrics holds the execution metrics of a single agent.
+type AgentMetrics struct {
+	Executions    *Counter
+	Successes     *Counter
+	Failures      *Counter
+	Cancellations *Counter
+	Retries       *Counter
+	Duration      *Histogram
+	InFlight      *Gauge
+}
+
+// NewAgentMetrics returns the metrics of the named agent in registry,
+// registering the agent metric families if needed. Agents with the same name
+// share their metrics.
+func NewAgentMetrics(registry *MetricsRegistry, agent string) *AgentMetrics {
+	return &AgentMetrics{
+		Executions: registry.Counter(AgentExecutionsMetric,
+			"Number of agent executions started.", "agent").WithLabelValues(agent),
+		Successes: registry.Counter(AgentSuccessesMetric,
+			"Number of agent executions that succeeded.", "agent").WithLabelValues(agent),
+		Failures: registry.Counter(AgentFailuresMetric,
+			"Number of agent executions that failed after all retries.", "agent").WithLabelValues(agent),
+		Cancellations: registry.Counter(AgentCancellationsMetric,
+			"Number of agent executions cancelled before they finished.", "agent").WithLabelValues(agent),
+		Retries: registry.Counter(AgentRetriesMetric,
+			"Number of retried agent execution attempts.", "agent").WithLabelValues(agent),
+		Duration: registry.Histogram(AgentDurationMetric,
+			"Duration of agent executions in seconds, including retries.", nil, "agent").WithLabelValues(agent),
+		InFlight: registry.Gauge(AgentInFlightMetric,
+			"Number of agent executions currently running.", "agent").WithLabelValues(agent),
+	}
+}
+
+// ExecutionStarted records the start of an execution.
+func (m *AgentMetrics) ExecutionStarted() {
+	m.Executions.Inc()
+	m.InFlight.Inc()
+}
+
+// ExecutionFinished records the end of an execution that started at start. An
+// execution that neither succeeded nor was cancelled counts as a failure.
+func (m *AgentMetrics) ExecutionFinished(start time.Time, succeeded, cancelled bool) {
+	m.InFlight.Dec()
+	m.Duration.Observe(time.Since(start).Seconds())
+	switch {
+	case succeeded:
+		m.Successes.Inc()
+	case cancelled:
+		m.Cancellations.Inc()
+	default:
+		m.Failures.Inc()
+	}
+}
+
+// Snapshot returns the current metric values, for inclusion in health reports.
+func (m *AgentMetrics) Snapshot() map[string]interface{} {
+	count := m.Duration.Count()
+	mean := 0.0
+	if count > 0 {
+		mean = m.Duration.Sum() / float64(count)
+	}
+	return map[string]interface{}{
+		"executions":            m.Executions.Value(),
+		"successes":             m.Successes.Value(),
+		"failures":              m.Failures.Value(),
+		"cancellations":         m.Cancellations.Value(),
+		"retries":               m.Retries.Value(),
+		"in_flight":             m.InFlight.Value(),
+		"mean_duration_seconds": mean,
+	}
+}
@@ -0,0 +1,212 @@
+package monitoring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnknownScheme is returned for a target whose scheme no collector is
+// registered for.
+var ErrUnknownScheme = errors.New("no collector for scheme")
+
+// AgentScheme is the scheme of targets naming agents. Targets without a
+// scheme, such as "web_data_fetcher", name agents too.
+const AgentScheme = "agent"
+
+// Sample is a measurement of a monitored target.
+type Sample struct {
+	Target    string                 `json:"target"`
+	Status    HealthStatus           `json:"status"`
+	Message   string                 `json:"message,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+	Metrics   map[string]float64     `json:"metrics,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// ApplyThresholds degrades a healthy or degraded sample whose metrics
+// exceed their threshold, naming the metrics in its message.
+func (s *Sample) ApplyThresholds(thresholds map[string]float64) {
+	if s.Status != StatusHealthy && s.Status != StatusDegraded {
+		return
+	}
+	var exceeded []string
+	for _, name := range sortedMetricNames(s.Metrics) {
+		limit, ok := thresholds[name]
+		if ok && s.Metrics[name] > limit {
+			exceeded = append(exceeded, fmt.Sprintf("%s %s exceeds %s", name, formatValue(s.Metrics[name]), formatValue(limit)))
+		}
+	}
+	if len(exceeded) > 0 {
+		s.Status = StatusDegraded
+		s.Message = strings.Join(exceeded, ", ")
+	}
+}
+
+func sortedMetricNames(metrics map[string]float64) []string {
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Collector measures targets of a URI scheme, such as "tcp://db:5432".
+// It returns an error if the target cannot be measured; a target that
+// answers badly is reported by the status of the sample instead.
+type Collector interface {
+	Collect(ctx context.Context, target *url.URL) (*Sample, error)
+}
+
+// CollectorFunc is a Collector implemented by a function.
+type CollectorFunc func(ctx context.Context, target *url.URL) (*Sample, error)
+
+// Collect calls the function.
+func (f CollectorFunc) Collect(ctx context.Context, target *url.URL) (*Sample, error) {
+	return f(ctx, target)
+}
+
+// ParseTarget parses a monitor target. A target without a scheme names an
+// agent and is returned as agent:NAME.
+func ParseTarget(target string) (*url.URL, error) {
+	if !strings.Contains(target, ":") {
+		if target == "" {
+			return nil, errors.New("empty monitor target")
+		}
+		return &url.URL{Scheme: AgentScheme, Opaque: target}, nil
+	}
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid monitor target %q: %w", target, err)
+	}
+	if parsed.Scheme == "" {
+		return nil, fmt.Errorf("monitor target %q has no scheme", target)
+	}
+	return parsed, nil
+}
+
+// Collectors maps URI schemes to collectors.
+type Collectors struct {
+	mutex      sync.RWMutex
+	collectors map[string]Collector
+}
+
+// NewCollectors creates a registry holding the built-in collectors: proc
+// for process metrics, http and https probes, tcp connect probes and disk
+// usage.
+func NewCollectors() *Collectors {
+	c := &Collectors{collectors: make(map[string]Collector)}
+	probe := NewHTTPCollector()
+	c.MustRegister("proc", NewProcessCollector())
+	c.MustRegister("http", probe)
+	c.MustRegister("https", probe)
+	c.MustRegister("tcp", NewTCPCollector())
+	c.MustRegister("disk", NewDiskCollector())
+	return c
+}
+
+var defaultCollectors = NewCollectors()
+
+// DefaultCollectors returns the process-wide registry monitors collect
+// with unless given their own.
+func DefaultCollectors() *Collectors {
+	return defaultCollectors
+}
+
+var validScheme = regexp.MustCompile(`^[a-z][a-z0-9+.-]*$`)
+
+// Register adds a collector for a lower-case scheme, which must not be
+// taken.
+func (c *Collectors) Register(scheme string, collector Collector) error {
+	if !validScheme.MatchString(scheme) {
+		return fmt.Errorf("invalid scheme %q", scheme)
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if _, exists := c.collectors[scheme]; exists {
+		return fmt.Errorf("a collector for scheme %s is already registered", scheme)
+	}
+	c.collectors[scheme] = collector
+	return nil
+}
+
+// MustRegister is like Register but panics on error.
+func (c *Collectors) MustRegister(scheme string, collector Collector) {
+	if err := c.Register(scheme, collector); err != nil {
+		panic(err)
+	}
+}
+
+// Unregister removes the collector of a scheme.
+func (c *Collectors) Unregister(scheme string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.collectors, scheme)
+}
+
+// Has reports whether a collector is registered for a scheme.
+func (c *Collectors) Has(scheme string) bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	_, ok := c.collectors[strings.ToLower(scheme)]
+	return ok
+}
+
+// Schemes returns the schemes collectors are registered for, in order.
+func (c *Collectors) Schemes() []string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	schemes := make([]string, 0, len(c.collectors))
+	for scheme := range c.collectors {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// Collect measures a target with the collector of its scheme. It always
+// returns a sample: targets that cannot be measured are unhealthy, with
+// the error as message.
+func (c *Collectors) Collect(ctx context.Context, target string) *Sample {
+	parsed, err := ParseTarget(target)
+	if err == nil {
+		c.mutex.RLock()
+		collector, ok := c.collectors[strings.ToLower(parsed.Scheme)]
+		c.mutex.RUnlock()
+		if !ok {
+			err = fmt.Errorf("%w %s", ErrUnknownScheme, parsed.Scheme)
+		} else {
+			return CollectWith(ctx, collector, target, parsed)
+		}
+	}
+	return &Sample{Target: target, Status: StatusUnhealthy, Message: err.Error(), Timestamp: time.Now()}
+}
+
+// CollectWith measures a parsed target with collector, filling in the
+// target and timestamp of the sample and turning errors into unhealthy
+// samples.
+func CollectWith(ctx context.Context, collector Collector, target string, parsed *url.URL) *Sample {
+	started := time.Now()
+	sample, err := collector.Collect(ctx, parsed)
+	if err != nil {
+		return &Sample{Target: target, Status: StatusUnhealthy, Message: err.Error(), Timestamp: started}
+	}
+	if sample == nil {
+		sample = &Sample{}
+	}
+	sample.Target = target
+	if sample.Status == "" {
+		sample.Status = StatusHealthy
+	}
+	if sample.Timestamp.IsZero() {
+		sample.Timestamp = started
+	}
+	return sample
+}
@@ -0,0 +1,47 @@
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// DiskCollector measures the file system holding the path of disk targets,
+// such as "disk:///var/lib". Samples have the metrics disk_total_bytes,
+// disk_free_bytes, the bytes available to unprivileged users, and
+// disk_used_percent.
+type DiskCollector struct{}
+
+// NewDiskCollector creates a disk usage collector.
+func NewDiskCollector() *DiskCollector {
+	return &DiskCollector{}
+}
+
+// Collect measures the file system.
+func (c *DiskCollector) Collect(ctx context.Context, target *url.URL) (*Sample, error) {
+	path := target.Path
+	if path == "" {
+		path = target.Opaque
+	}
+	if path == "" || target.Host != "" {
+		return nil, fmt.Errorf("disk target must be disk:///PATH, got %s", target)
+	}
+	total, free, available, err := diskUsage(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure disk %s: %w", path, err)
+	}
+	used := 0.0
+	// Usage is relative to what unprivileged users may use, as df reports it
+	if capacity := total - free + available; capacity > 0 {
+		used = 100 * float64(total-free) / float64(capacity)
+	}
+	return &Sample{
+		Status: StatusHealthy,
+		Metrics: map[string]float64{
+			"disk_total_bytes":  float64(total),
+			"disk_free_bytes":   float64(available),
+			"disk_used_percent": used,
+		},
+		Details: map[string]interface{}{"path": path},
+	}, nil
+}
@@ -0,0 +1,16 @@
+//go:build unix
+
+package monitoring
+
+import "syscall"
+
+// diskUsage returns the total, free and available bytes of the file system
+// holding path.
+func diskUsage(path string) (total, free, available uint64, err error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, 0, 0, err
+	}
+	size := uint64(stat.Bsize)
+	return uint64(stat.Blocks) * size, uint64(stat.Bfree) * size, uint64(stat.Bavail) * size, nil
+}
@@ -1,6 +1,9 @@
 package monitoring
 
 import (
+	"beluga/pkg/retry"
+	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -43,6 +46,7 @@ type HealthCheck struct {
 	LastResult  *HealthCheckResult
 	MaxRetries  int
 	RetryDelay  time.Duration
+	RetryPolicy *retry.Policy
 	Alerts      []AlertFunc
 	Logger      *Logger
 	mutex       sync.RWMutex
@@ -94,7 +98,6 @@ func (hc *HealthCheck) Start() {
 
 // RunCheck executes the health check once.
 func (hc *HealthCheck) RunCheck() {
-	var result *HealthCheckResult
 	var prevStatus HealthStatus
 
 	hc.mutex.RLock()
@@ -103,53 +106,18 @@ func (hc *HealthCheck) RunCheck() {
 	}
 	hc.mutex.RUnlock()
 
-	// Create a timeout context for the check
-	checkComplete := make(chan struct{})
-	var checkResult *HealthCheckResult
-
-	go func() {
-		checkResult = hc.Check()
-		close(checkComplete)
-	}()
-
-	// Wait for check to complete or timeout
-	select {
-	case <-checkComplete:
-		result = checkResult
-	case <-time.After(hc.Timeout):
-		result = &HealthCheckResult{
-			Status:      StatusUnhealthy,
-			Message:     fmt.Sprintf("Health check timed out after %v", hc.Timeout),
-			Timestamp:   time.Now(),
-			CheckName:   hc.Name,
-			ComponentID: hc.ComponentID,
-		}
-	}
-
-	if result == nil {
-		result = &HealthCheckResult{
-			Status:      StatusUnhealthy,
-			Message:     "Health check returned nil result",
-			Timestamp:   time.Now(),
-			CheckName:   hc.Name,
-			ComponentID: hc.ComponentID,
-		}
-	}
-
-	// Retry logic for failed checks
-	attempts := 1
-	for result.Status == StatusUnhealthy && attempts <= hc.MaxRetries {
-		hc.Logger.Warning("Health check failed for %s (%s), retrying (%d/%d)...", 
-			hc.Name, hc.ComponentID, attempts, hc.MaxRetries)
-		time.Sleep(hc.RetryDelay)
-
-		retryResult := hc.Check()
-		if retryResult != nil && retryResult.Status != StatusUnhealthy {
-			result = retryResult
-			result.Message = fmt.Sprintf("Recovered on retry %d: %s", attempts, result.Message)
-			break
+	// Retry failed checks according to the retry policy
+	var result *HealthCheckResult
+	policy := hc.retryPolicy()
+	attempts, _ := policy.Do(context.Background(), func(ctx context.Context) error {
+		result = hc.checkOnce()
+		if result.Status == StatusUnhealthy {
+			return errors.New(result.Message)
 		}
-		attempts++
+		return nil
+	})
+	if attempts > 1 && result.Status != StatusUnhealthy {
+		result.Message = fmt.Sprintf("Recovered on retry %d: %s", attempts-1, result.Message)
 	}
 
 	// Update last result
@@ -175,6 +143,61 @@ func (hc *HealthCheck) RunCheck() {
 	}
 }
 
+// checkOnce runs the check function, turning a timeout or nil result into an
+// unhealthy result.
+func (hc *HealthCheck) checkOnce() *HealthCheckResult {
+	checkComplete := make(chan *HealthCheckResult, 1)
+	go func() {
+		checkComplete <- hc.Check()
+	}()
+
+	// Wait for check to complete or timeout
+	select {
+	case result := <-checkComplete:
+		if result != nil {
+			return result
+		}
+		return &HealthCheckResult{
+			Status:      StatusUnhealthy,
+			Message:     "Health check returned nil result",
+			Timestamp:   time.Now(),
+			CheckName:   hc.Name,
+			ComponentID: hc.ComponentID,
+		}
+	case <-time.After(hc.Timeout):
+		return &HealthCheckResult{
+			Status:      StatusUnhealthy,
+			Message:     fmt.Sprintf("Health check timed out after %v", hc.Timeout),
+			Timestamp:   time.Now(),
+			CheckName:   hc.Name,
+			ComponentID: hc.ComponentID,
+		}
+	}
+}
+
+// retryPolicy returns the policy used to retry failed checks. Without a
+// RetryPolicy, checks are retried MaxRetries times, RetryDelay apart.
+func (hc *HealthCheck) retryPolicy() retry.Policy {
+	var policy retry.Policy
+	if hc.RetryPolicy != nil {
+		policy = *hc.RetryPolicy
+	} else {
+		policy = *retry.Constant(hc.MaxRetries, hc.RetryDelay)
+	}
+	// An unhealthy result is always worth checking again
+	policy.Classifier = func(error) bool { return true }
+
+	onRetry := policy.OnRetry
+	policy.OnRetry = func(attempt int, err error, delay time.Duration) {
+		hc.Logger.Warning("Health check failed for %s (%s), retrying (%d/%d)...",
+			hc.Name, hc.ComponentID, attempt, policy.MaxRetries)
+		if onRetry != nil {
+			onRetry(attempt, err, delay)
+		}
+	}
+	return policy
+}
+
 // triggerAlerts notifies all registered alert handlers.
 func (hc *HealthCheck) triggerAlerts(result *HealthCheckResult) {
 	for _, alert := range hc.Alerts {
@@ -315,8 +338,10 @@ func CreateAgentHealthCheckFunc(getHealthFunc func() map[string]interface{}) Hea
 		status := StatusHealthy
 		message := "Agent is healthy"
 		
-		// Check agent state
-		if agentState, ok := health["state"].(string); ok {
+		// Check agent state. Agents report their state as a named string
+		// type, so compare its string form.
+		if rawState, ok := health["state"]; ok {
+			agentState := fmt.Sprint(rawState)
 			if agentState == "error" {
 				status = StatusUnhealthy
 				message = "Agent is in error state"
@@ -337,6 +362,19 @@ func CreateAgentHealthCheckFunc(getHealthFunc func() map[string]interface{}) Hea
 			}
 		}
 		
+		// An open circuit breaker means the agent is shedding work to protect a
+		// failing dependency, which degrades an otherwise healthy agent.
+		if rawCircuit, ok := health["circuit_state"]; ok && status == StatusHealthy {
+			switch fmt.Sprint(rawCircuit) {
+			case "open":
+				status = StatusDegraded
+				message = "Agent circuit breaker is open"
+			case "half_open":
+				status = StatusDegraded
+				message = "Agent circuit breaker is half-open"
+			}
+		}
+
 		return &HealthCheckResult{
 			Status:      status,
 			Message:     message,
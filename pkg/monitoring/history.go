@@ -0,0 +1,152 @@
+package monitoring
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultHistorySize is the number of samples kept per target unless
+// configured otherwise.
+const DefaultHistorySize = 360
+
+// Point is a value of a metric at a time.
+type Point struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// Summary describes the points of a series.
+type Summary struct {
+	Count int     `json:"count"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Mean  float64 `json:"mean"`
+	Last  float64 `json:"last"`
+}
+
+// Summarize summarizes points.
+func Summarize(points []Point) Summary {
+	if len(points) == 0 {
+		return Summary{}
+	}
+	summary := Summary{Count: len(points), Min: math.Inf(1), Max: math.Inf(-1), Last: points[len(points)-1].Value}
+	sum := 0.0
+	for _, point := range points {
+		summary.Min = math.Min(summary.Min, point.Value)
+		summary.Max = math.Max(summary.Max, point.Value)
+		sum += point.Value
+	}
+	summary.Mean = sum / float64(len(points))
+	return summary
+}
+
+// History keeps the latest samples of each target, as time series of their
+// metrics.
+type History struct {
+	mutex   sync.RWMutex
+	size    int
+	samples map[string][]*Sample
+}
+
+// NewHistory creates a history keeping size samples per target;
+// DefaultHistorySize if size is not positive.
+func NewHistory(size int) *History {
+	if size <= 0 {
+		size = DefaultHistorySize
+	}
+	return &History{size: size, samples: make(map[string][]*Sample)}
+}
+
+// Add records a sample of its target, dropping the oldest if the target
+// has size samples already.
+func (h *History) Add(sample *Sample) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	samples := append(h.samples[sample.Target], sample)
+	if len(samples) > h.size {
+		samples = append([]*Sample(nil), samples[len(samples)-h.size:]...)
+	}
+	h.samples[sample.Target] = samples
+}
+
+// Targets returns the targets with samples, in order.
+func (h *History) Targets() []string {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	targets := make([]string, 0, len(h.samples))
+	for target := range h.samples {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+// Samples returns the samples of a target, oldest first.
+func (h *History) Samples(target string) []*Sample {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return append([]*Sample(nil), h.samples[target]...)
+}
+
+// Latest returns the latest sample of a target.
+func (h *History) Latest(target string) (*Sample, bool) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	samples := h.samples[target]
+	if len(samples) == 0 {
+		return nil, false
+	}
+	return samples[len(samples)-1], true
+}
+
+// Series returns the values of a metric of a target since a time, oldest
+// first. Samples without the metric are skipped.
+func (h *History) Series(target, metric string, since time.Time) []Point {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	var points []Point
+	for _, sample := range h.samples[target] {
+		value, ok := sample.Metrics[metric]
+		if ok && !sample.Timestamp.Before(since) {
+			points = append(points, Point{Time: sample.Timestamp, Value: value})
+		}
+	}
+	return points
+}
+
+// HealthCheck creates a health check of a target reporting its latest
+// sample. A target without a sample for three intervals is unknown. The
+// check reads the history only, so failed checks are not retried.
+func (h *History) HealthCheck(name, target string, interval time.Duration) *HealthCheck {
+	check := NewHealthCheck(name, target, interval, func() *HealthCheckResult {
+		result := &HealthCheckResult{CheckName: name, ComponentID: target, Timestamp: time.Now(), Details: make(map[string]interface{})}
+		sample, ok := h.Latest(target)
+		switch {
+		case !ok:
+			result.Status = StatusUnknown
+			result.Message = "No samples collected"
+		case interval > 0 && time.Since(sample.Timestamp) > 3*interval:
+			result.Status = StatusUnknown
+			result.Message = fmt.Sprintf("No samples collected since %s", sample.Timestamp.Format(time.RFC3339))
+		default:
+			result.Status = sample.Status
+			result.Message = sample.Message
+			if result.Message == "" {
+				result.Message = fmt.Sprintf("Target is %s", sample.Status)
+			}
+			for name, value := range sample.Metrics {
+				result.Details[name] = value
+			}
+			for name, value := range sample.Details {
+				result.Details[name] = value
+			}
+			result.Details["sampled_at"] = sample.Timestamp
+		}
+		return result
+	})
+	check.MaxRetries = 0
+	return check
+}
@@ -0,0 +1,368 @@
+package monitoring
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MetricType is the kind of a metric family.
+type MetricType string
+
+const (
+	CounterType   MetricType = "counter"
+	GaugeType     MetricType = "gauge"
+	HistogramType MetricType = "histogram"
+)
+
+// DefaultBuckets are the default histogram buckets, in seconds, suited to
+// request latencies.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// MetricsRegistry holds metric families and renders them in the Prometheus
+// text exposition format.
+type MetricsRegistry struct {
+	mutex    sync.RWMutex
+	families map[string]*metricFamily
+}
+
+// NewMetricsRegistry creates an empty metrics registry.
+func NewMetricsRegistry() *MetricsRegistry {
+	return &MetricsRegistry{
+		families: make(map[string]*metricFamily),
+	}
+}
+
+var defaultRegistry = NewMetricsRegistry()
+
+// DefaultMetricsRegistry returns the process-wide registry agents report to.
+func DefaultMetricsRegistry() *MetricsRegistry {
+	return defaultRegistry
+}
+
+// metricFamily is a named metric with one series per combination of label values.
+type metricFamily struct {
+	name       string
+	help       string
+	metricType MetricType
+	labelNames []string
+	buckets    []float64
+	mutex      sync.Mutex
+	series     map[string]*series
+}
+
+// series is a single time series of a family.
+type series struct {
+	labelValues []string
+	value       float64
+	// Histogram state: counts per bucket (not cumulative), sum and count.
+	bucketCounts []uint64
+	sum          float64
+	count        uint64
+}
+
+// CounterVec is a family of counters partitioned by labels.
+type CounterVec struct{ family *metricFamily }
+
+// GaugeVec is a family of gauges partitioned by labels.
+type GaugeVec struct{ family *metricFamily }
+
+// HistogramVec is a family of histograms partitioned by labels.
+type HistogramVec struct{ family *metricFamily }
+
+// Counter is a value that only goes up.
+type Counter struct {
+	family *metricFamily
+	series *series
+}
+
+// Gauge is a value that can go up and down.
+type Gauge struct {
+	family *metricFamily
+	series *series
+}
+
+// Histogram counts observations in buckets.
+type Histogram struct {
+	family *metricFamily
+	series *series
+}
+
+// Counter returns the counter family with the given name, registering it if
+// needed. It panics if the name is already registered with a different type
+// or labels.
+func (r *MetricsRegistry) Counter(name, help string, labelNames ...string) *CounterVec {
+	return &CounterVec{family: r.register(name, help, CounterType, labelNames, nil)}
+}
+
+// Gauge returns the gauge family with the given name, registering it if
+// needed. It panics if the name is already registered with a different type
+// or labels.
+func (r *MetricsRegistry) Gauge(name, help string, labelNames ...string) *GaugeVec {
+	return &GaugeVec{family: r.register(name, help, GaugeType, labelNames, nil)}
+}
+
+// Histogram returns the histogram family with the given name, registering it
+// with the given upper bucket bounds (DefaultBuckets if nil) if needed. It
+// panics if the name is already registered with a different type or labels.
+func (r *MetricsRegistry) Histogram(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	return &HistogramVec{family: r.register(name, help, HistogramType, labelNames, sorted)}
+}
+
+func (r *MetricsRegistry) register(name, help string, metricType MetricType, labelNames []string, buckets []float64) *metricFamily {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if existing, ok := r.families[name]; ok {
+		if existing.metricType != metricType || strings.Join(existing.labelNames, ",") != strings.Join(labelNames, ",") {
+			panic(fmt.Sprintf("metric %s already registered as %s with labels %v", name, existing.metricType, existing.labelNames))
+		}
+		return existing
+	}
+
+	family := &metricFamily{
+		name:       name,
+		help:       help,
+		metricType: metricType,
+		labelNames: append([]string(nil), labelNames...),
+		buckets:    buckets,
+		series:     make(map[string]*series),
+	}
+	r.families[name] = family
+	return family
+}
+
+// Unregister removes the family with the given name.
+func (r *MetricsRegistry) Unregister(name string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.families, name)
+}
+
+// get returns the series for the label values, creating it if needed. It
+// panics if the number of values does not match the family's labels.
+func (f *metricFamily) get(labelValues []string) *series {
+	if len(labelValues) != len(f.labelNames) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", f.name, len(f.labelNames), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		if f.metricType == HistogramType {
+			s.bucketCounts = make([]uint64, len(f.buckets))
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+// WithLabelValues returns the counter for the given label values.
+func (v *CounterVec) WithLabelValues(labelValues ...string) *Counter {
+	return &Counter{family: v.family, series: v.family.get(labelValues)}
+}
+
+// WithLabelValues returns the gauge for the given label values.
+func (v *GaugeVec) WithLabelValues(labelValues ...string) *Gauge {
+	return &Gauge{family: v.family, series: v.family.get(labelValues)}
+}
+
+// WithLabelValues returns the histogram for the given label values.
+func (v *HistogramVec) WithLabelValues(labelValues ...string) *Histogram {
+	return &Histogram{family: v.family, series: v.family.get(labelValues)}
+}
+
+// Inc adds one to the counter.
+func (c *Counter) Inc() { c.Add(1) }
+
+// Add adds delta, which must not be negative, to the counter.
+func (c *Counter) Add(delta float64) {
+	if delta < 0 {
+		panic(fmt.Sprintf("counter %s cannot decrease", c.family.name))
+	}
+	c.family.mutex.Lock()
+	defer c.family.mutex.Unlock()
+	c.series.value += delta
+}
+
+// Value returns the current value of the counter.
+func (c *Counter) Value() float64 {
+	c.family.mutex.Lock()
+	defer c.family.mutex.Unlock()
+	return c.series.value
+}
+
+// Set sets the gauge to value.
+func (g *Gauge) Set(value float64) {
+	g.family.mutex.Lock()
+	defer g.family.mutex.Unlock()
+	g.series.value = value
+}
+
+// Inc adds one to the gauge.
+func (g *Gauge) Inc() { g.Add(1) }
+
+// Dec subtracts one from the gauge.
+func (g *Gauge) Dec() { g.Add(-1) }
+
+// Add adds delta to the gauge.
+func (g *Gauge) Add(delta float64) {
+	g.family.mutex.Lock()
+	defer g.family.mutex.Unlock()
+	g.series.value += delta
+}
+
+// Value returns the current value of the gauge.
+func (g *Gauge) Value() float64 {
+	g.family.mutex.Lock()
+	defer g.family.mutex.Unlock()
+	return g.series.value
+}
+
+// Observe records a single observation.
+func (h *Histogram) Observe(value float64) {
+	h.family.mutex.Lock()
+	defer h.family.mutex.Unlock()
+
+	for i, bound := range h.family.buckets {
+		if value <= bound {
+			h.series.bucketCounts[i]++
+			break
+		}
+	}
+	h.series.sum += value
+	h.series.count++
+}
+
+// Count returns the number of observations.
+func (h *Histogram) Count() uint64 {
+	h.family.mutex.Lock()
+	defer h.family.mutex.Unlock()
+	return h.series.count
+}
+
+// Sum returns the sum of all observations.
+func (h *Histogram) Sum() float64 {
+	h.family.mutex.Lock()
+	defer h.family.mutex.Unlock()
+	return h.series.sum
+}
+
+// WritePrometheus writes every metric in the Prometheus text exposition
+// format (version 0.0.4), with families and series in a stable order.
+func (r *MetricsRegistry) WritePrometheus(w io.Writer) error {
+	r.mutex.RLock()
+	families := make([]*metricFamily, 0, len(r.families))
+	for _, family := range r.families {
+		families = append(families, family)
+	}
+	r.mutex.RUnlock()
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	out := bufio.NewWriter(w)
+	for _, family := range families {
+		family.write(out)
+	}
+	return out.Flush()
+}
+
+func (f *metricFamily) write(w *bufio.Writer) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if len(f.series) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.metricType)
+
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := f.series[key]
+		if f.metricType != HistogramType {
+			fmt.Fprintf(w, "%s%s %s\n", f.name, formatLabels(f.labelNames, s.labelValues, "", ""), formatValue(s.value))
+			continue
+		}
+
+		var cumulative uint64
+		for i, bound := range f.buckets {
+			cumulative += s.bucketCounts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, formatLabels(f.labelNames, s.labelValues, "le", formatValue(bound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, formatLabels(f.labelNames, s.labelValues, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", f.name, formatLabels(f.labelNames, s.labelValues, "", ""), formatValue(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", f.name, formatLabels(f.labelNames, s.labelValues, "", ""), s.count)
+	}
+}
+
+// formatLabels renders a label set, with an optional extra label such as a
+// histogram bucket's "le".
+func formatLabels(names, values []string, extraName, extraValue string) string {
+	if len(names) == 0 && extraName == "" {
+		return ""
+	}
+	pairs := make([]string, 0, len(names)+1)
+	for i, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", name, escapeLabelValue(values[i])))
+	}
+	if extraName != "" {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", extraName, extraValue))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+var (
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
+
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
+
+// MetricsHandler returns an HTTP handler that serves the registry in the
+// Prometheus text exposition format.
+func MetricsHandler(registry *MetricsRegistry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := registry.WritePrometheus(w); err != nil {
+			http.Error(w, fmt.Sprintf("failed to write metrics: %v", err), http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,110 @@
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DefaultProbeTimeout bounds probes whose context has no deadline.
+const DefaultProbeTimeout = 10 * time.Second
+
+// HTTPCollector probes http and https targets with a GET request. Targets
+// answering with a status below 400 are healthy. Samples have the metrics
+// status_code and latency_ms, the time until the response headers.
+type HTTPCollector struct {
+	Client  *http.Client
+	Timeout time.Duration
+}
+
+// NewHTTPCollector creates an HTTP probe which does not follow redirects.
+func NewHTTPCollector() *HTTPCollector {
+	return &HTTPCollector{
+		Client: &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}},
+		Timeout: DefaultProbeTimeout,
+	}
+}
+
+// Collect probes the target.
+func (c *HTTPCollector) Collect(ctx context.Context, target *url.URL) (*Sample, error) {
+	ctx, cancel := withProbeTimeout(ctx, c.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "beluga-monitor")
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	started := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	latency := time.Since(started)
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	sample := &Sample{
+		Status:    StatusHealthy,
+		Timestamp: started,
+		Message:   resp.Status,
+		Metrics: map[string]float64{
+			"status_code": float64(resp.StatusCode),
+			"latency_ms":  float64(latency) / float64(time.Millisecond),
+		},
+	}
+	if resp.StatusCode >= 400 {
+		sample.Status = StatusUnhealthy
+		sample.Message = fmt.Sprintf("HTTP status %s", resp.Status)
+	}
+	return sample, nil
+}
+
+// TCPCollector probes tcp://host:port targets by connecting to them.
+// Samples have the metric latency_ms, the time to connect.
+type TCPCollector struct {
+	Timeout time.Duration
+}
+
+// NewTCPCollector creates a TCP connect probe.
+func NewTCPCollector() *TCPCollector {
+	return &TCPCollector{Timeout: DefaultProbeTimeout}
+}
+
+// Collect connects to the target.
+func (c *TCPCollector) Collect(ctx context.Context, target *url.URL) (*Sample, error) {
+	if target.Port() == "" {
+		return nil, fmt.Errorf("tcp target must be tcp://host:port, got %s", target)
+	}
+	ctx, cancel := withProbeTimeout(ctx, c.Timeout)
+	defer cancel()
+	started := time.Now()
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", target.Host)
+	if err != nil {
+		return nil, err
+	}
+	latency := time.Since(started)
+	conn.Close()
+	return &Sample{
+		Status:    StatusHealthy,
+		Timestamp: started,
+		Metrics:   map[string]float64{"latency_ms": float64(latency) / float64(time.Millisecond)},
+	}, nil
+}
+
+// withProbeTimeout bounds ctx by timeout unless it has a deadline already.
+func withProbeTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
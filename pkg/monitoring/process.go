@@ -0,0 +1,146 @@
+package monitoring
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProcessCollector reads process metrics from /proc for targets such as
+// "proc://self" or "proc://1234":
+//
+//   - cpu_usage, the percentage of a CPU used since the previous sample, or
+//     since the process started for the first one
+//   - memory_use, the resident set size in MiB
+//   - open_fds, the number of open file descriptors
+//   - threads, the number of threads
+//   - goroutines, for this process only
+type ProcessCollector struct {
+	// Root is the proc file system, "/proc" by default.
+	Root string
+	// ClockTicks is the number of CPU time ticks per second /proc counts in.
+	ClockTicks float64
+
+	mutex    sync.Mutex
+	previous map[string]cpuTime
+}
+
+// cpuTime is the CPU time of a process at a time.
+type cpuTime struct {
+	seconds float64
+	at      time.Time
+}
+
+// NewProcessCollector creates a collector reading /proc.
+func NewProcessCollector() *ProcessCollector {
+	return &ProcessCollector{Root: "/proc", ClockTicks: 100, previous: make(map[string]cpuTime)}
+}
+
+// Collect samples the process.
+func (c *ProcessCollector) Collect(ctx context.Context, target *url.URL) (*Sample, error) {
+	pid := target.Host
+	if pid == "" {
+		pid = strings.Trim(target.Opaque, "/")
+	}
+	self := pid == "self" || pid == strconv.Itoa(os.Getpid())
+	if pid != "self" {
+		if _, err := strconv.Atoi(pid); err != nil {
+			return nil, fmt.Errorf("process target must be proc://self or proc://PID, got %s", target)
+		}
+	}
+	dir := filepath.Join(c.Root, pid)
+	now := time.Now()
+
+	stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read process %s: %w", pid, err)
+	}
+	// The command name in parentheses may contain spaces
+	end := strings.LastIndexByte(string(stat), ')')
+	if end < 0 {
+		return nil, fmt.Errorf("malformed stat of process %s", pid)
+	}
+	fields := strings.Fields(string(stat)[end+1:])
+	if len(fields) < 20 {
+		return nil, fmt.Errorf("malformed stat of process %s", pid)
+	}
+	// fields[0] is the third field of stat, the process state
+	utime, _ := strconv.ParseFloat(fields[11], 64)
+	stime, _ := strconv.ParseFloat(fields[12], 64)
+	threads, _ := strconv.ParseFloat(fields[17], 64)
+	started, _ := strconv.ParseFloat(fields[19], 64)
+	cpu := (utime + stime) / c.ClockTicks
+
+	sample := &Sample{
+		Status:    StatusHealthy,
+		Timestamp: now,
+		Metrics:   map[string]float64{"threads": threads},
+		Details:   map[string]interface{}{"pid": pid, "state": fields[0]},
+	}
+
+	c.mutex.Lock()
+	previous, seen := c.previous[pid]
+	if c.previous == nil {
+		c.previous = make(map[string]cpuTime)
+	}
+	c.previous[pid] = cpuTime{seconds: cpu, at: now}
+	c.mutex.Unlock()
+	if elapsed := now.Sub(previous.at).Seconds(); seen && elapsed > 0 {
+		sample.Metrics["cpu_usage"] = 100 * (cpu - previous.seconds) / elapsed
+	} else if uptime, err := c.uptime(); err == nil {
+		if alive := uptime - started/c.ClockTicks; alive > 0 {
+			sample.Metrics["cpu_usage"] = 100 * cpu / alive
+		}
+	}
+
+	if rss, err := c.residentKiB(dir); err == nil {
+		sample.Metrics["memory_use"] = rss / 1024
+	}
+	if fds, err := os.ReadDir(filepath.Join(dir, "fd")); err == nil {
+		sample.Metrics["open_fds"] = float64(len(fds))
+	}
+	if self {
+		sample.Metrics["goroutines"] = float64(runtime.NumGoroutine())
+	}
+	return sample, nil
+}
+
+// uptime returns the seconds since the system booted.
+func (c *ProcessCollector) uptime() (float64, error) {
+	data, err := os.ReadFile(filepath.Join(c.Root, "uptime"))
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("malformed uptime")
+	}
+	return strconv.ParseFloat(fields[0], 64)
+}
+
+// residentKiB returns the VmRSS of the process status.
+func (c *ProcessCollector) residentKiB(dir string) (float64, error) {
+	file, err := os.Open(filepath.Join(dir, "status"))
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), "VmRSS:"); ok {
+			return strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), " kB"), 64)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("no VmRSS in process status")
+}
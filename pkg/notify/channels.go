@@ -0,0 +1,313 @@
+package notify
+
+import (
+	"beluga/pkg/setting"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout bounds a delivery unless configured otherwise.
+const DefaultTimeout = 30 * time.Second
+
+// SMTPError is a reply of an SMTP server refusing a message.
+type SMTPError struct {
+	Code    int
+	Message string
+}
+
+func (e *SMTPError) Error() string {
+	return fmt.Sprintf("smtp %d: %s", e.Code, e.Message)
+}
+
+// Retryable reports whether the server refused the message temporarily,
+// with a 4xx reply.
+func (e *SMTPError) Retryable() bool {
+	return e.Code >= 400 && e.Code < 500
+}
+
+// SMTPChannel sends messages as email through an SMTP server. It upgrades
+// the connection with STARTTLS when the server offers it and authenticates
+// with PLAIN when given a username.
+type SMTPChannel struct {
+	// Addr is the server as host:port.
+	Addr string
+	// From is the sender, as "Name <address>" or an address.
+	From     string
+	Username string
+	Password string
+	Timeout  time.Duration
+	// TLSConfig configures STARTTLS; it verifies the server's host name if
+	// nil.
+	TLSConfig *tls.Config
+}
+
+// NewSMTPChannel creates an SMTP channel from the settings "addr", "from",
+// "username", "password_env", naming the environment variable holding the
+// password, and "timeout" in seconds, 30 by default.
+func NewSMTPChannel(settings map[string]interface{}) (*SMTPChannel, error) {
+	channel := &SMTPChannel{}
+	var err error
+	if channel.Addr, err = setting.String(settings, "addr", ""); err != nil {
+		return nil, err
+	}
+	if _, _, err := net.SplitHostPort(channel.Addr); err != nil {
+		return nil, fmt.Errorf("addr must be host:port, got %q", channel.Addr)
+	}
+	if channel.From, err = setting.String(settings, "from", ""); err != nil {
+		return nil, err
+	}
+	if _, err := mail.ParseAddress(channel.From); err != nil {
+		return nil, fmt.Errorf("invalid from address %q: %w", channel.From, err)
+	}
+	if channel.Username, err = setting.String(settings, "username", ""); err != nil {
+		return nil, err
+	}
+	passwordEnv, err := setting.String(settings, "password_env", "")
+	if err != nil {
+		return nil, err
+	}
+	if passwordEnv != "" {
+		channel.Password = os.Getenv(passwordEnv)
+	}
+	if channel.Timeout, err = setting.Seconds(settings, "timeout", DefaultTimeout); err != nil {
+		return nil, err
+	}
+	return channel, nil
+}
+
+// Send delivers the message in one SMTP session.
+func (c *SMTPChannel) Send(ctx context.Context, message *Message) error {
+	from, err := mail.ParseAddress(c.From)
+	if err != nil {
+		return fmt.Errorf("invalid from address %q: %w", c.From, err)
+	}
+	to, err := mail.ParseAddress(message.To.Address)
+	if err != nil {
+		return &SMTPError{Code: 501, Message: fmt.Sprintf("invalid recipient address %q", message.To.Address)}
+	}
+	host, _, err := net.SplitHostPort(c.Addr)
+	if err != nil {
+		return fmt.Errorf("smtp addr must be host:port, got %q", c.Addr)
+	}
+
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", c.Addr)
+	if err != nil {
+		return fmt.Errorf("smtp %s: %w", c.Addr, err)
+	}
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	// Close the connection if the context ends before the session does.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return c.wrap(err)
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		config := c.TLSConfig
+		if config == nil {
+			config = &tls.Config{ServerName: host}
+		}
+		if err := client.StartTLS(config); err != nil {
+			return c.wrap(err)
+		}
+	}
+	if c.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", c.Username, c.Password, host)); err != nil {
+			return c.wrap(err)
+		}
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return c.wrap(err)
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return c.wrap(err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return c.wrap(err)
+	}
+	if _, err := w.Write(message.Bytes(from.String())); err != nil {
+		return c.wrap(err)
+	}
+	if err := w.Close(); err != nil {
+		return c.wrap(err)
+	}
+	return c.wrap(client.Quit())
+}
+
+// wrap turns server replies into *SMTPError.
+func (c *SMTPChannel) wrap(err error) error {
+	if err == nil {
+		return nil
+	}
+	var reply *textproto.Error
+	if errors.As(err, &reply) {
+		return &SMTPError{Code: reply.Code, Message: reply.Msg}
+	}
+	return fmt.Errorf("smtp %s: %w", c.Addr, err)
+}
+
+// WebhookMessage is the JSON body the webhook channel posts.
+type WebhookMessage struct {
+	ID        string    `json:"id"`
+	Recipient Recipient `json:"recipient"`
+	Subject   string    `json:"subject"`
+	Text      string    `json:"text,omitempty"`
+	HTML      string    `json:"html,omitempty"`
+	Date      time.Time `json:"date"`
+}
+
+// WebhookStatusError is returned for a webhook answered with a non-2xx
+// status.
+type WebhookStatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *WebhookStatusError) Error() string {
+	return fmt.Sprintf("webhook %s: http status %d", e.URL, e.StatusCode)
+}
+
+// Retryable reports whether the webhook may succeed if sent again.
+func (e *WebhookStatusError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// WebhookChannel posts messages as JSON to a URL.
+type WebhookChannel struct {
+	URL     string
+	Headers map[string]string
+	Client  *http.Client
+}
+
+// NewWebhookChannel creates a webhook channel from the settings "url",
+// "headers" and "timeout" in seconds, 30 by default.
+func NewWebhookChannel(settings map[string]interface{}) (*WebhookChannel, error) {
+	channel := &WebhookChannel{}
+	var err error
+	if channel.URL, err = setting.String(settings, "url", ""); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(channel.URL, "http://") && !strings.HasPrefix(channel.URL, "https://") {
+		return nil, fmt.Errorf("url must be an http url, got %q", channel.URL)
+	}
+	if channel.Headers, err = setting.StringMap(settings, "headers"); err != nil {
+		return nil, err
+	}
+	timeout, err := setting.Seconds(settings, "timeout", DefaultTimeout)
+	if err != nil {
+		return nil, err
+	}
+	channel.Client = &http.Client{Timeout: timeout}
+	return channel, nil
+}
+
+// Send posts the message.
+func (c *WebhookChannel) Send(ctx context.Context, message *Message) error {
+	body, err := json.Marshal(WebhookMessage{
+		ID:        message.ID,
+		Recipient: message.To,
+		Subject:   message.Subject,
+		Text:      message.Text,
+		HTML:      message.HTML,
+		Date:      message.Date,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range c.Headers {
+		req.Header.Set(name, value)
+	}
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook %s: %w", c.URL, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &WebhookStatusError{URL: c.URL, StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+// FileChannel writes messages as .eml files to an outbox directory, for
+// development and for systems which pick mail up from a directory.
+type FileChannel struct {
+	Dir string
+	// From is the sender written in the messages.
+	From string
+}
+
+// NewFileChannel creates a file channel from the settings "dir", a
+// beluga-outbox directory in the temporary directory by default, and
+// "from".
+func NewFileChannel(settings map[string]interface{}) (*FileChannel, error) {
+	channel := &FileChannel{}
+	var err error
+	if channel.Dir, err = setting.String(settings, "dir", filepath.Join(os.TempDir(), "beluga-outbox")); err != nil {
+		return nil, err
+	}
+	if channel.From, err = setting.String(settings, "from", "beluga@localhost"); err != nil {
+		return nil, err
+	}
+	return channel, nil
+}
+
+// Send writes the message to DIR/ID.eml.
+func (c *FileChannel) Send(ctx context.Context, message *Message) error {
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return err
+	}
+	path := c.Path(message)
+	temp, err := os.CreateTemp(c.Dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(message.Bytes(c.From)); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
+
+// Path returns the file the message is written to.
+func (c *FileChannel) Path(message *Message) string {
+	return filepath.Join(c.Dir, message.ID+".eml")
+}
@@ -0,0 +1,92 @@
+package notify
+
+import (
+	"beluga/pkg/setting"
+	"fmt"
+	"sort"
+)
+
+// ChannelFromMap creates a channel from settings whose "type" is "smtp",
+// "webhook" or "file".
+func ChannelFromMap(settings map[string]interface{}) (Channel, error) {
+	kind, err := setting.String(settings, "type", "")
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case "smtp":
+		return NewSMTPChannel(settings)
+	case "webhook":
+		return NewWebhookChannel(settings)
+	case "file":
+		return NewFileChannel(settings)
+	case "":
+		return nil, fmt.Errorf("channel has no type")
+	}
+	return nil, fmt.Errorf("unknown channel type %q", kind)
+}
+
+// FromMap creates a notifier from the settings "templates", templates
+// defined by name as for Templates.LoadConfig, "templates_path", a
+// directory of template files, "channels", channels by name as for
+// ChannelFromMap, "channel", the default channel, and "history".
+func FromMap(settings map[string]interface{}) (*Notifier, error) {
+	notifier := NewNotifier()
+	if raw, ok := settings["templates"]; ok {
+		templates, ok := setting.StringKeys(raw)
+		if !ok {
+			return nil, fmt.Errorf("templates must be a map, got %T", raw)
+		}
+		if err := notifier.Templates.LoadConfig(templates); err != nil {
+			return nil, err
+		}
+	}
+	path, err := setting.String(settings, "templates_path", "")
+	if err != nil {
+		return nil, err
+	}
+	if path != "" {
+		if err := notifier.Templates.LoadDir(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if raw, ok := settings["channels"]; ok {
+		channels, ok := setting.StringKeys(raw)
+		if !ok {
+			return nil, fmt.Errorf("channels must be a map, got %T", raw)
+		}
+		for _, name := range sortedKeys(channels) {
+			fields, ok := setting.StringKeys(channels[name])
+			if !ok {
+				return nil, fmt.Errorf("channel %s must be a map, got %T", name, channels[name])
+			}
+			channel, err := ChannelFromMap(fields)
+			if err != nil {
+				return nil, fmt.Errorf("channel %s: %w", name, err)
+			}
+			notifier.AddChannel(name, channel)
+		}
+	}
+	if notifier.DefaultChannel, err = setting.String(settings, "channel", ""); err != nil {
+		return nil, err
+	}
+	if notifier.DefaultChannel != "" {
+		if _, ok := notifier.GetChannel(notifier.DefaultChannel); !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownChannel, notifier.DefaultChannel)
+		}
+	}
+	if notifier.History, err = setting.Int(settings, "history", DefaultHistory); err != nil {
+		return nil, err
+	}
+	return notifier, nil
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,64 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Bytes encodes the message as an RFC 5322 email from the given sender,
+// with a text and an HTML alternative if it has both bodies.
+func (m *Message) Bytes(from string) []byte {
+	var b bytes.Buffer
+	to := (&mail.Address{Name: m.To.Name, Address: m.To.Address}).String()
+	header := func(name, value string) {
+		fmt.Fprintf(&b, "%s: %s\r\n", name, value)
+	}
+	header("From", from)
+	header("To", to)
+	header("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header("Date", m.Date.Format(time.RFC1123Z))
+	header("Message-ID", "<"+m.ID+"@beluga>")
+	header("MIME-Version", "1.0")
+
+	switch {
+	case m.Text != "" && m.HTML != "":
+		writer := multipart.NewWriter(&b)
+		header("Content-Type", `multipart/alternative; boundary="`+writer.Boundary()+`"`)
+		b.WriteString("\r\n")
+		for _, part := range []struct{ contentType, body string }{
+			{"text/plain; charset=utf-8", m.Text},
+			{"text/html; charset=utf-8", m.HTML},
+		} {
+			w, _ := writer.CreatePart(textproto.MIMEHeader{
+				"Content-Type":              {part.contentType},
+				"Content-Transfer-Encoding": {"quoted-printable"},
+			})
+			writeQuotedPrintable(w, part.body)
+		}
+		writer.Close()
+	case m.HTML != "":
+		header("Content-Type", "text/html; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		b.WriteString("\r\n")
+		writeQuotedPrintable(&b, m.HTML)
+	default:
+		header("Content-Type", "text/plain; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		b.WriteString("\r\n")
+		writeQuotedPrintable(&b, m.Text)
+	}
+	return b.Bytes()
+}
+
+func writeQuotedPrintable(w interface{ Write([]byte) (int, error) }, body string) {
+	qp := quotedprintable.NewWriter(w)
+	qp.Write([]byte(strings.ReplaceAll(body, "\n", "\r\n")))
+	qp.Close()
+}
@@ -0,0 +1,414 @@
+// Package notify renders notifications from named templates and delivers
+// them to each recipient over channels such as SMTP, webhooks and a file
+// outbox, keeping a report of every delivery.
+package notify
+
+import (
+	"beluga/pkg/retry"
+	"beluga/pkg/setting"
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultHistory is the number of reports a notifier keeps unless
+// configured otherwise.
+const DefaultHistory = 100
+
+var (
+	// ErrUnknownTemplate is returned for a notification naming a template
+	// the notifier does not have.
+	ErrUnknownTemplate = errors.New("unknown template")
+	// ErrUnknownChannel fails the deliveries to a channel the notifier does
+	// not have.
+	ErrUnknownChannel = errors.New("unknown channel")
+	// ErrNoRecipients is returned for a notification without recipients.
+	ErrNoRecipients = errors.New("notification has no recipients")
+)
+
+// Recipient is an addressee of a notification. Address is what the channel
+// delivers to: an email address for SMTP, anything for the others. Channel
+// overrides the channel of the notification, and Data its data.
+type Recipient struct {
+	Address string                 `json:"address"`
+	Name    string                 `json:"name,omitempty"`
+	Channel string                 `json:"channel,omitempty"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+}
+
+// String returns the recipient as "Name <address>", or its address.
+func (r Recipient) String() string {
+	if r.Name == "" {
+		return r.Address
+	}
+	return r.Name + " <" + r.Address + ">"
+}
+
+// ParseRecipient reads a recipient from a string such as
+// "Ada <ada@example.com>" or from a map with "address", "name", "channel"
+// and "data".
+func ParseRecipient(raw interface{}) (Recipient, error) {
+	switch v := raw.(type) {
+	case Recipient:
+		return v, nil
+	case string:
+		v = strings.TrimSpace(v)
+		if v == "" {
+			return Recipient{}, errors.New("empty recipient")
+		}
+		if address, err := mail.ParseAddress(v); err == nil {
+			return Recipient{Address: address.Address, Name: address.Name}, nil
+		}
+		return Recipient{Address: v}, nil
+	}
+	fields, ok := setting.StringKeys(raw)
+	if !ok {
+		return Recipient{}, fmt.Errorf("recipient must be a string or a map, got %T", raw)
+	}
+	var recipient Recipient
+	var err error
+	if recipient.Address, err = setting.String(fields, "address", ""); err != nil {
+		return Recipient{}, err
+	}
+	if recipient.Address == "" {
+		return Recipient{}, errors.New("recipient has no address")
+	}
+	if recipient.Name, err = setting.String(fields, "name", ""); err != nil {
+		return Recipient{}, err
+	}
+	if recipient.Channel, err = setting.String(fields, "channel", ""); err != nil {
+		return Recipient{}, err
+	}
+	if data, ok := fields["data"]; ok {
+		if recipient.Data, ok = setting.StringKeys(data); !ok {
+			return Recipient{}, fmt.Errorf("recipient data must be a map, got %T", data)
+		}
+	}
+	return recipient, nil
+}
+
+// ParseRecipients reads a single recipient or a list of them.
+func ParseRecipients(raw interface{}) ([]Recipient, error) {
+	var items []interface{}
+	switch v := raw.(type) {
+	case nil:
+		return nil, nil
+	case []Recipient:
+		return v, nil
+	case []string:
+		for _, item := range v {
+			items = append(items, item)
+		}
+	case []interface{}:
+		items = v
+	default:
+		items = []interface{}{v}
+	}
+	recipients := make([]Recipient, 0, len(items))
+	for i, item := range items {
+		recipient, err := ParseRecipient(item)
+		if err != nil {
+			return nil, fmt.Errorf("recipient %d: %w", i+1, err)
+		}
+		recipients = append(recipients, recipient)
+	}
+	return recipients, nil
+}
+
+// Message is a notification rendered for one recipient.
+type Message struct {
+	ID      string
+	To      Recipient
+	Subject string
+	Text    string
+	HTML    string
+	Date    time.Time
+}
+
+// Channel delivers messages.
+type Channel interface {
+	Send(ctx context.Context, message *Message) error
+}
+
+// ChannelFunc adapts a function to Channel.
+type ChannelFunc func(ctx context.Context, message *Message) error
+
+// Send calls f.
+func (f ChannelFunc) Send(ctx context.Context, message *Message) error {
+	return f(ctx, message)
+}
+
+// Notification asks for a template to be rendered and sent to recipients.
+// Without a template, Subject and Text are sent as they are.
+type Notification struct {
+	Template   string
+	Subject    string
+	Text       string
+	Recipients []Recipient
+	Data       map[string]interface{}
+	// Channel sends to recipients which do not name theirs; the notifier's
+	// default channel if empty.
+	Channel string
+}
+
+// Delivery statuses.
+const (
+	StatusSent   = "sent"
+	StatusFailed = "failed"
+)
+
+// Delivery is the outcome of sending a notification to one recipient.
+type Delivery struct {
+	MessageID string    `json:"message_id"`
+	Recipient Recipient `json:"recipient"`
+	Channel   string    `json:"channel"`
+	Status    string    `json:"status"`
+	Subject   string    `json:"subject,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	SentAt    time.Time `json:"sent_at,omitempty"`
+
+	err error
+}
+
+// Report records the deliveries of a notification.
+type Report struct {
+	ID         string        `json:"id"`
+	Template   string        `json:"template,omitempty"`
+	Deliveries []Delivery    `json:"deliveries"`
+	Sent       int           `json:"sent"`
+	Failed     int           `json:"failed"`
+	StartedAt  time.Time     `json:"started_at"`
+	Duration   time.Duration `json:"duration"`
+}
+
+// DeliveryError is returned when some deliveries of a notification failed.
+type DeliveryError struct {
+	Report *Report
+}
+
+func (e *DeliveryError) Error() string {
+	var first *Delivery
+	for i := range e.Report.Deliveries {
+		if e.Report.Deliveries[i].Status == StatusFailed {
+			first = &e.Report.Deliveries[i]
+			break
+		}
+	}
+	return fmt.Sprintf("%d of %d notifications failed: %s: %s",
+		e.Report.Failed, len(e.Report.Deliveries), first.Recipient.Address, first.Error)
+}
+
+// Unwrap returns the errors of the failed deliveries.
+func (e *DeliveryError) Unwrap() []error {
+	var errs []error
+	for _, delivery := range e.Report.Deliveries {
+		if delivery.err != nil {
+			errs = append(errs, delivery.err)
+		}
+	}
+	return errs
+}
+
+// Retryable reports whether sending the notification again may succeed:
+// only if nothing was sent, so that no recipient is notified twice, and
+// every failure was transient.
+func (e *DeliveryError) Retryable() bool {
+	if e.Report.Sent > 0 {
+		return false
+	}
+	for _, delivery := range e.Report.Deliveries {
+		if delivery.err != nil && !retry.Retryable(delivery.err) {
+			return false
+		}
+	}
+	return true
+}
+
+// Notifier renders notifications and sends them over its channels.
+type Notifier struct {
+	Templates *Templates
+	// DefaultChannel sends notifications which do not name a channel. If
+	// empty and the notifier has a single channel, that one is used.
+	DefaultChannel string
+	// History is the number of reports kept; DefaultHistory if zero.
+	History int
+
+	mutex    sync.RWMutex
+	channels map[string]Channel
+	reports  []*Report
+	sequence uint64
+}
+
+// NewNotifier creates a notifier without templates or channels.
+func NewNotifier() *Notifier {
+	return &Notifier{Templates: NewTemplates(), channels: make(map[string]Channel)}
+}
+
+// AddChannel adds a channel, replacing one of the same name.
+func (n *Notifier) AddChannel(name string, channel Channel) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.channels[name] = channel
+}
+
+// GetChannel returns the named channel.
+func (n *Notifier) GetChannel(name string) (Channel, bool) {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	channel, ok := n.channels[name]
+	return channel, ok
+}
+
+// ChannelNames returns the channel names in order.
+func (n *Notifier) ChannelNames() []string {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	return sortedKeys(n.channels)
+}
+
+// Notify renders the notification for each recipient and sends it. The
+// report lists every delivery; if some failed it is returned with a
+// *DeliveryError. Errors in the notification itself, such as an unknown
+// template, are returned without a report.
+func (n *Notifier) Notify(ctx context.Context, notification Notification) (*Report, error) {
+	if len(notification.Recipients) == 0 {
+		return nil, ErrNoRecipients
+	}
+	var template *Template
+	if notification.Template != "" {
+		var ok bool
+		if template, ok = n.Templates.Get(notification.Template); !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownTemplate, notification.Template)
+		}
+	} else if notification.Text == "" {
+		return nil, errors.New("notification has neither a template nor a text")
+	}
+
+	report := &Report{
+		ID:        fmt.Sprintf("%x-%d", time.Now().UnixNano(), atomic.AddUint64(&n.sequence, 1)),
+		Template:  notification.Template,
+		StartedAt: time.Now(),
+	}
+	for i, recipient := range notification.Recipients {
+		delivery := Delivery{MessageID: fmt.Sprintf("%s-%d", report.ID, i+1), Recipient: recipient}
+		delivery.err = n.deliver(ctx, template, notification, &delivery)
+		if delivery.err != nil {
+			delivery.Status = StatusFailed
+			delivery.Error = delivery.err.Error()
+			report.Failed++
+		} else {
+			delivery.Status = StatusSent
+			delivery.SentAt = time.Now()
+			report.Sent++
+		}
+		report.Deliveries = append(report.Deliveries, delivery)
+	}
+	report.Duration = time.Since(report.StartedAt)
+	n.record(report)
+
+	if report.Failed > 0 {
+		return report, &DeliveryError{Report: report}
+	}
+	return report, nil
+}
+
+// deliver renders the notification for the delivery's recipient and sends
+// it over the recipient's channel.
+func (n *Notifier) deliver(ctx context.Context, template *Template, notification Notification, delivery *Delivery) error {
+	recipient := delivery.Recipient
+	name, channel, err := n.channelFor(recipient.Channel, notification.Channel)
+	delivery.Channel = name
+	if err != nil {
+		return retry.Permanent(err)
+	}
+
+	content := &Content{Subject: notification.Subject, Text: notification.Text}
+	if template != nil {
+		data := make(map[string]interface{}, len(notification.Data)+len(recipient.Data))
+		for key, value := range notification.Data {
+			data[key] = value
+		}
+		for key, value := range recipient.Data {
+			data[key] = value
+		}
+		if content, err = template.Render(TemplateData{Recipient: recipient, Data: data, Subject: notification.Subject}); err != nil {
+			return retry.Permanent(fmt.Errorf("failed to render template %s: %w", template.Name, err))
+		}
+	}
+	delivery.Subject = content.Subject
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return channel.Send(ctx, &Message{
+		ID:      delivery.MessageID,
+		To:      recipient,
+		Subject: content.Subject,
+		Text:    content.Text,
+		HTML:    content.HTML,
+		Date:    time.Now(),
+	})
+}
+
+// channelFor picks the first channel named, the default channel, or the
+// only channel.
+func (n *Notifier) channelFor(names ...string) (string, Channel, error) {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	name := n.DefaultChannel
+	for i := len(names) - 1; i >= 0; i-- {
+		if names[i] != "" {
+			name = names[i]
+		}
+	}
+	if name == "" {
+		if len(n.channels) != 1 {
+			return "", nil, fmt.Errorf("no channel given and %d channels configured", len(n.channels))
+		}
+		for only := range n.channels {
+			name = only
+		}
+	}
+	channel, ok := n.channels[name]
+	if !ok {
+		return name, nil, fmt.Errorf("%w: %s", ErrUnknownChannel, name)
+	}
+	return name, channel, nil
+}
+
+func (n *Notifier) record(report *Report) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	limit := n.History
+	if limit <= 0 {
+		limit = DefaultHistory
+	}
+	n.reports = append(n.reports, report)
+	if len(n.reports) > limit {
+		n.reports = append([]*Report(nil), n.reports[len(n.reports)-limit:]...)
+	}
+}
+
+// GetReport returns a report the notifier still keeps.
+func (n *Notifier) GetReport(id string) (*Report, bool) {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	for _, report := range n.reports {
+		if report.ID == id {
+			return report, true
+		}
+	}
+	return nil, false
+}
+
+// GetReports returns the reports kept, oldest first.
+func (n *Notifier) GetReports() []*Report {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	return append([]*Report(nil), n.reports...)
+}
@@ -0,0 +1,228 @@
+package notify
+
+import (
+	"beluga/pkg/setting"
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+)
+
+// Template file suffixes LoadDir recognizes. The files of a template share
+// its name, as in "recommendation.subject.tmpl".
+const (
+	SubjectSuffix = ".subject.tmpl"
+	TextSuffix    = ".txt.tmpl"
+	HTMLSuffix    = ".html.tmpl"
+)
+
+// TemplateData is what templates render: the recipient, the data of the
+// notification and its subject, as in "Hello {{.Recipient.Name}}" or
+// "{{.Data.decision}}".
+type TemplateData struct {
+	Recipient Recipient
+	Data      map[string]interface{}
+	Subject   string
+}
+
+// Content is a rendered notification.
+type Content struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+var funcs = map[string]interface{}{
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// Template renders the subject and the text and HTML bodies of a
+// notification. Bodies use text/template and html/template, so that values
+// in the HTML body are escaped. Referring to a missing key is an error.
+type Template struct {
+	Name    string
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+// NewTemplate parses a template. Any part may be empty, but not both
+// bodies.
+func NewTemplate(name, subject, text, html string) (*Template, error) {
+	if text == "" && html == "" {
+		return nil, fmt.Errorf("template %s has no body", name)
+	}
+	t := &Template{Name: name}
+	var err error
+	if t.subject, err = parseText(name+" subject", subject); err != nil {
+		return nil, err
+	}
+	if t.text, err = parseText(name+" text", text); err != nil {
+		return nil, err
+	}
+	if html != "" {
+		if t.html, err = htmltemplate.New(name + " html").Option("missingkey=error").Funcs(funcs).Parse(html); err != nil {
+			return nil, fmt.Errorf("invalid template %s: %w", name, err)
+		}
+	}
+	return t, nil
+}
+
+func parseText(name, source string) (*texttemplate.Template, error) {
+	if source == "" {
+		return nil, nil
+	}
+	t, err := texttemplate.New(name).Option("missingkey=error").Funcs(funcs).Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template %s: %w", name, err)
+	}
+	return t, nil
+}
+
+// Render renders the template for a recipient. The subject is rendered
+// first and is available to the bodies; without a subject template
+// data.Subject is kept.
+func (t *Template) Render(data TemplateData) (*Content, error) {
+	content := &Content{Subject: data.Subject}
+	var b bytes.Buffer
+	if t.subject != nil {
+		if err := t.subject.Execute(&b, data); err != nil {
+			return nil, err
+		}
+		content.Subject = strings.TrimSpace(b.String())
+		data.Subject = content.Subject
+	}
+	if t.text != nil {
+		b.Reset()
+		if err := t.text.Execute(&b, data); err != nil {
+			return nil, err
+		}
+		content.Text = b.String()
+	}
+	if t.html != nil {
+		b.Reset()
+		if err := t.html.Execute(&b, data); err != nil {
+			return nil, err
+		}
+		content.HTML = b.String()
+	}
+	return content, nil
+}
+
+// Templates holds named templates.
+type Templates struct {
+	mutex     sync.RWMutex
+	templates map[string]*Template
+}
+
+// NewTemplates creates an empty template set.
+func NewTemplates() *Templates {
+	return &Templates{templates: make(map[string]*Template)}
+}
+
+// Add adds templates, replacing those of the same names.
+func (s *Templates) Add(templates ...*Template) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, t := range templates {
+		s.templates[t.Name] = t
+	}
+}
+
+// Get returns the named template.
+func (s *Templates) Get(name string) (*Template, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	t, ok := s.templates[name]
+	return t, ok
+}
+
+// Names returns the template names in order.
+func (s *Templates) Names() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	names := make([]string, 0, len(s.templates))
+	for name := range s.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadDir adds the templates in dir, made of the files NAME.subject.tmpl,
+// NAME.txt.tmpl and NAME.html.tmpl.
+func (s *Templates) LoadDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read template directory: %w", err)
+	}
+	parts := make(map[string]map[string]string)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		for _, suffix := range []string{SubjectSuffix, TextSuffix, HTMLSuffix} {
+			name := strings.TrimSuffix(entry.Name(), suffix)
+			if name == entry.Name() {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				return fmt.Errorf("failed to read template: %w", err)
+			}
+			if parts[name] == nil {
+				parts[name] = make(map[string]string)
+			}
+			parts[name][suffix] = string(data)
+		}
+	}
+	var templates []*Template
+	for name, files := range parts {
+		t, err := NewTemplate(name, files[SubjectSuffix], files[TextSuffix], files[HTMLSuffix])
+		if err != nil {
+			return err
+		}
+		templates = append(templates, t)
+	}
+	s.Add(templates...)
+	return nil
+}
+
+// LoadConfig adds templates defined by name in settings, each with
+// "subject", "text" and "html".
+func (s *Templates) LoadConfig(settings map[string]interface{}) error {
+	var templates []*Template
+	for name, raw := range settings {
+		fields, ok := setting.StringKeys(raw)
+		if !ok {
+			return fmt.Errorf("template %s must be a map, got %T", name, raw)
+		}
+		parts := make(map[string]string, 3)
+		for key, value := range fields {
+			switch key {
+			case "subject", "text", "html":
+				s, ok := value.(string)
+				if !ok {
+					return fmt.Errorf("template %s: %s must be a string, got %T", name, key, value)
+				}
+				parts[key] = s
+			default:
+				return fmt.Errorf("template %s: unknown field %s", name, key)
+			}
+		}
+		t, err := NewTemplate(name, parts["subject"], parts["text"], parts["html"])
+		if err != nil {
+			return err
+		}
+		templates = append(templates, t)
+	}
+	s.Add(templates...)
+	return nil
+}
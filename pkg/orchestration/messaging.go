@@ -1,6 +1,8 @@
 package orchestration
 
 import (
+	"beluga/pkg/retry"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -40,18 +42,25 @@ func (ms *MessagingSystem) SendMessage(msg Message) error {
 	}
 }
 
-// SendMessageWithRetry sends a message with retry logic.
+// SendMessageWithRetry sends a message with retry logic, doubling the backoff
+// after each failed attempt.
 func (ms *MessagingSystem) SendMessageWithRetry(msg Message, retries int, backoff time.Duration) error {
-	for i := 0; i <= retries; i++ {
-		if err := ms.SendMessage(msg); err != nil {
-			log.Printf("Failed to send message (attempt %d): %v", i+1, err)
-			time.Sleep(backoff)
-			backoff *= 2 // Exponential backoff
-		} else {
-			return nil
+	return ms.SendMessageWithPolicy(context.Background(), msg, retry.Exponential(retries, backoff))
+}
+
+// SendMessageWithPolicy sends a message, retrying failed attempts according to policy.
+func (ms *MessagingSystem) SendMessageWithPolicy(ctx context.Context, msg Message, policy *retry.Policy) error {
+	attempts, err := policy.Do(ctx, func(ctx context.Context) error {
+		err := ms.SendMessage(msg)
+		if err != nil {
+			log.Printf("Failed to send message: %v", err)
 		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send message after %d attempts: %w", attempts, err)
 	}
-	return fmt.Errorf("failed to send message after %d retries", retries)
+	return nil
 }
 
 // ReceiveMessage receives a message from the messaging system.
@@ -1,6 +1,7 @@
 package orchestration
 
 import (
+	"context"
 	"fmt"
 	"sync"
 )
@@ -10,6 +11,18 @@ type Task struct {
 	ID       string
 	Execute  func() error
 	DependsOn []string
+
+	// ExecuteContext is the context-aware variant of Execute. When set, it is
+	// used in preference to Execute.
+	ExecuteContext func(ctx context.Context) error
+}
+
+// run executes the task, preferring ExecuteContext over Execute.
+func (t *Task) run(ctx context.Context) error {
+	if t.ExecuteContext != nil {
+		return t.ExecuteContext(ctx)
+	}
+	return t.Execute()
 }
 
 // Scheduler manages task execution based on dependencies and priorities.
@@ -42,11 +55,17 @@ func (s *Scheduler) AddTask(task *Task) error {
 
 // Run executes all tasks in the correct order based on dependencies.
 func (s *Scheduler) Run() error {
+	return s.RunContext(context.Background())
+}
+
+// RunContext executes all tasks in dependency order, passing ctx to each task.
+// No further tasks are started once ctx is cancelled.
+func (s *Scheduler) RunContext(ctx context.Context) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
 	for id, task := range s.tasks {
-		if err := s.runTask(id, task); err != nil {
+		if err := s.runTask(ctx, id, task); err != nil {
 			return err
 		}
 	}
@@ -54,7 +73,7 @@ func (s *Scheduler) Run() error {
 	return nil
 }
 
-func (s *Scheduler) runTask(id string, task *Task) error {
+func (s *Scheduler) runTask(ctx context.Context, id string, task *Task) error {
 	if s.completed[id] {
 		return nil
 	}
@@ -64,12 +83,16 @@ func (s *Scheduler) runTask(id string, task *Task) error {
 		if !exists {
 			return fmt.Errorf("dependency %s for task %s not found", dep, id)
 		}
-		if err := s.runTask(dep, depTask); err != nil {
+		if err := s.runTask(ctx, dep, depTask); err != nil {
 			return err
 		}
 	}
 
-	if err := task.Execute(); err != nil {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("task %s not started: %w", id, err)
+	}
+
+	if err := task.run(ctx); err != nil {
 		return fmt.Errorf("task %s failed: %w", id, err)
 	}
 
@@ -79,26 +102,23 @@ func (s *Scheduler) runTask(id string, task *Task) error {
 
 // ExecuteSequential runs tasks in a strictly sequential order.
 func (s *Scheduler) ExecuteSequential() error {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
-
-	for id, task := range s.tasks {
-		if err := s.runTask(id, task); err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return s.RunContext(context.Background())
 }
 
 // ExecuteAutonomous runs tasks without considering dependencies.
 func (s *Scheduler) ExecuteAutonomous() error {
+	return s.ExecuteAutonomousContext(context.Background())
+}
+
+// ExecuteAutonomousContext runs tasks concurrently without considering
+// dependencies, passing ctx to each task.
+func (s *Scheduler) ExecuteAutonomousContext(ctx context.Context) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
 	for id, task := range s.tasks {
 		go func(taskID string, t *Task) {
-			if err := t.Execute(); err != nil {
+			if err := t.run(ctx); err != nil {
 				fmt.Printf("Task %s failed: %v\n", taskID, err)
 			}
 		}(id, task)
@@ -0,0 +1,329 @@
+package prompts
+
+import (
+	"beluga/pkg/llm"
+	"beluga/pkg/setting"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// maxIncludeDepth bounds nested includes, catching templates that include
+// themselves.
+const maxIncludeDepth = 10
+
+// Extensions of the template files LoadDir reads.
+var templateExtensions = map[string]bool{".prompt": true, ".tmpl": true}
+
+// ErrUnknownTemplate is returned when rendering a template that is not in the library.
+var ErrUnknownTemplate = errors.New("unknown prompt template")
+
+// Library holds named prompt templates. Templates can include each other by
+// name, so that common fragments are written once as partials.
+type Library struct {
+	mutex     sync.RWMutex
+	templates map[string]*Template
+}
+
+// NewLibrary creates an empty library.
+func NewLibrary() *Library {
+	return &Library{templates: make(map[string]*Template)}
+}
+
+var defaultLibrary = NewLibrary()
+
+// DefaultLibrary returns the process-wide library, which agents resolve
+// template names against unless given their own.
+func DefaultLibrary() *Library {
+	return defaultLibrary
+}
+
+// Add adds templates to the library, replacing templates with the same name.
+func (l *Library) Add(templates ...*Template) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for _, t := range templates {
+		l.templates[t.Name] = t
+	}
+}
+
+// Parse parses source and adds the template under name.
+func (l *Library) Parse(name, source string) (*Template, error) {
+	t, err := Parse(name, source)
+	if err != nil {
+		return nil, err
+	}
+	l.Add(t)
+	return t, nil
+}
+
+// Get returns the named template.
+func (l *Library) Get(name string) (*Template, bool) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	t, ok := l.templates[name]
+	return t, ok
+}
+
+// Names returns the names of the templates, sorted.
+func (l *Library) Names() []string {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	names := make([]string, 0, len(l.templates))
+	for name := range l.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadDir adds every .prompt and .tmpl file under dir, recursively. A
+// template is named by its path relative to dir without the extension, using
+// forward slashes, such as "partials/header", unless its front matter names it.
+func (l *Library) LoadDir(dir string) error {
+	var templates []*Template
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !templateExtensions[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+		relative, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(strings.TrimSuffix(relative, filepath.Ext(relative)))
+		t, err := Parse(name, string(data))
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		templates = append(templates, t)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load prompt directory %s: %w", dir, err)
+	}
+	l.Add(templates...)
+	return nil
+}
+
+// LoadConfig adds the templates defined in a configuration map. Each entry
+// maps a template name to either its source or a map with the keys
+// "template", "description", "role" and "variables".
+func (l *Library) LoadConfig(definitions map[string]interface{}) error {
+	var templates []*Template
+	for name, raw := range definitions {
+		t, err := templateFromConfig(name, setting.NormalizeAll(raw))
+		if err != nil {
+			return err
+		}
+		templates = append(templates, t)
+	}
+	l.Add(templates...)
+	return nil
+}
+
+func templateFromConfig(name string, raw interface{}) (*Template, error) {
+	switch v := raw.(type) {
+	case string:
+		return Parse(name, v)
+	case map[string]interface{}:
+		source, ok := v["template"].(string)
+		if !ok {
+			return nil, fmt.Errorf("prompt %s: template must be a string, got %T", name, v["template"])
+		}
+		t, err := Parse(name, source)
+		if err != nil {
+			return nil, err
+		}
+		t.Description, _ = v["description"].(string)
+		t.Role, _ = v["role"].(string)
+		if rawVariables, ok := v["variables"]; ok {
+			declarations, isMap := rawVariables.(map[string]interface{})
+			if !isMap {
+				return nil, fmt.Errorf("prompt %s: variables must be a map, got %T", name, rawVariables)
+			}
+			if t.Variables, err = parseVariables(declarations); err != nil {
+				return nil, fmt.Errorf("prompt %s: %w", name, err)
+			}
+		}
+		return t, nil
+	default:
+		return nil, fmt.Errorf("prompt %s: expected a template string or map, got %T", name, raw)
+	}
+}
+
+// Render renders the named template to text. Role markers are dropped.
+func (l *Library) Render(name string, vars map[string]interface{}) (string, error) {
+	messages, err := l.RenderMessages(name, vars)
+	if err != nil {
+		return "", err
+	}
+	parts := make([]string, len(messages))
+	for i, message := range messages {
+		parts[i] = message.Content
+	}
+	return strings.Join(parts, "\n\n"), nil
+}
+
+// RenderMessages renders the named template to chat messages, starting a new
+// message at each role marker. Messages without content are omitted.
+func (l *Library) RenderMessages(name string, vars map[string]interface{}) ([]llm.Message, error) {
+	t, ok := l.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTemplate, name)
+	}
+	r := &renderer{library: l}
+	text, err := t.render(r, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	role := llm.Role(t.Role)
+	if role == "" {
+		role = llm.RoleUser
+	}
+	// Role markers split the text into the text before the first marker,
+	// then alternating role names and message texts
+	parts := strings.Split(text, roleMarker)
+	var messages []llm.Message
+	for i := 0; i < len(parts); i += 2 {
+		if i > 0 {
+			role = llm.Role(parts[i-1])
+		}
+		if content := strings.TrimSpace(parts[i]); content != "" {
+			messages = append(messages, llm.Message{Role: role, Content: content})
+		}
+	}
+	return messages, nil
+}
+
+// roleMarker delimits the role markers in rendered text. Escape removes it
+// from untrusted values.
+const roleMarker = "\x00"
+
+// renderer holds the state of a single render, shared by nested includes.
+type renderer struct {
+	library *Library
+	depth   int
+}
+
+func (r *renderer) role(name string) (string, error) {
+	switch llm.Role(name) {
+	case llm.RoleSystem, llm.RoleUser, llm.RoleAssistant:
+		return roleMarker + name + roleMarker, nil
+	}
+	return "", fmt.Errorf("unknown chat role %q", name)
+}
+
+func (r *renderer) include(name string, data ...interface{}) (string, error) {
+	t, ok := r.library.Get(name)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownTemplate, name)
+	}
+	if r.depth >= maxIncludeDepth {
+		return "", fmt.Errorf("includes nested deeper than %d at %s", maxIncludeDepth, name)
+	}
+
+	var vars map[string]interface{}
+	switch len(data) {
+	case 0:
+	case 1:
+		m, ok := data[0].(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("include %s: data must be a map, got %T", name, data[0])
+		}
+		vars = m
+	default:
+		return "", fmt.Errorf("include %s: expected at most one data argument", name)
+	}
+
+	r.depth++
+	defer func() { r.depth-- }()
+	return t.render(r, vars)
+}
+
+// Escape makes untrusted text safe to embed in a prompt: it removes control
+// characters other than newlines and tabs, which also strips role markers,
+// and escapes '&', '<' and '>' so that the text cannot close or open the
+// XML-style tags prompts use to fence input.
+func Escape(text string) string {
+	var b strings.Builder
+	b.Grow(len(text))
+	for _, r := range text {
+		switch {
+		case r == '&':
+			b.WriteString("&amp;")
+		case r == '<':
+			b.WriteString("&lt;")
+		case r == '>':
+			b.WriteString("&gt;")
+		case r == '\n' || r == '\t':
+			b.WriteRune(r)
+		case unicode.IsControl(r):
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// escapeValue escapes the strings within an untrusted value.
+func escapeValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return Escape(v)
+	case []string:
+		escaped := make([]string, len(v))
+		for i, s := range v {
+			escaped[i] = Escape(s)
+		}
+		return escaped
+	case []interface{}:
+		escaped := make([]interface{}, len(v))
+		for i, item := range v {
+			escaped[i] = escapeValue(item)
+		}
+		return escaped
+	case map[string]interface{}:
+		escaped := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			escaped[key] = escapeValue(item)
+		}
+		return escaped
+	}
+	return value
+}
+
+func toJSON(value interface{}) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func join(list interface{}, separator string) (string, error) {
+	v := reflect.ValueOf(list)
+	if list == nil || (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) {
+		return "", fmt.Errorf("join expects a list, got %T", list)
+	}
+	parts := make([]string, v.Len())
+	for i := range parts {
+		parts[i] = fmt.Sprint(v.Index(i).Interface())
+	}
+	return strings.Join(parts, separator), nil
+}
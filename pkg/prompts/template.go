@@ -0,0 +1,290 @@
+package prompts
+
+import (
+	"beluga/pkg/setting"
+	"bytes"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Variable types accepted in a template's variable declarations.
+const (
+	TypeString  = "string"
+	TypeNumber  = "number"
+	TypeInteger = "integer"
+	TypeBoolean = "boolean"
+	TypeList    = "list"
+	TypeObject  = "object"
+	TypeAny     = "any"
+)
+
+// ErrMissingVariable is returned when rendering without a required variable.
+var ErrMissingVariable = errors.New("missing template variable")
+
+// Variable declares a template variable.
+type Variable struct {
+	Type        string `json:"type" yaml:"type"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Required defaults to true for variables without a Default.
+	Required *bool       `json:"required,omitempty" yaml:"required,omitempty"`
+	Default  interface{} `json:"default,omitempty" yaml:"default,omitempty"`
+	// Untrusted values are escaped with Escape before rendering.
+	Untrusted bool `json:"untrusted,omitempty" yaml:"untrusted,omitempty"`
+}
+
+// isRequired reports whether rendering fails without the variable.
+func (v Variable) isRequired() bool {
+	if v.Required != nil {
+		return *v.Required
+	}
+	return v.Default == nil
+}
+
+// Template is a named prompt template. Its source uses Go text/template
+// syntax with these additional functions:
+//
+//	{{role "system"}}          starts a chat message with the given role
+//	{{include "name" .}}       renders another template of the library
+//	{{escape .value}}          escapes untrusted text, see Escape
+//	{{json .value}}            renders a value as JSON
+//	{{join .list ", "}}        joins a list
+//
+// Referencing a variable that was not provided is an error.
+type Template struct {
+	Name        string
+	Description string
+	// Role is the role of text preceding the first role marker; RoleUser if empty.
+	Role      string
+	Variables map[string]Variable
+	Source    string
+	parsed    *template.Template
+}
+
+// frontMatter is the YAML header of a template file.
+type frontMatter struct {
+	Name        string                 `yaml:"name"`
+	Description string                 `yaml:"description"`
+	Role        string                 `yaml:"role"`
+	Variables   map[string]interface{} `yaml:"variables"`
+}
+
+// Parse parses a template. The source may start with a YAML front matter
+// between "---" lines declaring the template's name, description, default
+// role and variables. A variable is declared either by its type alone or by
+// a map with the fields of Variable.
+func Parse(name, source string) (*Template, error) {
+	t := &Template{Name: name, Source: source}
+
+	normalized := strings.ReplaceAll(source, "\r\n", "\n")
+	if strings.HasPrefix(normalized, "---\n") {
+		end := strings.Index(normalized[4:], "\n---")
+		if end < 0 {
+			return nil, fmt.Errorf("template %s: unterminated front matter", name)
+		}
+		header := normalized[4 : 4+end]
+		body := strings.TrimPrefix(normalized[4+end+4:], "\n")
+
+		var meta frontMatter
+		if err := yaml.Unmarshal([]byte(header), &meta); err != nil {
+			return nil, fmt.Errorf("template %s: invalid front matter: %w", name, err)
+		}
+		if meta.Name != "" {
+			t.Name = meta.Name
+		}
+		t.Description = meta.Description
+		t.Role = meta.Role
+		variables, err := parseVariables(meta.Variables)
+		if err != nil {
+			return nil, fmt.Errorf("template %s: %w", name, err)
+		}
+		t.Variables = variables
+		t.Source = body
+	}
+
+	if err := t.compile(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// parseVariables reads variable declarations from decoded YAML or JSON.
+func parseVariables(raw map[string]interface{}) (map[string]Variable, error) {
+	variables := make(map[string]Variable, len(raw))
+	for name, spec := range raw {
+		var v Variable
+		switch s := spec.(type) {
+		case string:
+			v.Type = s
+		case map[interface{}]interface{}, map[string]interface{}:
+			// Round-trip through YAML to decode the map into a Variable
+			data, err := yaml.Marshal(s)
+			if err != nil {
+				return nil, fmt.Errorf("variable %s: %w", name, err)
+			}
+			if err := yaml.Unmarshal(data, &v); err != nil {
+				return nil, fmt.Errorf("variable %s: %w", name, err)
+			}
+			v.Default = setting.NormalizeAll(v.Default)
+		default:
+			return nil, fmt.Errorf("variable %s: expected a type or a map, got %T", name, spec)
+		}
+		if v.Type == "" {
+			v.Type = TypeAny
+		}
+		switch v.Type {
+		case TypeString, TypeNumber, TypeInteger, TypeBoolean, TypeList, TypeObject, TypeAny:
+		default:
+			return nil, fmt.Errorf("variable %s: unknown type %q", name, v.Type)
+		}
+		if v.Default != nil {
+			if err := checkType(v.Type, v.Default); err != nil {
+				return nil, fmt.Errorf("variable %s: default %w", name, err)
+			}
+		}
+		variables[name] = v
+	}
+	return variables, nil
+}
+
+// compile parses the template source. Functions are bound per render, so
+// placeholders are registered here.
+func (t *Template) compile() error {
+	parsed, err := template.New(t.Name).Option("missingkey=error").Funcs(placeholderFuncs).Parse(t.Source)
+	if err != nil {
+		return fmt.Errorf("failed to parse template %s: %w", t.Name, err)
+	}
+	t.parsed = parsed
+	return nil
+}
+
+var placeholderFuncs = template.FuncMap{
+	"role":    func(string) string { return "" },
+	"include": func(string, ...interface{}) (string, error) { return "", nil },
+	"escape":  Escape,
+	"json":    toJSON,
+	"join":    join,
+}
+
+// bind resolves the variables of a render: it checks required variables and
+// types, applies defaults and escapes untrusted values. Variables that are
+// not declared are passed through unchanged.
+func (t *Template) bind(vars map[string]interface{}) (map[string]interface{}, error) {
+	data := make(map[string]interface{}, len(vars)+len(t.Variables))
+	for key, value := range vars {
+		data[key] = value
+	}
+
+	names := make([]string, 0, len(t.Variables))
+	for name := range t.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		v := t.Variables[name]
+		value, ok := data[name]
+		if !ok || value == nil {
+			switch {
+			case v.Default != nil:
+				value = v.Default
+			case v.isRequired():
+				return nil, fmt.Errorf("template %s: %w: %s", t.Name, ErrMissingVariable, name)
+			default:
+				value = zeroValue(v.Type)
+			}
+		}
+		if err := checkType(v.Type, value); err != nil {
+			return nil, fmt.Errorf("template %s: variable %s %w", t.Name, name, err)
+		}
+		if v.Untrusted {
+			value = escapeValue(value)
+		}
+		data[name] = value
+	}
+	return data, nil
+}
+
+func zeroValue(variableType string) interface{} {
+	switch variableType {
+	case TypeString:
+		return ""
+	case TypeNumber, TypeInteger:
+		return 0
+	case TypeBoolean:
+		return false
+	case TypeList:
+		return []interface{}{}
+	case TypeObject:
+		return map[string]interface{}{}
+	}
+	return ""
+}
+
+// checkType reports whether value has the declared type.
+func checkType(variableType string, value interface{}) error {
+	v := reflect.ValueOf(value)
+	ok := true
+	switch variableType {
+	case TypeString:
+		_, ok = value.(string)
+	case TypeNumber, TypeInteger:
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		case reflect.Float32, reflect.Float64:
+			ok = variableType == TypeNumber || v.Float() == math.Trunc(v.Float())
+		default:
+			ok = false
+		}
+	case TypeBoolean:
+		_, ok = value.(bool)
+	case TypeList:
+		ok = value != nil && (v.Kind() == reflect.Slice || v.Kind() == reflect.Array)
+	case TypeObject:
+		ok = value != nil && (v.Kind() == reflect.Map || v.Kind() == reflect.Struct ||
+			(v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Struct))
+	}
+	if !ok {
+		return fmt.Errorf("must be of type %s, got %T", variableType, value)
+	}
+	return nil
+}
+
+// render executes the template with funcs bound to a render of library.
+func (t *Template) render(r *renderer, vars map[string]interface{}) (string, error) {
+	data, err := t.bind(vars)
+	if err != nil {
+		return "", err
+	}
+	clone, err := t.parsed.Clone()
+	if err != nil {
+		return "", fmt.Errorf("failed to render template %s: %w", t.Name, err)
+	}
+	clone.Funcs(template.FuncMap{
+		"role":    r.role,
+		"include": r.include,
+	})
+
+	var out bytes.Buffer
+	if err := clone.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("failed to render template %s: %w", t.Name, err)
+	}
+	return out.String(), nil
+}
+
+// RequiredVariables returns the names of the variables rendering requires.
+func (t *Template) RequiredVariables() []string {
+	var names []string
+	for name, v := range t.Variables {
+		if v.isRequired() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,82 @@
+package retry
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Backoff computes how long to wait before a retry.
+type Backoff interface {
+	// Delay returns the wait before retry number attempt (starting at 1),
+	// given the delay used before the previous retry (zero for the first).
+	Delay(attempt int, previous time.Duration) time.Duration
+}
+
+// ConstantBackoff waits the same interval before every retry.
+type ConstantBackoff struct {
+	Interval time.Duration
+}
+
+// Delay implements Backoff.
+func (c ConstantBackoff) Delay(attempt int, previous time.Duration) time.Duration {
+	return c.Interval
+}
+
+// ExponentialBackoff multiplies the delay by Multiplier after every retry,
+// starting from Initial and capped at Max. Jitter randomises each delay by up
+// to the given fraction in either direction (0.2 means ±20%).
+type ExponentialBackoff struct {
+	Initial    time.Duration
+	Multiplier float64
+	Max        time.Duration
+	Jitter     float64
+}
+
+// Delay implements Backoff.
+func (e ExponentialBackoff) Delay(attempt int, previous time.Duration) time.Duration {
+	multiplier := e.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	delay := float64(e.Initial) * math.Pow(multiplier, float64(attempt-1))
+	if e.Max > 0 && delay > float64(e.Max) {
+		delay = float64(e.Max)
+	}
+	if e.Jitter > 0 {
+		delay += delay * e.Jitter * (2*rand.Float64() - 1)
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	return time.Duration(delay)
+}
+
+// DecorrelatedJitterBackoff picks each delay at random between Base and three
+// times the previous delay, capped at Max. Spreading the delays this way keeps
+// many clients that fail together from retrying in lockstep.
+type DecorrelatedJitterBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// Delay implements Backoff.
+func (d DecorrelatedJitterBackoff) Delay(attempt int, previous time.Duration) time.Duration {
+	if previous < d.Base {
+		previous = d.Base
+	}
+
+	upper := 3 * previous
+	delay := d.Base
+	if upper > d.Base {
+		delay += time.Duration(rand.Int63n(int64(upper - d.Base)))
+	}
+	if d.Max > 0 && delay > d.Max {
+		delay = d.Max
+	}
+	return delay
+}
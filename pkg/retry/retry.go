@@ -0,0 +1,226 @@
+package retry
+
+import (
+	"beluga/pkg/setting"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Policy describes how an operation is retried.
+type Policy struct {
+	// MaxRetries is the number of retries after the first attempt.
+	MaxRetries int
+	// Backoff computes the wait before each retry. A nil Backoff retries immediately.
+	Backoff Backoff
+	// MaxElapsedTime stops retrying once the next retry would start later than
+	// this long after the first attempt. Zero means no limit.
+	MaxElapsedTime time.Duration
+	// Classifier reports whether an error may be retried. Nil means Retryable.
+	Classifier func(error) bool
+	// OnRetry, if set, is called before waiting for each retry.
+	OnRetry func(attempt int, err error, delay time.Duration)
+}
+
+// Constant returns a policy that retries up to maxRetries times with a fixed delay.
+func Constant(maxRetries int, delay time.Duration) *Policy {
+	return &Policy{
+		MaxRetries: maxRetries,
+		Backoff:    ConstantBackoff{Interval: delay},
+	}
+}
+
+// Exponential returns a policy that retries up to maxRetries times, doubling
+// the delay after each retry starting from initial.
+func Exponential(maxRetries int, initial time.Duration) *Policy {
+	return &Policy{
+		MaxRetries: maxRetries,
+		Backoff:    ExponentialBackoff{Initial: initial, Multiplier: 2},
+	}
+}
+
+// Do calls fn until it succeeds, returns an error that is not retryable, the
+// retries or elapsed time are exhausted, or ctx is done. It returns the number
+// of attempts made and the last error, or the context error if ctx ended the
+// retries.
+func (p *Policy) Do(ctx context.Context, fn func(ctx context.Context) error) (int, error) {
+	classify := p.Classifier
+	if classify == nil {
+		classify = Retryable
+	}
+
+	start := time.Now()
+	var delay time.Duration
+	attempts := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return attempts, err
+		}
+
+		attempts++
+		err := fn(ctx)
+		if err == nil {
+			return attempts, nil
+		}
+		if attempts > p.MaxRetries || !classify(err) {
+			return attempts, err
+		}
+
+		if p.Backoff != nil {
+			delay = p.Backoff.Delay(attempts, delay)
+		}
+		if p.MaxElapsedTime > 0 && time.Since(start)+delay > p.MaxElapsedTime {
+			return attempts, err
+		}
+
+		if p.OnRetry != nil {
+			p.OnRetry(attempts, err, delay)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return attempts, ctx.Err()
+		}
+	}
+}
+
+// permanentError marks an error as not retryable.
+type permanentError struct {
+	err error
+}
+
+func (p *permanentError) Error() string { return p.err.Error() }
+func (p *permanentError) Unwrap() error { return p.err }
+
+// Permanent wraps err so that Retryable reports false for it. It returns nil
+// if err is nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// Retryable is the default error classifier. It reports false for nil errors,
+// errors wrapped with Permanent, context cancellation and deadline errors, and
+// errors that implement Retryable() bool or Temporary() bool returning false.
+// Every other error is considered transient.
+func Retryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var permanent *permanentError
+	if errors.As(err, &permanent) {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var retryable interface{ Retryable() bool }
+	if errors.As(err, &retryable) {
+		return retryable.Retryable()
+	}
+	var temporary interface{ Temporary() bool }
+	if errors.As(err, &temporary) {
+		return temporary.Temporary()
+	}
+	return true
+}
+
+// Config is the serialisable form of a Policy as it appears in agent
+// configuration. Durations are given in seconds.
+type Config struct {
+	Strategy       string  `json:"strategy" yaml:"strategy"`
+	MaxRetries     int     `json:"max_retries" yaml:"max_retries"`
+	InitialDelay   float64 `json:"initial_delay" yaml:"initial_delay"`
+	MaxDelay       float64 `json:"max_delay" yaml:"max_delay"`
+	Multiplier     float64 `json:"multiplier" yaml:"multiplier"`
+	Jitter         float64 `json:"jitter" yaml:"jitter"`
+	MaxElapsedTime float64 `json:"max_elapsed_time" yaml:"max_elapsed_time"`
+}
+
+// Strategy names accepted in Config.
+const (
+	StrategyConstant           = "constant"
+	StrategyExponential        = "exponential"
+	StrategyDecorrelatedJitter = "decorrelated_jitter"
+)
+
+// Policy builds the retry policy described by the configuration.
+func (c Config) Policy() (*Policy, error) {
+	initial := seconds(c.InitialDelay)
+	max := seconds(c.MaxDelay)
+
+	var backoff Backoff
+	switch c.Strategy {
+	case "", StrategyConstant:
+		backoff = ConstantBackoff{Interval: initial}
+	case StrategyExponential:
+		backoff = ExponentialBackoff{Initial: initial, Multiplier: c.Multiplier, Max: max, Jitter: c.Jitter}
+	case StrategyDecorrelatedJitter:
+		backoff = DecorrelatedJitterBackoff{Base: initial, Max: max}
+	default:
+		return nil, fmt.Errorf("unknown retry strategy: %s", c.Strategy)
+	}
+
+	if c.MaxRetries < 0 {
+		return nil, fmt.Errorf("max_retries cannot be negative: %d", c.MaxRetries)
+	}
+
+	return &Policy{
+		MaxRetries:     c.MaxRetries,
+		Backoff:        backoff,
+		MaxElapsedTime: seconds(c.MaxElapsedTime),
+	}, nil
+}
+
+// ConfigFromMap reads a Config from a generic settings map such as the
+// "retry_policy" entry of an agent's settings.
+func ConfigFromMap(settings map[string]interface{}) (Config, error) {
+	var c Config
+
+	if strategy, ok := settings["strategy"]; ok {
+		s, isString := strategy.(string)
+		if !isString {
+			return c, fmt.Errorf("retry strategy must be a string, got %T", strategy)
+		}
+		c.Strategy = s
+	}
+
+	fields := map[string]*float64{
+		"initial_delay":    &c.InitialDelay,
+		"max_delay":        &c.MaxDelay,
+		"multiplier":       &c.Multiplier,
+		"jitter":           &c.Jitter,
+		"max_elapsed_time": &c.MaxElapsedTime,
+	}
+	for key, target := range fields {
+		if raw, ok := settings[key]; ok {
+			value, isNumber := setting.Number(raw)
+			if !isNumber {
+				return c, fmt.Errorf("retry %s must be a number, got %T", key, raw)
+			}
+			*target = value
+		}
+	}
+
+	if raw, ok := settings["max_retries"]; ok {
+		value, isNumber := setting.Number(raw)
+		if !isNumber {
+			return c, fmt.Errorf("retry max_retries must be a number, got %T", raw)
+		}
+		c.MaxRetries = int(value)
+	}
+
+	return c, nil
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
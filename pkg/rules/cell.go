@@ -0,0 +1,243 @@
+package rules
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Input types of decision table columns.
+const (
+	TypeNumber  = "number"
+	TypeString  = "string"
+	TypeBoolean = "boolean"
+)
+
+// interval is a range of numbers; infinite bounds are open.
+type interval struct {
+	lo, hi         float64
+	loOpen, hiOpen bool
+}
+
+func (i interval) contains(x float64) bool {
+	if x < i.lo || x == i.lo && i.loOpen {
+		return false
+	}
+	return x < i.hi || x == i.hi && !i.hiOpen
+}
+
+func (i interval) String() string {
+	switch {
+	case math.IsInf(i.lo, -1) && math.IsInf(i.hi, 1):
+		return "-"
+	case math.IsInf(i.lo, -1):
+		if i.hiOpen {
+			return "< " + formatNumber(i.hi)
+		}
+		return "<= " + formatNumber(i.hi)
+	case math.IsInf(i.hi, 1):
+		if i.loOpen {
+			return "> " + formatNumber(i.lo)
+		}
+		return ">= " + formatNumber(i.lo)
+	case i.lo == i.hi:
+		return formatNumber(i.lo)
+	}
+	open, close := "[", "]"
+	if i.loOpen {
+		open = "("
+	}
+	if i.hiOpen {
+		close = ")"
+	}
+	return open + formatNumber(i.lo) + ".." + formatNumber(i.hi) + close
+}
+
+func formatNumber(x float64) string {
+	return strconv.FormatFloat(x, 'g', -1, 64)
+}
+
+// test is a parsed input entry of a decision table: a unary test such as
+// "-", ">= 0.6", "[0.2..0.6)", "positive", "\"a\", \"b\"" or "not(< 0)".
+type test struct {
+	text    string
+	any     bool
+	negate  bool
+	numbers []interval
+	strings []string
+	bools   []bool
+}
+
+// parseTest parses an input entry of a column of type kind.
+func parseTest(text, kind string) (*test, error) {
+	t := &test{text: strings.TrimSpace(text)}
+	body := t.text
+	if body == "" || body == "-" {
+		t.any = true
+		return t, nil
+	}
+	if strings.HasPrefix(body, "not(") && strings.HasSuffix(body, ")") {
+		t.negate = true
+		body = strings.TrimSpace(body[4 : len(body)-1])
+	}
+
+	for _, alternative := range splitAlternatives(body) {
+		switch kind {
+		case TypeNumber:
+			intervals, err := parseNumberTest(alternative)
+			if err != nil {
+				return nil, err
+			}
+			t.numbers = append(t.numbers, intervals...)
+		case TypeBoolean:
+			value, err := strconv.ParseBool(alternative)
+			if err != nil {
+				return nil, fmt.Errorf("invalid boolean %s", alternative)
+			}
+			t.bools = append(t.bools, value)
+		default:
+			t.strings = append(t.strings, unquote(alternative))
+		}
+	}
+	return t, nil
+}
+
+// splitAlternatives splits a test at the commas outside quotes.
+func splitAlternatives(text string) []string {
+	var parts []string
+	var quote rune
+	start := 0
+	for i, r := range text {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ',':
+			parts = append(parts, strings.TrimSpace(text[start:i]))
+			start = i + 1
+		}
+	}
+	return append(parts, strings.TrimSpace(text[start:]))
+}
+
+func unquote(text string) string {
+	if len(text) >= 2 && (text[0] == '"' || text[0] == '\'') && text[len(text)-1] == text[0] {
+		return text[1 : len(text)-1]
+	}
+	return text
+}
+
+// parseNumberTest parses a comparison, a range or a number.
+func parseNumberTest(text string) ([]interval, error) {
+	inf := math.Inf(1)
+	for _, op := range []string{"<=", ">=", "!=", "<", ">", "="} {
+		if !strings.HasPrefix(text, op) {
+			continue
+		}
+		x, err := strconv.ParseFloat(strings.TrimSpace(text[len(op):]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number in %s", text)
+		}
+		switch op {
+		case "<=":
+			return []interval{{lo: -inf, hi: x, loOpen: true}}, nil
+		case ">=":
+			return []interval{{lo: x, hi: inf, hiOpen: true}}, nil
+		case "<":
+			return []interval{{lo: -inf, hi: x, loOpen: true, hiOpen: true}}, nil
+		case ">":
+			return []interval{{lo: x, hi: inf, loOpen: true, hiOpen: true}}, nil
+		case "!=":
+			return []interval{{lo: -inf, hi: x, loOpen: true, hiOpen: true}, {lo: x, hi: inf, loOpen: true, hiOpen: true}}, nil
+		}
+		return []interval{{lo: x, hi: x}}, nil
+	}
+
+	if bounds := strings.Split(text, ".."); len(bounds) == 2 && len(text) > 4 {
+		open, close := text[0], text[len(text)-1]
+		if strings.IndexByte("[(]", open) >= 0 && strings.IndexByte("])[", close) >= 0 {
+			lo, loErr := strconv.ParseFloat(strings.TrimSpace(bounds[0][1:]), 64)
+			hi, hiErr := strconv.ParseFloat(strings.TrimSpace(bounds[1][:len(bounds[1])-1]), 64)
+			if loErr != nil || hiErr != nil || lo > hi {
+				return nil, fmt.Errorf("invalid range %s", text)
+			}
+			return []interval{{lo: lo, hi: hi, loOpen: open != '[', hiOpen: close != ']'}}, nil
+		}
+	}
+
+	x, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number test %s", text)
+	}
+	return []interval{{lo: x, hi: x}}, nil
+}
+
+// matches reports whether a normalized input value passes the test. Null
+// passes only "-".
+func (t *test) matches(value interface{}, kind string) (bool, error) {
+	if t.any {
+		return true, nil
+	}
+	if value == nil {
+		return false, nil
+	}
+	matched := false
+	switch kind {
+	case TypeNumber:
+		x, ok := value.(float64)
+		if !ok {
+			return false, fmt.Errorf("expected a number, got %s", typeName(value))
+		}
+		for _, i := range t.numbers {
+			matched = matched || i.contains(x)
+		}
+	case TypeBoolean:
+		b, ok := value.(bool)
+		if !ok {
+			return false, fmt.Errorf("expected a boolean, got %s", typeName(value))
+		}
+		for _, candidate := range t.bools {
+			matched = matched || candidate == b
+		}
+	default:
+		s, ok := value.(string)
+		if !ok {
+			return false, fmt.Errorf("expected a string, got %s", typeName(value))
+		}
+		for _, candidate := range t.strings {
+			matched = matched || candidate == s
+		}
+	}
+	return matched != t.negate, nil
+}
+
+// inferType returns the type of a column from its entries: number if every
+// entry is a number test, boolean if every entry is true or false, and
+// string otherwise.
+func inferType(entries []string) string {
+	kind := ""
+	for _, entry := range entries {
+		test, err := parseTest(entry, TypeNumber)
+		if err != nil {
+			if _, err := parseTest(entry, TypeBoolean); err == nil && kind != TypeNumber {
+				kind = TypeBoolean
+				continue
+			}
+			return TypeString
+		}
+		if !test.any {
+			if kind == TypeBoolean {
+				return TypeString
+			}
+			kind = TypeNumber
+		}
+	}
+	if kind == "" {
+		return TypeString
+	}
+	return kind
+}
@@ -0,0 +1,919 @@
+package rules
+
+import (
+	"beluga/pkg/setting"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expression is a compiled condition such as
+//
+//	label == "positive" and score >= params.positive_threshold
+//
+// Expressions support number, string, boolean, null and list literals;
+// field access with dots and brackets ("items[0].name", "tags['a b']");
+// the operators + - * / %, == != < <= > >=, and or not (also && || !);
+// "x in list" for membership in a list or substring of a string,
+// "x between low and high" for inclusive ranges, and the functions len,
+// abs, lower, upper, min and max. Missing fields are null: ordering
+// comparisons with null are false, and null is false as a condition.
+// Expressions cannot call anything else, so they are safe to load from
+// configuration.
+type Expression struct {
+	source string
+	root   node
+	fields []*pathNode
+}
+
+// Compile parses an expression.
+func Compile(source string) (*Expression, error) {
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{source: source, tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, p.errorf("unexpected %s", p.peek())
+	}
+	return &Expression{source: source, root: root, fields: p.fields}, nil
+}
+
+// String returns the source of the expression.
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Evaluate returns the value of the expression, resolving fields in vars.
+func (e *Expression) Evaluate(vars map[string]interface{}) (interface{}, error) {
+	return e.root.eval(vars)
+}
+
+// Matches evaluates the expression as a condition, which must be a boolean
+// or null.
+func (e *Expression) Matches(vars map[string]interface{}) (bool, error) {
+	value, err := e.Evaluate(vars)
+	if err != nil {
+		return false, err
+	}
+	return truth(value)
+}
+
+// Fields returns the values of the fields the expression refers to, by
+// their text, for explaining a result. Fields whose index is itself an
+// expression are omitted.
+func (e *Expression) Fields(vars map[string]interface{}) map[string]interface{} {
+	values := make(map[string]interface{}, len(e.fields))
+	for _, field := range e.fields {
+		if !field.static {
+			continue
+		}
+		if value, err := field.eval(vars); err == nil {
+			values[field.text] = value
+		}
+	}
+	return values
+}
+
+// Lexer
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenIdent
+	tokenOp
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+	pos   int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of expression"
+	}
+	return strconv.Quote(t.text)
+}
+
+// operators are the operator tokens, longest first.
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "+", "-", "*", "/", "%", "(", ")", "[", "]", ",", "."}
+
+func lex(source string) ([]token, error) {
+	var tokens []token
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.' || runes[i] == 'e' || runes[i] == 'E' ||
+				(runes[i] == '-' || runes[i] == '+') && (runes[i-1] == 'e' || runes[i-1] == 'E')) {
+				i++
+			}
+			text := string(runes[start:i])
+			value, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %s at position %d", text, start)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: text, value: value, pos: start})
+		case r == '"' || r == '\'':
+			start := i
+			var text strings.Builder
+			for i++; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				text.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			tokens = append(tokens, token{kind: tokenString, text: string(runes[start:i]), value: text.String(), pos: start})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: string(runes[start:i]), pos: start})
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(string(runes[i:]), op) {
+					tokens = append(tokens, token{kind: tokenOp, text: op, pos: i})
+					i += len([]rune(op))
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at position %d", r, i)
+			}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+// Parser
+
+type parser struct {
+	source string
+	tokens []token
+	pos    int
+	fields []*pathNode
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is one of the operators or keywords.
+func (p *parser) accept(texts ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokenOp && t.kind != tokenIdent {
+		return "", false
+	}
+	for _, text := range texts {
+		if t.text == text {
+			p.next()
+			return text, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) expect(text string) error {
+	if _, ok := p.accept(text); !ok {
+		return p.errorf("expected %q, got %s", text, p.peek())
+	}
+	return nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%s at position %d of %q", fmt.Sprintf(format, args...), p.peek().pos, p.source)
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("or", "||"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{and: false, left: left, right: right}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("and", "&&"); !ok {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{and: true, left: left, right: right}
+	}
+}
+
+func (p *parser) parseNot() (node, error) {
+	if _, ok := p.accept("not", "!"); ok {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	if op, ok := p.accept("==", "!=", "<", "<=", ">", ">="); ok {
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		return &compareNode{op: op, left: left, right: right}, nil
+	}
+	if _, ok := p.accept("between"); ok {
+		low, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("and"); err != nil {
+			return nil, err
+		}
+		high, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		return &betweenNode{value: left, low: low, high: high}, nil
+	}
+	negated := false
+	if p.peek().text == "not" && p.pos+1 < len(p.tokens) && p.tokens[p.pos+1].text == "in" {
+		p.next()
+		negated = true
+	}
+	if _, ok := p.accept("in"); ok {
+		container, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		var n node = &inNode{value: left, container: container}
+		if negated {
+			n = &notNode{operand: n}
+		}
+		return n, nil
+	}
+	return left, nil
+}
+
+func (p *parser) parseAdditive() (node, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &arithmeticNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseMultiplicative() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("*", "/", "%")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &arithmeticNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if _, ok := p.accept("-"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &arithmeticNode{op: "-", left: &literalNode{value: 0.0}, right: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+// keywords cannot be used as field names.
+var keywords = map[string]bool{"and": true, "or": true, "not": true, "in": true, "between": true, "true": true, "false": true, "null": true}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber, tokenString:
+		return &literalNode{value: t.value}, nil
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null":
+			return &literalNode{value: nil}, nil
+		}
+		if keywords[t.text] {
+			p.pos--
+			return nil, p.errorf("unexpected %s", t)
+		}
+		if p.peek().text == "(" {
+			return p.parseCall(t)
+		}
+		return p.parsePath(t)
+	case tokenOp:
+		switch t.text {
+		case "(":
+			inner, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return inner, p.expect(")")
+		case "[":
+			items, err := p.parseList("]")
+			if err != nil {
+				return nil, err
+			}
+			return &listNode{items: items}, nil
+		}
+	}
+	p.pos--
+	return nil, p.errorf("unexpected %s", t)
+}
+
+func (p *parser) parseList(end string) ([]node, error) {
+	var items []node
+	if _, ok := p.accept(end); ok {
+		return items, nil
+	}
+	for {
+		item, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if _, ok := p.accept(end); ok {
+			return items, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	function, ok := functions[name.text]
+	if !ok {
+		p.pos--
+		return nil, p.errorf("unknown function %s", name.text)
+	}
+	p.next()
+	args, err := p.parseList(")")
+	if err != nil {
+		return nil, err
+	}
+	if len(args) < function.minArgs || function.maxArgs >= 0 && len(args) > function.maxArgs {
+		return nil, fmt.Errorf("wrong number of arguments to %s in %q", name.text, p.source)
+	}
+	return &callNode{name: name.text, function: function.call, args: args}, nil
+}
+
+func (p *parser) parsePath(root token) (node, error) {
+	path := &pathNode{root: root.text, static: true}
+	end := root.pos + len([]rune(root.text))
+	for {
+		if _, ok := p.accept("."); ok {
+			t := p.next()
+			if t.kind != tokenIdent {
+				p.pos--
+				return nil, p.errorf("expected a field name, got %s", t)
+			}
+			path.steps = append(path.steps, &literalNode{value: t.text})
+			end = t.pos + len([]rune(t.text))
+			continue
+		}
+		if _, ok := p.accept("["); ok {
+			index, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if _, isLiteral := index.(*literalNode); !isLiteral {
+				path.static = false
+			}
+			closing := p.peek()
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			path.steps = append(path.steps, index)
+			end = closing.pos + 1
+			continue
+		}
+		break
+	}
+	path.text = string([]rune(p.source)[root.pos:end])
+	p.fields = append(p.fields, path)
+	return path, nil
+}
+
+// Evaluation
+
+type node interface {
+	eval(vars map[string]interface{}) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(vars map[string]interface{}) (interface{}, error) {
+	return n.value, nil
+}
+
+type listNode struct {
+	items []node
+}
+
+func (n *listNode) eval(vars map[string]interface{}) (interface{}, error) {
+	list := make([]interface{}, len(n.items))
+	for i, item := range n.items {
+		value, err := item.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = value
+	}
+	return list, nil
+}
+
+// pathNode looks up a field by its name and the keys or indexes after it.
+type pathNode struct {
+	text   string
+	root   string
+	steps  []node
+	static bool
+}
+
+func (n *pathNode) eval(vars map[string]interface{}) (interface{}, error) {
+	value := normalize(vars[n.root])
+	for _, step := range n.steps {
+		key, err := step.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		switch container := value.(type) {
+		case map[string]interface{}:
+			name, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: cannot index an object with %v", n.text, key)
+			}
+			value = normalize(container[name])
+		case []interface{}:
+			index, ok := key.(float64)
+			if !ok || index != math.Trunc(index) {
+				return nil, fmt.Errorf("%s: cannot index a list with %v", n.text, key)
+			}
+			if index < 0 {
+				index += float64(len(container))
+			}
+			if index < 0 || int(index) >= len(container) {
+				return nil, nil
+			}
+			value = normalize(container[int(index)])
+		default:
+			return nil, nil
+		}
+	}
+	return value, nil
+}
+
+type logicalNode struct {
+	and         bool
+	left, right node
+}
+
+func (n *logicalNode) eval(vars map[string]interface{}) (interface{}, error) {
+	left, err := evalTruth(n.left, vars)
+	if err != nil {
+		return nil, err
+	}
+	if left != n.and {
+		return left, nil
+	}
+	return evalTruth(n.right, vars)
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n *notNode) eval(vars map[string]interface{}) (interface{}, error) {
+	value, err := evalTruth(n.operand, vars)
+	return !value, err
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n *compareNode) eval(vars map[string]interface{}) (interface{}, error) {
+	left, err := n.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	}
+	order, ok, err := compare(left, right)
+	if err != nil || !ok {
+		return false, err
+	}
+	switch n.op {
+	case "<":
+		return order < 0, nil
+	case "<=":
+		return order <= 0, nil
+	case ">":
+		return order > 0, nil
+	}
+	return order >= 0, nil
+}
+
+type betweenNode struct {
+	value, low, high node
+}
+
+func (n *betweenNode) eval(vars map[string]interface{}) (interface{}, error) {
+	values := make([]interface{}, 3)
+	for i, operand := range []node{n.value, n.low, n.high} {
+		value, err := operand.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	above, ok, err := compare(values[0], values[1])
+	if err != nil || !ok {
+		return false, err
+	}
+	below, ok, err := compare(values[0], values[2])
+	if err != nil || !ok {
+		return false, err
+	}
+	return above >= 0 && below <= 0, nil
+}
+
+type inNode struct {
+	value, container node
+}
+
+func (n *inNode) eval(vars map[string]interface{}) (interface{}, error) {
+	value, err := n.value.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	container, err := n.container.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	switch c := container.(type) {
+	case nil:
+		return false, nil
+	case []interface{}:
+		for _, item := range c {
+			if equal(value, item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case string:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot look for %s in a string", typeName(value))
+		}
+		return strings.Contains(c, s), nil
+	case map[string]interface{}:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot look for %s in an object", typeName(value))
+		}
+		_, exists := c[s]
+		return exists, nil
+	}
+	return nil, fmt.Errorf("cannot look for a value in %s", typeName(container))
+}
+
+type arithmeticNode struct {
+	op          string
+	left, right node
+}
+
+func (n *arithmeticNode) eval(vars map[string]interface{}) (interface{}, error) {
+	left, err := n.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	if left == nil || right == nil {
+		return nil, nil
+	}
+	if n.op == "+" {
+		if l, ok := left.(string); ok {
+			if r, ok := right.(string); ok {
+				return l + r, nil
+			}
+		}
+	}
+	l, lok := left.(float64)
+	r, rok := right.(float64)
+	if !lok || !rok {
+		return nil, fmt.Errorf("cannot apply %s to %s and %s", n.op, typeName(left), typeName(right))
+	}
+	switch n.op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	}
+	if r == 0 {
+		return nil, fmt.Errorf("division by zero")
+	}
+	if n.op == "/" {
+		return l / r, nil
+	}
+	return math.Mod(l, r), nil
+}
+
+type callNode struct {
+	name     string
+	function func(args []interface{}) (interface{}, error)
+	args     []node
+}
+
+func (n *callNode) eval(vars map[string]interface{}) (interface{}, error) {
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		value, err := arg.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = value
+	}
+	value, err := n.function(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", n.name, err)
+	}
+	return value, nil
+}
+
+// function is a function expressions may call. A negative maxArgs allows
+// any number of arguments.
+type function struct {
+	minArgs, maxArgs int
+	call             func(args []interface{}) (interface{}, error)
+}
+
+var functions = map[string]function{
+	"len": {1, 1, func(args []interface{}) (interface{}, error) {
+		switch v := args[0].(type) {
+		case nil:
+			return 0.0, nil
+		case string:
+			return float64(len([]rune(v))), nil
+		case []interface{}:
+			return float64(len(v)), nil
+		case map[string]interface{}:
+			return float64(len(v)), nil
+		}
+		return nil, fmt.Errorf("no length of %s", typeName(args[0]))
+	}},
+	"abs": {1, 1, func(args []interface{}) (interface{}, error) {
+		if args[0] == nil {
+			return nil, nil
+		}
+		v, ok := args[0].(float64)
+		if !ok {
+			return nil, fmt.Errorf("expected a number, got %s", typeName(args[0]))
+		}
+		return math.Abs(v), nil
+	}},
+	"lower": {1, 1, stringFunction(strings.ToLower)},
+	"upper": {1, 1, stringFunction(strings.ToUpper)},
+	"min":   {1, -1, extremum(-1)},
+	"max":   {1, -1, extremum(1)},
+}
+
+func stringFunction(transform func(string) string) func(args []interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		if args[0] == nil {
+			return nil, nil
+		}
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a string, got %s", typeName(args[0]))
+		}
+		return transform(s), nil
+	}
+}
+
+// extremum returns the smallest (sign -1) or largest (sign 1) of its
+// arguments, or of the list that is its only argument, ignoring nulls.
+func extremum(sign int) func(args []interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		if list, ok := args[0].([]interface{}); ok && len(args) == 1 {
+			args = list
+		}
+		var best interface{}
+		for _, arg := range args {
+			if arg == nil {
+				continue
+			}
+			if best == nil {
+				best = arg
+				continue
+			}
+			order, _, err := compare(arg, best)
+			if err != nil {
+				return nil, err
+			}
+			if order*sign > 0 {
+				best = arg
+			}
+		}
+		return best, nil
+	}
+}
+
+func evalTruth(n node, vars map[string]interface{}) (bool, error) {
+	value, err := n.eval(vars)
+	if err != nil {
+		return false, err
+	}
+	return truth(value)
+}
+
+// truth converts a condition's value to a boolean.
+func truth(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return v, nil
+	}
+	return false, fmt.Errorf("expected a condition, got %s", typeName(value))
+}
+
+// equal compares two values, including the items of lists and objects, after
+// normalizing them.
+func equal(a, b interface{}) bool {
+	a, b = normalize(a), normalize(b)
+	switch l := a.(type) {
+	case []interface{}:
+		r, ok := b.([]interface{})
+		if !ok || len(l) != len(r) {
+			return false
+		}
+		for i := range l {
+			if !equal(l[i], r[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		r, ok := b.(map[string]interface{})
+		if !ok || len(l) != len(r) {
+			return false
+		}
+		for key, value := range l {
+			other, exists := r[key]
+			if !exists || !equal(value, other) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+// compare orders two numbers or two strings. It reports false if either is
+// null, and fails for other types.
+func compare(a, b interface{}) (int, bool, error) {
+	a, b = normalize(a), normalize(b)
+	if a == nil || b == nil {
+		return 0, false, nil
+	}
+	switch l := a.(type) {
+	case float64:
+		if r, ok := b.(float64); ok {
+			switch {
+			case l < r:
+				return -1, true, nil
+			case l > r:
+				return 1, true, nil
+			}
+			return 0, true, nil
+		}
+	case string:
+		if r, ok := b.(string); ok {
+			return strings.Compare(l, r), true, nil
+		}
+	}
+	return 0, false, fmt.Errorf("cannot compare %s with %s", typeName(a), typeName(b))
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "a boolean"
+	case float64:
+		return "a number"
+	case string:
+		return "a string"
+	case []interface{}:
+		return "a list"
+	case map[string]interface{}:
+		return "an object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// normalize converts a value to the types expressions work with: nil, bool,
+// float64, string, []interface{} and map[string]interface{}, as
+// setting.Normalize does but with every number a float64.
+func normalize(value interface{}) interface{} {
+	value = setting.Normalize(value)
+	if n, ok := value.(int64); ok {
+		return float64(n)
+	}
+	return value
+}
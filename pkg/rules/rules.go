@@ -0,0 +1,332 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultOutcome is the outcome of a rule set without a configured default
+// when no rule matches.
+const DefaultOutcome = "no_action"
+
+// ParamsField is the name under which conditions find the parameters of a
+// rule set, as in "score >= params.positive_threshold".
+const ParamsField = "params"
+
+// HistoryField and RelatedField are the names under which a deciding agent
+// passes what it recalled, as in "len(history) > 0": its earlier
+// interactions, oldest first, and the records most related to the data.
+const (
+	HistoryField = "history"
+	RelatedField = "related"
+)
+
+// Rule yields its Outcome when its condition matches.
+type Rule struct {
+	Name string
+	// When is the condition, an Expression.
+	When    string
+	Outcome string
+	// Priority orders the rules; the matching rule with the highest priority
+	// decides. Rules of equal priority keep their order.
+	Priority    int
+	Description string
+	condition   *Expression
+}
+
+// Firing describes a rule that matched in a decision.
+type Firing struct {
+	Rule        string `json:"rule"`
+	Priority    int    `json:"priority"`
+	When        string `json:"when"`
+	Outcome     string `json:"outcome"`
+	Description string `json:"description,omitempty"`
+	// Values holds the fields the condition refers to and their values.
+	Values map[string]interface{} `json:"values,omitempty"`
+}
+
+// Decision is the outcome of evaluating a rule set or decision table,
+// together with the rules or rows that led to it.
+type Decision struct {
+	Outcome string `json:"outcome"`
+	// Rule names the rule or row that decided, or is empty if the default
+	// outcome was used.
+	Rule string `json:"rule,omitempty"`
+	// Fired lists every matching rule, the deciding rule first.
+	Fired []Firing `json:"fired"`
+	// Evaluated is the number of rules evaluated.
+	Evaluated int `json:"evaluated"`
+	// Outputs holds the outputs of the deciding rows of a decision table by
+	// name: one row, or every matching row of a collect table.
+	Outputs []map[string]interface{} `json:"outputs,omitempty"`
+	// HitPolicy is the hit policy of a decision table, and empty for rule
+	// sets.
+	HitPolicy string `json:"hit_policy,omitempty"`
+}
+
+// Default reports whether no rule matched.
+func (d *Decision) Default() bool {
+	return d.Rule == ""
+}
+
+// Explanation describes in words why the decision was made.
+func (d *Decision) Explanation() string {
+	kind := "rule"
+	if d.HitPolicy != "" {
+		kind = "row"
+	}
+	if d.Default() {
+		return fmt.Sprintf("no %s of %d matched, default outcome %s", kind, d.Evaluated, d.Outcome)
+	}
+	var b strings.Builder
+	for i, firing := range d.Fired {
+		name := kind + " " + firing.Rule
+		if d.HitPolicy == "" {
+			name += fmt.Sprintf(" (priority %d)", firing.Priority)
+		}
+		switch {
+		case d.HitPolicy == HitCollect:
+			if i > 0 {
+				b.WriteString("; ")
+			}
+			fmt.Fprintf(&b, "%s collected %s: %s", name, firing.Outcome, firing.When)
+		case i == 0:
+			fmt.Fprintf(&b, "%s decided %s: %s", name, firing.Outcome, firing.When)
+		default:
+			fmt.Fprintf(&b, "; %s also matched for %s", name, firing.Outcome)
+		}
+		if len(firing.Values) > 0 {
+			fmt.Fprintf(&b, " with %s", formatValues(firing.Values))
+		}
+	}
+	return b.String()
+}
+
+// String returns the outcome with its explanation.
+func (d *Decision) String() string {
+	return d.Outcome + " (" + d.Explanation() + ")"
+}
+
+func formatValues(values map[string]interface{}) string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		value := values[name]
+		if s, ok := value.(string); ok {
+			value = fmt.Sprintf("%q", s)
+		} else if value == nil {
+			value = "null"
+		}
+		parts[i] = fmt.Sprintf("%s = %v", name, value)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// RuleSet decides on data by its rules.
+type RuleSet struct {
+	rules []*Rule
+	// Default is the outcome when no rule matches.
+	Default string
+	// Params are available to conditions under ParamsField.
+	Params map[string]interface{}
+}
+
+// NewRuleSet compiles rules into a rule set with a default outcome and
+// parameters.
+func NewRuleSet(rules []Rule, defaultOutcome string, params map[string]interface{}) (*RuleSet, error) {
+	if defaultOutcome == "" {
+		defaultOutcome = DefaultOutcome
+	}
+	set := &RuleSet{Default: defaultOutcome, Params: params}
+	names := make(map[string]bool, len(rules))
+	for i := range rules {
+		rule := rules[i]
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule_%d", i+1)
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("duplicate rule %s", rule.Name)
+		}
+		names[rule.Name] = true
+		if rule.Outcome == "" {
+			return nil, fmt.Errorf("rule %s has no outcome", rule.Name)
+		}
+		condition, err := Compile(rule.When)
+		if err != nil {
+			return nil, fmt.Errorf("invalid condition of rule %s: %w", rule.Name, err)
+		}
+		rule.condition = condition
+		set.rules = append(set.rules, &rule)
+	}
+	sort.SliceStable(set.rules, func(i, j int) bool {
+		return set.rules[i].Priority > set.rules[j].Priority
+	})
+	return set, nil
+}
+
+// FromMap reads a rule set from settings such as the "decision_rules" of
+// an agent:
+//
+//	{
+//	  "rules": [
+//	    {"name": "promote", "when": "score >= params.positive_threshold",
+//	     "outcome": "recommend", "priority": 10, "description": "..."}
+//	  ],
+//	  "default": "hold",
+//	  "positive_threshold": 0.6
+//	}
+//
+// Keys other than "rules" and "default" are parameters; "params" may also
+// hold them explicitly.
+func FromMap(settings map[string]interface{}) (*RuleSet, error) {
+	params := make(map[string]interface{})
+	var rules []Rule
+	defaultOutcome := ""
+	for key, value := range settings {
+		switch key {
+		case "rules":
+			list, ok := value.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("rules must be a list, got %T", value)
+			}
+			for i, item := range list {
+				rule, err := ruleFromMap(item)
+				if err != nil {
+					return nil, fmt.Errorf("invalid rule %d: %w", i+1, err)
+				}
+				rules = append(rules, rule)
+			}
+		case "default":
+			outcome, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("default must be a string, got %T", value)
+			}
+			defaultOutcome = outcome
+		case ParamsField:
+			explicit, ok := normalize(value).(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("params must be a map, got %T", value)
+			}
+			for name, param := range explicit {
+				params[name] = param
+			}
+		default:
+			params[key] = value
+		}
+	}
+	return NewRuleSet(rules, defaultOutcome, params)
+}
+
+func ruleFromMap(raw interface{}) (Rule, error) {
+	fields, ok := normalize(raw).(map[string]interface{})
+	if !ok {
+		return Rule{}, fmt.Errorf("expected a map, got %T", raw)
+	}
+	var rule Rule
+	for key, value := range fields {
+		switch key {
+		case "name", "when", "outcome", "description":
+			s, ok := value.(string)
+			if !ok {
+				return rule, fmt.Errorf("%s must be a string, got %T", key, value)
+			}
+			switch key {
+			case "name":
+				rule.Name = s
+			case "when":
+				rule.When = s
+			case "outcome":
+				rule.Outcome = s
+			default:
+				rule.Description = s
+			}
+		case "priority":
+			priority, ok := normalize(value).(float64)
+			if !ok || priority != float64(int(priority)) {
+				return rule, fmt.Errorf("priority must be an integer, got %v", value)
+			}
+			rule.Priority = int(priority)
+		default:
+			return rule, fmt.Errorf("unknown field %s", key)
+		}
+	}
+	return rule, nil
+}
+
+// Rules returns the rules in the order they are evaluated.
+func (s *RuleSet) Rules() []Rule {
+	rules := make([]Rule, len(s.rules))
+	for i, rule := range s.rules {
+		rules[i] = *rule
+	}
+	return rules
+}
+
+// Extend returns the variables of data with fields, such as HistoryField
+// and RelatedField, added. Fields of data take precedence.
+func Extend(data interface{}, fields map[string]interface{}) map[string]interface{} {
+	vars := variables(data)
+	extended := make(map[string]interface{}, len(vars)+len(fields))
+	for key, value := range fields {
+		extended[key] = value
+	}
+	for key, value := range vars {
+		extended[key] = value
+	}
+	return extended
+}
+
+// variables returns data as the variables of conditions: its fields if it
+// is an object, or else data as "value".
+func variables(data interface{}) map[string]interface{} {
+	if vars, ok := normalize(data).(map[string]interface{}); ok {
+		return vars
+	}
+	return map[string]interface{}{"value": data}
+}
+
+// Decide evaluates every rule on data and returns the outcome of the
+// matching rule with the highest priority, or the default outcome. Data
+// that is not an object is available to conditions as "value". A condition
+// that fails to evaluate, for example by comparing a number to a string,
+// fails the decision.
+func (s *RuleSet) Decide(data interface{}) (*Decision, error) {
+	vars := variables(data)
+	if len(s.Params) > 0 {
+		scoped := make(map[string]interface{}, len(vars)+1)
+		for key, value := range vars {
+			scoped[key] = value
+		}
+		scoped[ParamsField] = s.Params
+		vars = scoped
+	}
+
+	decision := &Decision{Outcome: s.Default, Fired: []Firing{}, Evaluated: len(s.rules)}
+	for _, rule := range s.rules {
+		matched, err := rule.condition.Matches(vars)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rule.Name, err)
+		}
+		if !matched {
+			continue
+		}
+		decision.Fired = append(decision.Fired, Firing{
+			Rule:        rule.Name,
+			Priority:    rule.Priority,
+			When:        rule.When,
+			Outcome:     rule.Outcome,
+			Description: rule.Description,
+			Values:      rule.condition.Fields(vars),
+		})
+	}
+	if len(decision.Fired) > 0 {
+		decision.Outcome = decision.Fired[0].Outcome
+		decision.Rule = decision.Fired[0].Rule
+	}
+	return decision, nil
+}
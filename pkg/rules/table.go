@@ -0,0 +1,867 @@
+package rules
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Hit policies of decision tables.
+const (
+	// HitUnique expects at most one row to match; it is the default.
+	HitUnique = "unique"
+	// HitFirst uses the first matching row.
+	HitFirst = "first"
+	// HitPriority uses the matching row whose outputs come first in the
+	// values of the output columns.
+	HitPriority = "priority"
+	// HitCollect uses every matching row, optionally aggregating the first
+	// output.
+	HitCollect = "collect"
+)
+
+// Aggregations of the collect hit policy.
+const (
+	AggregateSum   = "sum"
+	AggregateMin   = "min"
+	AggregateMax   = "max"
+	AggregateCount = "count"
+)
+
+// Decider decides on data. RuleSet and DecisionTable are deciders.
+type Decider interface {
+	Decide(data interface{}) (*Decision, error)
+}
+
+// Input is an input column of a decision table.
+type Input struct {
+	Name string
+	// Expression computes the input from the data; it defaults to Name.
+	Expression string
+	// Type is TypeNumber, TypeString or TypeBoolean. It is inferred from the
+	// entries of the column if empty.
+	Type string
+	// Values optionally lists the values of a string input. Entries must
+	// use only these, and gaps are found among them rather than among all
+	// strings.
+	Values []string
+}
+
+// Output is an output column of a decision table.
+type Output struct {
+	Name string
+	// Values lists the values of the output by decreasing priority, for the
+	// priority hit policy.
+	Values []string
+}
+
+// Row is a rule of a decision table: if every input passes its entry in
+// When, the row yields the outputs in Then.
+type Row struct {
+	Name string
+	// When holds an input entry per input column, a unary test such as
+	// "-" for any value, "< 0.5", "[0.2..0.6)", "positive", "a, b" or
+	// "not(a)".
+	When []string
+	// Then holds a value per output column.
+	Then        []interface{}
+	Description string
+	tests       []*test
+}
+
+// DecisionTable decides on data by rows of input entries and outputs, as
+// DMN decision tables do. Create tables with NewDecisionTable, LoadTable or
+// TableFromMap.
+type DecisionTable struct {
+	Name      string
+	HitPolicy string
+	// Aggregation is the optional aggregation of a collect table.
+	Aggregation string
+	Inputs      []Input
+	Outputs     []Output
+	Rows        []Row
+	// Default holds the outputs by name when no row matches. Tables without
+	// a default must not have gaps.
+	Default     map[string]interface{}
+	expressions []*Expression
+}
+
+// NewDecisionTable checks and compiles a table definition.
+func NewDecisionTable(definition DecisionTable) (*DecisionTable, error) {
+	table := definition
+	if table.Name == "" {
+		table.Name = "decision_table"
+	}
+	table.HitPolicy = strings.ToLower(table.HitPolicy)
+	switch table.HitPolicy {
+	case "":
+		table.HitPolicy = HitUnique
+	case HitUnique, HitFirst, HitPriority, HitCollect:
+	default:
+		return nil, fmt.Errorf("unknown hit policy %s", definition.HitPolicy)
+	}
+	switch table.Aggregation {
+	case "":
+	case AggregateSum, AggregateMin, AggregateMax, AggregateCount:
+		if table.HitPolicy != HitCollect {
+			return nil, fmt.Errorf("aggregation %s needs the collect hit policy", table.Aggregation)
+		}
+	default:
+		return nil, fmt.Errorf("unknown aggregation %s", table.Aggregation)
+	}
+	if len(table.Inputs) == 0 || len(table.Outputs) == 0 {
+		return nil, fmt.Errorf("a decision table needs inputs and outputs")
+	}
+
+	table.Inputs = append([]Input(nil), definition.Inputs...)
+	table.expressions = make([]*Expression, len(table.Inputs))
+	for i := range table.Inputs {
+		input := &table.Inputs[i]
+		if input.Name == "" {
+			return nil, fmt.Errorf("input %d has no name", i+1)
+		}
+		if input.Expression == "" {
+			input.Expression = input.Name
+		}
+		expression, err := Compile(input.Expression)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expression of input %s: %w", input.Name, err)
+		}
+		table.expressions[i] = expression
+		if input.Type == "" {
+			if len(input.Values) > 0 {
+				input.Type = TypeString
+			} else {
+				entries := make([]string, 0, len(table.Rows))
+				for _, row := range table.Rows {
+					if i < len(row.When) {
+						entries = append(entries, row.When[i])
+					}
+				}
+				input.Type = inferType(entries)
+			}
+		}
+		switch input.Type {
+		case TypeNumber, TypeString, TypeBoolean:
+		default:
+			return nil, fmt.Errorf("input %s has unknown type %s", input.Name, input.Type)
+		}
+	}
+
+	outputs := make(map[string]bool, len(table.Outputs))
+	for i, output := range table.Outputs {
+		if output.Name == "" {
+			return nil, fmt.Errorf("output %d has no name", i+1)
+		}
+		if outputs[output.Name] {
+			return nil, fmt.Errorf("duplicate output %s", output.Name)
+		}
+		outputs[output.Name] = true
+	}
+	if table.HitPolicy == HitPriority && len(table.Outputs[0].Values) == 0 {
+		return nil, fmt.Errorf("the priority hit policy needs the values of output %s", table.Outputs[0].Name)
+	}
+	for name := range table.Default {
+		if !outputs[name] {
+			return nil, fmt.Errorf("default sets unknown output %s", name)
+		}
+	}
+
+	table.Rows = make([]Row, len(definition.Rows))
+	names := make(map[string]bool, len(definition.Rows))
+	for i, row := range definition.Rows {
+		if row.Name == "" {
+			row.Name = fmt.Sprintf("row_%d", i+1)
+		}
+		if names[row.Name] {
+			return nil, fmt.Errorf("duplicate row %s", row.Name)
+		}
+		names[row.Name] = true
+		if err := table.compileRow(&row); err != nil {
+			return nil, fmt.Errorf("row %s: %w", row.Name, err)
+		}
+		table.Rows[i] = row
+	}
+	return &table, nil
+}
+
+func (t *DecisionTable) compileRow(row *Row) error {
+	if len(row.When) != len(t.Inputs) {
+		return fmt.Errorf("expected %d input entries, got %d", len(t.Inputs), len(row.When))
+	}
+	if len(row.Then) != len(t.Outputs) {
+		return fmt.Errorf("expected %d outputs, got %d", len(t.Outputs), len(row.Then))
+	}
+	row.tests = make([]*test, len(row.When))
+	for i, entry := range row.When {
+		input := t.Inputs[i]
+		test, err := parseTest(entry, input.Type)
+		if err != nil {
+			return fmt.Errorf("input %s: %w", input.Name, err)
+		}
+		if len(input.Values) > 0 {
+			for _, value := range test.strings {
+				if !contains(input.Values, value) {
+					return fmt.Errorf("input %s: %q is not one of %s", input.Name, value, strings.Join(input.Values, ", "))
+				}
+			}
+		}
+		row.tests[i] = test
+	}
+	then := make([]interface{}, len(row.Then))
+	for i, value := range row.Then {
+		then[i] = normalize(value)
+		output := t.Outputs[i]
+		if len(output.Values) > 0 && !contains(output.Values, fmt.Sprint(then[i])) {
+			return fmt.Errorf("output %s: %v is not one of %s", output.Name, then[i], strings.Join(output.Values, ", "))
+		}
+	}
+	row.Then = then
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Decide evaluates the inputs on data and returns the outputs of the
+// matching rows according to the hit policy. The outcome is the first
+// output of the deciding row; collect tables join the first outputs of all
+// matching rows, or aggregate them. Without a matching row the default
+// outputs are used, or DefaultOutcome. Data that is not an object is
+// available to input expressions as "value". A unique table fails if more
+// than one row matches.
+func (t *DecisionTable) Decide(data interface{}) (*Decision, error) {
+	if len(t.expressions) != len(t.Inputs) {
+		return nil, fmt.Errorf("decision table %s was not created with NewDecisionTable", t.Name)
+	}
+	vars := variables(data)
+	inputs := make([]interface{}, len(t.Inputs))
+	values := make(map[string]interface{}, len(t.Inputs))
+	for i, expression := range t.expressions {
+		value, err := expression.Evaluate(vars)
+		if err != nil {
+			return nil, fmt.Errorf("input %s: %w", t.Inputs[i].Name, err)
+		}
+		inputs[i] = normalize(value)
+		values[t.Inputs[i].Name] = inputs[i]
+	}
+
+	var matched []*Row
+	for r := range t.Rows {
+		row := &t.Rows[r]
+		matches := true
+		for i, test := range row.tests {
+			ok, err := test.matches(inputs[i], t.Inputs[i].Type)
+			if err != nil {
+				return nil, fmt.Errorf("row %s: input %s: %w", row.Name, t.Inputs[i].Name, err)
+			}
+			if !ok {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			matched = append(matched, row)
+		}
+	}
+
+	decision := &Decision{Outcome: DefaultOutcome, Fired: []Firing{}, Evaluated: len(t.Rows), HitPolicy: t.HitPolicy}
+	if len(matched) == 0 {
+		if t.Default != nil {
+			decision.Outputs = []map[string]interface{}{t.Default}
+			if outcome, ok := t.Default[t.Outputs[0].Name]; ok {
+				decision.Outcome = fmt.Sprint(outcome)
+			}
+		}
+		return decision, nil
+	}
+
+	switch t.HitPolicy {
+	case HitUnique:
+		if len(matched) > 1 {
+			return nil, fmt.Errorf("rows %s and %s both match", matched[0].Name, matched[1].Name)
+		}
+	case HitPriority:
+		sort.SliceStable(matched, func(i, j int) bool {
+			return t.rank(matched[i]) < t.rank(matched[j])
+		})
+	}
+	for _, row := range matched {
+		decision.Fired = append(decision.Fired, Firing{
+			Rule:        row.Name,
+			When:        t.describeRow(row),
+			Outcome:     fmt.Sprint(row.Then[0]),
+			Description: row.Description,
+			Values:      values,
+		})
+	}
+	decision.Rule = matched[0].Name
+	if t.HitPolicy != HitCollect {
+		matched = matched[:1]
+	}
+	outcomes := make([]string, len(matched))
+	for i, row := range matched {
+		outputs := make(map[string]interface{}, len(t.Outputs))
+		for j, output := range t.Outputs {
+			outputs[output.Name] = row.Then[j]
+		}
+		decision.Outputs = append(decision.Outputs, outputs)
+		outcomes[i] = fmt.Sprint(row.Then[0])
+	}
+	decision.Outcome = strings.Join(outcomes, ", ")
+	if t.Aggregation != "" {
+		aggregate, err := t.aggregate(matched)
+		if err != nil {
+			return nil, err
+		}
+		decision.Outcome = formatNumber(aggregate)
+	}
+	return decision, nil
+}
+
+// rank orders rows by the priority of their outputs.
+func (t *DecisionTable) rank(row *Row) int {
+	rank := 0
+	for i, output := range t.Outputs {
+		position := len(output.Values)
+		for j, value := range output.Values {
+			if value == fmt.Sprint(row.Then[i]) {
+				position = j
+				break
+			}
+		}
+		rank = rank*(len(output.Values)+1) + position
+	}
+	return rank
+}
+
+func (t *DecisionTable) aggregate(rows []*Row) (float64, error) {
+	if t.Aggregation == AggregateCount {
+		distinct := make(map[string]bool, len(rows))
+		for _, row := range rows {
+			distinct[fmt.Sprint(row.Then[0])] = true
+		}
+		return float64(len(distinct)), nil
+	}
+	result := 0.0
+	for i, row := range rows {
+		x, ok := row.Then[0].(float64)
+		if !ok {
+			return 0, fmt.Errorf("aggregation %s needs numbers, row %s has %s", t.Aggregation, row.Name, typeName(row.Then[0]))
+		}
+		switch {
+		case i == 0:
+			result = x
+		case t.Aggregation == AggregateSum:
+			result += x
+		case t.Aggregation == AggregateMin:
+			result = math.Min(result, x)
+		default:
+			result = math.Max(result, x)
+		}
+	}
+	return result, nil
+}
+
+// describeRow lists the input entries of a row other than "-".
+func (t *DecisionTable) describeRow(row *Row) string {
+	var parts []string
+	for i, test := range row.tests {
+		if !test.any {
+			parts = append(parts, t.Inputs[i].Name+": "+test.text)
+		}
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// LoadTable reads a decision table from a CSV, YAML or JSON file; see
+// TableFromMap.
+func LoadTable(path string) (*DecisionTable, error) {
+	return TableFromSetting(path, "")
+}
+
+// TableFromMap reads a decision table from settings such as the
+// "decision_table" of an agent:
+//
+//	{
+//	  "name": "recommendation",
+//	  "hit_policy": "first",
+//	  "inputs": [{"name": "score", "type": "number"},
+//	             {"name": "label", "expression": "sentiment.label",
+//	              "values": ["positive", "neutral", "negative"]}],
+//	  "outputs": ["decision"],
+//	  "rows": [
+//	    {"when": [">= 0.6", "positive"], "then": "recommend"},
+//	    {"when": {"label": "negative"}, "then": {"decision": "suppress"}}
+//	  ],
+//	  "default": "hold"
+//	}
+//
+// Inputs and outputs are names or maps as above. The entries of a row are a
+// list, or a map by input name where missing inputs match any value; its
+// outputs are a list, a map by output name or a single value.
+//
+// The table may instead come from the file at "path", relative to dir, and
+// the settings then override the definition in the file. A YAML or JSON
+// file holds a definition as above. The header of a CSV file names the
+// columns, each further record is a row: the columns named by "outputs",
+// or else the last column, are outputs, a "description" column describes
+// the rows, and the other columns are inputs. The "inputs" and "outputs"
+// settings may describe the columns of a CSV file further.
+func TableFromMap(settings map[string]interface{}, dir string) (*DecisionTable, error) {
+	definition := settings
+	if raw, ok := settings["path"]; ok {
+		path, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("path must be a string, got %T", raw)
+		}
+		if dir != "" && !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		var err error
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".csv":
+			definition, err = csvDefinition(path, settings)
+		case ".yaml", ".yml", ".json":
+			definition, err = yamlDefinition(path, settings)
+		default:
+			err = fmt.Errorf("unsupported decision table format %s", filepath.Ext(path))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load decision table %s: %w", path, err)
+		}
+	}
+	return tableFromDefinition(definition)
+}
+
+// TableFromSetting reads a decision table from a setting that is either
+// the path of a file, relative to dir, or the settings of TableFromMap.
+func TableFromSetting(setting interface{}, dir string) (*DecisionTable, error) {
+	if path, ok := setting.(string); ok {
+		return TableFromMap(map[string]interface{}{"path": path}, dir)
+	}
+	settings, ok := normalize(setting).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a path or a map, got %T", setting)
+	}
+	return TableFromMap(settings, dir)
+}
+
+// yamlDefinition reads a definition from a file and applies settings to it.
+func yamlDefinition(path string, settings map[string]interface{}) (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	definition, ok := normalize(raw).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a map, got %T", raw)
+	}
+	for key, value := range settings {
+		if key != "path" {
+			definition[key] = value
+		}
+	}
+	return definition, nil
+}
+
+// csvDefinition builds a definition from the columns and rows of a CSV
+// file and the settings describing them.
+func csvDefinition(path string, settings map[string]interface{}) (map[string]interface{}, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader := csv.NewReader(file)
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("missing header")
+	}
+	header := records[0]
+	for i, name := range header {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+	}
+
+	described := make(map[string]interface{})
+	for _, key := range []string{"inputs", "outputs"} {
+		columns, err := columnsFromValue(settings[key])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		for _, column := range columns {
+			described[column["name"].(string)] = column
+		}
+	}
+	isOutput := make(map[string]bool)
+	if outputs, ok := settings["outputs"]; ok {
+		columns, _ := columnsFromValue(outputs)
+		for _, column := range columns {
+			isOutput[column["name"].(string)] = true
+		}
+	} else {
+		for i := len(header) - 1; i >= 0; i-- {
+			if !strings.EqualFold(header[i], "description") {
+				isOutput[header[i]] = true
+				break
+			}
+		}
+	}
+
+	var inputs, outputs []interface{}
+	for _, name := range header {
+		if strings.EqualFold(name, "description") {
+			continue
+		}
+		column, ok := described[name]
+		if !ok {
+			column = map[string]interface{}{"name": name}
+		}
+		if isOutput[name] {
+			outputs = append(outputs, column)
+		} else {
+			inputs = append(inputs, column)
+		}
+		delete(isOutput, name)
+	}
+	for name := range isOutput {
+		return nil, fmt.Errorf("output %s is not a column", name)
+	}
+
+	rows := make([]interface{}, 0, len(records)-1)
+	for _, record := range records[1:] {
+		when := make(map[string]interface{})
+		then := make(map[string]interface{})
+		row := map[string]interface{}{"when": when, "then": then}
+		for i, cell := range record {
+			name := header[i]
+			switch {
+			case strings.EqualFold(name, "description"):
+				row["description"] = cell
+			case containsColumn(outputs, name):
+				then[name] = parseScalar(cell)
+			default:
+				when[name] = cell
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	definition := map[string]interface{}{"inputs": inputs, "outputs": outputs, "rows": rows}
+	for key, value := range settings {
+		switch key {
+		case "path", "inputs", "outputs":
+		default:
+			definition[key] = value
+		}
+	}
+	return definition, nil
+}
+
+func containsColumn(columns []interface{}, name string) bool {
+	for _, column := range columns {
+		if column.(map[string]interface{})["name"] == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseScalar converts an output cell of a CSV file to a number or boolean
+// if it is one.
+func parseScalar(cell string) interface{} {
+	cell = strings.TrimSpace(cell)
+	if x, err := strconv.ParseFloat(cell, 64); err == nil {
+		return x
+	}
+	if b, err := strconv.ParseBool(cell); err == nil && (cell == "true" || cell == "false") {
+		return b
+	}
+	return unquote(cell)
+}
+
+// columnsFromValue reads a list of column names or column maps.
+func columnsFromValue(raw interface{}) ([]map[string]interface{}, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	list, ok := normalize(raw).([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a list, got %T", raw)
+	}
+	columns := make([]map[string]interface{}, len(list))
+	for i, item := range list {
+		switch v := normalize(item).(type) {
+		case string:
+			columns[i] = map[string]interface{}{"name": v}
+		case map[string]interface{}:
+			if _, ok := v["name"].(string); !ok {
+				return nil, fmt.Errorf("column %d has no name", i+1)
+			}
+			columns[i] = v
+		default:
+			return nil, fmt.Errorf("column %d must be a name or a map, got %T", i+1, item)
+		}
+	}
+	return columns, nil
+}
+
+func tableFromDefinition(settings map[string]interface{}) (*DecisionTable, error) {
+	var definition DecisionTable
+	var rows []interface{}
+	var defaults interface{}
+	for key, value := range settings {
+		switch key {
+		case "name", "hit_policy", "aggregation":
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must be a string, got %T", key, value)
+			}
+			switch key {
+			case "name":
+				definition.Name = s
+			case "hit_policy":
+				definition.HitPolicy = s
+			default:
+				definition.Aggregation = s
+			}
+		case "inputs":
+			columns, err := columnsFromValue(value)
+			if err != nil {
+				return nil, fmt.Errorf("inputs: %w", err)
+			}
+			for _, column := range columns {
+				input, err := inputFromMap(column)
+				if err != nil {
+					return nil, fmt.Errorf("input %v: %w", column["name"], err)
+				}
+				definition.Inputs = append(definition.Inputs, input)
+			}
+		case "outputs":
+			columns, err := columnsFromValue(value)
+			if err != nil {
+				return nil, fmt.Errorf("outputs: %w", err)
+			}
+			for _, column := range columns {
+				output := Output{Name: column["name"].(string)}
+				for field, raw := range column {
+					switch field {
+					case "name":
+					case "values":
+						values, err := stringList(raw)
+						if err != nil {
+							return nil, fmt.Errorf("output %s: values: %w", output.Name, err)
+						}
+						output.Values = values
+					default:
+						return nil, fmt.Errorf("output %s: unknown field %s", output.Name, field)
+					}
+				}
+				definition.Outputs = append(definition.Outputs, output)
+			}
+		case "rows":
+			list, ok := normalize(value).([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("rows must be a list, got %T", value)
+			}
+			rows = list
+		case "default":
+			defaults = normalize(value)
+		default:
+			return nil, fmt.Errorf("unknown decision table setting %s", key)
+		}
+	}
+
+	for i, raw := range rows {
+		row, err := definition.rowFromValue(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid row %d: %w", i+1, err)
+		}
+		definition.Rows = append(definition.Rows, row)
+	}
+	if defaults != nil {
+		outputs, err := definition.outputsFromValue(defaults)
+		if err != nil {
+			return nil, fmt.Errorf("invalid default: %w", err)
+		}
+		definition.Default = make(map[string]interface{}, len(outputs))
+		for i, output := range definition.Outputs {
+			if outputs[i] != nil {
+				definition.Default[output.Name] = outputs[i]
+			}
+		}
+	}
+	return NewDecisionTable(definition)
+}
+
+func inputFromMap(column map[string]interface{}) (Input, error) {
+	input := Input{Name: column["name"].(string)}
+	for key, value := range column {
+		switch key {
+		case "name":
+		case "expression", "type":
+			s, ok := value.(string)
+			if !ok {
+				return input, fmt.Errorf("%s must be a string, got %T", key, value)
+			}
+			if key == "type" {
+				input.Type = s
+			} else {
+				input.Expression = s
+			}
+		case "values":
+			values, err := stringList(value)
+			if err != nil {
+				return input, fmt.Errorf("values: %w", err)
+			}
+			input.Values = values
+		default:
+			return input, fmt.Errorf("unknown field %s", key)
+		}
+	}
+	return input, nil
+}
+
+func stringList(raw interface{}) ([]string, error) {
+	list, ok := normalize(raw).([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a list, got %T", raw)
+	}
+	values := make([]string, len(list))
+	for i, item := range list {
+		values[i] = fmt.Sprint(item)
+	}
+	return values, nil
+}
+
+func (t *DecisionTable) rowFromValue(raw interface{}) (Row, error) {
+	fields, ok := normalize(raw).(map[string]interface{})
+	if !ok {
+		return Row{}, fmt.Errorf("expected a map, got %T", raw)
+	}
+	var row Row
+	for key, value := range fields {
+		switch key {
+		case "name", "description":
+			s, ok := value.(string)
+			if !ok {
+				return row, fmt.Errorf("%s must be a string, got %T", key, value)
+			}
+			if key == "name" {
+				row.Name = s
+			} else {
+				row.Description = s
+			}
+		case "when":
+			when, err := t.entriesFromValue(value)
+			if err != nil {
+				return row, err
+			}
+			row.When = when
+		case "then":
+			then, err := t.outputsFromValue(normalize(value))
+			if err != nil {
+				return row, err
+			}
+			row.Then = then
+		default:
+			return row, fmt.Errorf("unknown field %s", key)
+		}
+	}
+	return row, nil
+}
+
+// entriesFromValue reads the input entries of a row from a list, or from a
+// map by input name.
+func (t *DecisionTable) entriesFromValue(raw interface{}) ([]string, error) {
+	switch v := normalize(raw).(type) {
+	case []interface{}:
+		entries := make([]string, len(v))
+		for i, entry := range v {
+			entries[i] = entryText(entry)
+		}
+		return entries, nil
+	case map[string]interface{}:
+		entries := make([]string, len(t.Inputs))
+		names := make(map[string]bool, len(t.Inputs))
+		for i, input := range t.Inputs {
+			names[input.Name] = true
+			entries[i] = "-"
+			if entry, ok := v[input.Name]; ok {
+				entries[i] = entryText(entry)
+			}
+		}
+		for name := range v {
+			if !names[name] {
+				return nil, fmt.Errorf("unknown input %s", name)
+			}
+		}
+		return entries, nil
+	}
+	return nil, fmt.Errorf("when must be a list or a map, got %T", raw)
+}
+
+func entryText(entry interface{}) string {
+	if entry == nil {
+		return "-"
+	}
+	if x, ok := normalize(entry).(float64); ok {
+		return formatNumber(x)
+	}
+	return fmt.Sprint(entry)
+}
+
+// outputsFromValue reads outputs from a list, a map by output name or a
+// single value for a table with one output. Outputs missing from a map are
+// nil.
+func (t *DecisionTable) outputsFromValue(value interface{}) ([]interface{}, error) {
+	switch v := value.(type) {
+	case []interface{}:
+		return v, nil
+	case map[string]interface{}:
+		outputs := make([]interface{}, len(t.Outputs))
+		for i, output := range t.Outputs {
+			outputs[i] = v[output.Name]
+		}
+		for name := range v {
+			found := false
+			for _, output := range t.Outputs {
+				found = found || output.Name == name
+			}
+			if !found {
+				return nil, fmt.Errorf("unknown output %s", name)
+			}
+		}
+		return outputs, nil
+	}
+	if len(t.Outputs) != 1 {
+		return nil, fmt.Errorf("expected %d outputs, got %v", len(t.Outputs), value)
+	}
+	return []interface{}{value}, nil
+}
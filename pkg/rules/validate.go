@@ -0,0 +1,227 @@
+package rules
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// maxProblems bounds the problems Validate reports.
+const maxProblems = 10
+
+// otherString stands for the strings no entry of a column names.
+const otherString = "\x00other"
+
+// ValidationError lists the problems of a decision table.
+type ValidationError struct {
+	Table    string
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("decision table %s: %s", e.Table, strings.Join(e.Problems, "; "))
+}
+
+// atom is a set of input values that every entry of a column either
+// matches entirely or not at all. Numbers split at the bounds of the
+// entries, strings into the values named and all other strings.
+type atom struct {
+	value interface{}
+	span  interval
+}
+
+// Validate checks the table for gaps, inputs no row matches, unless the
+// table has a default, and for overlaps, inputs several rows match, if its
+// hit policy is unique. It returns a *ValidationError describing up to
+// ten of them by their inputs, as in "gap at score: < 0, label: \"neutral\"".
+func (t *DecisionTable) Validate() error {
+	checkGaps := t.Default == nil
+	checkOverlaps := t.HitPolicy == HitUnique
+	if !checkGaps && !checkOverlaps {
+		return nil
+	}
+
+	v := &validator{table: t, gaps: checkGaps, overlaps: checkOverlaps}
+	v.atoms = make([][]atom, len(t.Inputs))
+	for i := range t.Inputs {
+		v.atoms[i] = t.columnAtoms(i)
+	}
+	rows := make([]int, len(t.Rows))
+	for i := range rows {
+		rows[i] = i
+	}
+	if len(rows) == 0 {
+		if checkGaps {
+			return &ValidationError{Table: t.Name, Problems: []string{"gap at any input: the table has no rows"}}
+		}
+		return nil
+	}
+	v.walk(0, rows, nil)
+	if len(v.problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Table: t.Name, Problems: v.problems}
+}
+
+// columnAtoms splits the values of an input into atoms.
+func (t *DecisionTable) columnAtoms(column int) []atom {
+	input := t.Inputs[column]
+	switch input.Type {
+	case TypeBoolean:
+		return []atom{{value: true}, {value: false}}
+	case TypeNumber:
+		var points []float64
+		for _, row := range t.Rows {
+			for _, span := range row.tests[column].numbers {
+				for _, x := range []float64{span.lo, span.hi} {
+					if !math.IsInf(x, 0) {
+						points = append(points, x)
+					}
+				}
+			}
+		}
+		sort.Float64s(points)
+		inf := math.Inf(1)
+		lo := -inf
+		var atoms []atom
+		for i, x := range points {
+			if i > 0 && x == points[i-1] {
+				continue
+			}
+			between := x - 1
+			if !math.IsInf(lo, -1) {
+				between = (lo + x) / 2
+			}
+			atoms = append(atoms,
+				atom{value: between, span: interval{lo: lo, hi: x, loOpen: true, hiOpen: true}},
+				atom{value: x, span: interval{lo: x, hi: x}})
+			lo = x
+		}
+		last := 0.0
+		if len(points) > 0 {
+			last = lo + 1
+		}
+		return append(atoms, atom{value: last, span: interval{lo: lo, hi: inf, loOpen: true, hiOpen: true}})
+	}
+
+	values := input.Values
+	if len(values) == 0 {
+		seen := make(map[string]bool)
+		for _, row := range t.Rows {
+			for _, value := range row.tests[column].strings {
+				if !seen[value] {
+					seen[value] = true
+					values = append(values, value)
+				}
+			}
+		}
+		sort.Strings(values)
+		values = append(values, otherString)
+	}
+	atoms := make([]atom, len(values))
+	for i, value := range values {
+		atoms[i] = atom{value: value}
+	}
+	return atoms
+}
+
+type validator struct {
+	table    *DecisionTable
+	atoms    [][]atom
+	gaps     bool
+	overlaps bool
+	problems []string
+}
+
+// walk partitions the values of column among rows, the rows that match
+// every earlier column by path, grouping the atoms each subset of rows
+// matches, and continues with the next column for every group.
+func (v *validator) walk(column int, rows []int, path []string) {
+	if len(v.problems) >= maxProblems {
+		return
+	}
+	if column == len(v.table.Inputs) {
+		if v.overlaps && len(rows) > 1 {
+			names := make([]string, len(rows))
+			for i, row := range rows {
+				names[i] = v.table.Rows[row].Name
+			}
+			v.problems = append(v.problems, fmt.Sprintf("rows %s overlap at %s", strings.Join(names, ", "), describePath(path)))
+		}
+		return
+	}
+
+	var keys []string
+	groups := make(map[string][]int)
+	subsets := make(map[string][]int)
+	for a, atom := range v.atoms[column] {
+		var subset []int
+		for _, row := range rows {
+			// The atoms have the type of the column, so tests cannot fail
+			if ok, _ := v.table.Rows[row].tests[column].matches(atom.value, v.table.Inputs[column].Type); ok {
+				subset = append(subset, row)
+			}
+		}
+		key := fmt.Sprint(subset)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+			subsets[key] = subset
+		}
+		groups[key] = append(groups[key], a)
+	}
+
+	for _, key := range keys {
+		next := path
+		if len(groups[key]) < len(v.atoms[column]) {
+			next = append(append([]string(nil), path...), v.table.Inputs[column].Name+": "+v.describe(column, groups[key]))
+		}
+		if len(subsets[key]) == 0 {
+			if v.gaps && len(v.problems) < maxProblems {
+				v.problems = append(v.problems, "gap at "+describePath(next))
+			}
+			continue
+		}
+		v.walk(column+1, subsets[key], next)
+	}
+}
+
+func describePath(path []string) string {
+	if len(path) == 0 {
+		return "any input"
+	}
+	return strings.Join(path, ", ")
+}
+
+// describe writes a group of atoms of a column as an input entry.
+func (v *validator) describe(column int, group []int) string {
+	atoms := v.atoms[column]
+	var parts []string
+	if v.table.Inputs[column].Type != TypeNumber {
+		for _, a := range group {
+			switch value := atoms[a].value.(type) {
+			case string:
+				if value == otherString {
+					parts = append(parts, "other values")
+				} else {
+					parts = append(parts, fmt.Sprintf("%q", value))
+				}
+			default:
+				parts = append(parts, fmt.Sprint(value))
+			}
+		}
+		return strings.Join(parts, ", ")
+	}
+
+	// Adjacent atoms merge into ranges
+	for start := 0; start < len(group); {
+		end := start
+		for end+1 < len(group) && group[end+1] == group[end]+1 {
+			end++
+		}
+		first, last := atoms[group[start]].span, atoms[group[end]].span
+		parts = append(parts, interval{lo: first.lo, loOpen: first.loOpen, hi: last.hi, hiOpen: last.hiOpen}.String())
+		start = end + 1
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,284 @@
+// Package setting reads typed values from the settings maps that configure
+// agents, actions, analyzers and notifiers, and normalizes decoded data. The
+// maps may come from Go code, JSON or YAML, so numbers can have any numeric
+// type and nested maps decoded from YAML have interface{} keys.
+package setting
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// StringKeys returns a settings map with string keys, converting the
+// map[interface{}]interface{} that YAML decodes nested maps to.
+func StringKeys(raw interface{}) (map[string]interface{}, bool) {
+	switch v := raw.(type) {
+	case map[string]interface{}:
+		return v, true
+	case map[interface{}]interface{}:
+		values := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			values[fmt.Sprint(key)] = value
+		}
+		return values, true
+	}
+	return nil, false
+}
+
+// Number converts a value of any numeric type to a float64.
+func Number(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// Normalize converts a value to the data model of decoded JSON: nil, bool,
+// string, int64 for integers, float64 for other numbers, []interface{} and
+// map[string]interface{}. Maps with non-string keys, as YAML decodes them, get
+// string keys, and other slices and maps are copied without converting their
+// elements. Other values, such as structs, are converted through their JSON
+// encoding.
+func Normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil, bool, string, int64, float64, []interface{}, map[string]interface{}:
+		return v
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.String:
+		return rv.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n := rv.Uint()
+		if n <= math.MaxInt64 {
+			return int64(n)
+		}
+		return float64(n)
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.Slice, reflect.Array:
+		list := make([]interface{}, rv.Len())
+		for i := range list {
+			list[i] = rv.Index(i).Interface()
+		}
+		return list
+	case reflect.Map:
+		object := make(map[string]interface{}, rv.Len())
+		for _, key := range rv.MapKeys() {
+			object[fmt.Sprint(key.Interface())] = rv.MapIndex(key).Interface()
+		}
+		return object
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return fmt.Sprint(value)
+	}
+	return NormalizeAll(decoded)
+}
+
+// NormalizeAll converts a value and, recursively, its elements as Normalize
+// does. Lists and string-keyed maps are converted in place.
+func NormalizeAll(value interface{}) interface{} {
+	switch v := Normalize(value).(type) {
+	case []interface{}:
+		for i, item := range v {
+			v[i] = NormalizeAll(item)
+		}
+		return v
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = NormalizeAll(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// String returns the string setting key, or defaultValue if it is not set.
+func String(settings map[string]interface{}, key, defaultValue string) (string, error) {
+	raw, ok := settings[key]
+	if !ok {
+		return defaultValue, nil
+	}
+	value, ok := raw.(string)
+	if !ok {
+		return "", fmt.Errorf("%s must be a string, got %T", key, raw)
+	}
+	return value, nil
+}
+
+// Bool returns the boolean setting key, or defaultValue if it is not set.
+func Bool(settings map[string]interface{}, key string, defaultValue bool) (bool, error) {
+	raw, ok := settings[key]
+	if !ok {
+		return defaultValue, nil
+	}
+	value, ok := raw.(bool)
+	if !ok {
+		return false, fmt.Errorf("%s must be a boolean, got %T", key, raw)
+	}
+	return value, nil
+}
+
+// Float returns the numeric setting key, which may also be given as a
+// string, or defaultValue if it is not set.
+func Float(settings map[string]interface{}, key string, defaultValue float64) (float64, error) {
+	raw, ok := settings[key]
+	if !ok {
+		return defaultValue, nil
+	}
+	if s, isString := raw.(string); isString {
+		value, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%s must be a number, got %q", key, s)
+		}
+		return value, nil
+	}
+	value, ok := Number(raw)
+	if !ok {
+		return 0, fmt.Errorf("%s must be a number, got %T", key, raw)
+	}
+	return value, nil
+}
+
+// Int returns the setting key, which must be a non-negative integer, or
+// defaultValue if it is not set.
+func Int(settings map[string]interface{}, key string, defaultValue int) (int, error) {
+	value, err := Float(settings, key, float64(defaultValue))
+	if err != nil {
+		return 0, err
+	}
+	if value != float64(int(value)) || value < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer, got %v", key, value)
+	}
+	return int(value), nil
+}
+
+// Floats returns the list of numbers setting key, or defaultValue if it is
+// not set.
+func Floats(settings map[string]interface{}, key string, defaultValue []float64) ([]float64, error) {
+	raw, ok := settings[key]
+	if !ok {
+		return defaultValue, nil
+	}
+	switch v := raw.(type) {
+	case []float64:
+		return v, nil
+	case []int:
+		values := make([]float64, len(v))
+		for i, item := range v {
+			values[i] = float64(item)
+		}
+		return values, nil
+	case []interface{}:
+		values := make([]float64, len(v))
+		for i, item := range v {
+			value, ok := Number(item)
+			if !ok {
+				return nil, fmt.Errorf("%s must be a list of numbers, got %T", key, item)
+			}
+			values[i] = value
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("%s must be a list of numbers, got %T", key, raw)
+}
+
+// Seconds returns the setting key, a whole number of seconds, as a duration,
+// or defaultValue if it is not set.
+func Seconds(settings map[string]interface{}, key string, defaultValue time.Duration) (time.Duration, error) {
+	if _, ok := settings[key]; !ok {
+		return defaultValue, nil
+	}
+	seconds, err := Int(settings, key, 0)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// Strings returns the list of strings setting key, or nil if it is not set.
+func Strings(settings map[string]interface{}, key string) ([]string, error) {
+	raw, ok := settings[key]
+	if !ok {
+		return nil, nil
+	}
+	switch v := raw.(type) {
+	case []string:
+		return v, nil
+	case []interface{}:
+		values := make([]string, len(v))
+		for i, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must be a list of strings, got %T", key, item)
+			}
+			values[i] = s
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("%s must be a list of strings, got %T", key, raw)
+}
+
+// StringMap returns the map setting key with its values formatted as
+// strings, or nil if it is not set.
+func StringMap(settings map[string]interface{}, key string) (map[string]string, error) {
+	raw, ok := settings[key]
+	if !ok {
+		return nil, nil
+	}
+	if v, ok := raw.(map[string]string); ok {
+		return v, nil
+	}
+	fields, ok := StringKeys(raw)
+	if !ok {
+		return nil, fmt.Errorf("%s must be a map, got %T", key, raw)
+	}
+	values := make(map[string]string, len(fields))
+	for name, value := range fields {
+		values[name] = fmt.Sprint(value)
+	}
+	return values, nil
+}
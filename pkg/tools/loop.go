@@ -0,0 +1,138 @@
+package tools
+
+import (
+	"beluga/pkg/llm"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// DefaultMaxIterations bounds the model round trips of a tool-call loop.
+const DefaultMaxIterations = 10
+
+// ErrMaxIterations is returned when the model still requests tools after the
+// last allowed iteration.
+var ErrMaxIterations = errors.New("tool-call loop reached its iteration limit")
+
+// Invocation records a tool call made during a loop.
+type Invocation struct {
+	Call   llm.ToolCall
+	Args   map[string]interface{}
+	Result interface{}
+	Err    error
+}
+
+// LoopResult is the outcome of a tool-call loop.
+type LoopResult struct {
+	// Response is the model's final response, which requested no tools.
+	Response *llm.Response
+	// Messages is the whole conversation, including tool calls and results.
+	Messages    []llm.Message
+	Invocations []Invocation
+	Iterations  int
+	// Usage totals the tokens of every model request in the loop.
+	Usage llm.Usage
+}
+
+// Loop lets a language model use the tools of a registry: it sends the tool
+// schemas with the conversation, invokes the tools the model calls and feeds
+// their results back until the model answers without calling a tool.
+type Loop struct {
+	LLM      llm.LLM
+	Registry *Registry
+	// MaxIterations bounds the model requests; DefaultMaxIterations if zero.
+	MaxIterations int
+	// OnInvocation, if set, is called after each tool invocation.
+	OnInvocation func(invocation Invocation)
+}
+
+// Run runs the loop starting from request. A tool that fails or receives
+// invalid arguments does not end the loop: the error is reported to the model
+// as the tool's result so that it can correct itself.
+func (l *Loop) Run(ctx context.Context, request llm.Request) (*LoopResult, error) {
+	if l.LLM == nil {
+		return nil, errors.New("tool-call loop requires an llm")
+	}
+	registry := l.Registry
+	if registry == nil {
+		registry = DefaultRegistry()
+	}
+	maxIterations := l.MaxIterations
+	if maxIterations <= 0 {
+		maxIterations = DefaultMaxIterations
+	}
+
+	request.Tools = registry.Definitions()
+	request.Messages = append([]llm.Message(nil), request.Messages...)
+	result := &LoopResult{}
+
+	for result.Iterations < maxIterations {
+		result.Iterations++
+		response, err := l.LLM.Chat(ctx, request)
+		if err != nil {
+			result.Messages = request.Messages
+			return result, fmt.Errorf("tool-call loop iteration %d: %w", result.Iterations, err)
+		}
+		result.Usage.Add(response.Usage)
+		result.Response = response
+		request.Messages = append(request.Messages, response.Message)
+
+		if len(response.Message.ToolCalls) == 0 {
+			result.Messages = request.Messages
+			return result, nil
+		}
+
+		for _, call := range response.Message.ToolCalls {
+			invocation := l.invoke(ctx, registry, call)
+			result.Invocations = append(result.Invocations, invocation)
+			if l.OnInvocation != nil {
+				l.OnInvocation(invocation)
+			}
+			request.Messages = append(request.Messages, llm.Message{
+				Role:       llm.RoleTool,
+				Content:    formatResult(invocation),
+				ToolCallID: call.ID,
+			})
+		}
+		if err := ctx.Err(); err != nil {
+			result.Messages = request.Messages
+			return result, err
+		}
+	}
+
+	result.Messages = request.Messages
+	return result, fmt.Errorf("%w (%d)", ErrMaxIterations, maxIterations)
+}
+
+func (l *Loop) invoke(ctx context.Context, registry *Registry, call llm.ToolCall) Invocation {
+	invocation := Invocation{Call: call}
+	args := map[string]interface{}{}
+	if call.Function.Arguments != "" {
+		if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
+			invocation.Err = fmt.Errorf("invalid arguments for tool %s: arguments are not a JSON object: %w", call.Function.Name, err)
+			return invocation
+		}
+	}
+	invocation.Args = args
+	invocation.Result, invocation.Err = registry.Invoke(ctx, call.Function.Name, args)
+	return invocation
+}
+
+// formatResult renders an invocation's result, or its error, for the model.
+func formatResult(invocation Invocation) string {
+	if invocation.Err != nil {
+		return "error: " + invocation.Err.Error()
+	}
+	switch v := invocation.Result.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	data, err := json.Marshal(invocation.Result)
+	if err != nil {
+		return fmt.Sprint(invocation.Result)
+	}
+	return string(data)
+}
@@ -0,0 +1,218 @@
+package tools
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+)
+
+// Schema is the subset of JSON Schema used to describe and validate tool
+// arguments. It marshals to standard JSON Schema.
+type Schema struct {
+	Type        string             `json:"type,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Enum        []interface{}      `json:"enum,omitempty"`
+	Minimum     *float64           `json:"minimum,omitempty"`
+	Maximum     *float64           `json:"maximum,omitempty"`
+	MinLength   *int               `json:"minLength,omitempty"`
+	MaxLength   *int               `json:"maxLength,omitempty"`
+	Pattern     string             `json:"pattern,omitempty"`
+	Default     interface{}        `json:"default,omitempty"`
+	// AdditionalProperties, if false, rejects object keys not in Properties.
+	AdditionalProperties *bool `json:"additionalProperties,omitempty"`
+}
+
+// Object returns an object schema with the given properties, all of those
+// named in required being mandatory.
+func Object(properties map[string]*Schema, required ...string) *Schema {
+	return &Schema{Type: "object", Properties: properties, Required: required}
+}
+
+// String returns a string schema.
+func String(description string) *Schema {
+	return &Schema{Type: "string", Description: description}
+}
+
+// Number returns a number schema.
+func Number(description string) *Schema {
+	return &Schema{Type: "number", Description: description}
+}
+
+// Integer returns an integer schema.
+func Integer(description string) *Schema {
+	return &Schema{Type: "integer", Description: description}
+}
+
+// Boolean returns a boolean schema.
+func Boolean(description string) *Schema {
+	return &Schema{Type: "boolean", Description: description}
+}
+
+// Array returns an array schema whose elements match items.
+func Array(description string, items *Schema) *Schema {
+	return &Schema{Type: "array", Description: description, Items: items}
+}
+
+// ValidationError reports an argument that does not match its schema.
+type ValidationError struct {
+	// Path locates the argument, such as "recipients[1]".
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// Validate checks value against the schema, returning a *ValidationError for
+// the first mismatch found. Values may be decoded JSON or ordinary Go values
+// such as typed slices, maps with string keys and any numeric type.
+func (s *Schema) Validate(value interface{}) error {
+	return s.validate("", value)
+}
+
+func (s *Schema) validate(path string, value interface{}) error {
+	if s == nil {
+		return nil
+	}
+	fail := func(format string, args ...interface{}) error {
+		return &ValidationError{Path: path, Message: fmt.Sprintf(format, args...)}
+	}
+
+	if len(s.Enum) > 0 {
+		allowed := false
+		for _, option := range s.Enum {
+			if equalValues(value, option) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fail("must be one of %v", s.Enum)
+		}
+	}
+
+	switch s.Type {
+	case "":
+		return nil
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fail("expected a string, got %s", describe(value))
+		}
+		length := len([]rune(str))
+		if s.MinLength != nil && length < *s.MinLength {
+			return fail("must be at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			return fail("must be at most %d characters", *s.MaxLength)
+		}
+		if s.Pattern != "" {
+			re, err := regexp.Compile(s.Pattern)
+			if err != nil {
+				return fail("invalid pattern %q: %v", s.Pattern, err)
+			}
+			if !re.MatchString(str) {
+				return fail("must match %q", s.Pattern)
+			}
+		}
+	case "number", "integer":
+		number, ok := toNumber(value)
+		if !ok {
+			return fail("expected a %s, got %s", s.Type, describe(value))
+		}
+		if s.Type == "integer" && number != math.Trunc(number) {
+			return fail("expected an integer, got %v", number)
+		}
+		if s.Minimum != nil && number < *s.Minimum {
+			return fail("must be at least %v", *s.Minimum)
+		}
+		if s.Maximum != nil && number > *s.Maximum {
+			return fail("must be at most %v", *s.Maximum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fail("expected a boolean, got %s", describe(value))
+		}
+	case "array":
+		v := reflect.ValueOf(value)
+		if value == nil || (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) {
+			return fail("expected an array, got %s", describe(value))
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), v.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+	case "object":
+		v := reflect.ValueOf(value)
+		if value == nil || v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+			return fail("expected an object, got %s", describe(value))
+		}
+		for _, name := range s.Required {
+			if !v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key())).IsValid() {
+				return &ValidationError{Path: join(path, name), Message: "is required"}
+			}
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, key := range keys {
+			property, known := s.Properties[key.String()]
+			if !known {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					return &ValidationError{Path: join(path, key.String()), Message: "is not allowed"}
+				}
+				continue
+			}
+			if err := property.validate(join(path, key.String()), v.MapIndex(key).Interface()); err != nil {
+				return err
+			}
+		}
+	default:
+		return fail("unsupported schema type %q", s.Type)
+	}
+	return nil
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func describe(value interface{}) string {
+	if value == nil {
+		return "null"
+	}
+	return reflect.TypeOf(value).String()
+}
+
+func toNumber(value interface{}) (float64, bool) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+func equalValues(a, b interface{}) bool {
+	if x, ok := toNumber(a); ok {
+		y, ok := toNumber(b)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
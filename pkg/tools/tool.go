@@ -0,0 +1,181 @@
+package tools
+
+import (
+	"beluga/pkg/llm"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// ErrUnknownTool is returned when invoking a tool that is not registered.
+var ErrUnknownTool = errors.New("unknown tool")
+
+// Tool is a capability an agent or a language model can invoke.
+type Tool interface {
+	// Name identifies the tool. It may contain letters, digits, '_' and '-'.
+	Name() string
+	// Description tells a language model what the tool does.
+	Description() string
+	// Parameters describes the arguments Invoke accepts. Nil accepts any
+	// arguments.
+	Parameters() *Schema
+	// Invoke runs the tool with arguments that match Parameters.
+	Invoke(ctx context.Context, args map[string]interface{}) (interface{}, error)
+}
+
+// FuncTool is a Tool implemented by a function.
+type FuncTool struct {
+	name        string
+	description string
+	parameters  *Schema
+	fn          func(ctx context.Context, args map[string]interface{}) (interface{}, error)
+}
+
+// NewTool creates a tool calling fn.
+func NewTool(name, description string, parameters *Schema, fn func(ctx context.Context, args map[string]interface{}) (interface{}, error)) *FuncTool {
+	return &FuncTool{name: name, description: description, parameters: parameters, fn: fn}
+}
+
+func (t *FuncTool) Name() string        { return t.name }
+func (t *FuncTool) Description() string { return t.description }
+func (t *FuncTool) Parameters() *Schema { return t.parameters }
+
+// Invoke calls the tool's function.
+func (t *FuncTool) Invoke(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	return t.fn(ctx, args)
+}
+
+var validName = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// Registry holds the tools available to an agent.
+type Registry struct {
+	mutex sync.RWMutex
+	tools map[string]Tool
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{tools: make(map[string]Tool)}
+}
+
+var defaultRegistry = NewRegistry()
+
+// DefaultRegistry returns the process-wide registry, from which agents are
+// given the tools their "tools" setting lists.
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// Register adds tools to the registry. It fails, registering none of them,
+// if a name is invalid or already taken.
+func (r *Registry) Register(tools ...Tool) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	seen := make(map[string]bool, len(tools))
+	for _, tool := range tools {
+		name := tool.Name()
+		if !validName.MatchString(name) {
+			return fmt.Errorf("invalid tool name %q", name)
+		}
+		if _, exists := r.tools[name]; exists || seen[name] {
+			return fmt.Errorf("tool %s is already registered", name)
+		}
+		seen[name] = true
+	}
+	for _, tool := range tools {
+		r.tools[tool.Name()] = tool
+	}
+	return nil
+}
+
+// MustRegister is like Register but panics on error. It suits registering
+// built-in tools at start-up.
+func (r *Registry) MustRegister(tools ...Tool) {
+	if err := r.Register(tools...); err != nil {
+		panic(err)
+	}
+}
+
+// Unregister removes the named tool.
+func (r *Registry) Unregister(name string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.tools, name)
+}
+
+// Get returns the named tool.
+func (r *Registry) Get(name string) (Tool, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	tool, ok := r.tools[name]
+	return tool, ok
+}
+
+// List returns the registered tools sorted by name.
+func (r *Registry) List() []Tool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	tools := make([]Tool, 0, len(r.tools))
+	for _, tool := range r.tools {
+		tools = append(tools, tool)
+	}
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name() < tools[j].Name() })
+	return tools
+}
+
+// Subset returns a registry holding the named tools of r.
+func (r *Registry) Subset(names ...string) (*Registry, error) {
+	subset := NewRegistry()
+	for _, name := range names {
+		tool, ok := r.Get(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownTool, name)
+		}
+		if err := subset.Register(tool); err != nil {
+			return nil, err
+		}
+	}
+	return subset, nil
+}
+
+// Definitions describes the registered tools for a language model request.
+func (r *Registry) Definitions() []llm.ToolDefinition {
+	tools := r.List()
+	definitions := make([]llm.ToolDefinition, len(tools))
+	for i, tool := range tools {
+		parameters := tool.Parameters()
+		if parameters == nil {
+			parameters = &Schema{Type: "object"}
+		}
+		definitions[i] = llm.ToolDefinition{
+			Name:        tool.Name(),
+			Description: tool.Description(),
+			Parameters:  parameters,
+		}
+	}
+	return definitions
+}
+
+// Invoke validates args against the named tool's parameters and invokes it.
+// Invalid arguments are reported as a *ValidationError.
+func (r *Registry) Invoke(ctx context.Context, name string, args map[string]interface{}) (interface{}, error) {
+	tool, ok := r.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTool, name)
+	}
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	if err := tool.Parameters().Validate(args); err != nil {
+		return nil, fmt.Errorf("invalid arguments for tool %s: %w", name, err)
+	}
+	result, err := tool.Invoke(ctx, args)
+	if err != nil {
+		return nil, fmt.Errorf("tool %s failed: %w", name, err)
+	}
+	return result, nil
+}